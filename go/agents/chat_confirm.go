@@ -3,7 +3,9 @@ package agents
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"sync"
 	"time"
@@ -11,77 +13,174 @@ import (
 	ckdb "career-koala/db"
 )
 
-type pendingWrite struct {
-	Payload ckdb.WritePayload
-	RawJSON string
-	Summary string
+// PendingWriteStore holds chat write requests awaiting confirmation, keyed by
+// user and session. *ckdb.PendingWriteStore is the Postgres implementation;
+// an in-memory store is used until SetPendingWriteStore is called.
+type PendingWriteStore interface {
+	Get(ctx context.Context, userID, sessionID string) (*ckdb.PendingWrite, error)
+	Put(ctx context.Context, pw ckdb.PendingWrite) error
+	Resolve(ctx context.Context, id int64, status string) (bool, error)
+	// Apply marks an open write applied and applies it on dbConn, so it is
+	// applied at most once. It returns ckdb.ErrPendingWriteResolved if the
+	// write was no longer open.
+	Apply(ctx context.Context, pw *ckdb.PendingWrite, dbConn *sql.DB) (string, error)
 }
 
-var pendingWrites = struct {
-	sync.Mutex
-	items map[string]pendingWrite
-}{
-	items: make(map[string]pendingWrite),
+const pendingWriteTTL = 30 * time.Minute
+
+var pendingStore PendingWriteStore = NewMemoryPendingWriteStore()
+
+// SetPendingWriteStore replaces the store used by HandlePendingWrite and
+// MaybeCaptureWrite.
+func SetPendingWriteStore(store PendingWriteStore) {
+	pendingStore = store
 }
 
 func HandlePendingWrite(ctx context.Context, userID, sessionID, message string, dbConn *sql.DB) (string, bool) {
-	key := pendingKey(userID, sessionID)
-	pendingWrites.Lock()
-	pending, ok := pendingWrites.items[key]
-	pendingWrites.Unlock()
-	if !ok {
+	pending, err := pendingStore.Get(ctx, userID, sessionID)
+	if err != nil {
+		log.Printf("pending write lookup user=%s session=%s: %v", userID, sessionID, err)
+		return "", false
+	}
+	if pending == nil {
 		return "", false
 	}
 
 	answer := strings.TrimSpace(strings.ToLower(message))
 	if isAffirmative(answer) {
 		if dbConn == nil {
-			pendingWrites.Lock()
-			delete(pendingWrites.items, key)
-			pendingWrites.Unlock()
+			resolvePending(ctx, pending, ckdb.PendingWriteFailed)
 			return "Failed to apply writes: database unavailable", true
 		}
 		wctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 		defer cancel()
-		result, err := ckdb.ApplyWriteRequests(wctx, dbConn, pending.Payload)
-		pendingWrites.Lock()
-		delete(pendingWrites.items, key)
-		pendingWrites.Unlock()
+		result, err := pendingStore.Apply(wctx, pending, dbConn)
+		if errors.Is(err, ckdb.ErrPendingWriteResolved) {
+			return "That write request was already handled.", true
+		}
 		if err != nil {
+			resolvePending(ctx, pending, ckdb.PendingWriteFailed)
 			return fmt.Sprintf("Failed to apply writes: %v", err), true
 		}
 		return fmt.Sprintf("Applied writes: %s", result), true
 	}
 	if isNegative(answer) {
-		pendingWrites.Lock()
-		delete(pendingWrites.items, key)
-		pendingWrites.Unlock()
+		resolvePending(ctx, pending, ckdb.PendingWriteDeclined)
 		return "Okay, skipping the write. Let me know how else I can help.", true
 	}
 
 	return fmt.Sprintf("Pending write request detected. Reply \"yes\" to apply or \"no\" to skip.\n\n%s", pending.RawJSON), true
 }
 
-func MaybeCaptureWrite(userID, sessionID string, replies []string) (string, bool) {
+func resolvePending(ctx context.Context, pending *ckdb.PendingWrite, status string) {
+	if _, err := pendingStore.Resolve(ctx, pending.ID, status); err != nil {
+		log.Printf("resolve pending write id=%d status=%s: %v", pending.ID, status, err)
+	}
+}
+
+func MaybeCaptureWrite(ctx context.Context, userID, sessionID string, replies []string, dbConn *sql.DB) (string, bool) {
 	for _, reply := range replies {
 		payload, rawJSON, summary, err := ckdb.ExtractWritePayload(reply)
 		if err != nil || payload == nil {
 			continue
 		}
-		key := pendingKey(userID, sessionID)
-		pendingWrites.Lock()
-		pendingWrites.items[key] = pendingWrite{
-			Payload: *payload,
-			RawJSON: rawJSON,
-			Summary: summary,
+		diff := ""
+		if dbConn != nil {
+			pctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			changes, err := ckdb.PreviewWriteRequests(pctx, dbConn, *payload)
+			cancel()
+			if err != nil {
+				return fmt.Sprintf("I couldn't prepare that write request: %v", err), true
+			}
+			diff = ckdb.FormatRowChanges(changes)
+		}
+		if err := pendingStore.Put(ctx, ckdb.PendingWrite{
+			UserID:    userID,
+			SessionID: sessionID,
+			Payload:   *payload,
+			RawJSON:   rawJSON,
+			Summary:   summary,
+			ExpiresAt: time.Now().Add(pendingWriteTTL),
+		}); err != nil {
+			log.Printf("store pending write user=%s session=%s: %v", userID, sessionID, err)
+			return "I couldn't save that write request for confirmation; please try again.", true
+		}
+		prompt := fmt.Sprintf("I can apply the following write request(s): %s\n\n", summary)
+		if diff != "" {
+			prompt += fmt.Sprintf("Affected rows:\n```\n%s\n```\n\n", diff)
 		}
-		pendingWrites.Unlock()
-		prompt := fmt.Sprintf("I can apply the following write request(s): %s\n\nReply \"yes\" to insert, or \"no\" to skip.\n\n```json\n%s\n```", summary, rawJSON)
+		prompt += fmt.Sprintf("Reply \"yes\" to apply, or \"no\" to skip.\n\n```json\n%s\n```", rawJSON)
 		return prompt, true
 	}
 	return "", false
 }
 
+type memoryPendingStore struct {
+	sync.Mutex
+	nextID int64
+	items  map[string]ckdb.PendingWrite
+}
+
+// NewMemoryPendingWriteStore returns a process-local store, suitable for tests
+// and single-replica development.
+func NewMemoryPendingWriteStore() PendingWriteStore {
+	return &memoryPendingStore{items: make(map[string]ckdb.PendingWrite)}
+}
+
+func (m *memoryPendingStore) Get(ctx context.Context, userID, sessionID string) (*ckdb.PendingWrite, error) {
+	m.Lock()
+	defer m.Unlock()
+	key := pendingKey(userID, sessionID)
+	pw, ok := m.items[key]
+	if !ok {
+		return nil, nil
+	}
+	if !pw.ExpiresAt.IsZero() && time.Now().After(pw.ExpiresAt) {
+		delete(m.items, key)
+		return nil, nil
+	}
+	return &pw, nil
+}
+
+func (m *memoryPendingStore) Put(ctx context.Context, pw ckdb.PendingWrite) error {
+	m.Lock()
+	defer m.Unlock()
+	m.nextID++
+	pw.ID = m.nextID
+	pw.Status = ckdb.PendingWriteOpen
+	pw.CreatedAt = time.Now()
+	m.items[pendingKey(pw.UserID, pw.SessionID)] = pw
+	return nil
+}
+
+func (m *memoryPendingStore) Resolve(ctx context.Context, id int64, status string) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.resolveLocked(id), nil
+}
+
+func (m *memoryPendingStore) resolveLocked(id int64) bool {
+	for key, pw := range m.items {
+		if pw.ID == id {
+			delete(m.items, key)
+			return true
+		}
+	}
+	return false
+}
+
+// Apply removes the write before applying it, so it applies at most once.
+// Unlike the Postgres store, a write that then fails to apply isn't kept.
+func (m *memoryPendingStore) Apply(ctx context.Context, pw *ckdb.PendingWrite, dbConn *sql.DB) (string, error) {
+	m.Lock()
+	claimed := m.resolveLocked(pw.ID)
+	m.Unlock()
+	if !claimed {
+		return "", ckdb.ErrPendingWriteResolved
+	}
+	return ckdb.ApplyWriteRequests(ctx, dbConn, pw.Payload)
+}
+
 func pendingKey(userID, sessionID string) string {
 	return userID + ":" + sessionID
 }
@@ -1,7 +1,9 @@
 package agents
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	ckdb "career-koala/db"
 	"google.golang.org/adk/agent"
@@ -15,11 +17,33 @@ func NewCodingAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
 	listCoding, err := functiontool.New(functiontool.Config{
 		Name:        "list_coding_problems",
 		Description: "List recent coding problems (limited set).",
-	}, func(ctx tool.Context, limit struct {
+	}, userTool(dbConn, func(ctx context.Context, limit struct {
 		Limit int `json:"limit"`
 	}) ([]ckdb.CodingProblem, error) {
 		return ckdb.ListRecentCoding(ctx, dbConn, limit.Limit)
-	})
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	dueReviews, err := functiontool.New(functiontool.Config{
+		Name:        "list_due_reviews",
+		Description: "List solved coding problems due for spaced-repetition review today, most overdue first.",
+	}, userTool(dbConn, func(ctx context.Context, limit struct {
+		Limit int `json:"limit"`
+	}) ([]ckdb.DueReview, error) {
+		return ckdb.ListDueReviews(ctx, dbConn, time.Now().UTC(), limit.Limit)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	patternReport, err := functiontool.New(functiontool.Config{
+		Name:        "coding_pattern_report",
+		Description: "Report solved/unsolved problems by pattern and difficulty, attempt success rate per pattern, and the least-practiced patterns.",
+	}, userTool(dbConn, func(ctx context.Context, _ struct{}) (ckdb.CodingPatternReport, error) {
+		return ckdb.GetCodingPatternReport(ctx, dbConn)
+	}))
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +52,7 @@ func NewCodingAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
 		Name:        "coding_agent",
 		Model:       m,
 		Description: "Specialist agent for coding practice and interview prep: LeetCode-style problems, CS fundamentals, and daily coding habits.",
-		Instruction: "You are the Coding Practice Agent.\n- Your responsibility is to help the user plan coding and interview prep using their DB history (the UI handles data entry).\n- Use the 'list_coding_problems' tool to fetch recent DB entries (if none exist, say so and give a short starter checklist).\n- Turn those entries into a structured plan, possibly with problem categories like arrays, graphs, or DP.\n- Encourage consistent, focused practice instead of huge unrealistic goals.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to add/update coding problems or goals, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"coding_problems\" | \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\",\n      \"records\": [\n        {\"leetcode_number\":0,\"title\":\"\",\"pattern\":\"\",\"problem_link\":\"\",\"difficulty\":\"\",\"already_solved\":false,\"notes\":\"\"}\n      ]\n    }\n  ]\n}\n- For goals, use fields: description, target_date|week_of|month_of (YYYY-MM-DD), completed (false), and link IDs (job_application_id, coding_problem_id, project_id, contact_id) as null if unknown.\n- Do NOT handle job applications, networking, or long-term project planning.",
-		Tools:       []tool.Tool{listCoding},
+		Instruction: "You are the Coding Practice Agent.\n- Your responsibility is to help the user plan coding and interview prep using their DB history (the UI handles data entry).\n- Use the 'list_coding_problems' tool to fetch recent DB entries (if none exist, say so and give a short starter checklist).\n- Use the 'coding_pattern_report' tool when asked about strengths, weak patterns or what to solve next; recommend problems from the least-practiced and lowest success-rate patterns.\n- Use the 'list_due_reviews' tool to find problems due for re-review and put those first in any practice plan.\n- Turn those entries into a structured plan, possibly with problem categories like arrays, graphs, or DP.\n- Encourage consistent, focused practice instead of huge unrealistic goals.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to add/update coding problems or goals, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"coding_problems\" | \"coding_attempts\" | \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\",\n      \"records\": [\n        {\"leetcode_number\":0,\"title\":\"\",\"pattern\":\"\",\"problem_link\":\"\",\"difficulty\":\"\",\"already_solved\":false,\"notes\":\"\"}\n      ]\n    }\n  ]\n}\n- To change or remove existing rows, use \"action\": \"update\" with records like {\"id\":7,\"set\":{\"already_solved\":true}} (or {\"match\":{\"leetcode_number\":42},\"set\":{\"already_solved\":true}} when the id is unknown), or \"action\": \"delete\" with {\"id\":...} or {\"match\":{...}}. Take ids from 'list_coding_problems' whenever possible.\n- To log a practice attempt, insert into coding_attempts with fields: coding_problem_id, attempted_on (YYYY-MM-DD), outcome (solved|hints|failed), minutes_spent, confidence (0-5), notes.\n- For goals, use fields: description, target_date|week_of|month_of (YYYY-MM-DD), completed (false), and link IDs (job_application_id, coding_problem_id, project_id, contact_id) as null if unknown.\n- Do NOT handle job applications, networking, or long-term project planning.",
+		Tools:       []tool.Tool{listCoding, dueReviews, patternReport},
 	})
 }
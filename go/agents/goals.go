@@ -0,0 +1,169 @@
+package agents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	ckdb "career-koala/db"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// goalRollover is the plan returned by plan_daily_goal_rollover: the
+// overdue goals and the write request that moves them, for the user to
+// confirm.
+type goalRollover struct {
+	Date     string            `json:"date"`
+	Overdue  []ckdb.Goal       `json:"overdue"`
+	Proposal ckdb.WritePayload `json:"proposal"`
+}
+
+type weeklyGoalPlan struct {
+	WeekOf      string                `json:"week_of"`
+	Suggestions []ckdb.GoalSuggestion `json:"suggestions"`
+}
+
+func NewGoalsAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
+	listGoals, err := functiontool.New(functiontool.Config{
+		Name:        "list_goals",
+		Description: "List goals of one period (daily, weekly or monthly), optionally in a date range (from/to as YYYY-MM-DD) and by status (open or done). Newest first. Countable goals include their metric and progress towards target_count.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		Period string `json:"period"`
+		From   string `json:"from"`
+		To     string `json:"to"`
+		Status string `json:"status"`
+		Limit  int    `json:"limit"`
+	}) ([]ckdb.Goal, error) {
+		filters := map[string]string{}
+		if args.From != "" {
+			filters["from"] = args.From
+		}
+		if args.To != "" {
+			filters["to"] = args.To
+		}
+		switch strings.ToLower(strings.TrimSpace(args.Status)) {
+		case "open", "incomplete", "pending":
+			filters["completed"] = "false"
+		case "done", "completed", "complete":
+			filters["completed"] = "true"
+		}
+		limit := args.Limit
+		if limit <= 0 || limit > 50 {
+			limit = 20
+		}
+		page, err := ckdb.ListGoalsPage(ctx, dbConn, goalPeriod(args.Period), ckdb.ListParams{Filters: filters, Sort: "-target_date", Limit: limit})
+		return page.Items, err
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := functiontool.New(functiontool.Config{
+		Name:        "goal_stats",
+		Description: "Completion rate and streaks for daily, weekly or monthly goals over the last N periods (default 30 days, 12 weeks or 6 months). A period counts towards a streak when all its goals were completed.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		Period  string `json:"period"`
+		Periods int    `json:"periods"`
+	}) (ckdb.GoalStats, error) {
+		return ckdb.GetGoalStats(ctx, dbConn, goalPeriod(args.Period), time.Now(), args.Periods)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	rollover, err := functiontool.New(functiontool.Config{
+		Name:        "plan_daily_goal_rollover",
+		Description: "Find incomplete daily goals dated before date (YYYY-MM-DD, default today) and build the write request that moves them to that date. Nothing is saved until the user confirms.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		Date string `json:"date"`
+	}) (goalRollover, error) {
+		date := time.Now().UTC().Truncate(24 * time.Hour)
+		if args.Date != "" {
+			var err error
+			if date, err = parseToolTime(args.Date, false); err != nil {
+				return goalRollover{}, err
+			}
+		}
+		overdue, err := ckdb.ListOverdueDailyGoals(ctx, dbConn, date)
+		if err != nil {
+			return goalRollover{}, err
+		}
+		plan := goalRollover{Date: date.Format("2006-01-02"), Overdue: overdue}
+		req := ckdb.WriteRequest{Action: "update", Table: "daily_goals"}
+		for _, g := range overdue {
+			req.Records = append(req.Records, map[string]interface{}{"id": g.ID, "set": map[string]interface{}{"target_date": plan.Date}})
+		}
+		if len(req.Records) > 0 {
+			plan.Proposal.WriteRequests = []ckdb.WriteRequest{req}
+		}
+		return plan, nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	suggest, err := functiontool.New(functiontool.Config{
+		Name:        "suggest_weekly_goals",
+		Description: "Propose goals for a week (week_of YYYY-MM-DD, default next week) from linked records: interviews that week, stale applications, coding reviews due, the least practiced pattern, active projects and contacts not met lately. Each suggestion carries its link ids and a reason.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		WeekOf string `json:"week_of"`
+	}) (weeklyGoalPlan, error) {
+		weekOf := time.Now().UTC().AddDate(0, 0, 7)
+		if args.WeekOf != "" {
+			var err error
+			if weekOf, err = parseToolTime(args.WeekOf, false); err != nil {
+				return weeklyGoalPlan{}, err
+			}
+		}
+		suggestions, err := ckdb.SuggestWeeklyGoals(ctx, dbConn, weekOf)
+		if err != nil {
+			return weeklyGoalPlan{}, fmt.Errorf("suggest weekly goals: %w", err)
+		}
+		plan := weeklyGoalPlan{Suggestions: suggestions}
+		if len(suggestions) > 0 {
+			plan.WeekOf = suggestions[0].TargetDate.Format("2006-01-02")
+		}
+		return plan, nil
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	recurrences, err := functiontool.New(functiontool.Config{
+		Name:        "list_goal_recurrences",
+		Description: "List the recurring goal rules (e.g. 'solve 2 problems every weekday') with their schedule, target count and whether they are active. Their instances appear in list_goals with a recurrence_id.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		ActiveOnly bool `json:"active_only"`
+	}) ([]ckdb.GoalRecurrence, error) {
+		return ckdb.ListGoalRecurrences(ctx, dbConn, args.ActiveOnly)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return llmagent.New(llmagent.Config{
+		Name:        "goals_agent",
+		Model:       m,
+		Description: "Specialist for daily, weekly and monthly goals: reviewing progress and streaks, recurring goals, carrying over unfinished goals and planning next week.",
+		Instruction: "You are the Goals Agent.\n- Your responsibility is to help the user plan and review their daily, weekly and monthly goals using their DB history (the UI handles data entry).\n- Use the 'list_goals' tool to see goals for a period (status open or done), and 'goal_stats' when the user asks how they are doing; report completion rate and streaks plainly and point out the weakest stretch.\n- When the user wants to carry unfinished daily goals forward, call 'plan_daily_goal_rollover' and return its 'proposal' unchanged as the JSON write suggestion (if it is empty, say nothing is overdue). Goals from a recurring rule are never rolled over; a new one is created each period.\n- Use 'list_goal_recurrences' to see the recurring rules. When the user wants a repeating goal (\"solve 2 problems every weekday\", \"message 3 contacts each week\"), propose a goal_recurrences insert with {\"rule\":\"solve 2 problems every weekday\",\"starts_on\":\"YYYY-MM-DD\",\"coding_problem_id\":null} instead of one goal per day; to pause a rule, update it with {\"id\":2,\"set\":{\"active\":false}}.\n- Countable goals (\"apply to 5 jobs this week\", \"solve 3 DP problems today\") track themselves: propose a goals insert with {\"rule\":\"apply to 5 jobs this week\"} (rules work for goal_recurrences too). list_goals then shows their metric and progress, and they complete on their own once progress reaches target_count, as do goals linked to a problem that gets solved, an application that moves stage or a contact that is met; do not propose marking those complete.\n- When planning next week, call 'suggest_weekly_goals', keep the 3-6 most useful suggestions (mention the reason for each), and propose them as weekly_goals inserts with their week_of and link ids.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to add/update goals, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\" | \"goals\" | \"goal_recurrences\",\n      \"records\": [\n        {\"description\":\"\",\"week_of\":\"YYYY-MM-DD\",\"completed\":false,\"job_application_id\":null,\"coding_problem_id\":null,\"project_id\":null,\"contact_id\":null}\n      ]\n    }\n  ]\n}\n- Use target_date for daily_goals, week_of (a Monday) for weekly_goals and month_of (the first of the month) for monthly_goals.\n- To change or remove existing goals, use \"action\": \"update\" with records like {\"id\":9,\"set\":{\"completed\":true}} or \"action\": \"delete\" with {\"id\":...}. Take ids from 'list_goals' whenever possible.\n- Do NOT do the underlying work (job applications, coding, projects, networking); those belong to other agents.",
+		Tools:       []tool.Tool{listGoals, stats, rollover, suggest, recurrences},
+	})
+}
+
+// goalPeriod maps the tool's period argument to a goal type, defaulting to
+// daily.
+func goalPeriod(period string) string {
+	switch strings.ToLower(strings.TrimSpace(period)) {
+	case "weekly", "week":
+		return "weekly"
+	case "monthly", "month":
+		return "monthly"
+	}
+	return "daily"
+}
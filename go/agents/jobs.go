@@ -1,7 +1,9 @@
 package agents
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	ckdb "career-koala/db"
 	"google.golang.org/adk/agent"
@@ -15,11 +17,37 @@ func NewJobAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
 	listJobs, err := functiontool.New(functiontool.Config{
 		Name:        "list_job_applications",
 		Description: "List recent job applications (limited set).",
-	}, func(ctx tool.Context, limit struct {
+	}, userTool(dbConn, func(ctx context.Context, limit struct {
 		Limit int `json:"limit"`
 	}) ([]ckdb.JobApplication, error) {
 		return ckdb.ListRecentJobs(ctx, dbConn, limit.Limit)
-	})
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	funnel, err := functiontool.New(functiontool.Config{
+		Name:        "job_funnel_summary",
+		Description: "Summarize the job search funnel: applications per week, stage conversion rates, median days to response, response rate by company and stale applications.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		Weeks     int `json:"weeks"`
+		StaleDays int `json:"stale_days"`
+	}) (ckdb.JobAnalytics, error) {
+		return ckdb.GetJobAnalytics(ctx, dbConn, ckdb.JobAnalyticsOptions{Weeks: args.Weeks, StaleDays: args.StaleDays})
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	interviews, err := functiontool.New(functiontool.Config{
+		Name:        "list_upcoming_interviews",
+		Description: "List upcoming meetings (interviews, screens, onsites) linked to job applications, soonest first. Pass job_application_id to see one application only.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		JobApplicationID int64 `json:"job_application_id"`
+		Limit            int   `json:"limit"`
+	}) ([]ckdb.Interview, error) {
+		return ckdb.ListUpcomingInterviews(ctx, dbConn, args.JobApplicationID, time.Now(), args.Limit)
+	}))
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +56,7 @@ func NewJobAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
 		Name:        "job_applications_agent",
 		Model:       m,
 		Description: "Specialist agent that focuses ONLY on job search and applications: resume/cover letter tweaks, tailoring to job descriptions, and creating small daily application tasks.",
-		Instruction: "You are the Job Applications Agent.\n- Your responsibility is to help the user make progress on job search tasks using their DB history (the UI handles data entry).\n- Use the 'list_job_applications' tool to fetch recent DB entries (if none exist, say so and give a short starter checklist).\n- Turn those entries into a short, realistic plan for today.\n- Give specific suggestions (for example which type of role/company to target), but keep things achievable.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to add/update job applications or goals, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"job_applications\" | \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\",\n      \"records\": [\n        {\"job_title\":\"\",\"company\":\"\",\"job_link\":\"\",\"applied_date\":\"YYYY-MM-DD\",\"result_date\":null,\"status\":\"applied\",\"notes\":\"\"}\n      ]\n    }\n  ]\n}\n- For goals, use fields: description, target_date|week_of|month_of (YYYY-MM-DD), completed (false), and link IDs (job_application_id, coding_problem_id, project_id, contact_id) as null if unknown.\n- Do NOT handle coding practice, networking, or project planning; those belong to other agents.",
-		Tools:       []tool.Tool{listJobs},
+		Instruction: "You are the Job Applications Agent.\n- Your responsibility is to help the user make progress on job search tasks using their DB history (the UI handles data entry).\n- Use the 'list_job_applications' tool to fetch recent DB entries (if none exist, say so and give a short starter checklist).\n- Use the 'list_upcoming_interviews' tool when the user asks about upcoming interviews or how to prepare for one application (pass its job_application_id).\n- Use the 'job_funnel_summary' tool when the user asks how their search is going or what to focus on; base advice on its numbers (response rate, conversion between stages, stale applications to follow up on).\n- Turn those entries into a short, realistic plan for today.\n- Give specific suggestions (for example which type of role/company to target), but keep things achievable.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to add/update job applications or goals, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"job_applications\" | \"meetings\" | \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\",\n      \"records\": [\n        {\"job_title\":\"\",\"company\":\"\",\"job_link\":\"\",\"applied_date\":\"YYYY-MM-DD\",\"result_date\":null,\"status\":\"applied\",\"notes\":\"\"}\n      ]\n    }\n  ]\n}\n- To change or remove existing rows, use \"action\": \"update\" with records like {\"id\":12,\"set\":{\"status\":\"rejected\"}} (or {\"match\":{\"company\":\"Stripe\"},\"set\":{\"status\":\"rejected\"}} when the id is unknown), or \"action\": \"delete\" with {\"id\":...} or {\"match\":{...}}. Take ids from 'list_job_applications' whenever possible.\n- status must be one of: saved, applied, recruiter_screen, technical, onsite, offer, accepted, rejected, withdrawn, ghosted. Applications only move forward through the pipeline; accepted, rejected and withdrawn are final.\n- To schedule an interview, insert into meetings with fields: session_name, session_type (virtual|in-person), session_time (RFC 3339), location, organizer, company, notes, job_application_id (the application it belongs to) and contact_id (the interviewer, if known).\n- For goals, use fields: description, target_date|week_of|month_of (YYYY-MM-DD), completed (false), and link IDs (job_application_id, coding_problem_id, project_id, contact_id) as null if unknown.\n- Do NOT handle coding practice, networking, or project planning; those belong to other agents.",
+		Tools:       []tool.Tool{listJobs, funnel, interviews},
 	})
 }
@@ -0,0 +1,115 @@
+package agents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	ckdb "career-koala/db"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// defaultMeetingWindow is the range list_meetings covers when no dates are
+// given: the next (or, for past meetings, the last) two weeks.
+const defaultMeetingWindow = 14 * 24 * time.Hour
+
+// meetingList echoes the resolved range and the current time so the model
+// can reason about "tomorrow" or "this week".
+type meetingList struct {
+	Now      time.Time      `json:"now"`
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Meetings []ckdb.Meeting `json:"meetings"`
+}
+
+func NewMeetingsAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
+	listMeetings, err := functiontool.New(functiontool.Config{
+		Name:        "list_meetings",
+		Description: "List meetings in a date range. from/to are YYYY-MM-DD (or RFC 3339); without them, lists the next 14 days, or the last 14 days when past is true. Upcoming meetings are soonest first, past meetings most recent first.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		From  string `json:"from"`
+		To    string `json:"to"`
+		Past  bool   `json:"past"`
+		Limit int    `json:"limit"`
+	}) (meetingList, error) {
+		now := time.Now().UTC()
+		from, to := now, now.Add(defaultMeetingWindow)
+		if args.Past {
+			from, to = now.Add(-defaultMeetingWindow), now
+		}
+		var err error
+		if args.From != "" {
+			if from, err = parseToolTime(args.From, false); err != nil {
+				return meetingList{}, err
+			}
+		}
+		if args.To != "" {
+			if to, err = parseToolTime(args.To, true); err != nil {
+				return meetingList{}, err
+			}
+		}
+		sort := "session_time"
+		if args.Past {
+			sort = "-session_time"
+		}
+		limit := args.Limit
+		if limit <= 0 || limit > 50 {
+			limit = 20
+		}
+		page, err := ckdb.ListMeetingsPage(ctx, dbConn, ckdb.ListParams{
+			Filters: map[string]string{"from": from.Format(time.RFC3339), "to": to.Format(time.RFC3339)},
+			Sort:    sort,
+			Limit:   limit,
+		})
+		return meetingList{Now: now, From: from, To: to, Meetings: page.Items}, err
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	prep, err := functiontool.New(functiontool.Config{
+		Name:        "meeting_prep",
+		Description: "Gather prep material for one meeting: the linked job application and its status history, notes on the contacts attending, and earlier meetings with the same application, people or company.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		MeetingID int64 `json:"meeting_id"`
+	}) (ckdb.MeetingPrep, error) {
+		if args.MeetingID <= 0 {
+			return ckdb.MeetingPrep{}, fmt.Errorf("meeting_id is required; take it from list_meetings")
+		}
+		return ckdb.GetMeetingPrep(ctx, dbConn, args.MeetingID)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return llmagent.New(llmagent.Config{
+		Name:        "meetings_agent",
+		Model:       m,
+		Description: "Specialist for the user's calendar: upcoming and past meetings, interview and coffee chat prep, and scheduling new meetings.",
+		Instruction: "You are the Meetings Agent.\n- Your responsibility is to help the user stay on top of their meetings (interviews, recruiter calls, coffee chats, meetups) using their DB history (the UI handles data entry).\n- Use the 'list_meetings' tool to see what is coming up or what happened in a date range; its 'now' field is the current time, so resolve 'tomorrow' or 'this week' against it and pass from/to as YYYY-MM-DD.\n- Use the 'meeting_prep' tool before giving prep advice for a specific meeting; build a short checklist from the linked application's stage, the contacts' notes and what came up in earlier meetings.\n- If there are no meetings, say so and suggest one or two worth scheduling.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to schedule or log a meeting, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"meetings\" | \"meeting_contacts\" | \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\",\n      \"records\": [\n        {\"session_name\":\"\",\"session_type\":\"virtual\",\"session_time\":\"YYYY-MM-DDTHH:MM:SSZ\",\"location\":\"\",\"organizer\":\"\",\"company\":\"\",\"notes\":\"\",\"job_application_id\":null,\"contact_id\":null,\"contact_ids\":[]}\n      ]\n    }\n  ]\n}\n- session_type must be virtual or in-person; session_time is RFC 3339 with a time zone offset. Link job_application_id for interviews and contact_id for the main person met when known; other attendees go in contact_ids.\n- To change or remove existing meetings, use \"action\": \"update\" with records like {\"id\":4,\"set\":{\"session_time\":\"2026-11-02T15:00:00Z\"}} or \"action\": \"delete\" with {\"id\":...}. Take ids from 'list_meetings' whenever possible.\n- For goals, use fields: description, target_date|week_of|month_of (YYYY-MM-DD), completed (false), and link IDs (job_application_id, coding_problem_id, project_id, contact_id) as null if unknown.\n- Do NOT tailor resumes, plan coding practice or projects; those belong to other agents.",
+		Tools:       []tool.Tool{listMeetings, prep},
+	})
+}
+
+// parseToolTime accepts a date or an RFC 3339 timestamp from a tool call. A
+// bare date used as the end of a range covers the whole day.
+func parseToolTime(raw string, endOfDay bool) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", raw)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
@@ -1,7 +1,9 @@
 package agents
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	ckdb "career-koala/db"
 	"google.golang.org/adk/agent"
@@ -15,11 +17,25 @@ func NewNetworkingAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
 	listContacts, err := functiontool.New(functiontool.Config{
 		Name:        "list_contacts",
 		Description: "List recent networking contacts (limited set).",
-	}, func(ctx tool.Context, limit struct {
+	}, userTool(dbConn, func(ctx context.Context, limit struct {
 		Limit int `json:"limit"`
 	}) ([]ckdb.NetworkingContact, error) {
 		return ckdb.ListRecentContacts(ctx, dbConn, limit.Limit)
-	})
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	meetings, err := functiontool.New(functiontool.Config{
+		Name:        "contact_meeting_history",
+		Description: "For each contact: how many times the user met them, the last meeting and the next scheduled one. Contacts not seen the longest come first. Filter by contact_id or name.",
+	}, userTool(dbConn, func(ctx context.Context, args struct {
+		ContactID int64  `json:"contact_id"`
+		Name      string `json:"name"`
+		Limit     int    `json:"limit"`
+	}) ([]ckdb.ContactMeetings, error) {
+		return ckdb.ListContactMeetings(ctx, dbConn, args.ContactID, args.Name, time.Now(), args.Limit)
+	}))
 	if err != nil {
 		return nil, err
 	}
@@ -28,7 +44,7 @@ func NewNetworkingAgent(m model.LLM, dbConn *sql.DB) (agent.Agent, error) {
 		Name:        "networking_agent",
 		Model:       m,
 		Description: "Specialist agent for networking and relationship building: LinkedIn outreach, recruiter follow-ups, and engagement on posts.",
-		Instruction: "You are the Networking Agent.\n- Your responsibility is to help the user build and maintain professional relationships using their DB history (the UI handles data entry).\n- Use the 'list_contacts' tool to fetch recent DB entries (if none exist, say so and give a short starter plan).\n- Turn those into a small set of concrete, non-spammy actions for today.\n- Help the user think of what to say in a personalized, respectful way.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to add/update contacts or goals, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"networking_contacts\" | \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\",\n      \"records\": [\n        {\"person_name\":\"\",\"how_met\":\"\",\"linkedin_connected\":false,\"company\":\"\",\"position\":\"\",\"notes\":\"\"}\n      ]\n    }\n  ]\n}\n- For goals, use fields: description, target_date|week_of|month_of (YYYY-MM-DD), completed (false), and link IDs (job_application_id, coding_problem_id, project_id, contact_id) as null if unknown.\n- Do NOT handle coding practice, deep project work, or resume tailoring.",
-		Tools:       []tool.Tool{listContacts},
+		Instruction: "You are the Networking Agent.\n- Your responsibility is to help the user build and maintain professional relationships using their DB history (the UI handles data entry).\n- Use the 'list_contacts' tool to fetch recent DB entries (if none exist, say so and give a short starter plan).\n- Use the 'contact_meeting_history' tool when the user asks when they last met someone or who they have not talked to in a while; suggest follow-ups based on the last meeting.\n- Turn those into a small set of concrete, non-spammy actions for today.\n- Help the user think of what to say in a personalized, respectful way.\n- Read-only: do NOT write to the database or request data entry.\n- If the user asks to add/update contacts or goals, return ONLY a JSON write suggestion in a fenced code block using this schema:\n{\n  \"write_requests\": [\n    {\n      \"action\": \"insert\",\n      \"table\": \"networking_contacts\" | \"meetings\" | \"meeting_contacts\" | \"daily_goals\" | \"weekly_goals\" | \"monthly_goals\",\n      \"records\": [\n        {\"person_name\":\"\",\"how_met\":\"\",\"linkedin_connected\":false,\"company\":\"\",\"position\":\"\",\"notes\":\"\"}\n      ]\n    }\n  ]\n}\n- To change or remove existing rows, use \"action\": \"update\" with records like {\"id\":5,\"set\":{\"linkedin_connected\":true}} (or {\"match\":{\"person_name\":\"Jane Doe\"},\"set\":{\"linkedin_connected\":true}} when the id is unknown), or \"action\": \"delete\" with {\"id\":...} or {\"match\":{...}}. Take ids from 'list_contacts' whenever possible.\n- To log a coffee chat or call, insert into meetings with fields: session_name, session_type (virtual|in-person), session_time (RFC 3339), location, company, notes, contact_id (the person met) and contact_ids (other attendees). To add an attendee to an existing meeting, insert into meeting_contacts with meeting_id and contact_id.\n- For goals, use fields: description, target_date|week_of|month_of (YYYY-MM-DD), completed (false), and link IDs (job_application_id, coding_problem_id, project_id, contact_id) as null if unknown.\n- Do NOT handle coding practice, deep project work, or resume tailoring.",
+		Tools:       []tool.Tool{listContacts, meetings},
 	})
 }
@@ -0,0 +1,305 @@
+// Package router picks the specialist agent a chat message is about from a
+// declarative rule set of weighted keywords and patterns. Messages the rules
+// can't place with enough confidence are left to the LLM root agent.
+package router
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules.yaml
+var defaultRules []byte
+
+// Rules is a rule set, as written in YAML.
+type Rules struct {
+	// Threshold is the confidence the best candidate needs to be routed to;
+	// below it the message goes to the LLM root agent without a hint.
+	// Defaults to 0.5.
+	Threshold float64 `yaml:"threshold"`
+	// Fallthrough is the score set aside for "none of the agents", so a
+	// single weak keyword isn't enough on its own. Defaults to 1.
+	Fallthrough float64     `yaml:"fallthrough"`
+	Agents      []AgentRule `yaml:"agents"`
+}
+
+// AgentRule says when a message is for one agent.
+type AgentRule struct {
+	// Name is the agent's hint name (jobs, coding, ...). Option and Label
+	// make up the "Option 1 (Jobs): " hint the root agent transfers on.
+	Name   string `yaml:"name"`
+	Option int    `yaml:"option"`
+	Label  string `yaml:"label"`
+	// Replies are whole messages that pick the agent outright, like "1" or
+	// "jobs" in answer to the options menu.
+	Replies []string `yaml:"replies"`
+	// Match signals add their weight to the agent's score, Not signals
+	// subtract theirs.
+	Match []Signal `yaml:"match"`
+	Not   []Signal `yaml:"not"`
+}
+
+// Signal is a keyword or a regular expression and the weight it carries.
+// Keywords match whole words ignoring case, a plural s/es and the spacing or
+// hyphens between the words of a phrase; patterns are RE2, also matched
+// ignoring case. Each signal counts once however often it matches.
+type Signal struct {
+	Keyword string  `yaml:"keyword"`
+	Pattern string  `yaml:"pattern"`
+	Weight  float64 `yaml:"weight"` // default 1
+}
+
+// Candidate is an agent a message scored for.
+type Candidate struct {
+	Agent  string `json:"agent"`
+	Option int    `json:"option"`
+	Label  string `json:"label"`
+	// Score is the matched weights less the negative ones; a menu reply
+	// scores 1.
+	Score float64 `json:"score"`
+	// Confidence is the candidate's share of the total score, the
+	// fallthrough included, from 0 to 1.
+	Confidence float64 `json:"confidence"`
+	// Matched lists the signals that matched, negative ones prefixed "-".
+	Matched []string `json:"matched"`
+}
+
+// Hint prefixes msg with the candidate's "Option N (Label): " hint.
+func (c Candidate) Hint(msg string) string {
+	return fmt.Sprintf("Option %d (%s): %s", c.Option, c.Label, msg)
+}
+
+// Router scores messages against a compiled rule set. It is safe for
+// concurrent use.
+type Router struct {
+	threshold float64
+	fallback  float64
+	agents    []agentRule
+}
+
+type agentRule struct {
+	AgentRule
+	replies map[string]bool
+	match   []signal
+	not     []signal
+}
+
+type signal struct {
+	name   string
+	re     *regexp.Regexp
+	weight float64
+}
+
+// explicitHint matches messages that already name an agent.
+var explicitHint = regexp.MustCompile(`(?i)\[agent:|\boption \d`)
+
+// New validates and compiles rules.
+func New(rules Rules) (*Router, error) {
+	r := &Router{threshold: rules.Threshold, fallback: rules.Fallthrough}
+	if r.threshold == 0 {
+		r.threshold = 0.5
+	}
+	if r.threshold < 0 || r.threshold > 1 {
+		return nil, fmt.Errorf("threshold %v is not between 0 and 1", rules.Threshold)
+	}
+	if r.fallback == 0 {
+		r.fallback = 1
+	}
+	if r.fallback < 0 {
+		return nil, fmt.Errorf("fallthrough %v is negative", rules.Fallthrough)
+	}
+	if len(rules.Agents) == 0 {
+		return nil, errors.New("no agents")
+	}
+
+	names := map[string]bool{}
+	options := map[int]bool{}
+	replies := map[string]string{}
+	for _, a := range rules.Agents {
+		if a.Name == "" {
+			return nil, errors.New("agent without a name")
+		}
+		if names[a.Name] {
+			return nil, fmt.Errorf("agent %s: listed twice", a.Name)
+		}
+		names[a.Name] = true
+		if a.Option <= 0 || options[a.Option] {
+			return nil, fmt.Errorf("agent %s: option must be a positive number no other agent uses", a.Name)
+		}
+		options[a.Option] = true
+		if a.Label == "" {
+			return nil, fmt.Errorf("agent %s: label is required", a.Name)
+		}
+
+		rule := agentRule{AgentRule: a, replies: map[string]bool{}}
+		for _, reply := range a.Replies {
+			reply = normalize(reply)
+			if other, ok := replies[reply]; ok && other != a.Name {
+				return nil, fmt.Errorf("agent %s: reply %q already picks %s", a.Name, reply, other)
+			}
+			replies[reply] = a.Name
+			rule.replies[reply] = true
+		}
+		var err error
+		if rule.match, err = compileSignals(a.Match); err != nil {
+			return nil, fmt.Errorf("agent %s: match: %w", a.Name, err)
+		}
+		if rule.not, err = compileSignals(a.Not); err != nil {
+			return nil, fmt.Errorf("agent %s: not: %w", a.Name, err)
+		}
+		r.agents = append(r.agents, rule)
+	}
+	return r, nil
+}
+
+func compileSignals(signals []Signal) ([]signal, error) {
+	out := make([]signal, 0, len(signals))
+	for _, s := range signals {
+		if (s.Keyword == "") == (s.Pattern == "") {
+			return nil, errors.New("each signal needs exactly one of keyword and pattern")
+		}
+		if s.Weight < 0 {
+			return nil, fmt.Errorf("%s%s: weight %v is negative", s.Keyword, s.Pattern, s.Weight)
+		}
+		weight := s.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		name, expr := s.Pattern, "(?i)"+s.Pattern
+		if s.Keyword != "" {
+			words := strings.Fields(s.Keyword)
+			if len(words) == 0 {
+				return nil, fmt.Errorf("keyword %q is blank", s.Keyword)
+			}
+			for i, w := range words {
+				words[i] = regexp.QuoteMeta(w)
+			}
+			name, expr = s.Keyword, `(?i)\b`+strings.Join(words, `[\s-]+`)+`(?:s|es)?\b`
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %q: %w", s.Pattern, err)
+		}
+		out = append(out, signal{name: name, re: re, weight: weight})
+	}
+	return out, nil
+}
+
+// Parse reads a YAML rule set. Unknown fields are errors, so a misspelled
+// key doesn't silently drop a rule.
+func Parse(data []byte) (*Router, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var rules Rules
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	return New(rules)
+}
+
+// LoadFile reads a YAML rule set from path.
+func LoadFile(path string) (*Router, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Default is the built-in rule set (rules.yaml in this package).
+func Default() *Router {
+	r, err := Parse(defaultRules)
+	if err != nil {
+		panic("router: built-in rules: " + err.Error())
+	}
+	return r
+}
+
+// FromEnv loads the rule set named by AGENT_ROUTES_FILE, or the built-in
+// one when it's unset.
+func FromEnv(getenv func(string) string) (*Router, error) {
+	if path := strings.TrimSpace(getenv("AGENT_ROUTES_FILE")); path != "" {
+		return LoadFile(path)
+	}
+	return Default(), nil
+}
+
+// Score ranks the agents msg scored above zero for, best first; agents
+// with equal scores keep their order in the rule set. A message that is a
+// menu reply has that one candidate, at confidence 1.
+func (r *Router) Score(msg string) []Candidate {
+	trim := normalize(msg)
+	for _, a := range r.agents {
+		if a.replies[trim] {
+			c := a.candidate(1, []string{"reply " + trim})
+			c.Confidence = 1
+			return []Candidate{c}
+		}
+	}
+
+	var out []Candidate
+	total := r.fallback
+	for _, a := range r.agents {
+		var score float64
+		var matched []string
+		for _, s := range a.match {
+			if s.re.MatchString(msg) {
+				score += s.weight
+				matched = append(matched, s.name)
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		for _, s := range a.not {
+			if s.re.MatchString(msg) {
+				score -= s.weight
+				matched = append(matched, "-"+s.name)
+			}
+		}
+		if score <= 0 {
+			continue
+		}
+		total += score
+		out = append(out, a.candidate(score, matched))
+	}
+	for i := range out {
+		out[i].Confidence = out[i].Score / total
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// Route returns the best candidate for msg, or false when msg already names
+// an agent ("[agent:jobs]", "Option 2") or no candidate reaches the
+// threshold, so the LLM root agent should decide.
+func (r *Router) Route(msg string) (Candidate, bool) {
+	if explicitHint.MatchString(msg) {
+		return Candidate{}, false
+	}
+	candidates := r.Score(msg)
+	if len(candidates) == 0 || candidates[0].Confidence < r.threshold {
+		return Candidate{}, false
+	}
+	return candidates[0], true
+}
+
+func (a agentRule) candidate(score float64, matched []string) Candidate {
+	return Candidate{Agent: a.Name, Option: a.Option, Label: a.Label, Score: score, Matched: matched}
+}
+
+func normalize(msg string) string {
+	return strings.Join(strings.Fields(strings.ToLower(msg)), " ")
+}
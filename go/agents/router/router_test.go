@@ -0,0 +1,161 @@
+package router
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDefaultRoutes is the routing corpus: messages and the agent the
+// built-in rules should hint, or "" when the LLM root agent should decide.
+func TestDefaultRoutes(t *testing.T) {
+	r := Default()
+	cases := []struct {
+		msg   string
+		agent string
+	}{
+		// Menu replies.
+		{"1", "jobs"},
+		{"  Coding ", "coding"},
+		{"problem", "coding"},
+		{"calendar", "meetings"},
+		{"6", "goals"},
+
+		// Jobs.
+		{"what's the status of my Stripe application?", "jobs"},
+		{"list the jobs I applied to last week", "jobs"},
+		{"I got an offer from Datadog, how should I negotiate salary?", "jobs"},
+		{"tailor my resume and cover letter for the Figma role", "jobs"},
+
+		// Coding.
+		{"give me a medium leetcode problem on graphs", "coding"},
+		{"I solved LC 146 in 25 minutes", "coding"},
+		{"what's in my review queue today?", "coding"},
+		{"set up a mock interview on dynamic programming", "coding"},
+
+		// Projects.
+		{"add my resume builder project to my portfolio", "projects"},
+		{"update the README for my GitHub repo", "projects"},
+
+		// Networking.
+		{"follow up with the recruiter contact about my application problem", "networking"},
+		{"who in my network haven't I talked to in a while?", "networking"},
+		{"draft a LinkedIn message asking Maya for a referral", "networking"},
+		{"remind me to reconnect with my old mentor", "networking"},
+
+		// Meetings.
+		{"prep for my interview for the Stripe application", "meetings"},
+		{"what's on my calendar tomorrow?", "meetings"},
+		{"prepare me for tomorrow's coffee chat with Priya", "meetings"},
+		{"reschedule the meeting with Sam", "meetings"},
+
+		// Goals.
+		{"set a goal to apply to 5 jobs this week", "goals"},
+		{"how is my streak going?", "goals"},
+		{"roll over the goals I didn't finish", "goals"},
+		{"help me plan next week", "goals"},
+
+		// Explicit hints pass through untouched.
+		{"[agent:coding] what should I practice?", ""},
+		{"Option 3 please, show my projects", ""},
+
+		// Nothing to go on, or too close to call.
+		{"hi!", ""},
+		{"what can you do?", ""},
+		{"resume", ""},
+		{"my job search project needs a contact list", ""},
+	}
+	for _, tc := range cases {
+		got, ok := r.Route(tc.msg)
+		if !ok {
+			got.Agent = ""
+		}
+		if got.Agent != tc.agent {
+			t.Errorf("Route(%q) = %q, want %q; candidates %+v", tc.msg, got.Agent, tc.agent, r.Score(tc.msg))
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	r, err := New(Rules{Agents: []AgentRule{
+		{Name: "jobs", Option: 1, Label: "Jobs", Match: []Signal{{Keyword: "job", Weight: 2}, {Keyword: "cover letter"}}},
+		{Name: "goals", Option: 2, Label: "Goals", Match: []Signal{{Pattern: `\bgoals?\b`, Weight: 3}}, Not: []Signal{{Keyword: "job"}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := r.Score("Jobs goal: two cover-letters a day")
+	if len(got) != 2 || got[0].Agent != "jobs" || got[1].Agent != "goals" {
+		t.Fatalf("Score order = %+v", got)
+	}
+	// jobs 2+1, goals 3-1, fallthrough 1.
+	if got[0].Score != 3 || got[1].Score != 2 || got[0].Confidence != 0.5 || got[1].Confidence != 2.0/6 {
+		t.Fatalf("scores = %+v", got)
+	}
+	if strings.Join(got[1].Matched, ",") != `\bgoals?\b,-job` {
+		t.Fatalf("goals matched %v", got[1].Matched)
+	}
+	if c, ok := r.Route("Jobs goal: two cover-letters a day"); !ok || c.Hint("x") != "Option 1 (Jobs): x" {
+		t.Fatalf("Route = %+v, %v", c, ok)
+	}
+
+	// A negative signal alone doesn't make a candidate.
+	if got := r.Score("a new job"); len(got) != 1 || got[0].Agent != "jobs" {
+		t.Fatalf("Score(a new job) = %+v", got)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("rules.yaml", `
+threshold: 0.6
+agents:
+  - name: coding
+    option: 2
+    label: Coding
+    replies: [lc]
+    match:
+      - pattern: '\blc ?\d+'
+        weight: 4
+`)
+	r, err := FromEnv(func(key string) string {
+		if key == "AGENT_ROUTES_FILE" {
+			return path
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := r.Route("LC 1 again"); !ok || c.Agent != "coding" || c.Confidence != 0.8 {
+		t.Fatalf("Route = %+v, %v", c, ok)
+	}
+	if c, ok := r.Route("lc"); !ok || c.Confidence != 1 {
+		t.Fatalf("Route(reply) = %+v, %v", c, ok)
+	}
+
+	bad := map[string]string{
+		"unknown field":    "agents:\n  - name: jobs\n    option: 1\n    label: Jobs\n    keywords: [job]\n",
+		"no agents":        "threshold: 0.5\n",
+		"bad threshold":    "threshold: 2\nagents:\n  - {name: jobs, option: 1, label: Jobs}\n",
+		"duplicate option": "agents:\n  - {name: jobs, option: 1, label: Jobs}\n  - {name: goals, option: 1, label: Goals}\n",
+		"shared reply":     "agents:\n  - {name: jobs, option: 1, label: Jobs, replies: [x]}\n  - {name: goals, option: 2, label: Goals, replies: [X]}\n",
+		"two matchers":     "agents:\n  - name: jobs\n    option: 1\n    label: Jobs\n    match: [{keyword: job, pattern: job}]\n",
+		"bad pattern":      "agents:\n  - name: jobs\n    option: 1\n    label: Jobs\n    match: [{pattern: '('}]\n",
+	}
+	for name, body := range bad {
+		if _, err := LoadFile(write("bad.yaml", body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -1,13 +1,17 @@
 package agents
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 
+	ckdb "career-koala/db"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/agenttool"
+	"google.golang.org/adk/tool/functiontool"
 )
 
 func NewRootAgent(m model.LLM, children []agent.Agent) (agent.Agent, error) {
@@ -19,8 +23,8 @@ func NewRootAgent(m model.LLM, children []agent.Agent) (agent.Agent, error) {
 	root, err := llmagent.New(llmagent.Config{
 		Name:        "root_agent",
 		Model:       m,
-		Description: "Main career companion coordinating four specialists (jobs, coding, projects, networking).",
-		Instruction: "You are the main Career Companion Agent orchestrating a team of specialists.\n\nYou have four sub-agents:\n- 'job_applications_agent' for job search and applications.\n- 'coding_agent' for coding practice and interview prep.\n- 'networking_agent' for networking and relationship building.\n- 'projects_agent' for personal and portfolio projects.\n\nRouting logic:\n- If the user specifies a hint like [agent:jobs|coding|projects|networking] or 'Option 1/2/3/4', immediately transfer to that child agent without confirmation.\n- Otherwise, infer the most relevant agent and transfer.\n- Never loop or ask the user to pick an option; only ask a brief clarifying question if the request is genuinely ambiguous.\n\nNotes:\n- Read-only policy: do NOT write to the database or ask for data entry.\n- If the user asks to add or update data, route to the relevant specialist; that agent will respond with a JSON write suggestion only.\n- Data lives in the Postgres DB; the UI handles data entry.\n- Your job is routing, not domain analysis.",
+		Description: "Main career companion coordinating six specialists (jobs, coding, projects, networking, meetings, goals).",
+		Instruction: "You are the main Career Companion Agent orchestrating a team of specialists.\n\nYou have six sub-agents:\n- 'job_applications_agent' for job search and applications.\n- 'coding_agent' for coding practice and interview prep.\n- 'networking_agent' for networking and relationship building.\n- 'projects_agent' for personal and portfolio projects.\n- 'meetings_agent' for the calendar: upcoming and past meetings, interview/coffee chat prep and scheduling.\n- 'goals_agent' for daily/weekly/monthly goals: progress, streaks, carrying goals over and planning next week.\n\nRouting logic:\n- If the user specifies a hint like [agent:jobs|coding|projects|networking|meetings|goals] or 'Option 1/2/3/4/5/6', immediately transfer to that child agent without confirmation.\n- Otherwise, infer the most relevant agent and transfer.\n- Never loop or ask the user to pick an option; only ask a brief clarifying question if the request is genuinely ambiguous.\n\nNotes:\n- Read-only policy: do NOT write to the database or ask for data entry.\n- If the user asks to add or update data, route to the relevant specialist; that agent will respond with a JSON write suggestion only.\n- Data lives in the Postgres DB; the UI handles data entry.\n- Your job is routing, not domain analysis.",
 		SubAgents:   children,
 		Tools:       tools,
 	})
@@ -29,3 +33,20 @@ func NewRootAgent(m model.LLM, children []agent.Agent) (agent.Agent, error) {
 	}
 	return root, nil
 }
+
+// userTool scopes the database queries of a tool to the session's user: the
+// one the chat handler put in ctx, else the user named by the session,
+// created on first use.
+func userTool[TArgs, TResults any](dbConn *sql.DB, fn func(context.Context, TArgs) (TResults, error)) functiontool.Func[TArgs, TResults] {
+	return func(ctx tool.Context, args TArgs) (TResults, error) {
+		if _, ok := ckdb.UserFromContext(ctx); ok {
+			return fn(ctx, args)
+		}
+		u, err := ckdb.EnsureUser(ctx, dbConn, ctx.UserID())
+		if err != nil {
+			var zero TResults
+			return zero, err
+		}
+		return fn(ckdb.WithUser(ctx, u.ID), args)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	ckdb "career-koala/db"
+)
+
+func jobAnalyticsHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		weeks, ok := queryInt(w, r, "weeks")
+		if !ok {
+			return
+		}
+		staleDays, ok := queryInt(w, r, "stale_days")
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		summary, err := ckdb.GetJobAnalytics(ctx, dbConn, ckdb.JobAnalyticsOptions{Weeks: weeks, StaleDays: staleDays})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to compute job analytics")
+			return
+		}
+		writeJSON(w, summary)
+	}
+}
+
+func codingAnalyticsHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		report, err := ckdb.GetCodingPatternReport(ctx, dbConn)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to compute coding analytics")
+			return
+		}
+		writeJSON(w, report)
+	}
+}
+
+// queryInt reads an optional positive integer query parameter, writing a 400
+// and reporting false if it is malformed.
+func queryInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return n, true
+}
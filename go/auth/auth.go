@@ -0,0 +1,182 @@
+// Package auth authenticates API requests, by personal API token (looked up
+// in Postgres) or by a JWT bearer token from an OIDC provider, checked
+// against the provider's JWKS.
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// User is a token's owner, or the JWT's user claim. A JWT's user is
+	// only unique together with Issuer, so it isn't a user name.
+	User string
+	// Issuer is the JWT's iss; empty for API tokens.
+	Issuer string
+	// Method is "token" or "jwt".
+	Method string
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity the middleware authenticated, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// ErrInvalidToken is returned by a TokenLookup for an unknown, revoked or
+// expired token.
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenLookup returns the user name owning a personal API token.
+type TokenLookup func(ctx context.Context, token string) (string, error)
+
+// Authenticator checks the bearer token of each request. A request with a
+// token that doesn't verify is always rejected; one without a token only
+// when Required.
+type Authenticator struct {
+	// Tokens resolves personal API tokens; nil disables them.
+	Tokens TokenLookup
+	// JWT verifies JWT bearer tokens; nil disables them.
+	JWT      *JWTVerifier
+	Required bool
+	// Public paths need no token even when Required.
+	Public map[string]bool
+}
+
+// Authenticate returns the caller of r; ok is false when r has no bearer
+// token.
+func (a *Authenticator) Authenticate(r *http.Request) (id Identity, ok bool, err error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return Identity{}, false, nil
+	}
+	scheme, token, found := strings.Cut(header, " ")
+	token = strings.TrimSpace(token)
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Identity{}, true, errors.New("expected a Bearer token")
+	}
+	if LooksLikeJWT(token) {
+		if a.JWT == nil {
+			return Identity{}, true, errors.New("JWT bearer tokens are not enabled")
+		}
+		claims, user, err := a.JWT.Verify(r.Context(), token)
+		if err != nil {
+			return Identity{}, true, err
+		}
+		issuer, _ := claims["iss"].(string)
+		if issuer == "" {
+			return Identity{}, true, errors.New("token has no iss claim")
+		}
+		return Identity{User: user, Issuer: issuer, Method: "jwt"}, true, nil
+	}
+	if a.Tokens == nil {
+		return Identity{}, true, errors.New("API tokens are not enabled")
+	}
+	user, err := a.Tokens(r.Context(), token)
+	if err != nil {
+		return Identity{}, true, err
+	}
+	return Identity{User: user, Method: "token"}, true, nil
+}
+
+// Middleware authenticates each request and puts the identity in its
+// context. Preflight requests pass through untouched.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, ok, err := a.Authenticate(r)
+		switch {
+		case err != nil:
+			if !errors.Is(err, ErrInvalidToken) {
+				log.Printf("auth %s %s: %v", r.Method, r.URL.Path, err)
+			}
+			unauthorized(w, "invalid_token", "invalid or expired token")
+			return
+		case ok:
+			r = r.WithContext(WithIdentity(r.Context(), id))
+		case a.Required && !a.Public[r.URL.Path]:
+			unauthorized(w, "", "authentication required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func unauthorized(w http.ResponseWriter, code, msg string) {
+	challenge := `Bearer realm="career-koala"`
+	if code != "" {
+		challenge += fmt.Sprintf(`, error=%q`, code)
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
+
+// FromEnv builds the authenticator configured in the environment (read
+// through getenv, usually os.Getenv). tokens resolves API tokens, which are
+// always accepted.
+//
+//   - AUTH_REQUIRED (default true whenever API tokens or JWTs are enabled)
+//     rejects requests without a token. Only AUTH_REQUIRED=false lets them
+//     through, to act as the user they name.
+//   - AUTH_JWKS_URL (an http(s) URL or a file path) enables JWT bearer
+//     tokens and needs AUTH_JWT_ISSUER, checked against iss. AUTH_JWT_AUDIENCE
+//     is checked against aud when set, and AUTH_JWT_USER_CLAIM names the
+//     claim identifying the user at the issuer (default sub).
+func FromEnv(getenv func(string) string, tokens TokenLookup) (*Authenticator, error) {
+	env := func(key string) string { return strings.TrimSpace(getenv(key)) }
+	a := &Authenticator{Tokens: tokens, Public: map[string]bool{"/meta": true}}
+	var required *bool
+	if raw := env("AUTH_REQUIRED"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("AUTH_REQUIRED: %q is not a boolean", raw)
+		}
+		required = &v
+	}
+	if url := env("AUTH_JWKS_URL"); url != "" {
+		// Without an issuer check any issuer whose keys share the JWKS
+		// could mint identities.
+		if env("AUTH_JWT_ISSUER") == "" {
+			return nil, errors.New("AUTH_JWKS_URL needs AUTH_JWT_ISSUER")
+		}
+		a.JWT = &JWTVerifier{
+			Keys:      &KeySet{URL: url},
+			Issuer:    env("AUTH_JWT_ISSUER"),
+			Audience:  env("AUTH_JWT_AUDIENCE"),
+			UserClaim: env("AUTH_JWT_USER_CLAIM"),
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		// Fail at startup rather than on the first request if the keys
+		// can't be read.
+		if err := a.JWT.Keys.Refresh(ctx); err != nil {
+			return nil, fmt.Errorf("AUTH_JWKS_URL: %w", err)
+		}
+	}
+	a.Required = a.Tokens != nil || a.JWT != nil
+	if required != nil {
+		a.Required = *required
+	}
+	return a, nil
+}
@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKeys are a locally generated RSA and P-256 key, published as a JWKS.
+type testKeys struct {
+	rsa  *rsa.PrivateKey
+	ec   *ecdsa.PrivateKey
+	jwks []byte
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, ec: ecKey, jwks: jwks}
+}
+
+func (k testKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	enc := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signing := enc(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(signing))
+	var sig []byte
+	switch alg {
+	case "RS256":
+		s, err := rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = s
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, k.ec, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(keys.jwks)
+	}))
+	defer srv.Close()
+
+	now := time.Unix(1_800_000_000, 0)
+	v := &JWTVerifier{
+		Keys:      &KeySet{URL: srv.URL},
+		Issuer:    "https://issuer.test",
+		Audience:  "career-koala",
+		UserClaim: "email",
+		now:       func() time.Time { return now },
+	}
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":   "https://issuer.test",
+			"aud":   []string{"other", "career-koala"},
+			"sub":   "abc123",
+			"email": "ana@example.com",
+			"exp":   now.Add(time.Hour).Unix(),
+			"nbf":   now.Add(-time.Minute).Unix(),
+		}
+	}
+	with := func(key string, val interface{}) map[string]interface{} {
+		c := valid()
+		if val == nil {
+			delete(c, key)
+		} else {
+			c[key] = val
+		}
+		return c
+	}
+
+	for _, alg := range []struct{ name, kid string }{{"RS256", "rsa-1"}, {"ES256", "ec-1"}} {
+		_, user, err := v.Verify(context.Background(), keys.sign(t, alg.name, alg.kid, valid()))
+		if err != nil || user != "ana@example.com" {
+			t.Fatalf("%s: Verify = %q, %v", alg.name, user, err)
+		}
+	}
+
+	tampered := keys.sign(t, "RS256", "rsa-1", valid())
+	parts := strings.Split(tampered, ".")
+	forged, _ := json.Marshal(with("email", "mallory@example.com"))
+	tampered = parts[0] + "." + base64.RawURLEncoding.EncodeToString(forged) + "." + parts[2]
+
+	unsigned := strings.Split(keys.sign(t, "RS256", "rsa-1", valid()), ".")
+	noneHeader, _ := json.Marshal(map[string]string{"alg": "none"})
+
+	cases := map[string]string{
+		"expired":        keys.sign(t, "RS256", "rsa-1", with("exp", now.Add(-time.Hour).Unix())),
+		"no exp":         keys.sign(t, "RS256", "rsa-1", with("exp", nil)),
+		"not yet valid":  keys.sign(t, "RS256", "rsa-1", with("nbf", now.Add(time.Hour).Unix())),
+		"wrong issuer":   keys.sign(t, "RS256", "rsa-1", with("iss", "https://evil.test")),
+		"wrong audience": keys.sign(t, "RS256", "rsa-1", with("aud", "someone-else")),
+		"no user claim":  keys.sign(t, "RS256", "rsa-1", with("email", nil)),
+		"unknown kid":    keys.sign(t, "RS256", "rsa-2", valid()),
+		"key type swap":  keys.sign(t, "ES256", "rsa-1", valid()),
+		"tampered":       tampered,
+		"alg none":       base64.RawURLEncoding.EncodeToString(noneHeader) + "." + unsigned[1] + ".",
+		"malformed":      "not-a-token",
+	}
+	for name, token := range cases {
+		if _, _, err := v.Verify(context.Background(), token); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestKeySetFromFile(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, keys.jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ks := &KeySet{URL: path}
+	if _, err := ks.Key(context.Background(), "ec-1"); err != nil {
+		t.Fatalf("Key: %v", err)
+	}
+	if _, err := ks.Key(context.Background(), "enc-1"); err == nil {
+		t.Fatalf("expected encryption keys to be skipped")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	keys := newTestKeys(t)
+	a := &Authenticator{
+		Tokens: func(ctx context.Context, token string) (string, error) {
+			if token == "ckt_good" {
+				return "bob", nil
+			}
+			return "", ErrInvalidToken
+		},
+		JWT:      &JWTVerifier{Keys: StaticKeySet(map[string]crypto.PublicKey{"rsa-1": &keys.rsa.PublicKey})},
+		Required: true,
+		Public:   map[string]bool{"/meta": true},
+	}
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := FromContext(r.Context())
+		w.Write([]byte(id.User + "/" + id.Issuer + "/" + id.Method))
+	}))
+	jwt := keys.sign(t, "RS256", "rsa-1", map[string]interface{}{"iss": "https://issuer.test", "sub": "ana", "exp": time.Now().Add(time.Hour).Unix()})
+	noIssuer := keys.sign(t, "RS256", "rsa-1", map[string]interface{}{"sub": "ana", "exp": time.Now().Add(time.Hour).Unix()})
+
+	cases := []struct {
+		name, method, path, header string
+		status                     int
+		body                       string
+	}{
+		{"api token", "GET", "/jobs", "Bearer ckt_good", 200, "bob//token"},
+		{"jwt", "GET", "/jobs", "Bearer " + jwt, 200, "ana/https://issuer.test/jwt"},
+		{"jwt without issuer", "GET", "/jobs", "Bearer " + noIssuer, 401, ""},
+		{"unknown token", "GET", "/jobs", "Bearer ckt_bad", 401, ""},
+		{"basic auth", "GET", "/jobs", "Basic Ym9iOnB3", 401, ""},
+		{"missing", "GET", "/jobs", "", 401, ""},
+		{"public path", "GET", "/meta", "", 200, "//"},
+		{"bad token on public path", "GET", "/meta", "Bearer ckt_bad", 401, ""},
+		{"preflight", "OPTIONS", "/jobs", "", 200, "//"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Errorf("%s: status %d, want %d", tc.name, rec.Code, tc.status)
+			continue
+		}
+		if tc.status == 401 {
+			if !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("%s: missing WWW-Authenticate challenge", tc.name)
+			}
+			continue
+		}
+		if rec.Body.String() != tc.body {
+			t.Errorf("%s: body %q, want %q", tc.name, rec.Body.String(), tc.body)
+		}
+	}
+
+	a.Required = false
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/jobs", nil))
+	if rec.Code != 200 || rec.Body.String() != "//" {
+		t.Fatalf("optional auth: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestFromEnvRequiresAuthByDefault(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	lookup := func(ctx context.Context, token string) (string, error) { return "", ErrInvalidToken }
+	cases := []struct {
+		name     string
+		vars     map[string]string
+		tokens   TokenLookup
+		required bool
+	}{
+		{"tokens enabled", nil, lookup, true},
+		{"explicitly disabled", map[string]string{"AUTH_REQUIRED": "false"}, lookup, false},
+		{"nothing to authenticate with", nil, nil, false},
+		{"explicitly required", map[string]string{"AUTH_REQUIRED": "true"}, nil, true},
+	}
+	for _, tc := range cases {
+		a, err := FromEnv(env(tc.vars), tc.tokens)
+		if err != nil || a.Required != tc.required {
+			t.Errorf("%s: Required = %v, %v; want %v", tc.name, a != nil && a.Required, err, tc.required)
+		}
+	}
+	if _, err := FromEnv(env(map[string]string{"AUTH_REQUIRED": "maybe"}), lookup); err == nil {
+		t.Error("expected an error for a non-boolean AUTH_REQUIRED")
+	}
+	if _, err := FromEnv(env(map[string]string{"AUTH_JWKS_URL": "/dev/null"}), lookup); err == nil || !strings.Contains(err.Error(), "AUTH_JWT_ISSUER") {
+		t.Errorf("FromEnv without AUTH_JWT_ISSUER = %v, want an error", err)
+	}
+}
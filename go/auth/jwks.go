@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeySet is the signing keys of a token issuer, read from a JWKS document
+// at URL (an http(s) URL or a local file path). Keys are cached for TTL and
+// refetched early, at most every MinRefresh, when a token names a key id
+// the cache doesn't have, so rotated keys are picked up.
+type KeySet struct {
+	URL        string
+	TTL        time.Duration
+	MinRefresh time.Duration
+	// Client defaults to one with a 10s timeout.
+	Client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// StaticKeySet serves fixed keys by key id, e.g. for tests.
+func StaticKeySet(keys map[string]crypto.PublicKey) *KeySet {
+	return &KeySet{keys: keys}
+}
+
+// Key returns the key with id kid; an empty kid matches the only key of a
+// set that has one.
+func (ks *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ttl, minRefresh := ks.TTL, ks.MinRefresh
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	if minRefresh <= 0 {
+		minRefresh = time.Minute
+	}
+	age := time.Since(ks.fetched)
+	key, ok := ks.lookup(kid)
+	if ks.URL != "" && (ks.keys == nil || age > ttl || (!ok && age > minRefresh)) {
+		keys, err := ks.fetch(ctx)
+		if err != nil {
+			if ks.keys == nil {
+				return nil, err
+			}
+			// Keep serving the cached keys while the issuer is unreachable.
+		} else {
+			ks.keys, ks.fetched = keys, time.Now()
+			key, ok = ks.lookup(kid)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("no signing key %q", kid)
+	}
+	return key, nil
+}
+
+// Refresh fetches the keys now.
+func (ks *KeySet) Refresh(ctx context.Context) error {
+	keys, err := ks.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	ks.keys, ks.fetched = keys, time.Now()
+	ks.mu.Unlock()
+	return nil
+}
+
+func (ks *KeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			return k, true
+		}
+	}
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+func (ks *KeySet) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if !strings.HasPrefix(ks.URL, "http://") && !strings.HasPrefix(ks.URL, "https://") {
+		data, err := os.ReadFile(strings.TrimPrefix(ks.URL, "file://"))
+		if err != nil {
+			return nil, fmt.Errorf("read jwks: %w", err)
+		}
+		return ParseJWKS(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := ks.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: %s returned %s", ks.URL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+// jwk is the subset of RFC 7517 used for RSA and EC signing keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS reads the RSA and EC signature keys of a JWKS document by key
+// id. Encryption keys and key types it can't use are skipped.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		var err error
+		switch k.Kty {
+		case "RSA":
+			key, err = rsaKey(k)
+		case "EC":
+			key, err = ecKey(k)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse jwks: key %d (%q): %w", i, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("parse jwks: no usable signing keys")
+	}
+	return keys, nil
+}
+
+func rsaKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	if key.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("%d-bit RSA key is too short", key.N.BitLen())
+	}
+	return key, nil
+}
+
+func ecKey(k jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	var check ecdh.Curve
+	switch k.Crv {
+	case "P-256":
+		curve, check = elliptic.P256(), ecdh.P256()
+	case "P-384":
+		curve, check = elliptic.P384(), ecdh.P384()
+	case "P-521":
+		curve, check = elliptic.P521(), ecdh.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	size := (curve.Params().BitSize + 7) / 8
+	x, errX := base64.RawURLEncoding.DecodeString(k.X)
+	y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+	if errX != nil || errY != nil || len(x) != size || len(y) != size {
+		return nil, errors.New("invalid coordinates")
+	}
+	point := append(append([]byte{4}, x...), y...)
+	if _, err := check.NewPublicKey(point); err != nil {
+		return nil, errors.New("point is not on the curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// JWTVerifier checks signed JWTs (RS256/384/512 and ES256/384/512) against
+// an issuer's keys and reads the user from a claim.
+type JWTVerifier struct {
+	Keys *KeySet
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// UserClaim names the claim holding the user name; default "sub".
+	UserClaim string
+	// Leeway is the clock skew allowed on exp and nbf; default 1 minute.
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+// Claims are the decoded payload of a verified token.
+type Claims map[string]interface{}
+
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+}
+
+// LooksLikeJWT reports whether token has the three dot-separated parts of a
+// compact JWS.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks the token's signature and registered claims and returns its
+// claims and user name.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Claims, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, "", errors.New("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, "", fmt.Errorf("header: %w", err)
+	}
+	hash, ok := algorithms[header.Alg]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported algorithm %q", header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, "", errors.New("malformed signature")
+	}
+	key, err := v.Keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, "", err
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, hash, h.Sum(nil), sig); err != nil {
+		return nil, "", err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, "", fmt.Errorf("claims: %w", err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, "", err
+	}
+	userClaim := v.UserClaim
+	if userClaim == "" {
+		userClaim = "sub"
+	}
+	user, _ := claims[userClaim].(string)
+	if strings.TrimSpace(user) == "" {
+		return nil, "", fmt.Errorf("token has no %s claim", userClaim)
+	}
+	return claims, strings.TrimSpace(user), nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, hash crypto.Hash, digest, sig []byte) error {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("%s token signed with an RSA key", alg)
+		}
+		if rsa.VerifyPKCS1v15(k, hash, digest, sig) != nil {
+			return errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(sig) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	return nil
+}
+
+func (v *JWTVerifier) checkClaims(c Claims) error {
+	now := time.Now()
+	if v.now != nil {
+		now = v.now()
+	}
+	leeway := v.Leeway
+	if leeway == 0 {
+		leeway = time.Minute
+	}
+	exp, ok := c.time("exp")
+	if !ok {
+		return errors.New("token has no exp claim")
+	}
+	if now.After(exp.Add(leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := c.time("nbf"); ok && now.Add(leeway).Before(nbf) {
+		return errors.New("token not valid yet")
+	}
+	if v.Issuer != "" {
+		if iss, _ := c["iss"].(string); iss != v.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+	if v.Audience != "" && !c.hasAudience(v.Audience) {
+		return errors.New("token is not for this audience")
+	}
+	return nil
+}
+
+// time reads a NumericDate claim.
+func (c Claims) time(name string) (time.Time, bool) {
+	n, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	secs, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(secs), 0), true
+}
+
+// hasAudience reports whether aud, a string or a list of them, contains want.
+func (c Claims) hasAudience(want string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	ckdb "career-koala/db"
+)
+
+// queryLocation reads an optional IANA time zone query parameter.
+func queryLocation(r *http.Request, name string) (*time.Location, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(raw)
+	if err != nil {
+		return nil, errors.New("invalid " + name + ": unknown time zone " + raw)
+	}
+	return loc, nil
+}
+
+// meetingsCalendarHandler serves /meetings.ics. Query parameters: tz (IANA
+// zone for event times, default UTC), alarm (reminder minutes) and duration
+// (meeting length in minutes, default 60).
+func meetingsCalendarHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var opts ckdb.CalendarOptions
+		var err error
+		if opts.Location, err = queryLocation(r, "tz"); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		var ok bool
+		if opts.AlarmMinutes, ok = queryInt(w, r, "alarm"); !ok {
+			return
+		}
+		if opts.DurationMinutes, ok = queryInt(w, r, "duration"); !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		var buf bytes.Buffer
+		if err := ckdb.WriteMeetingsCalendar(ctx, dbConn, &buf, opts); err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to build calendar")
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="meetings.ics"`)
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+// meetingsCalendarImportHandler upserts the events of an uploaded .ics file.
+// Query parameters: dry_run, tz (zone for floating times) and keywords
+// (comma separated filter, e.g. "interview,coffee").
+func meetingsCalendarImportHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var opts ckdb.CalendarImportOptions
+		var err error
+		if opts.DryRun, err = queryBool(r, "dry_run"); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if opts.Location, err = queryLocation(r, "tz"); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		for _, k := range strings.Split(r.URL.Query().Get("keywords"), ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				opts.Keywords = append(opts.Keywords, k)
+			}
+		}
+
+		body, err := importBody(w, r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer body.Close()
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		result, err := ckdb.ImportMeetingsCalendar(ctx, dbConn, body, opts)
+		if err != nil {
+			var fieldErr *ckdb.FieldError
+			if errors.As(err, &fieldErr) {
+				writeError(w, http.StatusBadRequest, fieldErr.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to import calendar")
+			return
+		}
+		writeJSON(w, result)
+	}
+}
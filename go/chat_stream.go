@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"time"
+
+	"career-koala/agents"
+	"career-koala/agents/router"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Server-sent events of /chat/stream, in the order they can arrive. Each
+// data line is one JSON object.
+const (
+	// streamSession opens every stream: {"session_id"}.
+	streamSession = "session"
+	// streamDelta is a chunk of a reply being generated: {"author", "text"}.
+	streamDelta = "delta"
+	// streamMessage is a complete reply, which replaces the deltas before
+	// it: {"author", "text"}.
+	streamMessage = "message"
+	// streamToolCall and streamToolResult bracket a tool run: {"author",
+	// "id", "name", "args"} and {"author", "id", "name"}.
+	streamToolCall   = "tool_call"
+	streamToolResult = "tool_result"
+	// streamTransfer is one agent handing the chat to another: {"from", "to"}.
+	streamTransfer = "transfer"
+	// streamConfirm asks the user to confirm a captured write request; the
+	// next message answers it: {"text"}.
+	streamConfirm = "confirm"
+	// streamDone ends a successful stream with the same body /chat returns,
+	// trace included.
+	streamDone = "done"
+	// streamError ends a failed stream: {"error"}.
+	streamError = "error"
+)
+
+type streamText struct {
+	Author string `json:"author"`
+	Text   string `json:"text"`
+}
+
+type streamTool struct {
+	Author string         `json:"author"`
+	ID     string         `json:"id,omitempty"`
+	Name   string         `json:"name"`
+	Args   map[string]any `json:"args,omitempty"`
+}
+
+// sseWriter writes server-sent events, flushing each one. After a failed
+// write (the client went away) it drops everything and err is set.
+type sseWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	err error
+}
+
+func newSSEWriter(w http.ResponseWriter) *sseWriter {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stop nginx-style proxies from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	return &sseWriter{w: w, rc: http.NewResponseController(w)}
+}
+
+func (s *sseWriter) send(event string, v any) {
+	if s.err != nil {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(errorResponse{Error: err.Error()})
+		event = streamError
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		s.err = err
+		return
+	}
+	s.err = s.rc.Flush()
+}
+
+// chatStreamHandler is /chat as server-sent events: the reply text as it is
+// generated, tool calls and agent transfers as they happen, then the write
+// confirmation prompt (if any) and the final replies. A client that
+// disconnects cancels the run.
+func chatStreamHandler(rnr *runner.Runner, sessSvc session.Service, dbConn *sql.DB, routes *router.Router) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		req, userCtx, ok := prepareChat(w, r, sessSvc, dbConn, routes)
+		if !ok {
+			return
+		}
+		// userCtx derives from the request context, which is cancelled when
+		// the client disconnects.
+		ctx, cancel := context.WithTimeout(userCtx, chatTimeout)
+		defer cancel()
+
+		stream := newSSEWriter(w)
+		stream.send(streamSession, map[string]string{"session_id": req.SessionID})
+
+		if reply, handled := agents.HandlePendingWrite(ctx, req.UserID, req.SessionID, req.Message, dbConn); handled {
+			stream.send(streamMessage, streamText{Author: "career_koala", Text: reply})
+			stream.send(streamDone, chatResponse{SessionID: req.SessionID, Replies: []string{reply}})
+			return
+		}
+
+		seq := rnr.Run(ctx, req.UserID, req.SessionID, &genai.Content{
+			Parts: []*genai.Part{{Text: req.Message}},
+		}, agent.RunConfig{StreamingMode: agent.StreamingModeSSE})
+		relayRun(stream, seq, req, userCtx, dbConn, func() bool {
+			return errors.Is(r.Context().Err(), context.Canceled)
+		})
+	}
+}
+
+// chatClock times the steps of streamed runs; tests fix it.
+var chatClock = time.Now
+
+// relayRun sends the events of seq, one run for req, to stream and finishes
+// it with the confirm (if a write request was captured) and done events, or
+// an error event if the run fails. disconnected reports whether a run error
+// is just the client having gone away, which needs no error event.
+func relayRun(stream *sseWriter, seq iter.Seq2[*session.Event, error], req chatRequest, userCtx context.Context, dbConn *sql.DB, disconnected func() bool) {
+	trace := newChatTrace(chatClock())
+	for event, err := range seq {
+		if err != nil {
+			if disconnected() {
+				log.Printf("chat stream user=%s session=%s: client disconnected", req.UserID, req.SessionID)
+				return
+			}
+			log.Printf("chat stream error user=%s session=%s: %v", req.UserID, req.SessionID, err)
+			stream.send(streamError, errorResponse{Error: err.Error()})
+			return
+		}
+		if event == nil || event.Author == "user" {
+			continue
+		}
+		trace.observe(event, chatClock())
+		if event.LLMResponse.Content != nil {
+			for _, part := range event.LLMResponse.Content.Parts {
+				switch {
+				case part == nil:
+				case part.FunctionCall != nil:
+					stream.send(streamToolCall, streamTool{Author: event.Author, ID: part.FunctionCall.ID, Name: part.FunctionCall.Name, Args: part.FunctionCall.Args})
+				case part.FunctionResponse != nil:
+					stream.send(streamToolResult, streamTool{Author: event.Author, ID: part.FunctionResponse.ID, Name: part.FunctionResponse.Name})
+				case part.Text == "", part.Thought:
+				case event.Partial:
+					stream.send(streamDelta, streamText{Author: event.Author, Text: part.Text})
+				default:
+					stream.send(streamMessage, streamText{Author: event.Author, Text: part.Text})
+				}
+			}
+		}
+		if to := event.Actions.TransferToAgent; to != "" {
+			stream.send(streamTransfer, map[string]string{"from": event.Author, "to": to})
+		}
+		if stream.err != nil {
+			// Stopping the iteration ends the run.
+			log.Printf("chat stream user=%s session=%s: %v", req.UserID, req.SessionID, stream.err)
+			return
+		}
+	}
+
+	if prompt, ok := agents.MaybeCaptureWrite(userCtx, req.UserID, req.SessionID, trace.replies, dbConn); ok {
+		trace.confirm(prompt, chatClock())
+		stream.send(streamConfirm, map[string]string{"text": prompt})
+	}
+	log.Printf("chat stream user=%s session=%s: %s", req.UserID, req.SessionID, trace.summary())
+	stream.send(streamDone, trace.response(req.SessionID))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// fixChatClock stops the clock for the test, so step timings are all zero.
+func fixChatClock(t *testing.T) {
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	chatClock = func() time.Time { return at }
+	t.Cleanup(func() { chatClock = time.Now })
+}
+
+// events yields evs and then, if err isn't nil, err.
+func events(err error, evs ...*session.Event) iter.Seq2[*session.Event, error] {
+	return func(yield func(*session.Event, error) bool) {
+		for _, ev := range evs {
+			if !yield(ev, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func textEvent(author, text string, partial bool) *session.Event {
+	return &session.Event{
+		Author:      author,
+		LLMResponse: model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel), Partial: partial},
+	}
+}
+
+func partsEvent(author string, parts ...*genai.Part) *session.Event {
+	return &session.Event{Author: author, LLMResponse: model.LLMResponse{Content: &genai.Content{Role: genai.RoleModel, Parts: parts}}}
+}
+
+func transferEvent(from, to string) *session.Event {
+	return &session.Event{Author: from, Actions: session.EventActions{TransferToAgent: to}}
+}
+
+func relayToRecorder(seq iter.Seq2[*session.Event, error], req chatRequest, disconnected bool) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	stream := newSSEWriter(rec)
+	relayRun(stream, seq, req, context.Background(), nil, func() bool { return disconnected })
+	return rec
+}
+
+func TestRelayRunStreamsEvents(t *testing.T) {
+	fixChatClock(t)
+	seq := events(nil,
+		textEvent("user", "show my problems", false),
+		transferEvent("root_agent", "coding_agent"),
+		textEvent("coding_agent", "Here", true),
+		partsEvent("coding_agent", &genai.Part{FunctionCall: &genai.FunctionCall{ID: "c1", Name: "list_coding_problems", Args: map[string]any{"limit": 20}}}),
+		partsEvent("coding_agent", &genai.Part{FunctionResponse: &genai.FunctionResponse{ID: "c1", Name: "list_coding_problems", Response: map[string]any{"items": []any{}}}}),
+		textEvent("coding_agent", "Here are your problems.", false),
+	)
+	rec := relayToRecorder(seq, chatRequest{UserID: "ana", SessionID: "s1"}, false)
+
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !rec.Flushed {
+		t.Fatal("expected the events to be flushed")
+	}
+	want := "event: transfer\ndata: {\"from\":\"root_agent\",\"to\":\"coding_agent\"}\n\n" +
+		"event: delta\ndata: {\"author\":\"coding_agent\",\"text\":\"Here\"}\n\n" +
+		"event: tool_call\ndata: {\"author\":\"coding_agent\",\"id\":\"c1\",\"name\":\"list_coding_problems\",\"args\":{\"limit\":20}}\n\n" +
+		"event: tool_result\ndata: {\"author\":\"coding_agent\",\"id\":\"c1\",\"name\":\"list_coding_problems\"}\n\n" +
+		"event: message\ndata: {\"author\":\"coding_agent\",\"text\":\"Here are your problems.\"}\n\n" +
+		"event: done\ndata: {\"session_id\":\"s1\",\"replies\":[\"Here are your problems.\"],\"answered_by\":\"coding_agent\",\"trace\":[" +
+		"{\"kind\":\"transfer\",\"author\":\"root_agent\",\"to\":\"coding_agent\",\"at_ms\":0,\"latency_ms\":0}," +
+		"{\"kind\":\"tool_call\",\"author\":\"coding_agent\",\"tool\":\"list_coding_problems\",\"args\":{\"limit\":20},\"args_bytes\":12,\"result_bytes\":12,\"at_ms\":0,\"latency_ms\":0}," +
+		"{\"kind\":\"reply\",\"author\":\"coding_agent\",\"reply\":0,\"at_ms\":0,\"latency_ms\":0}]}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("stream:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRelayRunSendsConfirmBeforeDone(t *testing.T) {
+	fixChatClock(t)
+	reply := "```json\n{\"write_requests\":[{\"action\":\"insert\",\"table\":\"projects\",\"records\":[{\"name\":\"Koala\"}]}]}\n```"
+	rec := relayToRecorder(events(nil, textEvent("projects_agent", reply, false)), chatRequest{UserID: "ana", SessionID: "confirm-stream"}, false)
+	body := rec.Body.String()
+
+	confirm := strings.Index(body, "event: confirm\ndata: {\"text\":\"I can apply the following write request(s): insert 1 -\\u003e projects")
+	done := strings.Index(body, "event: done\n")
+	if confirm < 0 || done < confirm {
+		t.Fatalf("expected a confirm event before done:\n%s", body)
+	}
+	if !strings.Contains(body[done:], `{"kind":"confirm","author":"projects_agent","reply":0,"at_ms":0,"latency_ms":0}`) {
+		t.Fatalf("done event lacks the confirm step:\n%s", body[done:])
+	}
+	if !strings.HasSuffix(body, "\n\n") || strings.Count(body, "event: ") != 3 {
+		t.Fatalf("expected message, confirm and done events only:\n%s", body)
+	}
+}
+
+func TestRelayRunReportsErrorsAfterHeaders(t *testing.T) {
+	fixChatClock(t)
+	seq := events(errors.New("model unavailable"), textEvent("root_agent", "Hi", true))
+	rec := relayToRecorder(seq, chatRequest{UserID: "ana", SessionID: "s1"}, false)
+
+	// The status went out with the first event, so the failure is an event.
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	want := "event: delta\ndata: {\"author\":\"root_agent\",\"text\":\"Hi\"}\n\n" +
+		"event: error\ndata: {\"error\":\"model unavailable\"}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("stream:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRelayRunStopsQuietlyWhenClientDisconnects(t *testing.T) {
+	fixChatClock(t)
+	seq := events(context.Canceled, textEvent("root_agent", "Hi", true))
+	rec := relayToRecorder(seq, chatRequest{UserID: "ana", SessionID: "s1"}, true)
+	want := "event: delta\ndata: {\"author\":\"root_agent\",\"text\":\"Hi\"}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("stream:\n%s\nwant:\n%s", got, want)
+	}
+}
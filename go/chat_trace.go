@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/session"
+)
+
+// Kinds of trace steps.
+const (
+	traceReply    = "reply"
+	traceToolCall = "tool_call"
+	traceTransfer = "transfer"
+	traceConfirm  = "confirm"
+)
+
+// traceStep is one thing that happened in an agent run.
+type traceStep struct {
+	Kind   string `json:"kind"`
+	Author string `json:"author"`
+	// Reply is the index in chatResponse.Replies of a reply or confirm step.
+	Reply *int `json:"reply,omitempty"`
+	// Tool, Args and the sizes (bytes of JSON) describe a tool call;
+	// ResultBytes stays nil while the call has no result.
+	Tool        string         `json:"tool,omitempty"`
+	Args        map[string]any `json:"args,omitempty"`
+	ArgsBytes   int            `json:"args_bytes,omitempty"`
+	ResultBytes *int           `json:"result_bytes,omitempty"`
+	// To is the agent a transfer hands the chat to.
+	To string `json:"to,omitempty"`
+	// AtMS is when the step happened, in milliseconds since the run started.
+	// LatencyMS is how long it took: the tool's run time for a tool call, the
+	// time since the previous step otherwise.
+	AtMS      int64 `json:"at_ms"`
+	LatencyMS int64 `json:"latency_ms"`
+
+	callID string
+}
+
+// chatTrace records the steps of one agent run from its events, and
+// collects the replies.
+type chatTrace struct {
+	start   time.Time
+	last    time.Time
+	steps   []traceStep
+	replies []string
+}
+
+func newChatTrace(start time.Time) *chatTrace {
+	return &chatTrace{start: start, last: start}
+}
+
+// observe records ev, received at now. Partial (streamed) events are
+// skipped; the complete event that follows them is recorded.
+func (t *chatTrace) observe(ev *session.Event, now time.Time) {
+	if ev == nil || ev.Author == "user" || ev.Partial {
+		return
+	}
+	if ev.LLMResponse.Content != nil {
+		for _, part := range ev.LLMResponse.Content.Parts {
+			switch {
+			case part == nil:
+			case part.FunctionCall != nil:
+				t.add(now, traceStep{
+					Kind:      traceToolCall,
+					Author:    ev.Author,
+					Tool:      part.FunctionCall.Name,
+					Args:      part.FunctionCall.Args,
+					ArgsBytes: jsonSize(part.FunctionCall.Args),
+					callID:    part.FunctionCall.ID,
+				})
+			case part.FunctionResponse != nil:
+				t.finishCall(part.FunctionResponse.ID, part.FunctionResponse.Name, jsonSize(part.FunctionResponse.Response), now)
+			case part.Text == "", part.Thought:
+			default:
+				idx := len(t.replies)
+				t.replies = append(t.replies, part.Text)
+				t.add(now, traceStep{Kind: traceReply, Author: ev.Author, Reply: &idx})
+			}
+		}
+	}
+	if to := ev.Actions.TransferToAgent; to != "" {
+		t.add(now, traceStep{Kind: traceTransfer, Author: ev.Author, To: to})
+	}
+}
+
+func (t *chatTrace) add(now time.Time, step traceStep) {
+	step.AtMS = now.Sub(t.start).Milliseconds()
+	step.LatencyMS = now.Sub(t.last).Milliseconds()
+	t.last = now
+	t.steps = append(t.steps, step)
+}
+
+// finishCall records the result of the latest open call with the id (or,
+// for calls without ids, the name).
+func (t *chatTrace) finishCall(id, name string, size int, now time.Time) {
+	for i := len(t.steps) - 1; i >= 0; i-- {
+		s := &t.steps[i]
+		if s.Kind != traceToolCall || s.ResultBytes != nil {
+			continue
+		}
+		if (id != "" && s.callID == id) || (id == "" && s.Tool == name) {
+			s.ResultBytes = &size
+			s.LatencyMS = now.Sub(t.start).Milliseconds() - s.AtMS
+			t.last = now
+			return
+		}
+	}
+}
+
+// confirm replaces the replies with the prompt confirming a captured write
+// request.
+func (t *chatTrace) confirm(prompt string, now time.Time) {
+	for i := range t.steps {
+		t.steps[i].Reply = nil
+	}
+	t.replies = []string{prompt}
+	idx := 0
+	t.add(now, traceStep{Kind: traceConfirm, Author: t.answeredBy(), Reply: &idx})
+}
+
+// answeredBy is the author of the last reply.
+func (t *chatTrace) answeredBy() string {
+	for i := len(t.steps) - 1; i >= 0; i-- {
+		if k := t.steps[i].Kind; k == traceReply || k == traceConfirm {
+			return t.steps[i].Author
+		}
+	}
+	return ""
+}
+
+// summary reads like "answered by coding_agent using
+// list_coding_problems(limit=20)", for logs.
+func (t *chatTrace) summary() string {
+	by := t.answeredBy()
+	if by == "" {
+		return "no reply"
+	}
+	var tools []string
+	for _, s := range t.steps {
+		if s.Kind == traceToolCall && s.Author == by {
+			tools = append(tools, s.Tool+"("+formatArgs(s.Args)+")")
+		}
+	}
+	if len(tools) == 0 {
+		return "answered by " + by
+	}
+	return "answered by " + by + " using " + strings.Join(tools, ", ")
+}
+
+func formatArgs(args map[string]any) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, args[k]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func jsonSize(v any) int {
+	if v == nil {
+		return 0
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// response is the chatResponse of the run.
+func (t *chatTrace) response(sessionID string) chatResponse {
+	replies := t.replies
+	if replies == nil {
+		replies = []string{}
+	}
+	return chatResponse{SessionID: sessionID, Replies: replies, AnsweredBy: t.answeredBy(), Trace: t.steps}
+}
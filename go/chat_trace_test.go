@@ -0,0 +1,157 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// stepView is the part of a traceStep the tests compare; Result is -1 for a
+// call without a result.
+type stepView struct {
+	Kind, Author, Tool string
+	Result             int
+	AtMS, LatencyMS    int64
+}
+
+func viewSteps(steps []traceStep) []stepView {
+	var res []stepView
+	for _, s := range steps {
+		v := stepView{Kind: s.Kind, Author: s.Author, Tool: s.Tool, Result: -1, AtMS: s.AtMS, LatencyMS: s.LatencyMS}
+		if s.ResultBytes != nil {
+			v.Result = *s.ResultBytes
+		}
+		res = append(res, v)
+	}
+	return res
+}
+
+func call(id, name string, args map[string]any) *genai.Part {
+	return &genai.Part{FunctionCall: &genai.FunctionCall{ID: id, Name: name, Args: args}}
+}
+
+func result(id, name string, response map[string]any) *genai.Part {
+	return &genai.Part{FunctionResponse: &genai.FunctionResponse{ID: id, Name: name, Response: response}}
+}
+
+func TestChatTraceMatchesResultsByCallID(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	trace := newChatTrace(start)
+
+	// Two calls of the same tool in one event, answered in reverse order.
+	trace.observe(partsEvent("jobs_agent",
+		call("c1", "list_jobs", map[string]any{"status": "applied"}),
+		call("c2", "list_jobs", map[string]any{"status": "offer"}),
+	), at(10))
+	trace.observe(partsEvent("jobs_agent", result("c2", "list_jobs", map[string]any{"n": 1})), at(50))
+	trace.observe(partsEvent("jobs_agent", result("c1", "list_jobs", map[string]any{"n": 22})), at(80))
+	// A result for a call never seen changes nothing.
+	trace.observe(partsEvent("jobs_agent", result("c9", "list_jobs", map[string]any{"n": 3})), at(90))
+	trace.observe(textEvent("jobs_agent", "You have 22 applications.", false), at(100))
+
+	want := []stepView{
+		{Kind: traceToolCall, Author: "jobs_agent", Tool: "list_jobs", Result: len(`{"n":22}`), AtMS: 10, LatencyMS: 70},
+		{Kind: traceToolCall, Author: "jobs_agent", Tool: "list_jobs", Result: len(`{"n":1}`), AtMS: 10, LatencyMS: 40},
+		{Kind: traceReply, Author: "jobs_agent", Result: -1, AtMS: 100, LatencyMS: 20},
+	}
+	if got := viewSteps(trace.steps); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps:\n%+v\nwant:\n%+v", got, want)
+	}
+	if trace.steps[0].ArgsBytes != len(`{"status":"applied"}`) {
+		t.Fatalf("args_bytes = %d", trace.steps[0].ArgsBytes)
+	}
+}
+
+func TestChatTraceMatchesCallsWithoutIDsByName(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	trace := newChatTrace(start)
+
+	trace.observe(partsEvent("coding_agent", call("", "list_coding_problems", nil)), at(5))
+	trace.observe(partsEvent("coding_agent", call("", "list_coding_problems", nil)), at(15))
+	trace.observe(partsEvent("coding_agent", call("", "coverage_report", nil)), at(20))
+	// The result goes to the latest open call of that name.
+	trace.observe(partsEvent("coding_agent", result("", "list_coding_problems", map[string]any{})), at(45))
+	trace.observe(partsEvent("coding_agent", result("", "list_coding_problems", map[string]any{})), at(60))
+
+	want := []stepView{
+		{Kind: traceToolCall, Author: "coding_agent", Tool: "list_coding_problems", Result: 2, AtMS: 5, LatencyMS: 55},
+		{Kind: traceToolCall, Author: "coding_agent", Tool: "list_coding_problems", Result: 2, AtMS: 15, LatencyMS: 30},
+		// Never answered: no result, latency since the previous step.
+		{Kind: traceToolCall, Author: "coding_agent", Tool: "coverage_report", Result: -1, AtMS: 20, LatencyMS: 5},
+	}
+	if got := viewSteps(trace.steps); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestChatTraceSkipsPartialThoughtAndUserEvents(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	trace := newChatTrace(start)
+
+	trace.observe(nil, start)
+	trace.observe(textEvent("user", "hi", false), start.Add(time.Millisecond))
+	trace.observe(textEvent("root_agent", "Hel", true), start.Add(2*time.Millisecond))
+	trace.observe(partsEvent("root_agent", &genai.Part{Text: "thinking", Thought: true}), start.Add(3*time.Millisecond))
+	trace.observe(transferEvent("root_agent", "networking_agent"), start.Add(4*time.Millisecond))
+	trace.observe(textEvent("networking_agent", "Hello!", false), start.Add(9*time.Millisecond))
+
+	want := []stepView{
+		{Kind: traceTransfer, Author: "root_agent", Result: -1, AtMS: 4, LatencyMS: 4},
+		{Kind: traceReply, Author: "networking_agent", Result: -1, AtMS: 9, LatencyMS: 5},
+	}
+	if got := viewSteps(trace.steps); !reflect.DeepEqual(got, want) {
+		t.Fatalf("steps:\n%+v\nwant:\n%+v", got, want)
+	}
+	if trace.steps[0].To != "networking_agent" {
+		t.Fatalf("transfer to %q", trace.steps[0].To)
+	}
+	if !reflect.DeepEqual(trace.replies, []string{"Hello!"}) {
+		t.Fatalf("replies = %q", trace.replies)
+	}
+}
+
+func TestChatTraceConfirmReplacesReplies(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	trace := newChatTrace(start)
+	trace.observe(partsEvent("projects_agent", call("c1", "list_projects", map[string]any{"active": true})), start.Add(10*time.Millisecond))
+	trace.observe(partsEvent("projects_agent", result("c1", "list_projects", map[string]any{})), start.Add(30*time.Millisecond))
+	trace.observe(textEvent("projects_agent", "```json\n{}\n```", false), start.Add(40*time.Millisecond))
+	trace.observe(textEvent("projects_agent", "Shall I add it?", false), start.Add(45*time.Millisecond))
+
+	trace.confirm("Reply \"yes\" to apply.", start.Add(60*time.Millisecond))
+
+	resp := trace.response("s1")
+	if !reflect.DeepEqual(resp.Replies, []string{"Reply \"yes\" to apply."}) || resp.AnsweredBy != "projects_agent" {
+		t.Fatalf("response = %+v", resp)
+	}
+	last := resp.Trace[len(resp.Trace)-1]
+	if last.Kind != traceConfirm || last.Author != "projects_agent" || last.Reply == nil || *last.Reply != 0 || last.AtMS != 60 || last.LatencyMS != 15 {
+		t.Fatalf("confirm step = %+v", last)
+	}
+	// The replies the steps pointed at are gone.
+	for _, s := range resp.Trace[:len(resp.Trace)-1] {
+		if s.Reply != nil {
+			t.Fatalf("step %+v still points at a replaced reply", s)
+		}
+	}
+	if got := trace.summary(); got != "answered by projects_agent using list_projects(active=true)" {
+		t.Fatalf("summary = %q", got)
+	}
+}
+
+func TestChatTraceEmptyRun(t *testing.T) {
+	trace := newChatTrace(time.Now())
+	trace.observe(&session.Event{Author: "root_agent"}, time.Now())
+	resp := trace.response("s1")
+	if resp.Replies == nil || len(resp.Replies) != 0 || resp.AnsweredBy != "" || len(resp.Trace) != 0 {
+		t.Fatalf("response = %+v", resp)
+	}
+	if got := trace.summary(); got != "no reply" {
+		t.Fatalf("summary = %q", got)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	ckdb "career-koala/db"
+)
+
+const usage = `usage: import <command> [flags] <file>
+
+commands:
+  coding   import a CSV list of coding problems
+  archive  restore a JSON archive from GET /export`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	switch cmd {
+	case "coding":
+		runCoding(args)
+	case "archive":
+		runArchive(args)
+	default:
+		log.Fatalf("unknown command %q\n%s", cmd, usage)
+	}
+}
+
+func runCoding(args []string) {
+	fs := flag.NewFlagSet("coding", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "validate and report without saving")
+	mapping := fs.String("map", "", "header overrides, e.g. number=LC #,title=Name")
+	goals := fs.String("goals", "", "create linked goals: daily or weekly")
+	start := fs.String("goal-start", "", "first goal date (YYYY-MM-DD, default today)")
+	perGoal := fs.Int("per-goal", 0, "problems per goal period (default 1 daily, 5 weekly)")
+	user := fs.String("user", ckdb.DefaultUserName, "user to import for")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("usage: import coding [flags] <file.csv|->")
+	}
+
+	opts := ckdb.CodingImportOptions{DryRun: *dryRun, GoalType: *goals, PerGoal: *perGoal}
+	var err error
+	if opts.Mapping, err = ckdb.ParseImportMapping(*mapping); err != nil {
+		log.Fatal(err)
+	}
+	if *start != "" {
+		if opts.GoalStart, err = time.Parse("2006-01-02", *start); err != nil {
+			log.Fatalf("invalid -goal-start: %v", err)
+		}
+	}
+
+	in := openInput(fs.Arg(0))
+	defer in.Close()
+	dbConn := openDB()
+	defer dbConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	ctx = asUser(ctx, dbConn, *user)
+	result, err := ckdb.ImportCodingProblems(ctx, dbConn, in, opts)
+	if err != nil {
+		log.Fatalf("import coding: %v", err)
+	}
+	printJSON(result)
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runArchive(args []string) {
+	fs := flag.NewFlagSet("archive", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "validate and report without saving")
+	user := fs.String("user", ckdb.DefaultUserName, "user to restore the archive for")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("usage: import archive [-dry-run] [-user name] <archive.json|->")
+	}
+
+	in := openInput(fs.Arg(0))
+	defer in.Close()
+	var archive ckdb.Archive
+	if err := json.NewDecoder(in).Decode(&archive); err != nil {
+		log.Fatalf("decode archive: %v", err)
+	}
+	dbConn := openDB()
+	defer dbConn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	ctx = asUser(ctx, dbConn, *user)
+	result, err := ckdb.ImportArchive(ctx, dbConn, archive, *dryRun)
+	if err != nil {
+		log.Fatalf("import archive: %v", err)
+	}
+	// The id map is only useful to API callers; keep the CLI output short.
+	result.IDMap = nil
+	printJSON(result)
+}
+
+func openInput(path string) io.ReadCloser {
+	if path == "-" {
+		return io.NopCloser(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("open %s: %v", path, err)
+	}
+	return f
+}
+
+func openDB() *sql.DB {
+	dbConn, err := sql.Open("pgx", ckdb.DSNFromEnv())
+	if err != nil {
+		log.Fatalf("db open: %v", err)
+	}
+	if err := dbConn.Ping(); err != nil {
+		log.Fatalf("db ping: %v", err)
+	}
+	return dbConn
+}
+
+// asUser scopes ctx to the user called name, creating it if needed.
+func asUser(ctx context.Context, dbConn *sql.DB, name string) context.Context {
+	u, err := ckdb.EnsureUser(ctx, dbConn, name)
+	if err != nil {
+		log.Fatalf("user %s: %v", name, err)
+	}
+	return ckdb.WithUser(ctx, u.ID)
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
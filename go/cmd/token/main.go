@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	ckdb "career-koala/db"
+)
+
+const usage = `usage: token <command> [flags]
+
+commands:
+  create  issue a personal API token (printed once)
+  list    list tokens without their secrets
+  revoke  revoke a token by id`
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	switch cmd {
+	case "create":
+		runCreate(args)
+	case "list":
+		runList(args)
+	case "revoke":
+		runRevoke(args)
+	default:
+		log.Fatalf("unknown command %q\n%s", cmd, usage)
+	}
+}
+
+func runCreate(args []string) {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	user := fs.String("user", ckdb.DefaultUserName, "user the token acts as (created if new)")
+	name := fs.String("name", "", "label to tell tokens apart, e.g. laptop")
+	expires := fs.Duration("expires", 0, "lifetime, e.g. 2160h (default never)")
+	_ = fs.Parse(args)
+	if fs.NArg() != 0 {
+		log.Fatal("usage: token create [-user name] [-name label] [-expires duration]")
+	}
+
+	dbConn := openDB()
+	defer dbConn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tok, secret, err := ckdb.CreateAPIToken(ctx, dbConn, *user, *name, *expires)
+	if err != nil {
+		log.Fatalf("create token: %v", err)
+	}
+	printJSON(struct {
+		ckdb.APIToken
+		Token string `json:"token"`
+	}{tok, secret})
+	fmt.Fprintln(os.Stderr, "Store the token now; it can't be shown again. Send it as \"Authorization: Bearer <token>\".")
+}
+
+func runList(args []string) {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	user := fs.String("user", "", "only this user's tokens (default all)")
+	_ = fs.Parse(args)
+
+	dbConn := openDB()
+	defer dbConn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tokens, err := ckdb.ListAPITokens(ctx, dbConn, *user)
+	if err != nil {
+		log.Fatalf("list tokens: %v", err)
+	}
+	printJSON(tokens)
+}
+
+func runRevoke(args []string) {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		log.Fatal("usage: token revoke <id>")
+	}
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		log.Fatalf("invalid token id %q", fs.Arg(0))
+	}
+
+	dbConn := openDB()
+	defer dbConn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := ckdb.RevokeAPIToken(ctx, dbConn, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Fatalf("no token with id %d", id)
+		}
+		log.Fatalf("revoke token: %v", err)
+	}
+	fmt.Printf("revoked token %d\n", id)
+}
+
+func openDB() *sql.DB {
+	dbConn, err := sql.Open("pgx", ckdb.DSNFromEnv())
+	if err != nil {
+		log.Fatalf("db open: %v", err)
+	}
+	if err := dbConn.Ping(); err != nil {
+		log.Fatalf("db ping: %v", err)
+	}
+	return dbConn
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
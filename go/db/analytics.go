@@ -0,0 +1,283 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// funnelStages are the forward pipeline stages used for conversion rates.
+var funnelStages = []string{JobApplied, JobRecruiterScreen, JobTechnical, JobOnsite, JobOffer, JobAccepted}
+
+// openJobStatuses are stages still waiting on the company or the candidate.
+var openJobStatuses = []string{JobApplied, JobRecruiterScreen, JobTechnical, JobOnsite, JobOffer}
+
+// respondedStatuses mean the company answered the application.
+var respondedStatuses = []string{JobRecruiterScreen, JobTechnical, JobOnsite, JobOffer, JobAccepted, JobRejected}
+
+type JobAnalyticsOptions struct {
+	Weeks     int // weeks of applications-per-week history, default 12
+	StaleDays int // days without an update before an open application is stale, default 14
+}
+
+type WeekCount struct {
+	WeekOf time.Time `json:"week_of"`
+	Count  int       `json:"count"`
+}
+
+// StageConversion is the share of applications that reached From and went on
+// to reach To (or a later stage).
+type StageConversion struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Reached int     `json:"reached"`
+	Moved   int     `json:"moved"`
+	Rate    float64 `json:"rate"`
+}
+
+type CompanyResponse struct {
+	Company   string  `json:"company"`
+	Applied   int     `json:"applied"`
+	Responded int     `json:"responded"`
+	Rate      float64 `json:"rate"`
+}
+
+type StaleApplication struct {
+	ID         int64     `json:"id"`
+	JobTitle   string    `json:"job_title"`
+	Company    string    `json:"company"`
+	Status     string    `json:"status"`
+	LastUpdate time.Time `json:"last_update"`
+	DaysIdle   int       `json:"days_idle"`
+}
+
+type JobAnalytics struct {
+	Total                int                `json:"total"`
+	ByStatus             map[string]int     `json:"by_status"`
+	ApplicationsPerWeek  []WeekCount        `json:"applications_per_week"`
+	StageConversions     []StageConversion  `json:"stage_conversions"`
+	MedianDaysToResponse *float64           `json:"median_days_to_response"`
+	ResponseRate         float64            `json:"response_rate"`
+	ResponseByCompany    []CompanyResponse  `json:"response_by_company"`
+	StaleDays            int                `json:"stale_days"`
+	StaleApplications    []StaleApplication `json:"stale_applications"`
+}
+
+// GetJobAnalytics computes the job search funnel summary.
+func GetJobAnalytics(ctx context.Context, db DBTX, opts JobAnalyticsOptions) (JobAnalytics, error) {
+	if opts.Weeks <= 0 {
+		opts.Weeks = 12
+	}
+	if opts.StaleDays <= 0 {
+		opts.StaleDays = 14
+	}
+	a := JobAnalytics{ByStatus: map[string]int{}, StaleDays: opts.StaleDays}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return a, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT status, count(*) FROM job_applications WHERE user_id = $1 GROUP BY status`, uid)
+	if err != nil {
+		return a, err
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			rows.Close()
+			return a, err
+		}
+		a.ByStatus[status] = n
+		a.Total += n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return a, err
+	}
+
+	if a.ApplicationsPerWeek, err = applicationsPerWeek(ctx, db, uid, opts.Weeks); err != nil {
+		return a, err
+	}
+	ranks, err := furthestStages(ctx, db, uid)
+	if err != nil {
+		return a, err
+	}
+	a.StageConversions = funnelConversions(ranks)
+
+	var median sql.NullFloat64
+	err = db.QueryRowContext(ctx,
+		`SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY result_date - applied_date)
+         FROM job_applications
+         WHERE user_id = $1 AND applied_date IS NOT NULL AND result_date IS NOT NULL AND result_date >= applied_date`, uid,
+	).Scan(&median)
+	if err != nil {
+		return a, err
+	}
+	if median.Valid {
+		a.MedianDaysToResponse = &median.Float64
+	}
+
+	if a.ResponseByCompany, err = responseByCompany(ctx, db, uid); err != nil {
+		return a, err
+	}
+	var applied, responded int
+	for _, c := range a.ResponseByCompany {
+		applied += c.Applied
+		responded += c.Responded
+	}
+	a.ResponseRate = ratio(responded, applied)
+
+	if a.StaleApplications, err = ListStaleJobApplications(ctx, db, opts.StaleDays); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+func applicationsPerWeek(ctx context.Context, db DBTX, uid int64, weeks int) ([]WeekCount, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT w::date, count(j.id)
+         FROM generate_series(date_trunc('week', CURRENT_DATE) - ($1::int - 1) * INTERVAL '1 week',
+                              date_trunc('week', CURRENT_DATE), INTERVAL '1 week') AS w
+         LEFT JOIN job_applications j ON date_trunc('week', j.applied_date) = w AND j.user_id = $2
+         GROUP BY w ORDER BY w`, weeks, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []WeekCount{}
+	for rows.Next() {
+		var wc WeekCount
+		if err := rows.Scan(&wc.WeekOf, &wc.Count); err != nil {
+			return nil, err
+		}
+		res = append(res, wc)
+	}
+	return res, rows.Err()
+}
+
+// furthestStages returns, per application, the index in funnelStages of the
+// furthest stage it ever reached (-1 for applications never sent).
+func furthestStages(ctx context.Context, db DBTX, uid int64) (map[int64]int, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT job_application_id, to_status FROM job_status_history WHERE user_id = $1
+         UNION
+         SELECT id, status FROM job_applications WHERE user_id = $1`, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ranks := map[int64]int{}
+	for rows.Next() {
+		var id int64
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		rank, seen := ranks[id]
+		if r := funnelRank(status); !seen || r > rank {
+			ranks[id] = r
+		}
+	}
+	return ranks, rows.Err()
+}
+
+// funnelRank places a status in the funnel. Rejected, withdrawn and ghosted
+// applications were at least applied to; saved ones were not.
+func funnelRank(status string) int {
+	for i, stage := range funnelStages {
+		if stage == status {
+			return i
+		}
+	}
+	if status == JobSaved || status == "" {
+		return -1
+	}
+	return 0
+}
+
+func funnelConversions(ranks map[int64]int) []StageConversion {
+	reached := make([]int, len(funnelStages))
+	for _, rank := range ranks {
+		for i := 0; i <= rank; i++ {
+			reached[i]++
+		}
+	}
+	res := make([]StageConversion, 0, len(funnelStages)-1)
+	for i := 0; i+1 < len(funnelStages); i++ {
+		res = append(res, StageConversion{
+			From:    funnelStages[i],
+			To:      funnelStages[i+1],
+			Reached: reached[i],
+			Moved:   reached[i+1],
+			Rate:    ratio(reached[i+1], reached[i]),
+		})
+	}
+	return res
+}
+
+func responseByCompany(ctx context.Context, db DBTX, uid int64) ([]CompanyResponse, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT COALESCE(NULLIF(TRIM(j.company),''), '(unknown)') AS company,
+                count(*),
+                count(*) FILTER (WHERE j.result_date IS NOT NULL OR EXISTS (
+                    SELECT 1 FROM job_status_history h
+                    WHERE h.job_application_id = j.id AND h.to_status = ANY($1)))
+         FROM job_applications j
+         WHERE j.status <> $2 AND j.user_id = $3
+         GROUP BY 1 ORDER BY 2 DESC, 1`, pqStringArray(respondedStatuses), JobSaved, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []CompanyResponse{}
+	for rows.Next() {
+		var c CompanyResponse
+		if err := rows.Scan(&c.Company, &c.Applied, &c.Responded); err != nil {
+			return nil, err
+		}
+		c.Rate = ratio(c.Responded, c.Applied)
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// ListStaleJobApplications returns open applications whose last status change
+// (or applied date) is more than days ago, oldest first.
+func ListStaleJobApplications(ctx context.Context, db DBTX, days int) ([]StaleApplication, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT j.id, j.job_title, COALESCE(j.company,''), j.status, t.last_update
+         FROM job_applications j
+         CROSS JOIN LATERAL (
+             SELECT GREATEST(MAX(h.changed_at), j.applied_date::timestamptz) AS last_update
+             FROM job_status_history h WHERE h.job_application_id = j.id
+         ) t
+         WHERE j.user_id = $3 AND j.status = ANY($1) AND t.last_update < now() - make_interval(days => $2)
+         ORDER BY t.last_update, j.id`, pqStringArray(openJobStatuses), days, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	now := time.Now()
+	res := []StaleApplication{}
+	for rows.Next() {
+		var s StaleApplication
+		if err := rows.Scan(&s.ID, &s.JobTitle, &s.Company, &s.Status, &s.LastUpdate); err != nil {
+			return nil, err
+		}
+		s.DaysIdle = int(now.Sub(s.LastUpdate).Hours() / 24)
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,28 @@
+package db
+
+import "testing"
+
+func TestFunnelConversions(t *testing.T) {
+	ranks := map[int64]int{
+		1: funnelRank(JobSaved),
+		2: funnelRank(JobApplied),
+		3: funnelRank(JobGhosted),
+		4: funnelRank(JobRecruiterScreen),
+		5: funnelRank(JobOnsite),
+	}
+	conv := funnelConversions(ranks)
+	if len(conv) != len(funnelStages)-1 {
+		t.Fatalf("expected %d conversions, got %d", len(funnelStages)-1, len(conv))
+	}
+	first := conv[0]
+	if first.From != JobApplied || first.To != JobRecruiterScreen || first.Reached != 4 || first.Moved != 2 || first.Rate != 0.5 {
+		t.Fatalf("unexpected applied -> screen conversion: %+v", first)
+	}
+	// Skipping a stage still counts as passing through it.
+	if conv[1].Reached != 2 || conv[1].Moved != 1 {
+		t.Fatalf("unexpected screen -> technical conversion: %+v", conv[1])
+	}
+	if last := conv[len(conv)-1]; last.Reached != 0 || last.Rate != 0 {
+		t.Fatalf("expected empty offer -> accepted conversion, got %+v", last)
+	}
+}
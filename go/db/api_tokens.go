@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix starts every personal API token, so a leaked one is easy to
+// recognise (and to tell apart from a JWT).
+const APITokenPrefix = "ckt_"
+
+// apiTokenShownPrefix is how much of a token listings show.
+const apiTokenShownPrefix = len(APITokenPrefix) + 6
+
+// APIToken is a personal API token without its secret, which is only
+// returned once, by CreateAPIToken.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	User       string     `json:"user"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+const apiTokenSelect = `SELECT t.id, u.name, t.name, t.prefix, t.created_at, t.expires_at, t.last_used_at, t.revoked_at FROM api_tokens t JOIN users u ON u.id = t.user_id`
+
+func scanAPIToken(row rowScanner) (APIToken, error) {
+	var t APIToken
+	err := row.Scan(&t.ID, &t.User, &t.Name, &t.Prefix, &t.CreatedAt, &t.ExpiresAt, &t.LastUsedAt, &t.RevokedAt)
+	return t, err
+}
+
+func hashAPIToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIToken issues a token for the user called userName (created if
+// needed) and returns it with its secret. A zero expiresIn never expires.
+func CreateAPIToken(ctx context.Context, db DBTX, userName, name string, expiresIn time.Duration) (APIToken, string, error) {
+	if expiresIn < 0 {
+		return APIToken{}, "", fieldErrorf("expiry must not be negative")
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return APIToken{}, "", err
+	}
+	secret := APITokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	var expiresAt *time.Time
+	if expiresIn > 0 {
+		t := time.Now().Add(expiresIn)
+		expiresAt = &t
+	}
+	var tok APIToken
+	err := inTx(ctx, db, func(tx DBTX) error {
+		u, err := EnsureUser(ctx, tx, userName)
+		if err != nil {
+			return err
+		}
+		var id int64
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO api_tokens (user_id, name, token_hash, prefix, expires_at) VALUES ($1,$2,$3,$4,$5) RETURNING id`,
+			u.ID, strings.TrimSpace(name), hashAPIToken(secret), secret[:apiTokenShownPrefix], expiresAt,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		tok, err = scanAPIToken(tx.QueryRowContext(ctx, apiTokenSelect+` WHERE t.id=$1`, id))
+		return err
+	})
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	return tok, secret, nil
+}
+
+// LookupAPIToken returns the owner of a live (unrevoked, unexpired) token and
+// records its use; sql.ErrNoRows means the token is unknown or dead.
+func LookupAPIToken(ctx context.Context, db DBTX, secret string) (User, error) {
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		return User{}, sql.ErrNoRows
+	}
+	return scanUser(db.QueryRowContext(ctx,
+		`UPDATE api_tokens t SET last_used_at = now()
+         FROM users u
+         WHERE u.id = t.user_id AND t.token_hash = $1 AND t.revoked_at IS NULL
+           AND (t.expires_at IS NULL OR t.expires_at > now())
+         RETURNING u.id, u.name, u.created_at, COALESCE(u.notify_email, ''), COALESCE(u.notify_webhook_url, '')`,
+		hashAPIToken(secret)))
+}
+
+// ListAPITokens returns the tokens of the user called userName (every
+// user's when empty), newest first.
+func ListAPITokens(ctx context.Context, db DBTX, userName string) ([]APIToken, error) {
+	rows, err := db.QueryContext(ctx,
+		apiTokenSelect+` WHERE $1 = '' OR u.name = $1 ORDER BY t.created_at DESC, t.id DESC`, strings.TrimSpace(userName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, t)
+	}
+	return res, rows.Err()
+}
+
+// RevokeAPIToken stops a token from working. Revoking it again is a no-op;
+// an unknown id is sql.ErrNoRows.
+func RevokeAPIToken(ctx context.Context, db DBTX, id int64) error {
+	res, err := db.ExecContext(ctx, `UPDATE api_tokens SET revoked_at = COALESCE(revoked_at, now()) WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAPITokenChecksBeforeQuerying(t *testing.T) {
+	ctx := context.Background()
+	// A nil DBTX panics if queried, so these must fail up front.
+	if _, err := LookupAPIToken(ctx, nil, "eyJhbGciOiJSUzI1NiJ9.e30.sig"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for a non-API token, got %v", err)
+	}
+	var fieldErr *FieldError
+	if _, _, err := CreateAPIToken(ctx, nil, "ana", "laptop", -time.Hour); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *FieldError for a negative expiry, got %v", err)
+	}
+}
+
+func TestHashAPIToken(t *testing.T) {
+	a, b := hashAPIToken(APITokenPrefix+"one"), hashAPIToken(APITokenPrefix+"two")
+	if a == b || len(a) != 64 || a != hashAPIToken(APITokenPrefix+"one") {
+		t.Fatalf("unexpected hashes %q %q", a, b)
+	}
+}
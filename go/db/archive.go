@@ -0,0 +1,344 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveVersion is bumped whenever the archive layout changes in a way
+// older importers cannot read.
+const ArchiveVersion = 2
+
+// Archive is a full export of the career data. Rows are the JSON form of each
+// table row (to_jsonb), keyed by table name.
+type Archive struct {
+	Version    int                          `json:"version"`
+	ExportedAt time.Time                    `json:"exported_at"`
+	Tables     map[string][]json.RawMessage `json:"tables"`
+}
+
+// archiveTable describes one exported table. Refs maps foreign key columns to
+// the table they point at; tables are listed so references come first.
+type archiveTable struct {
+	Name string
+	Refs map[string]string
+	// ImportOnly tables are no longer exported but still restored from
+	// older archives.
+	ImportOnly bool
+}
+
+var goalRefs = map[string]string{
+	"job_application_id": "job_applications",
+	"coding_problem_id":  "coding_problems",
+	"project_id":         "projects",
+	"contact_id":         "networking_contacts",
+}
+
+var archiveTables = []archiveTable{
+	{Name: "job_applications"},
+	{Name: "job_status_history", Refs: map[string]string{"job_application_id": "job_applications"}},
+	{Name: "coding_problems"},
+	{Name: "coding_attempts", Refs: map[string]string{"coding_problem_id": "coding_problems"}},
+	{Name: "projects"},
+	{Name: "networking_contacts"},
+	{Name: "meetings", Refs: map[string]string{"job_application_id": "job_applications", "contact_id": "networking_contacts"}},
+	{Name: "meeting_contacts", Refs: map[string]string{"meeting_id": "meetings", "contact_id": "networking_contacts"}},
+	{Name: "goal_recurrences", Refs: goalRefs},
+	{Name: "goals", Refs: map[string]string{
+		"job_application_id": "job_applications",
+		"coding_problem_id":  "coding_problems",
+		"project_id":         "projects",
+		"contact_id":         "networking_contacts",
+		"recurrence_id":      "goal_recurrences",
+	}},
+	// Version 1 archives had a table per goal period; they are restored
+	// through the compatibility views.
+	{Name: "daily_goals", Refs: goalRefs, ImportOnly: true},
+	{Name: "weekly_goals", Refs: goalRefs, ImportOnly: true},
+	{Name: "monthly_goals", Refs: goalRefs, ImportOnly: true},
+}
+
+// ArchiveTableNames lists the exported tables in restore order.
+func ArchiveTableNames() []string {
+	names := make([]string, 0, len(archiveTables))
+	for _, t := range archiveTables {
+		if t.ImportOnly {
+			continue
+		}
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func lookupArchiveTable(name string) (archiveTable, bool) {
+	for _, t := range archiveTables {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return archiveTable{}, false
+}
+
+// ExportArchive reads every archived table for the user in ctx. Rows leave
+// out user_id, so an archive can be restored into another account.
+func ExportArchive(ctx context.Context, db DBTX) (Archive, error) {
+	archive := Archive{Version: ArchiveVersion, ExportedAt: time.Now().UTC(), Tables: map[string][]json.RawMessage{}}
+	for _, t := range archiveTables {
+		if t.ImportOnly {
+			continue
+		}
+		rows, err := exportRows(ctx, db, t.Name)
+		if err != nil {
+			return archive, fmt.Errorf("export %s: %w", t.Name, err)
+		}
+		archive.Tables[t.Name] = rows
+	}
+	return archive, nil
+}
+
+func exportRows(ctx context.Context, db DBTX, table string) ([]json.RawMessage, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT to_jsonb(t) - 'user_id' FROM %s t WHERE user_id = $1 ORDER BY id", table), uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []json.RawMessage{}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		res = append(res, json.RawMessage(raw))
+	}
+	return res, rows.Err()
+}
+
+// WriteTableCSV writes one archived table as CSV, columns in table order.
+// Arrays are joined with ";".
+func WriteTableCSV(ctx context.Context, db DBTX, table string, w io.Writer) error {
+	if t, ok := lookupArchiveTable(table); !ok || t.ImportOnly {
+		return fieldErrorf("unknown table %q (use one of: %s)", table, strings.Join(ArchiveTableNames(), ", "))
+	}
+	columns, err := tableColumns(ctx, db, table)
+	if err != nil {
+		return err
+	}
+	rows, err := exportRows(ctx, db, table)
+	if err != nil {
+		return err
+	}
+	out := csv.NewWriter(w)
+	if err := out.Write(columns); err != nil {
+		return err
+	}
+	record := make([]string, len(columns))
+	for _, raw := range rows {
+		row, err := decodeArchiveRow(raw)
+		if err != nil {
+			return err
+		}
+		for i, col := range columns {
+			record[i] = csvValue(row[col])
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func csvValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, csvValue(item))
+		}
+		return strings.Join(items, ";")
+	case map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// tableColumns returns the columns of table in definition order, except the
+// owner column, which is never exported or imported.
+func tableColumns(ctx context.Context, db DBTX, table string) ([]string, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT column_name FROM information_schema.columns
+         WHERE table_schema = current_schema() AND table_name = $1 AND column_name <> 'user_id'
+         ORDER BY ordinal_position`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+// ArchiveImportResult reports the rows restored per table.
+type ArchiveImportResult struct {
+	DryRun   bool           `json:"dry_run"`
+	Imported map[string]int `json:"imported"`
+	// IDMap maps old ids to new ids per table (empty on dry runs).
+	IDMap map[string]map[int64]int64 `json:"id_map,omitempty"`
+}
+
+// ImportArchive restores an archive in one transaction. Every row gets a new
+// id and foreign keys are rewritten to the new ids, so an archive can be
+// loaded into an empty database or merged into an existing one. Rows are
+// restored for the user in ctx.
+func ImportArchive(ctx context.Context, dbConn *sql.DB, archive Archive, dryRun bool) (ArchiveImportResult, error) {
+	res := ArchiveImportResult{DryRun: dryRun, Imported: map[string]int{}}
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return res, fieldErrorf("unsupported archive version %d (this server reads up to %d)", archive.Version, ArchiveVersion)
+	}
+	for name := range archive.Tables {
+		if _, ok := lookupArchiveTable(name); !ok {
+			return res, fieldErrorf("unknown table %q in archive", name)
+		}
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	idMap := map[string]map[int64]int64{}
+	for _, t := range archiveTables {
+		rows := archive.Tables[t.Name]
+		idMap[t.Name] = map[int64]int64{}
+		if len(rows) == 0 {
+			continue
+		}
+		if err := importTable(ctx, tx, uid, t, rows, idMap); err != nil {
+			return res, err
+		}
+		res.Imported[t.Name] = len(rows)
+		if t.Name == "job_applications" && len(archive.Tables["job_status_history"]) > 0 {
+			// The insert trigger recorded a fresh history row for every job;
+			// the archived history replaces it.
+			if err := dropImportedHistory(ctx, tx, uid, idMap["job_applications"]); err != nil {
+				return res, err
+			}
+		}
+	}
+
+	if dryRun {
+		return res, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return res, err
+	}
+	res.IDMap = idMap
+	return res, nil
+}
+
+func importTable(ctx context.Context, q DBTX, uid int64, t archiveTable, rows []json.RawMessage, idMap map[string]map[int64]int64) error {
+	columns, err := tableColumns(ctx, q, t.Name)
+	if err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, col := range columns {
+		known[col] = true
+	}
+
+	for i, raw := range rows {
+		row, err := decodeArchiveRow(raw)
+		if err != nil {
+			return fieldErrorf("%s row %d: %v", t.Name, i+1, err)
+		}
+		oldID := getIntPtr(row, "id")
+		delete(row, "id")
+		delete(row, "user_id")
+		for col, refTable := range t.Refs {
+			ref := getIntPtr(row, col)
+			if ref == nil {
+				continue
+			}
+			newID, ok := idMap[refTable][*ref]
+			if !ok {
+				return fieldErrorf("%s row %d: %s %d not found in archive", t.Name, i+1, col, *ref)
+			}
+			row[col] = newID
+		}
+
+		cols := make([]string, 0, len(row))
+		for col := range row {
+			if !known[col] {
+				return fieldErrorf("%s row %d: unknown column %q", t.Name, i+1, col)
+			}
+			cols = append(cols, col)
+		}
+		sort.Strings(cols)
+		cols = append(cols, "user_id")
+		row["user_id"] = uid
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		var newID int64
+		err = q.QueryRowContext(ctx, fmt.Sprintf(
+			"INSERT INTO %[1]s (%[2]s) SELECT %[2]s FROM jsonb_populate_record(NULL::%[1]s, $1::jsonb) RETURNING id",
+			t.Name, strings.Join(cols, ", "),
+		), data).Scan(&newID)
+		if err != nil {
+			return fmt.Errorf("%s row %d: %w", t.Name, i+1, err)
+		}
+		if oldID != nil {
+			idMap[t.Name][*oldID] = newID
+		}
+	}
+	return nil
+}
+
+func dropImportedHistory(ctx context.Context, q DBTX, uid int64, jobs map[int64]int64) error {
+	ids := make([]string, 0, len(jobs))
+	for _, id := range jobs {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	_, err := q.ExecContext(ctx, `DELETE FROM job_status_history WHERE user_id = $1 AND job_application_id = ANY($2::int[])`, uid, "{"+strings.Join(ids, ",")+"}")
+	return err
+}
+
+func decodeArchiveRow(raw json.RawMessage) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	row := map[string]interface{}{}
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
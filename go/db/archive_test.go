@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestCSVValue(t *testing.T) {
+	cases := []struct {
+		in   interface{}
+		want string
+	}{
+		{nil, ""},
+		{"hello", "hello"},
+		{json.Number("42"), "42"},
+		{true, "true"},
+		{[]interface{}{"go", "sql"}, "go;sql"},
+		{map[string]interface{}{"a": json.Number("1")}, `{"a":1}`},
+	}
+	for _, c := range cases {
+		if got := csvValue(c.in); got != c.want {
+			t.Errorf("csvValue(%#v) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestArchiveTablesReferenceEarlierTables(t *testing.T) {
+	seen := map[string]bool{}
+	for _, table := range archiveTables {
+		for col, ref := range table.Refs {
+			if !seen[ref] {
+				t.Errorf("%s.%s references %s before it is restored", table.Name, col, ref)
+			}
+		}
+		seen[table.Name] = true
+	}
+}
+
+func TestImportArchiveRejectsBadArchives(t *testing.T) {
+	cases := map[string]Archive{
+		"old version":    {Version: 0},
+		"future version": {Version: ArchiveVersion + 1},
+		"unknown table":  {Version: ArchiveVersion, Tables: map[string][]json.RawMessage{"users": nil}},
+	}
+	for name, archive := range cases {
+		// Validation happens before the transaction starts, so no db is needed.
+		_, err := ImportArchive(context.Background(), nil, archive, true)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Errorf("%s: expected field error, got %v", name, err)
+		}
+	}
+}
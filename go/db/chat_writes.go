@@ -49,7 +49,23 @@ func ExtractWritePayload(text string) (*WritePayload, string, string, error) {
 	for _, req := range payload.WriteRequests {
 		action := strings.ToLower(strings.TrimSpace(req.Action))
 		table := strings.TrimSpace(req.Table)
-		if action != "insert" || table == "" || len(req.Records) == 0 {
+		if table == "" || len(req.Records) == 0 {
+			return nil, "", "", fmt.Errorf("invalid write request")
+		}
+		switch action {
+		case "insert":
+		case "update", "delete":
+			for _, record := range req.Records {
+				if _, err := recordSelector(record); err != nil {
+					return nil, "", "", fmt.Errorf("invalid %s request: %w", action, err)
+				}
+				if action == "update" {
+					if _, err := recordSet(record); err != nil {
+						return nil, "", "", fmt.Errorf("invalid update request: %w", err)
+					}
+				}
+			}
+		default:
 			return nil, "", "", fmt.Errorf("invalid write request")
 		}
 		summaryParts = append(summaryParts, fmt.Sprintf("%s %d -> %s", action, len(req.Records), table))
@@ -57,142 +73,335 @@ func ExtractWritePayload(text string) (*WritePayload, string, string, error) {
 	return &payload, raw, strings.Join(summaryParts, ", "), nil
 }
 
+// WriteFailure identifies a request (and record, when applicable) of a
+// WritePayload that could not be applied. Indexes are 1-based; Record is 0
+// when the request itself is invalid.
+type WriteFailure struct {
+	Request int
+	Record  int
+	Table   string
+	Err     error
+}
+
+func (f WriteFailure) String() string {
+	if f.Record == 0 {
+		return fmt.Sprintf("request %d (%s): %v", f.Request, f.Table, f.Err)
+	}
+	return fmt.Sprintf("request %d (%s) record %d: %v", f.Request, f.Table, f.Record, f.Err)
+}
+
+// ApplyError is returned by ApplyWriteRequests when any record fails. The
+// surrounding transaction has been rolled back, so nothing was written.
+type ApplyError struct {
+	Failures []WriteFailure
+}
+
+func (e *ApplyError) Error() string {
+	parts := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		parts = append(parts, f.String())
+	}
+	return "no changes were saved; " + strings.Join(parts, "; ")
+}
+
+type recordWriter func(ctx context.Context, q DBTX, record map[string]interface{}) error
+
+var insertWriters = map[string]recordWriter{
+	"job_applications": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		_, err := InsertJobApplication(ctx, q, JobApplication{
+			JobTitle:   getString(record, "job_title"),
+			Company:    getString(record, "company"),
+			JobLink:    getString(record, "job_link"),
+			Applied:    getDatePtr(record, "applied_date"),
+			ResultDate: getDatePtr(record, "result_date"),
+			Status:     getString(record, "status"),
+			Notes:      getString(record, "notes"),
+		})
+		return err
+	},
+	"coding_problems": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		_, err := InsertCodingProblem(ctx, q, CodingProblem{
+			LeetCodeNumber: getInt(record, "leetcode_number"),
+			Title:          getString(record, "title"),
+			Pattern:        getString(record, "pattern"),
+			ProblemLink:    getString(record, "problem_link"),
+			Difficulty:     getString(record, "difficulty"),
+			AlreadySolved:  getBool(record, "already_solved"),
+			Notes:          getString(record, "notes"),
+		})
+		return err
+	},
+	"projects": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		_, err := InsertProject(ctx, q, Project{
+			Name:      getString(record, "name"),
+			RepoURL:   getString(record, "repo_url"),
+			Active:    getBool(record, "active"),
+			TechStack: getStringSlice(record, "tech_stack"),
+			Summary:   getString(record, "summary"),
+		})
+		return err
+	},
+	"networking_contacts": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		_, err := InsertNetworkingContact(ctx, q, NetworkingContact{
+			PersonName:        getString(record, "person_name"),
+			HowMet:            getString(record, "how_met"),
+			LinkedInConnected: getBool(record, "linkedin_connected"),
+			Company:           getString(record, "company"),
+			Position:          getString(record, "position"),
+			Notes:             getString(record, "notes"),
+		})
+		return err
+	},
+	"coding_attempts": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		attempt, err := attemptFromRecord(record)
+		if err != nil {
+			return err
+		}
+		_, err = InsertCodingAttempt(ctx, q, attempt)
+		return err
+	},
+	"meetings": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		sessionTime := getTimestamp(record, "session_time")
+		if sessionTime == nil {
+			return fmt.Errorf("session_time is required")
+		}
+		var contactIDs []int64
+		if raw, ok := record["contact_ids"]; ok && raw != nil {
+			ids, err := intSlice(raw)
+			if err != nil {
+				return fmt.Errorf("contact_ids: %v", err)
+			}
+			contactIDs = ids
+		}
+		_, err := InsertMeeting(ctx, q, Meeting{
+			SessionName:    getString(record, "session_name"),
+			SessionType:    getString(record, "session_type"),
+			SessionTime:    *sessionTime,
+			Location:       getString(record, "location"),
+			Organizer:      getString(record, "organizer"),
+			Company:        getString(record, "company"),
+			Notes:          getString(record, "notes"),
+			JobApplication: getIntPtr(record, "job_application_id"),
+			Contact:        getIntPtr(record, "contact_id"),
+			ContactIDs:     contactIDs,
+		})
+		return err
+	},
+	"meeting_contacts": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		meetingID, contactID := getIntPtr(record, "meeting_id"), getIntPtr(record, "contact_id")
+		if meetingID == nil || contactID == nil {
+			return fmt.Errorf("meeting_id and contact_id are required")
+		}
+		return AddMeetingContact(ctx, q, *meetingID, *contactID)
+	},
+	"daily_goals": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		goal, err := goalFromRecord(record, "target_date")
+		if err != nil {
+			return err
+		}
+		_, err = InsertDailyGoal(ctx, q, goal)
+		return err
+	},
+	"weekly_goals": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		goal, err := goalFromRecord(record, "week_of")
+		if err != nil {
+			return err
+		}
+		_, err = InsertWeeklyGoal(ctx, q, goal)
+		return err
+	},
+	"monthly_goals": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		goal, err := goalFromRecord(record, "month_of")
+		if err != nil {
+			return err
+		}
+		_, err = InsertMonthlyGoal(ctx, q, goal)
+		return err
+	},
+	"goals": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		goal, err := GoalFromRecord(record, time.Now())
+		if err != nil {
+			return err
+		}
+		_, err = InsertGoal(ctx, q, goal.Period, goal)
+		return err
+	},
+	"goal_recurrences": func(ctx context.Context, q DBTX, record map[string]interface{}) error {
+		rec, err := GoalRecurrenceFromRecord(record)
+		if err != nil {
+			return err
+		}
+		_, err = InsertGoalRecurrence(ctx, q, rec)
+		return err
+	},
+}
+
+func goalFromRecord(record map[string]interface{}, dateKey string) (Goal, error) {
+	date := getDate(record, dateKey)
+	if date == nil {
+		return Goal{}, fmt.Errorf("%s is required", dateKey)
+	}
+	goal := Goal{
+		Description:    getString(record, "description"),
+		TargetDate:     *date,
+		Completed:      getBool(record, "completed"),
+		Metric:         getString(record, "metric"),
+		MetricFilter:   getString(record, "metric_filter"),
+		JobApplication: getIntPtr(record, "job_application_id"),
+		CodingProblem:  getIntPtr(record, "coding_problem_id"),
+		Project:        getIntPtr(record, "project_id"),
+		Contact:        getIntPtr(record, "contact_id"),
+	}
+	if n := getIntPtr(record, "target_count"); n != nil {
+		goal.TargetCount = int(*n)
+	}
+	return goal, nil
+}
+
+var errNoRowsMatched = fmt.Errorf("no rows matched")
+
+// recordSelector returns the WHERE map of an update/delete record: either its
+// "id" or its "match" object.
+func recordSelector(record map[string]interface{}) (map[string]interface{}, error) {
+	if id := getIntPtr(record, "id"); id != nil {
+		return idMatch(*id), nil
+	}
+	if match, ok := record["match"].(map[string]interface{}); ok && len(match) > 0 {
+		return match, nil
+	}
+	return nil, fmt.Errorf("id or match is required")
+}
+
+func recordSet(record map[string]interface{}) (map[string]interface{}, error) {
+	set, ok := record["set"].(map[string]interface{})
+	if !ok || len(set) == 0 {
+		return nil, fmt.Errorf("set is required")
+	}
+	return set, nil
+}
+
+func applyRecord(ctx context.Context, q DBTX, action, table string, record map[string]interface{}) error {
+	if action == "insert" {
+		return insertWriters[table](ctx, q, record)
+	}
+	spec, err := lookupTableSpec(table)
+	if err != nil {
+		return err
+	}
+	where, err := recordSelector(record)
+	if err != nil {
+		return err
+	}
+	var affected int64
+	if action == "update" {
+		set, err := recordSet(record)
+		if err != nil {
+			return err
+		}
+		affected, err = updateRows(ctx, q, spec, where, set)
+		if err != nil {
+			return err
+		}
+	} else {
+		affected, err = deleteRows(ctx, q, spec, where)
+		if err != nil {
+			return err
+		}
+	}
+	if affected == 0 {
+		return errNoRowsMatched
+	}
+	return nil
+}
+
+// ApplyWriteRequests applies the whole payload in a single transaction. Each
+// record runs under its own savepoint so a failure can be attributed to the
+// exact request/record while the remaining records are still checked; if
+// anything fails the transaction is rolled back and an *ApplyError is returned.
 func ApplyWriteRequests(ctx context.Context, dbConn *sql.DB, payload WritePayload) (string, error) {
+	return applyWriteRequests(ctx, dbConn, payload, nil)
+}
+
+// applyWriteRequests is ApplyWriteRequests, running claim (if set) first in
+// the same transaction.
+func applyWriteRequests(ctx context.Context, dbConn *sql.DB, payload WritePayload, claim func(tx *sql.Tx) error) (string, error) {
+	if failures := validateWriteRequests(payload); len(failures) > 0 {
+		return "", &ApplyError{Failures: failures}
+	}
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	if claim != nil {
+		if err := claim(tx); err != nil {
+			return "", err
+		}
+	}
+
 	total := 0
 	summaries := []string{}
-	for _, req := range payload.WriteRequests {
+	var failures []WriteFailure
+	for i, req := range payload.WriteRequests {
 		action := strings.ToLower(strings.TrimSpace(req.Action))
 		table := strings.ToLower(strings.TrimSpace(req.Table))
-		if action != "insert" {
-			return "", fmt.Errorf("unsupported action: %s", req.Action)
-		}
-		switch table {
-		case "job_applications":
-			for _, record := range req.Records {
-				if _, err := InsertJobApplication(ctx, dbConn, JobApplication{
-					JobTitle:   getString(record, "job_title"),
-					Company:    getString(record, "company"),
-					JobLink:    getString(record, "job_link"),
-					Applied:    getDatePtr(record, "applied_date"),
-					ResultDate: getDatePtr(record, "result_date"),
-					Status:     getString(record, "status"),
-					Notes:      getString(record, "notes"),
-				}); err != nil {
-					return "", err
-				}
-				total++
-			}
-			summaries = append(summaries, fmt.Sprintf("%d job_applications", len(req.Records)))
-		case "coding_problems":
-			for _, record := range req.Records {
-				if _, err := InsertCodingProblem(ctx, dbConn, CodingProblem{
-					LeetCodeNumber: getInt(record, "leetcode_number"),
-					Title:          getString(record, "title"),
-					Pattern:        getString(record, "pattern"),
-					ProblemLink:    getString(record, "problem_link"),
-					Difficulty:     getString(record, "difficulty"),
-					AlreadySolved:  getBool(record, "already_solved"),
-					Notes:          getString(record, "notes"),
-				}); err != nil {
-					return "", err
-				}
-				total++
-			}
-			summaries = append(summaries, fmt.Sprintf("%d coding_problems", len(req.Records)))
-		case "projects":
-			for _, record := range req.Records {
-				if _, err := InsertProject(ctx, dbConn, Project{
-					Name:      getString(record, "name"),
-					RepoURL:   getString(record, "repo_url"),
-					Active:    getBool(record, "active"),
-					TechStack: getStringSlice(record, "tech_stack"),
-					Summary:   getString(record, "summary"),
-				}); err != nil {
-					return "", err
-				}
-				total++
-			}
-			summaries = append(summaries, fmt.Sprintf("%d projects", len(req.Records)))
-		case "networking_contacts":
-			for _, record := range req.Records {
-				if _, err := InsertNetworkingContact(ctx, dbConn, NetworkingContact{
-					PersonName:        getString(record, "person_name"),
-					HowMet:            getString(record, "how_met"),
-					LinkedInConnected: getBool(record, "linkedin_connected"),
-					Company:           getString(record, "company"),
-					Position:          getString(record, "position"),
-					Notes:             getString(record, "notes"),
-				}); err != nil {
-					return "", err
-				}
-				total++
-			}
-			summaries = append(summaries, fmt.Sprintf("%d networking_contacts", len(req.Records)))
-		case "daily_goals":
-			for _, record := range req.Records {
-				date := getDate(record, "target_date")
-				if date == nil {
-					return "", fmt.Errorf("daily_goals requires target_date")
-				}
-				if _, err := InsertDailyGoal(ctx, dbConn, Goal{
-					Description:    getString(record, "description"),
-					TargetDate:     *date,
-					Completed:      getBool(record, "completed"),
-					JobApplication: getIntPtr(record, "job_application_id"),
-					CodingProblem:  getIntPtr(record, "coding_problem_id"),
-					Project:        getIntPtr(record, "project_id"),
-					Contact:        getIntPtr(record, "contact_id"),
-				}); err != nil {
-					return "", err
-				}
-				total++
+		for j, record := range req.Records {
+			if err := withSavepoint(ctx, tx, func() error {
+				return applyRecord(ctx, tx, action, table, record)
+			}); err != nil {
+				failures = append(failures, WriteFailure{Request: i + 1, Record: j + 1, Table: table, Err: err})
+				continue
 			}
-			summaries = append(summaries, fmt.Sprintf("%d daily_goals", len(req.Records)))
-		case "weekly_goals":
-			for _, record := range req.Records {
-				date := getDate(record, "week_of")
-				if date == nil {
-					return "", fmt.Errorf("weekly_goals requires week_of")
-				}
-				if _, err := InsertWeeklyGoal(ctx, dbConn, Goal{
-					Description:    getString(record, "description"),
-					TargetDate:     *date,
-					Completed:      getBool(record, "completed"),
-					JobApplication: getIntPtr(record, "job_application_id"),
-					CodingProblem:  getIntPtr(record, "coding_problem_id"),
-					Project:        getIntPtr(record, "project_id"),
-					Contact:        getIntPtr(record, "contact_id"),
-				}); err != nil {
-					return "", err
-				}
-				total++
+			total++
+		}
+		if action == "insert" {
+			summaries = append(summaries, fmt.Sprintf("%d %s", len(req.Records), table))
+		} else {
+			summaries = append(summaries, fmt.Sprintf("%d %s %s", len(req.Records), table, action+"d"))
+		}
+	}
+	if len(failures) > 0 {
+		return "", &ApplyError{Failures: failures}
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d records (%s)", total, strings.Join(summaries, ", ")), nil
+}
+
+func validateWriteRequests(payload WritePayload) []WriteFailure {
+	var failures []WriteFailure
+	for i, req := range payload.WriteRequests {
+		action := strings.ToLower(strings.TrimSpace(req.Action))
+		table := strings.ToLower(strings.TrimSpace(req.Table))
+		switch action {
+		case "insert":
+			if _, ok := insertWriters[table]; !ok {
+				failures = append(failures, WriteFailure{Request: i + 1, Table: table, Err: fmt.Errorf("unsupported table: %s", req.Table)})
 			}
-			summaries = append(summaries, fmt.Sprintf("%d weekly_goals", len(req.Records)))
-		case "monthly_goals":
-			for _, record := range req.Records {
-				date := getDate(record, "month_of")
-				if date == nil {
-					return "", fmt.Errorf("monthly_goals requires month_of")
-				}
-				if _, err := InsertMonthlyGoal(ctx, dbConn, Goal{
-					Description:    getString(record, "description"),
-					TargetDate:     *date,
-					Completed:      getBool(record, "completed"),
-					JobApplication: getIntPtr(record, "job_application_id"),
-					CodingProblem:  getIntPtr(record, "coding_problem_id"),
-					Project:        getIntPtr(record, "project_id"),
-					Contact:        getIntPtr(record, "contact_id"),
-				}); err != nil {
-					return "", err
-				}
-				total++
+		case "update", "delete":
+			if _, err := lookupTableSpec(table); err != nil {
+				failures = append(failures, WriteFailure{Request: i + 1, Table: table, Err: err})
 			}
-			summaries = append(summaries, fmt.Sprintf("%d monthly_goals", len(req.Records)))
 		default:
-			return "", fmt.Errorf("unsupported table: %s", req.Table)
+			failures = append(failures, WriteFailure{Request: i + 1, Table: table, Err: fmt.Errorf("unsupported action: %s", req.Action)})
 		}
 	}
-	return fmt.Sprintf("%d records (%s)", total, strings.Join(summaries, ", ")), nil
+	return failures
+}
+
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func() error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT write_record"); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT write_record"); rbErr != nil {
+			return fmt.Errorf("%w (rollback to savepoint: %v)", err, rbErr)
+		}
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT write_record")
+	return err
 }
 
 func getString(record map[string]interface{}, key string) string {
@@ -1,7 +1,9 @@
 package db
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -42,13 +44,43 @@ func TestExtractWritePayloadRawJSON(t *testing.T) {
 }
 
 func TestExtractWritePayloadInvalidAction(t *testing.T) {
-	text := "```json\n{\"write_requests\":[{\"action\":\"update\",\"table\":\"projects\",\"records\":[{\"name\":\"Koala\"}]}]}\n```"
+	text := "```json\n{\"write_requests\":[{\"action\":\"merge\",\"table\":\"projects\",\"records\":[{\"name\":\"Koala\"}]}]}\n```"
 	payload, _, _, err := ExtractWritePayload(text)
 	if err == nil {
 		t.Fatalf("expected error for invalid action, got nil payload=%v", payload)
 	}
 }
 
+func TestExtractWritePayloadUpdateAndDelete(t *testing.T) {
+	text := "```json\n{\"write_requests\":[" +
+		"{\"action\":\"update\",\"table\":\"job_applications\",\"records\":[{\"match\":{\"company\":\"Stripe\"},\"set\":{\"status\":\"rejected\"}}]}," +
+		"{\"action\":\"delete\",\"table\":\"coding_problems\",\"records\":[{\"id\":5}]}" +
+		"]}\n```"
+	payload, _, summary, err := ExtractWritePayload(text)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload == nil || len(payload.WriteRequests) != 2 {
+		t.Fatalf("expected payload with two requests")
+	}
+	if summary != "update 1 -> job_applications, delete 1 -> coding_problems" {
+		t.Fatalf("unexpected summary: %q", summary)
+	}
+}
+
+func TestExtractWritePayloadUpdateRequiresSelectorAndSet(t *testing.T) {
+	cases := []string{
+		"{\"write_requests\":[{\"action\":\"update\",\"table\":\"projects\",\"records\":[{\"set\":{\"active\":true}}]}]}",
+		"{\"write_requests\":[{\"action\":\"update\",\"table\":\"projects\",\"records\":[{\"id\":3}]}]}",
+		"{\"write_requests\":[{\"action\":\"delete\",\"table\":\"projects\",\"records\":[{\"match\":{}}]}]}",
+	}
+	for _, text := range cases {
+		if _, _, _, err := ExtractWritePayload(text); err == nil {
+			t.Fatalf("expected error for %s", text)
+		}
+	}
+}
+
 func TestExtractWritePayloadEmpty(t *testing.T) {
 	payload, raw, summary, err := ExtractWritePayload("no json here")
 	if err != nil {
@@ -93,3 +125,46 @@ func TestValueHelpers(t *testing.T) {
 		t.Fatalf("getDate: %v", date)
 	}
 }
+
+func TestApplyWriteRequestsRejectsInvalidRequestsUpFront(t *testing.T) {
+	payload := WritePayload{WriteRequests: []WriteRequest{
+		{Action: "insert", Table: "projects", Records: []map[string]interface{}{{"name": "Koala"}}},
+		{Action: "upsert", Table: "projects", Records: []map[string]interface{}{{"name": "Koala"}}},
+		{Action: "insert", Table: "users", Records: []map[string]interface{}{{"name": "x"}}},
+	}}
+	_, err := ApplyWriteRequests(context.Background(), nil, payload)
+	var applyErr *ApplyError
+	if !errors.As(err, &applyErr) {
+		t.Fatalf("expected *ApplyError, got %v", err)
+	}
+	if len(applyErr.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %d: %v", len(applyErr.Failures), applyErr)
+	}
+	if applyErr.Failures[0].Request != 2 || applyErr.Failures[1].Request != 3 {
+		t.Fatalf("unexpected failure indexes: %+v", applyErr.Failures)
+	}
+}
+
+func TestApplyErrorMessage(t *testing.T) {
+	err := &ApplyError{Failures: []WriteFailure{
+		{Request: 1, Record: 3, Table: "job_applications", Err: errors.New("boom")},
+		{Request: 2, Table: "daily_goals", Err: errors.New("unsupported action: merge")},
+	}}
+	want := "no changes were saved; request 1 (job_applications) record 3: boom; request 2 (daily_goals): unsupported action: merge"
+	if got := err.Error(); got != want {
+		t.Fatalf("unexpected message:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestGoalFromRecordRequiresDate(t *testing.T) {
+	if _, err := goalFromRecord(map[string]interface{}{"description": "x"}, "week_of"); err == nil {
+		t.Fatalf("expected error for missing week_of")
+	}
+	goal, err := goalFromRecord(map[string]interface{}{"description": "x", "week_of": "2025-12-01", "project_id": json.Number("3")}, "week_of")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if goal.Project == nil || *goal.Project != 3 {
+		t.Fatalf("unexpected project link: %v", goal.Project)
+	}
+}
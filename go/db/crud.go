@@ -0,0 +1,299 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	jobSelect     = `SELECT id, job_title, COALESCE(company,''), COALESCE(job_link,''), applied_date, result_date, COALESCE(status,''), COALESCE(notes,'') FROM job_applications`
+	codingSelect  = `SELECT id, COALESCE(leetcode_number,0), COALESCE(title,''), COALESCE(pattern,''), COALESCE(problem_link,''), COALESCE(difficulty,''), COALESCE(already_solved,false), COALESCE(notes,'') FROM coding_problems`
+	projectSelect = `SELECT id, name, COALESCE(repo_url,''), COALESCE(active,false), COALESCE(summary,''), COALESCE(to_json(tech_stack), '[]'::json) FROM projects`
+	contactSelect = `SELECT id, person_name, COALESCE(how_met,''), COALESCE(linkedin_connected,false), COALESCE(company,''), COALESCE(position,''), COALESCE(notes,'') FROM networking_contacts`
+	meetingSelect = `SELECT id, session_name, session_type, session_time, COALESCE(location,''), COALESCE(organizer,''), COALESCE(company,''), COALESCE(notes,''), job_application_id, contact_id FROM meetings`
+	goalSelect    = `SELECT id, period::text, description, target_date, completed, target_count, job_application_id, coding_problem_id, project_id, contact_id, recurrence_id, COALESCE(metric, ''), COALESCE(metric_filter, ''), goal_progress(goals) FROM goals`
+)
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJobApplication(row rowScanner) (JobApplication, error) {
+	var r JobApplication
+	err := row.Scan(&r.ID, &r.JobTitle, &r.Company, &r.JobLink, &r.Applied, &r.ResultDate, &r.Status, &r.Notes)
+	return r, err
+}
+
+func scanCodingProblem(row rowScanner) (CodingProblem, error) {
+	var r CodingProblem
+	err := row.Scan(&r.ID, &r.LeetCodeNumber, &r.Title, &r.Pattern, &r.ProblemLink, &r.Difficulty, &r.AlreadySolved, &r.Notes)
+	return r, err
+}
+
+func scanProject(row rowScanner) (Project, error) {
+	var r Project
+	var techJSON []byte
+	if err := row.Scan(&r.ID, &r.Name, &r.RepoURL, &r.Active, &r.Summary, &techJSON); err != nil {
+		return r, err
+	}
+	if len(techJSON) > 0 {
+		if err := json.Unmarshal(techJSON, &r.TechStack); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+func scanNetworkingContact(row rowScanner) (NetworkingContact, error) {
+	var r NetworkingContact
+	err := row.Scan(&r.ID, &r.PersonName, &r.HowMet, &r.LinkedInConnected, &r.Company, &r.Position, &r.Notes)
+	return r, err
+}
+
+func scanMeeting(row rowScanner) (Meeting, error) {
+	var r Meeting
+	var sessionTime sql.NullTime
+	err := row.Scan(&r.ID, &r.SessionName, &r.SessionType, &sessionTime, &r.Location, &r.Organizer, &r.Company, &r.Notes, &r.JobApplication, &r.Contact)
+	r.SessionTime = sessionTime.Time
+	return r, err
+}
+
+func scanGoal(row rowScanner) (Goal, error) {
+	var r Goal
+	err := row.Scan(&r.ID, &r.Period, &r.Description, &r.TargetDate, &r.Completed, &r.TargetCount, &r.JobApplication, &r.CodingProblem, &r.Project, &r.Contact, &r.RecurrenceID, &r.Metric, &r.MetricFilter, &r.Progress)
+	return r, err
+}
+
+func GetJobApplication(ctx context.Context, db DBTX, id int64) (JobApplication, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return JobApplication{}, err
+	}
+	return scanJobApplication(db.QueryRowContext(ctx, jobSelect+` WHERE id=$1 AND user_id=$2`, id, uid))
+}
+
+func GetCodingProblem(ctx context.Context, db DBTX, id int64) (CodingProblem, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return CodingProblem{}, err
+	}
+	return scanCodingProblem(db.QueryRowContext(ctx, codingSelect+` WHERE id=$1 AND user_id=$2`, id, uid))
+}
+
+func GetProject(ctx context.Context, db DBTX, id int64) (Project, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return Project{}, err
+	}
+	return scanProject(db.QueryRowContext(ctx, projectSelect+` WHERE id=$1 AND user_id=$2`, id, uid))
+}
+
+func GetNetworkingContact(ctx context.Context, db DBTX, id int64) (NetworkingContact, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return NetworkingContact{}, err
+	}
+	return scanNetworkingContact(db.QueryRowContext(ctx, contactSelect+` WHERE id=$1 AND user_id=$2`, id, uid))
+}
+
+func GetMeeting(ctx context.Context, db DBTX, id int64) (Meeting, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return Meeting{}, err
+	}
+	m, err := scanMeeting(db.QueryRowContext(ctx, meetingSelect+` WHERE id=$1 AND user_id=$2`, id, uid))
+	if err != nil {
+		return m, err
+	}
+	meetings := []Meeting{m}
+	err = attachMeetingContacts(ctx, db, meetings)
+	return meetings[0], err
+}
+
+func GetGoal(ctx context.Context, db DBTX, goalType string, id int64) (Goal, error) {
+	period, err := goalPeriodOf(goalType)
+	if err != nil {
+		return Goal{}, err
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return Goal{}, err
+	}
+	return scanGoal(db.QueryRowContext(ctx, goalSelect+` WHERE id=$1 AND period=$2 AND user_id=$3`, id, period, uid))
+}
+
+// The Update* functions apply a partial update (column -> JSON value). Unknown
+// columns and malformed values are reported as *FieldError; a missing row as
+// sql.ErrNoRows.
+
+func UpdateJobApplication(ctx context.Context, db DBTX, id int64, fields map[string]interface{}) error {
+	return updateByID(ctx, db, "job_applications", id, fields)
+}
+
+func UpdateCodingProblem(ctx context.Context, db DBTX, id int64, fields map[string]interface{}) error {
+	return updateByID(ctx, db, "coding_problems", id, fields)
+}
+
+func UpdateProject(ctx context.Context, db DBTX, id int64, fields map[string]interface{}) error {
+	return updateByID(ctx, db, "projects", id, fields)
+}
+
+func UpdateNetworkingContact(ctx context.Context, db DBTX, id int64, fields map[string]interface{}) error {
+	return updateByID(ctx, db, "networking_contacts", id, fields)
+}
+
+// UpdateMeeting also accepts "contact_ids", which replaces the attendee list.
+func UpdateMeeting(ctx context.Context, db DBTX, id int64, fields map[string]interface{}) error {
+	raw, hasContacts := fields["contact_ids"]
+	if !hasContacts {
+		return updateByID(ctx, db, "meetings", id, fields)
+	}
+	contactIDs, err := intSlice(raw)
+	if err != nil {
+		return fieldErrorf("contact_ids: %v", err)
+	}
+	delete(fields, "contact_ids")
+	return inTx(ctx, db, func(tx DBTX) error {
+		if len(fields) > 0 {
+			if err := updateByID(ctx, tx, "meetings", id, fields); err != nil {
+				return err
+			}
+		} else if _, err := GetMeeting(ctx, tx, id); err != nil {
+			return err
+		}
+		return SetMeetingContacts(ctx, tx, id, contactIDs)
+	})
+}
+
+// UpdateGoalFields updates a goal of the given type. The old per-type date
+// columns (week_of, month_of) are still accepted for target_date.
+func UpdateGoalFields(ctx context.Context, db DBTX, goalType string, id int64, fields map[string]interface{}) error {
+	period, err := goalPeriodOf(goalType)
+	if err != nil {
+		return err
+	}
+	if dateCol := goalDateColumns[period]; dateCol != "target_date" {
+		if val, ok := fields[dateCol]; ok {
+			fields["target_date"] = val
+			delete(fields, dateCol)
+		}
+	}
+	return inTx(ctx, db, func(tx DBTX) error {
+		if _, err := GetGoal(ctx, tx, period, id); err != nil {
+			return err
+		}
+		return updateByID(ctx, tx, "goals", id, fields)
+	})
+}
+
+func DeleteJobApplication(ctx context.Context, db DBTX, id int64) error {
+	return deleteByID(ctx, db, "job_applications", id)
+}
+
+func DeleteCodingProblem(ctx context.Context, db DBTX, id int64) error {
+	return deleteByID(ctx, db, "coding_problems", id)
+}
+
+func DeleteProject(ctx context.Context, db DBTX, id int64) error {
+	return deleteByID(ctx, db, "projects", id)
+}
+
+func DeleteNetworkingContact(ctx context.Context, db DBTX, id int64) error {
+	return deleteByID(ctx, db, "networking_contacts", id)
+}
+
+func DeleteMeeting(ctx context.Context, db DBTX, id int64) error {
+	return deleteByID(ctx, db, "meetings", id)
+}
+
+func DeleteGoal(ctx context.Context, db DBTX, goalType string, id int64) error {
+	period, err := goalPeriodOf(goalType)
+	if err != nil {
+		return err
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	res, err := db.ExecContext(ctx, `DELETE FROM goals WHERE id=$1 AND period=$2 AND user_id=$3`, id, period, uid)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// InsertGoal inserts a daily, weekly or monthly goal. A zero TargetCount is
+// stored as 1; a goal whose metric already reaches it is stored completed.
+func InsertGoal(ctx context.Context, db DBTX, goalType string, in Goal) (int64, error) {
+	period, err := goalPeriodOf(goalType)
+	if err != nil {
+		return 0, err
+	}
+	metric, err := normalizeGoalMetric(in.Metric)
+	if err != nil {
+		return 0, err
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	filter := strings.TrimSpace(in.MetricFilter)
+	var id int64
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO goals (user_id, period, description, target_date, completed, target_count, job_application_id, coding_problem_id, project_id, contact_id, recurrence_id, metric, metric_filter, metric_terms)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING id`,
+		uid, period, in.Description, in.TargetDate, in.Completed, max(in.TargetCount, 1), in.JobApplication, in.CodingProblem, in.Project, in.Contact, in.RecurrenceID,
+		nullIfEmpty(metric), nullIfEmpty(filter), metricTerms(filter),
+	).Scan(&id)
+	return id, err
+}
+
+// goalDateColumns is the date column each goal type had before the tables
+// were merged; the compatibility views and write requests still use them.
+var goalDateColumns = map[string]string{"daily": "target_date", "weekly": "week_of", "monthly": "month_of"}
+
+func idMatch(id int64) map[string]interface{} {
+	return map[string]interface{}{"id": json.Number(strconv.FormatInt(id, 10))}
+}
+
+func updateByID(ctx context.Context, db DBTX, table string, id int64, fields map[string]interface{}) error {
+	spec, err := lookupTableSpec(table)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, db, func(tx DBTX) error {
+		rows, err := updateRows(ctx, tx, spec, idMatch(id), fields)
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+func deleteByID(ctx context.Context, db DBTX, table string, id int64) error {
+	spec, err := lookupTableSpec(table)
+	if err != nil {
+		return err
+	}
+	rows, err := deleteRows(ctx, db, spec, idMatch(id))
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
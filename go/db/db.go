@@ -96,6 +96,14 @@ func ensureSchema(ctx context.Context, db *sql.DB) error {
 	return goose.UpContext(ctx, db, ".")
 }
 
+// DBTX is the subset of *sql.DB and *sql.Tx used by the query helpers, so the
+// same helper can run standalone or inside a caller-managed transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 type JobApplication struct {
 	ID         int64      `json:"id"`
 	JobTitle   string     `json:"job_title"`
@@ -137,15 +145,24 @@ type NetworkingContact struct {
 	Notes             string `json:"notes"`
 }
 
+// Goal is one daily, weekly or monthly goal. Goals generated from a
+// GoalRecurrence carry its id. A goal with a Metric counts its Progress from
+// the other tables and completes itself once it reaches TargetCount.
 type Goal struct {
 	ID             int64     `json:"id"`
+	Period         string    `json:"period"`
 	Description    string    `json:"description"`
 	TargetDate     time.Time `json:"target_date"`
 	Completed      bool      `json:"completed"`
+	TargetCount    int       `json:"target_count"`
 	JobApplication *int64    `json:"job_application_id,omitempty"`
 	CodingProblem  *int64    `json:"coding_problem_id,omitempty"`
 	Project        *int64    `json:"project_id,omitempty"`
 	Contact        *int64    `json:"contact_id,omitempty"`
+	RecurrenceID   *int64    `json:"recurrence_id,omitempty"`
+	Metric         string    `json:"metric,omitempty"`
+	MetricFilter   string    `json:"metric_filter,omitempty"`
+	Progress       *int      `json:"progress,omitempty"`
 }
 
 type Meeting struct {
@@ -157,6 +174,12 @@ type Meeting struct {
 	Organizer   string    `json:"organizer"`
 	Company     string    `json:"company"`
 	Notes       string    `json:"notes"`
+	// JobApplication and Contact link the meeting to the application it is
+	// an interview for and the main person met; ContactIDs lists any other
+	// attendees (meeting_contacts).
+	JobApplication *int64  `json:"job_application_id,omitempty"`
+	Contact        *int64  `json:"contact_id,omitempty"`
+	ContactIDs     []int64 `json:"contact_ids,omitempty"`
 }
 
 type Snapshot struct {
@@ -168,90 +191,107 @@ type Snapshot struct {
 	WeeklyGoals        []Goal              `json:"weekly_goals"`
 	MonthlyGoals       []Goal              `json:"monthly_goals"`
 	Meetings           []Meeting           `json:"meetings"`
+	Counts             map[string]int64    `json:"counts"`
 }
 
-func InsertJobApplication(ctx context.Context, db *sql.DB, in JobApplication) (int64, error) {
-	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO job_applications (job_title, company, job_link, applied_date, result_date, status, notes)
-         VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
-		in.JobTitle, in.Company, in.JobLink, in.Applied, in.ResultDate, in.Status, in.Notes,
-	).Scan(&id)
-	return id, err
-}
-
-func InsertCodingProblem(ctx context.Context, db *sql.DB, in CodingProblem) (int64, error) {
+func InsertJobApplication(ctx context.Context, db DBTX, in JobApplication) (int64, error) {
+	status, err := defaultJobStatus(in)
+	if err != nil {
+		return 0, err
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
 	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO coding_problems (leetcode_number, title, pattern, problem_link, difficulty, already_solved, notes)
-         VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
-		in.LeetCodeNumber, in.Title, in.Pattern, in.ProblemLink, in.Difficulty, in.AlreadySolved, in.Notes,
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO job_applications (user_id, job_title, company, job_link, applied_date, result_date, status, notes)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`,
+		uid, in.JobTitle, in.Company, in.JobLink, in.Applied, in.ResultDate, status, in.Notes,
 	).Scan(&id)
 	return id, err
 }
 
-func InsertProject(ctx context.Context, db *sql.DB, in Project) (int64, error) {
+func InsertCodingProblem(ctx context.Context, db DBTX, in CodingProblem) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
 	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO projects (name, repo_url, active, tech_stack, summary)
-         VALUES ($1,$2,$3,$4,$5) RETURNING id`,
-		in.Name, in.RepoURL, in.Active, pqStringArray(in.TechStack), in.Summary,
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO coding_problems (user_id, leetcode_number, title, pattern, problem_link, difficulty, already_solved, notes)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`,
+		uid, in.LeetCodeNumber, in.Title, in.Pattern, in.ProblemLink, in.Difficulty, in.AlreadySolved, in.Notes,
 	).Scan(&id)
 	return id, err
 }
 
-func InsertNetworkingContact(ctx context.Context, db *sql.DB, in NetworkingContact) (int64, error) {
+func InsertProject(ctx context.Context, db DBTX, in Project) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
 	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO networking_contacts (person_name, how_met, linkedin_connected, company, position, notes)
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO projects (user_id, name, repo_url, active, tech_stack, summary)
          VALUES ($1,$2,$3,$4,$5,$6) RETURNING id`,
-		in.PersonName, in.HowMet, in.LinkedInConnected, in.Company, in.Position, in.Notes,
+		uid, in.Name, in.RepoURL, in.Active, pqStringArray(in.TechStack), in.Summary,
 	).Scan(&id)
 	return id, err
 }
 
-func InsertDailyGoal(ctx context.Context, db *sql.DB, in Goal) (int64, error) {
+func InsertNetworkingContact(ctx context.Context, db DBTX, in NetworkingContact) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
 	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO daily_goals (description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id)
+	err = db.QueryRowContext(ctx,
+		`INSERT INTO networking_contacts (user_id, person_name, how_met, linkedin_connected, company, position, notes)
          VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
-		in.Description, in.TargetDate, in.Completed, in.JobApplication, in.CodingProblem, in.Project, in.Contact,
+		uid, in.PersonName, in.HowMet, in.LinkedInConnected, in.Company, in.Position, in.Notes,
 	).Scan(&id)
 	return id, err
 }
 
-func InsertWeeklyGoal(ctx context.Context, db *sql.DB, in Goal) (int64, error) {
-	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO weekly_goals (description, week_of, completed, job_application_id, coding_problem_id, project_id, contact_id)
-         VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
-		in.Description, in.TargetDate, in.Completed, in.JobApplication, in.CodingProblem, in.Project, in.Contact,
-	).Scan(&id)
-	return id, err
+func InsertDailyGoal(ctx context.Context, db DBTX, in Goal) (int64, error) {
+	return InsertGoal(ctx, db, "daily", in)
 }
 
-func InsertMonthlyGoal(ctx context.Context, db *sql.DB, in Goal) (int64, error) {
-	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO monthly_goals (description, month_of, completed, job_application_id, coding_problem_id, project_id, contact_id)
-         VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
-		in.Description, in.TargetDate, in.Completed, in.JobApplication, in.CodingProblem, in.Project, in.Contact,
-	).Scan(&id)
-	return id, err
+func InsertWeeklyGoal(ctx context.Context, db DBTX, in Goal) (int64, error) {
+	return InsertGoal(ctx, db, "weekly", in)
+}
+
+func InsertMonthlyGoal(ctx context.Context, db DBTX, in Goal) (int64, error) {
+	return InsertGoal(ctx, db, "monthly", in)
 }
 
-func InsertMeeting(ctx context.Context, db *sql.DB, in Meeting) (int64, error) {
+func InsertMeeting(ctx context.Context, db DBTX, in Meeting) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
 	var id int64
-	err := db.QueryRowContext(ctx,
-		`INSERT INTO meetings (session_name, session_type, session_time, location, organizer, company, notes)
-         VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
-		in.SessionName, in.SessionType, in.SessionTime, in.Location, in.Organizer, in.Company, in.Notes,
-	).Scan(&id)
-	return id, err
+	err = inTx(ctx, db, func(tx DBTX) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO meetings (user_id, session_name, session_type, session_time, location, organizer, company, notes, job_application_id, contact_id)
+             VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`,
+			uid, in.SessionName, in.SessionType, in.SessionTime, in.Location, in.Organizer, in.Company, in.Notes, in.JobApplication, in.Contact,
+		).Scan(&id)
+		if err != nil || len(in.ContactIDs) == 0 {
+			return err
+		}
+		return SetMeetingContacts(ctx, tx, id, in.ContactIDs)
+	})
+	return id, linkError(err)
 }
 
-func ListJobApplications(ctx context.Context, db *sql.DB) ([]JobApplication, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, job_title, COALESCE(company,''), COALESCE(job_link,''), applied_date, result_date, COALESCE(status,''), COALESCE(notes,'') FROM job_applications ORDER BY id`)
+func ListJobApplications(ctx context.Context, db DBTX) ([]JobApplication, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, job_title, COALESCE(company,''), COALESCE(job_link,''), applied_date, result_date, COALESCE(status,''), COALESCE(notes,'') FROM job_applications WHERE user_id=$1 ORDER BY id`, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -267,8 +307,12 @@ func ListJobApplications(ctx context.Context, db *sql.DB) ([]JobApplication, err
 	return res, rows.Err()
 }
 
-func ListCodingProblems(ctx context.Context, db *sql.DB) ([]CodingProblem, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, leetcode_number, title, pattern, problem_link, difficulty, already_solved, notes FROM coding_problems ORDER BY id`)
+func ListCodingProblems(ctx context.Context, db DBTX) ([]CodingProblem, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, leetcode_number, title, pattern, problem_link, difficulty, already_solved, notes FROM coding_problems WHERE user_id=$1 ORDER BY id`, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -282,8 +326,12 @@ func ListCodingProblems(ctx context.Context, db *sql.DB) ([]CodingProblem, error
 	return res, rows.Err()
 }
 
-func ListProjects(ctx context.Context, db *sql.DB) ([]Project, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, name, repo_url, active, summary, COALESCE(to_json(tech_stack), '[]'::json) FROM projects ORDER BY id`)
+func ListProjects(ctx context.Context, db DBTX) ([]Project, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, name, repo_url, active, summary, COALESCE(to_json(tech_stack), '[]'::json) FROM projects WHERE user_id=$1 ORDER BY id`, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -305,8 +353,12 @@ func ListProjects(ctx context.Context, db *sql.DB) ([]Project, error) {
 	return res, rows.Err()
 }
 
-func ListNetworkingContacts(ctx context.Context, db *sql.DB) ([]NetworkingContact, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, person_name, how_met, linkedin_connected, company, position, notes FROM networking_contacts ORDER BY id`)
+func ListNetworkingContacts(ctx context.Context, db DBTX) ([]NetworkingContact, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, person_name, how_met, linkedin_connected, company, position, notes FROM networking_contacts WHERE user_id=$1 ORDER BY id`, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -320,53 +372,45 @@ func ListNetworkingContacts(ctx context.Context, db *sql.DB) ([]NetworkingContac
 	return res, rows.Err()
 }
 
-func ListDailyGoals(ctx context.Context, db *sql.DB) ([]Goal, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id FROM daily_goals ORDER BY id`)
+func ListDailyGoals(ctx context.Context, db DBTX) ([]Goal, error) {
+	return listGoals(ctx, db, "daily")
+}
+
+func ListWeeklyGoals(ctx context.Context, db DBTX) ([]Goal, error) {
+	return listGoals(ctx, db, "weekly")
+}
+
+func ListMonthlyGoals(ctx context.Context, db DBTX) ([]Goal, error) {
+	return listGoals(ctx, db, "monthly")
+}
+
+func listGoals(ctx context.Context, db DBTX, period string) ([]Goal, error) {
+	uid, err := currentUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var res []Goal
-	for rows.Next() {
-		var r Goal
-		_ = rows.Scan(&r.ID, &r.Description, &r.TargetDate, &r.Completed, &r.JobApplication, &r.CodingProblem, &r.Project, &r.Contact)
-		res = append(res, r)
-	}
-	return res, rows.Err()
-}
-
-func ListWeeklyGoals(ctx context.Context, db *sql.DB) ([]Goal, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, description, week_of, completed, job_application_id, coding_problem_id, project_id, contact_id FROM weekly_goals ORDER BY id`)
+	rows, err := db.QueryContext(ctx, goalSelect+` WHERE period = $1 AND user_id = $2 ORDER BY id`, period, uid)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var res []Goal
 	for rows.Next() {
-		var r Goal
-		_ = rows.Scan(&r.ID, &r.Description, &r.TargetDate, &r.Completed, &r.JobApplication, &r.CodingProblem, &r.Project, &r.Contact)
+		r, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
 		res = append(res, r)
 	}
 	return res, rows.Err()
 }
 
-func ListMonthlyGoals(ctx context.Context, db *sql.DB) ([]Goal, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, description, month_of, completed, job_application_id, coding_problem_id, project_id, contact_id FROM monthly_goals ORDER BY id`)
+func ListMeetings(ctx context.Context, db DBTX) ([]Meeting, error) {
+	uid, err := currentUser(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-	var res []Goal
-	for rows.Next() {
-		var r Goal
-		_ = rows.Scan(&r.ID, &r.Description, &r.TargetDate, &r.Completed, &r.JobApplication, &r.CodingProblem, &r.Project, &r.Contact)
-		res = append(res, r)
-	}
-	return res, rows.Err()
-}
-
-func ListMeetings(ctx context.Context, db *sql.DB) ([]Meeting, error) {
-	rows, err := db.QueryContext(ctx, `SELECT id, session_name, session_type, session_time, location, organizer, company, notes FROM meetings ORDER BY id`)
+	rows, err := db.QueryContext(ctx, `SELECT id, session_name, session_type, session_time, location, organizer, company, notes, job_application_id, contact_id FROM meetings WHERE user_id=$1 ORDER BY id`, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -374,47 +418,97 @@ func ListMeetings(ctx context.Context, db *sql.DB) ([]Meeting, error) {
 	var res []Meeting
 	for rows.Next() {
 		var r Meeting
-		_ = rows.Scan(&r.ID, &r.SessionName, &r.SessionType, &r.SessionTime, &r.Location, &r.Organizer, &r.Company, &r.Notes)
+		_ = rows.Scan(&r.ID, &r.SessionName, &r.SessionType, &r.SessionTime, &r.Location, &r.Organizer, &r.Company, &r.Notes, &r.JobApplication, &r.Contact)
 		res = append(res, r)
 	}
 	return res, rows.Err()
 }
 
-func GetSnapshot(ctx context.Context, db *sql.DB) (Snapshot, error) {
+// SnapshotLimit bounds each list in the dashboard snapshot; use the
+// per-entity list endpoints to page through everything.
+const SnapshotLimit = 25
+
+// GetSnapshot returns a bounded dashboard summary: the most recent
+// SnapshotLimit rows of each table plus total row counts.
+func GetSnapshot(ctx context.Context, db DBTX) (Snapshot, error) {
 	var s Snapshot
-	var err error
-	if s.JobApplications, err = ListJobApplications(ctx, db); err != nil {
+	recent := ListParams{Limit: SnapshotLimit}
+	jobs, err := ListJobApplicationsPage(ctx, db, recent)
+	if err != nil {
 		return s, err
 	}
-	if s.CodingProblems, err = ListCodingProblems(ctx, db); err != nil {
+	coding, err := ListCodingProblemsPage(ctx, db, recent)
+	if err != nil {
 		return s, err
 	}
-	if s.Projects, err = ListProjects(ctx, db); err != nil {
+	projects, err := ListProjectsPage(ctx, db, recent)
+	if err != nil {
 		return s, err
 	}
-	if s.NetworkingContacts, err = ListNetworkingContacts(ctx, db); err != nil {
+	contacts, err := ListNetworkingContactsPage(ctx, db, recent)
+	if err != nil {
 		return s, err
 	}
-	if s.DailyGoals, err = ListDailyGoals(ctx, db); err != nil {
+	meetings, err := ListMeetingsPage(ctx, db, ListParams{Sort: "-session_time", Limit: SnapshotLimit})
+	if err != nil {
 		return s, err
 	}
-	if s.WeeklyGoals, err = ListWeeklyGoals(ctx, db); err != nil {
-		return s, err
+	s.JobApplications = jobs.Items
+	s.CodingProblems = coding.Items
+	s.Projects = projects.Items
+	s.NetworkingContacts = contacts.Items
+	s.Meetings = meetings.Items
+	goals := map[string]*[]Goal{"daily": &s.DailyGoals, "weekly": &s.WeeklyGoals, "monthly": &s.MonthlyGoals}
+	for goalType, dest := range goals {
+		page, err := ListGoalsPage(ctx, db, goalType, ListParams{Sort: "-target_date", Limit: SnapshotLimit})
+		if err != nil {
+			return s, err
+		}
+		*dest = page.Items
 	}
-	if s.MonthlyGoals, err = ListMonthlyGoals(ctx, db); err != nil {
+
+	uid, err := currentUser(ctx)
+	if err != nil {
 		return s, err
 	}
-	if s.Meetings, err = ListMeetings(ctx, db); err != nil {
+	var counts [9]int64
+	err = db.QueryRowContext(ctx, `SELECT
+        (SELECT count(*) FROM job_applications WHERE user_id = $1),
+        (SELECT count(*) FROM coding_problems WHERE user_id = $1),
+        (SELECT count(*) FROM projects WHERE user_id = $1),
+        (SELECT count(*) FROM networking_contacts WHERE user_id = $1),
+        (SELECT count(*) FROM goals WHERE user_id = $1 AND period = 'daily'),
+        (SELECT count(*) FROM goals WHERE user_id = $1 AND period = 'weekly'),
+        (SELECT count(*) FROM goals WHERE user_id = $1 AND period = 'monthly'),
+        (SELECT count(*) FROM meetings WHERE user_id = $1),
+        (SELECT count(*) FROM goals WHERE user_id = $1 AND completed)`, uid,
+	).Scan(&counts[0], &counts[1], &counts[2], &counts[3], &counts[4], &counts[5], &counts[6], &counts[7], &counts[8])
+	if err != nil {
 		return s, err
 	}
+	s.Counts = map[string]int64{
+		"job_applications":    counts[0],
+		"coding_problems":     counts[1],
+		"projects":            counts[2],
+		"networking_contacts": counts[3],
+		"daily_goals":         counts[4],
+		"weekly_goals":        counts[5],
+		"monthly_goals":       counts[6],
+		"meetings":            counts[7],
+		"goals_completed":     counts[8],
+	}
 	return s, nil
 }
 
-func ListRecentJobs(ctx context.Context, db *sql.DB, limit int) ([]JobApplication, error) {
+func ListRecentJobs(ctx context.Context, db DBTX, limit int) ([]JobApplication, error) {
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := db.QueryContext(ctx, `SELECT id, job_title, COALESCE(company,''), COALESCE(job_link,''), applied_date, result_date, COALESCE(status,''), COALESCE(notes,'') FROM job_applications ORDER BY COALESCE(applied_date, result_date) DESC NULLS LAST, id DESC LIMIT $1`, limit)
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, job_title, COALESCE(company,''), COALESCE(job_link,''), applied_date, result_date, COALESCE(status,''), COALESCE(notes,'') FROM job_applications WHERE user_id=$2 ORDER BY COALESCE(applied_date, result_date) DESC NULLS LAST, id DESC LIMIT $1`, limit, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -430,11 +524,15 @@ func ListRecentJobs(ctx context.Context, db *sql.DB, limit int) ([]JobApplicatio
 	return res, rows.Err()
 }
 
-func ListRecentCoding(ctx context.Context, db *sql.DB, limit int) ([]CodingProblem, error) {
+func ListRecentCoding(ctx context.Context, db DBTX, limit int) ([]CodingProblem, error) {
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := db.QueryContext(ctx, `SELECT id, leetcode_number, title, pattern, problem_link, difficulty, already_solved, notes FROM coding_problems ORDER BY id DESC LIMIT $1`, limit)
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, leetcode_number, title, pattern, problem_link, difficulty, already_solved, notes FROM coding_problems WHERE user_id=$2 ORDER BY id DESC LIMIT $1`, limit, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -448,11 +546,15 @@ func ListRecentCoding(ctx context.Context, db *sql.DB, limit int) ([]CodingProbl
 	return res, rows.Err()
 }
 
-func ListRecentProjects(ctx context.Context, db *sql.DB, limit int) ([]Project, error) {
+func ListRecentProjects(ctx context.Context, db DBTX, limit int) ([]Project, error) {
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := db.QueryContext(ctx, `SELECT id, name, repo_url, active, summary, COALESCE(to_json(tech_stack), '[]'::json) FROM projects ORDER BY id DESC LIMIT $1`, limit)
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, name, repo_url, active, summary, COALESCE(to_json(tech_stack), '[]'::json) FROM projects WHERE user_id=$2 ORDER BY id DESC LIMIT $1`, limit, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -474,11 +576,15 @@ func ListRecentProjects(ctx context.Context, db *sql.DB, limit int) ([]Project,
 	return res, rows.Err()
 }
 
-func ListRecentContacts(ctx context.Context, db *sql.DB, limit int) ([]NetworkingContact, error) {
+func ListRecentContacts(ctx context.Context, db DBTX, limit int) ([]NetworkingContact, error) {
 	if limit <= 0 {
 		limit = 20
 	}
-	rows, err := db.QueryContext(ctx, `SELECT id, person_name, how_met, linkedin_connected, company, position, notes FROM networking_contacts ORDER BY id DESC LIMIT $1`, limit)
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, `SELECT id, person_name, how_met, linkedin_connected, company, position, notes FROM networking_contacts WHERE user_id=$2 ORDER BY id DESC LIMIT $1`, limit, uid)
 	if err != nil {
 		return nil, err
 	}
@@ -499,40 +605,17 @@ func pqStringArray(in []string) interface{} {
 	return "{" + strings.Join(in, ",") + "}"
 }
 
-func UpdateGoalCompleted(ctx context.Context, db *sql.DB, goalType string, id int64, completed bool) error {
-	table, err := goalTable(goalType)
+func UpdateGoalCompleted(ctx context.Context, db DBTX, goalType string, id int64, completed bool) error {
+	period, err := goalPeriodOf(goalType)
 	if err != nil {
 		return err
 	}
 
-	res, err := db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET completed=$1 WHERE id=$2", table), completed, id)
+	uid, err := currentUser(ctx)
 	if err != nil {
 		return err
 	}
-	rows, err := res.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return sql.ErrNoRows
-	}
-	return nil
-}
-
-func UpdateJobStatus(ctx context.Context, db *sql.DB, id int64, status string) error {
-	status = strings.TrimSpace(status)
-	if status == "" {
-		return fmt.Errorf("status is required")
-	}
-	statusLower := strings.ToLower(status)
-	isRejected := strings.Contains(statusLower, "reject")
-	res, err := db.ExecContext(
-		ctx,
-		"UPDATE job_applications SET status=$1, result_date=CASE WHEN $2 THEN CURRENT_DATE ELSE result_date END WHERE id=$3",
-		status,
-		isRejected,
-		id,
-	)
+	res, err := db.ExecContext(ctx, "UPDATE goals SET completed=$1 WHERE id=$2 AND period=$3 AND user_id=$4", completed, id, period, uid)
 	if err != nil {
 		return err
 	}
@@ -546,17 +629,21 @@ func UpdateJobStatus(ctx context.Context, db *sql.DB, id int64, status string) e
 	return nil
 }
 
-func UpdateGoal(ctx context.Context, db *sql.DB, goalType string, id int64, completed bool, description string) error {
-	table, err := goalTable(goalType)
+func UpdateGoal(ctx context.Context, db DBTX, goalType string, id int64, completed bool, description string) error {
+	period, err := goalPeriodOf(goalType)
+	if err != nil {
+		return err
+	}
+	uid, err := currentUser(ctx)
 	if err != nil {
 		return err
 	}
 	desc := strings.TrimSpace(description)
 	var res sql.Result
 	if desc == "" {
-		res, err = db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET completed=$1 WHERE id=$2", table), completed, id)
+		res, err = db.ExecContext(ctx, "UPDATE goals SET completed=$1 WHERE id=$2 AND period=$3 AND user_id=$4", completed, id, period, uid)
 	} else {
-		res, err = db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET completed=$1, description=$2 WHERE id=$3", table), completed, desc, id)
+		res, err = db.ExecContext(ctx, "UPDATE goals SET completed=$1, description=$2 WHERE id=$3 AND period=$4 AND user_id=$5", completed, desc, id, period, uid)
 	}
 	if err != nil {
 		return err
@@ -571,8 +658,8 @@ func UpdateGoal(ctx context.Context, db *sql.DB, goalType string, id int64, comp
 	return nil
 }
 
-func UpdateGoalCompletedByDescription(ctx context.Context, db *sql.DB, goalType, description string, completed bool) (int64, error) {
-	table, err := goalTable(goalType)
+func UpdateGoalCompletedByDescription(ctx context.Context, db DBTX, goalType, description string, completed bool) (int64, error) {
+	period, err := goalPeriodOf(goalType)
 	if err != nil {
 		return 0, err
 	}
@@ -580,7 +667,11 @@ func UpdateGoalCompletedByDescription(ctx context.Context, db *sql.DB, goalType,
 	if desc == "" {
 		return 0, fmt.Errorf("description is required")
 	}
-	res, err := db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET completed=$1 WHERE description ILIKE $2", table), completed, desc)
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx, "UPDATE goals SET completed=$1 WHERE period=$2 AND description ILIKE $3 AND user_id=$4", completed, period, desc, uid)
 	if err != nil {
 		return 0, err
 	}
@@ -594,14 +685,12 @@ func UpdateGoalCompletedByDescription(ctx context.Context, db *sql.DB, goalType,
 	return rows, nil
 }
 
-func goalTable(goalType string) (string, error) {
-	switch strings.ToLower(strings.TrimSpace(goalType)) {
-	case "daily":
-		return "daily_goals", nil
-	case "weekly":
-		return "weekly_goals", nil
-	case "monthly":
-		return "monthly_goals", nil
+// goalPeriodOf validates a goal type (daily, weekly or monthly) and returns
+// it as a goal_period value.
+func goalPeriodOf(goalType string) (string, error) {
+	switch period := strings.ToLower(strings.TrimSpace(goalType)); period {
+	case "daily", "weekly", "monthly":
+		return period, nil
 	default:
 		return "", fmt.Errorf("invalid goal type")
 	}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Digest is the daily summary: the goals of the day, week and month
+// containing Date, meetings coming up and applications gone quiet.
+type Digest struct {
+	Date              time.Time          `json:"date"`
+	Goals             []Goal             `json:"goals"`
+	Meetings          []Meeting          `json:"meetings"`
+	StaleApplications []StaleApplication `json:"stale_applications"`
+}
+
+// GetDailyDigest builds the digest of the user in ctx for asOf: goals of the
+// current day, week and month, meetings in the next within, and applications
+// with no status change in staleDays.
+func GetDailyDigest(ctx context.Context, db DBTX, asOf time.Time, within time.Duration, staleDays int) (Digest, error) {
+	d := Digest{Date: periodStart("daily", asOf)}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return d, err
+	}
+	rows, err := db.QueryContext(ctx,
+		goalSelect+` WHERE user_id = $4
+           AND ((period = 'daily' AND target_date = $1)
+            OR (period = 'weekly' AND target_date = $2)
+            OR (period = 'monthly' AND target_date = $3))
+         ORDER BY period, completed, id`,
+		d.Date, periodStart("weekly", asOf), periodStart("monthly", asOf), uid)
+	if err != nil {
+		return d, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return d, err
+		}
+		d.Goals = append(d.Goals, g)
+	}
+	if err := rows.Err(); err != nil {
+		return d, err
+	}
+	if d.Meetings, err = listMeetingsBetween(ctx, db, asOf, asOf.Add(within)); err != nil {
+		return d, err
+	}
+	d.StaleApplications, err = ListStaleJobApplications(ctx, db, staleDays)
+	return d, err
+}
+
+// Empty reports whether there is nothing to send.
+func (d Digest) Empty() bool {
+	return len(d.Goals) == 0 && len(d.Meetings) == 0 && len(d.StaleApplications) == 0
+}
+
+// Subject is a one-line summary for the email subject.
+func (d Digest) Subject() string {
+	open := 0
+	for _, g := range d.Goals {
+		if !g.Completed {
+			open++
+		}
+	}
+	return fmt.Sprintf("Your %s: %d open %s, %d %s, %d stale %s", d.Date.Format("Mon Jan 2"),
+		open, pluralize(open, "goal"), len(d.Meetings), pluralize(len(d.Meetings), "meeting"),
+		len(d.StaleApplications), pluralize(len(d.StaleApplications), "application"))
+}
+
+// Text renders the digest as plain text; meeting times are shown in loc.
+func (d Digest) Text(loc *time.Location) string {
+	var b strings.Builder
+	section := func(title string) {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(title + "\n")
+	}
+	headings := map[string]string{"daily": "Today", "weekly": "This week", "monthly": "This month"}
+	period := ""
+	for _, g := range d.Goals {
+		if g.Period != period {
+			period = g.Period
+			section(headings[period])
+		}
+		mark := "[ ]"
+		if g.Completed {
+			mark = "[x]"
+		}
+		line := fmt.Sprintf("- %s %s", mark, g.Description)
+		if g.Progress != nil {
+			line += fmt.Sprintf(" (%d/%d)", *g.Progress, g.TargetCount)
+		}
+		b.WriteString(line + "\n")
+	}
+	if len(d.Meetings) > 0 {
+		section("Upcoming meetings")
+		for _, m := range d.Meetings {
+			line := fmt.Sprintf("- %s  %s", m.SessionTime.In(loc).Format("Mon 15:04"), m.SessionName)
+			var details []string
+			for _, s := range []string{m.SessionType, m.Company, m.Location} {
+				if s != "" {
+					details = append(details, s)
+				}
+			}
+			if len(details) > 0 {
+				line += " (" + strings.Join(details, ", ") + ")"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	if len(d.StaleApplications) > 0 {
+		section("Stale applications")
+		for _, s := range d.StaleApplications {
+			title := s.JobTitle
+			if s.Company != "" {
+				title += " at " + s.Company
+			}
+			b.WriteString(fmt.Sprintf("- %s: %s, no update for %d days\n", title, s.Status, s.DaysIdle))
+		}
+	}
+	if b.Len() == 0 {
+		return "Nothing planned today.\n"
+	}
+	return b.String()
+}
+
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
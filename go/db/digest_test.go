@@ -0,0 +1,48 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestText(t *testing.T) {
+	two := 2
+	d := Digest{
+		Date: day("2026-10-16"),
+		Goals: []Goal{
+			{Period: "daily", Description: "Apply to 5 jobs", TargetCount: 5, Progress: &two},
+			{Period: "daily", Description: "Review problem 42", Completed: true, TargetCount: 1},
+			{Period: "weekly", Description: "Finish the portfolio", TargetCount: 1},
+		},
+		Meetings: []Meeting{{
+			SessionName: "Acme onsite",
+			SessionType: "interview",
+			Company:     "Acme",
+			SessionTime: time.Date(2026, 10, 16, 17, 0, 0, 0, time.UTC),
+		}},
+		StaleApplications: []StaleApplication{{JobTitle: "Backend Engineer", Company: "Initech", Status: "applied", DaysIdle: 21}},
+	}
+	want := `Today
+- [ ] Apply to 5 jobs (2/5)
+- [x] Review problem 42
+
+This week
+- [ ] Finish the portfolio
+
+Upcoming meetings
+- Fri 10:00  Acme onsite (interview, Acme)
+
+Stale applications
+- Backend Engineer at Initech: applied, no update for 21 days
+`
+	loc := time.FixedZone("PDT", -7*3600)
+	if got := d.Text(loc); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+	if got, want := d.Subject(), "Your Fri Oct 16: 2 open goals, 1 meeting, 1 stale application"; got != want {
+		t.Errorf("Subject() = %q, want %q", got, want)
+	}
+	if (Digest{}).Text(loc) != "Nothing planned today.\n" || !(Digest{}).Empty() {
+		t.Error("an empty digest should say so")
+	}
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GoalMetrics are what a goal can count from the other tables over its day,
+// week or month. Progress and completion are maintained by the database
+// (see migration 000008), so they hold whichever code path wrote the rows.
+var GoalMetrics = map[string]string{
+	"jobs_applied":       "job applications with applied_date in the period",
+	"problems_solved":    "distinct coding problems with a solved attempt in the period",
+	"problems_attempted": "coding attempts logged in the period",
+	"meetings":           "meetings held in the period",
+	"contacts_met":       "distinct contacts met in the period",
+}
+
+// normalizeGoalMetric validates a metric name; "" means no metric.
+func normalizeGoalMetric(raw string) (string, error) {
+	metric := strings.ToLower(strings.TrimSpace(raw))
+	if metric == "" {
+		return "", nil
+	}
+	if _, ok := GoalMetrics[metric]; !ok {
+		names := make([]string, 0, len(GoalMetrics))
+		for name := range GoalMetrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fieldErrorf("metric must be one of %s", strings.Join(names, ", "))
+	}
+	return metric, nil
+}
+
+// metricTerms expands a metric filter into the lowercase terms matched
+// against the counted rows: each comma-separated part as written, plus every
+// alias and the label of the coding pattern it names, so "DP" also counts
+// problems tagged "Dynamic Programming" or "memoization".
+func metricTerms(filter string) interface{} {
+	seen := map[string]bool{}
+	for _, part := range strings.Split(filter, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		seen[part] = true
+		key := NormalizePattern(part)
+		if key == PatternOther {
+			continue
+		}
+		seen[strings.ToLower(patternLabel(key))] = true
+		for alias, k := range patternAliases {
+			if k == key {
+				seen[alias] = true
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	terms := make([]string, 0, len(seen))
+	for t := range seen {
+		terms = append(terms, strconv.Quote(t))
+	}
+	sort.Strings(terms)
+	return "{" + strings.Join(terms, ",") + "}"
+}
+
+// normalizeGoalMetricSet is the beforeUpdate hook of goals and
+// goal_recurrences: it validates metric and keeps metric_terms in step with
+// metric_filter.
+func normalizeGoalMetricSet(set map[string]interface{}) error {
+	if raw, ok := set["metric"]; ok {
+		metric, err := normalizeGoalMetric(stringValue(raw))
+		if err != nil {
+			return err
+		}
+		set["metric"] = nullIfEmpty(metric)
+	}
+	if raw, ok := set["metric_filter"]; ok {
+		filter := strings.TrimSpace(stringValue(raw))
+		set["metric_filter"] = nullIfEmpty(filter)
+		set["metric_terms"] = metricTerms(filter)
+	}
+	return nil
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+var (
+	metricPhrase = regexp.MustCompile(`(?i)^(.*?)\b(\d+)\s+((?:[\w&+/-]+\s+){0,3}?)(job applications|applications|jobs|roles|positions|problems|questions|contacts|people|coffee chats|meetings|meetups|interviews)\b`)
+	// genericQualifiers say nothing about which rows to count.
+	genericQualifiers = map[string]bool{"new": true, "more": true, "different": true, "leetcode": true, "coding": true, "job": true, "jobs": true, "tech": true}
+	attemptVerbs      = map[string]bool{"attempt": true, "try": true, "practice": true, "do": true, "work": true}
+)
+
+// ParseGoalMetric recognises countable goals such as "apply to 5 jobs",
+// "solve 3 DP problems" or "meet 2 people". It returns the metric, the
+// filter implied by any words between the number and the noun, and the
+// number; metric is "" when the description isn't countable.
+func ParseGoalMetric(description string) (metric, filter string, count int) {
+	m := metricPhrase.FindStringSubmatch(description)
+	if m == nil {
+		return "", "", 0
+	}
+	count, err := strconv.Atoi(m[2])
+	if err != nil || count <= 0 {
+		return "", "", 0
+	}
+	var qualifiers []string
+	for _, w := range strings.Fields(m[3]) {
+		if !genericQualifiers[strings.ToLower(w)] {
+			qualifiers = append(qualifiers, w)
+		}
+	}
+	filter = strings.Join(qualifiers, " ")
+	verbs := strings.Fields(strings.ToLower(m[1]))
+
+	switch noun := strings.ToLower(m[4]); noun {
+	case "job applications", "applications", "jobs", "roles", "positions":
+		metric = "jobs_applied"
+	case "problems", "questions":
+		metric = "problems_solved"
+		if len(verbs) > 0 && attemptVerbs[verbs[0]] {
+			metric = "problems_attempted"
+		}
+	case "contacts", "people", "coffee chats":
+		metric = "contacts_met"
+	case "meetups", "interviews":
+		metric = "meetings"
+		if filter == "" {
+			filter = strings.TrimSuffix(noun, "s")
+		}
+	default:
+		metric = "meetings"
+	}
+	return metric, filter, count
+}
+
+var goalWhen = regexp.MustCompile(`(?i)\s+(today|tomorrow|this week|next week|this month|next month)\.?$`)
+
+// ParseGoal reads a one-off goal such as "apply to 5 jobs this week" or
+// "solve 3 DP problems today" relative to asOf. Countable descriptions get
+// a metric (see ParseGoalMetric); the first number is the target count.
+func ParseGoal(text string, asOf time.Time) (Goal, error) {
+	text = strings.TrimSpace(text)
+	m := goalWhen.FindStringSubmatchIndex(text)
+	if m == nil {
+		return Goal{}, fieldErrorf("unrecognised goal %q (end it with today, this week or this month)", text)
+	}
+	desc := strings.TrimSpace(text[:m[0]])
+	if desc == "" {
+		return Goal{}, fieldErrorf("description is required")
+	}
+	goal := Goal{TargetCount: 1}
+	shift := 0
+	switch when := strings.ToLower(text[m[2]:m[3]]); when {
+	case "today", "tomorrow":
+		goal.Period = "daily"
+		if when == "tomorrow" {
+			shift = 1
+		}
+	case "this week", "next week":
+		goal.Period = "weekly"
+		if when == "next week" {
+			shift = 1
+		}
+	default:
+		goal.Period = "monthly"
+		if when == "next month" {
+			shift = 1
+		}
+	}
+	goal.TargetDate = addPeriods(goal.Period, periodStart(goal.Period, asOf), shift)
+	r, size := utf8.DecodeRuneInString(desc)
+	goal.Description = string(unicode.ToUpper(r)) + desc[size:]
+	goal.Metric, goal.MetricFilter, goal.TargetCount = ParseGoalMetric(desc)
+	if goal.Metric == "" {
+		goal.TargetCount = 1
+		if n, err := strconv.Atoi(firstNumber.FindString(desc)); err == nil && n > 0 {
+			goal.TargetCount = n
+		}
+	}
+	return goal, nil
+}
+
+// GoalFromRecord builds a goal from a JSON object (a write-request record or
+// a request body): a "rule" such as "apply to 5 jobs this week", or period,
+// description and target_date. Explicit fields override what the rule
+// implies.
+func GoalFromRecord(record map[string]interface{}, asOf time.Time) (Goal, error) {
+	rule := getString(record, "rule")
+	if rule == "" {
+		goal, err := goalFromRecord(record, "target_date")
+		goal.Period = getString(record, "period")
+		return goal, err
+	}
+	goal, err := ParseGoal(rule, asOf)
+	if err != nil {
+		return goal, err
+	}
+	if period := getString(record, "period"); period != "" {
+		goal.Period = period
+	}
+	if desc := getString(record, "description"); desc != "" {
+		goal.Description = desc
+	}
+	if d := getDate(record, "target_date"); d != nil {
+		goal.TargetDate = *d
+	}
+	if n := getIntPtr(record, "target_count"); n != nil {
+		goal.TargetCount = int(*n)
+	}
+	if _, ok := record["metric"]; ok {
+		goal.Metric = getString(record, "metric")
+	}
+	if _, ok := record["metric_filter"]; ok {
+		goal.MetricFilter = getString(record, "metric_filter")
+	}
+	goal.Completed = getBool(record, "completed")
+	goal.JobApplication = getIntPtr(record, "job_application_id")
+	goal.CodingProblem = getIntPtr(record, "coding_problem_id")
+	goal.Project = getIntPtr(record, "project_id")
+	goal.Contact = getIntPtr(record, "contact_id")
+	return goal, nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGoalMetric(t *testing.T) {
+	cases := []struct {
+		in     string
+		metric string
+		filter string
+		count  int
+	}{
+		{"Apply to 5 jobs", "jobs_applied", "", 5},
+		{"apply to 3 backend roles", "jobs_applied", "backend", 3},
+		{"Send 10 job applications", "jobs_applied", "", 10},
+		{"Solve 3 DP problems", "problems_solved", "DP", 3},
+		{"solve 2 new leetcode problems", "problems_solved", "", 2},
+		{"solve 2 medium sliding window problems", "problems_solved", "medium sliding window", 2},
+		{"Attempt 4 problems", "problems_attempted", "", 4},
+		{"meet 2 people", "contacts_met", "", 2},
+		{"Message 3 contacts", "contacts_met", "", 3},
+		{"do 2 mock interviews", "meetings", "mock", 2},
+		{"go to 1 meetups", "meetings", "meetup", 1},
+		{"Update the resume", "", "", 0},
+		{"Review problem 42", "", "", 0},
+	}
+	for _, c := range cases {
+		metric, filter, count := ParseGoalMetric(c.in)
+		if metric != c.metric || filter != c.filter || count != c.count {
+			t.Errorf("ParseGoalMetric(%q) = %q %q %d, want %q %q %d", c.in, metric, filter, count, c.metric, c.filter, c.count)
+		}
+	}
+}
+
+func TestMetricTerms(t *testing.T) {
+	if got := metricTerms(""); got != nil {
+		t.Fatalf("metricTerms(\"\") = %v, want nil", got)
+	}
+	got, _ := metricTerms("DP").(string)
+	for _, want := range []string{`"dp"`, `"dynamic programming"`, `"memoization"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("metricTerms(DP) = %s, missing %s", got, want)
+		}
+	}
+	if got := metricTerms("Backend, Stripe"); got != `{"backend","stripe"}` {
+		t.Errorf("metricTerms(Backend, Stripe) = %v", got)
+	}
+}
+
+func TestParseGoal(t *testing.T) {
+	asOf := day("2026-10-16") // a Friday
+	cases := []struct {
+		in     string
+		period string
+		date   string
+		desc   string
+		metric string
+		count  int
+	}{
+		{"apply to 5 jobs this week", "weekly", "2026-10-12", "Apply to 5 jobs", "jobs_applied", 5},
+		{"solve 3 DP problems today", "daily", "2026-10-16", "Solve 3 DP problems", "problems_solved", 3},
+		{"meet 2 people next week", "weekly", "2026-10-19", "Meet 2 people", "contacts_met", 2},
+		{"update the resume tomorrow", "daily", "2026-10-17", "Update the resume", "", 1},
+		{"finish 2 projects this month", "monthly", "2026-10-01", "Finish 2 projects", "", 2},
+	}
+	for _, c := range cases {
+		got, err := ParseGoal(c.in, asOf)
+		if err != nil {
+			t.Errorf("ParseGoal(%q): %v", c.in, err)
+			continue
+		}
+		if got.Period != c.period || got.TargetDate.Format("2006-01-02") != c.date || got.Description != c.desc ||
+			got.Metric != c.metric || got.TargetCount != c.count {
+			t.Errorf("ParseGoal(%q) = %s %s %q %q x%d, want %s %s %q %q x%d", c.in,
+				got.Period, got.TargetDate.Format("2006-01-02"), got.Description, got.Metric, got.TargetCount,
+				c.period, c.date, c.desc, c.metric, c.count)
+		}
+	}
+	if _, err := ParseGoal("apply to 5 jobs", asOf); err == nil {
+		t.Error("expected an error without a period")
+	}
+}
+
+func TestParseGoalRuleMetric(t *testing.T) {
+	rec, err := ParseGoalRule("solve 3 DP problems every week")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Metric != "problems_solved" || rec.MetricFilter != "DP" || rec.TargetCount != 3 {
+		t.Fatalf("unexpected recurrence %+v", rec)
+	}
+}
+
+func TestNormalizeGoalMetricSet(t *testing.T) {
+	set := map[string]interface{}{"metric": "Jobs_Applied", "metric_filter": ""}
+	if err := normalizeGoalMetricSet(set); err != nil {
+		t.Fatal(err)
+	}
+	if set["metric"] != "jobs_applied" || set["metric_filter"] != nil || set["metric_terms"] != nil {
+		t.Fatalf("unexpected set %v", set)
+	}
+	if err := normalizeGoalMetricSet(map[string]interface{}{"metric": "pushups"}); err == nil {
+		t.Fatal("expected an error for an unknown metric")
+	}
+}
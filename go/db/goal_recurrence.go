@@ -0,0 +1,493 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GoalRecurrence is a rule that creates a goal every matching day, week or
+// month, e.g. "solve 2 problems every weekday". Weekdays (ISO, 1 = Monday)
+// only apply to daily rules; empty means every day.
+type GoalRecurrence struct {
+	ID                  int64      `json:"id"`
+	Period              string     `json:"period"`
+	Description         string     `json:"description"`
+	TargetCount         int        `json:"target_count"`
+	Weekdays            []int      `json:"weekdays,omitempty"`
+	Schedule            string     `json:"schedule"`
+	StartsOn            time.Time  `json:"starts_on"`
+	EndsOn              *time.Time `json:"ends_on,omitempty"`
+	Active              bool       `json:"active"`
+	JobApplication      *int64     `json:"job_application_id,omitempty"`
+	CodingProblem       *int64     `json:"coding_problem_id,omitempty"`
+	Project             *int64     `json:"project_id,omitempty"`
+	Contact             *int64     `json:"contact_id,omitempty"`
+	MaterializedThrough *time.Time `json:"materialized_through,omitempty"`
+	Metric              string     `json:"metric,omitempty"`
+	MetricFilter        string     `json:"metric_filter,omitempty"`
+}
+
+const recurrenceSelect = `SELECT id, period::text, description, target_count, COALESCE(array_to_string(weekdays, ','), ''), starts_on, ends_on, active, job_application_id, coding_problem_id, project_id, contact_id, materialized_through, COALESCE(metric, ''), COALESCE(metric_filter, '') FROM goal_recurrences`
+
+// maxMaterialize caps how many instances one rule creates per run, so a
+// rule started long ago doesn't flood the goal lists.
+const maxMaterialize = 366
+
+func scanGoalRecurrence(row rowScanner) (GoalRecurrence, error) {
+	var r GoalRecurrence
+	var weekdays string
+	err := row.Scan(&r.ID, &r.Period, &r.Description, &r.TargetCount, &weekdays, &r.StartsOn, &r.EndsOn, &r.Active,
+		&r.JobApplication, &r.CodingProblem, &r.Project, &r.Contact, &r.MaterializedThrough, &r.Metric, &r.MetricFilter)
+	if err != nil {
+		return r, err
+	}
+	for _, part := range strings.Split(weekdays, ",") {
+		if day, err := strconv.Atoi(part); err == nil {
+			r.Weekdays = append(r.Weekdays, day)
+		}
+	}
+	r.Schedule = describeSchedule(r.Period, r.Weekdays)
+	return r, nil
+}
+
+func GetGoalRecurrence(ctx context.Context, db DBTX, id int64) (GoalRecurrence, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return GoalRecurrence{}, err
+	}
+	return scanGoalRecurrence(db.QueryRowContext(ctx, recurrenceSelect+` WHERE id=$1 AND user_id=$2`, id, uid))
+}
+
+// ListGoalRecurrences returns the recurrence rules, active ones first.
+func ListGoalRecurrences(ctx context.Context, db DBTX, activeOnly bool) ([]GoalRecurrence, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, recurrenceSelect+` WHERE user_id = $2 AND (active OR NOT $1) ORDER BY active DESC, id`, activeOnly, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []GoalRecurrence{}
+	for rows.Next() {
+		r, err := scanGoalRecurrence(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// InsertGoalRecurrence saves a rule and creates its instances up to today.
+// A zero StartsOn means today.
+func InsertGoalRecurrence(ctx context.Context, db DBTX, in GoalRecurrence) (int64, error) {
+	period, err := goalPeriodOf(in.Period)
+	if err != nil {
+		return 0, fieldErrorf("period must be daily, weekly or monthly")
+	}
+	if strings.TrimSpace(in.Description) == "" {
+		return 0, fieldErrorf("description is required")
+	}
+	if len(in.Weekdays) > 0 && period != "daily" {
+		return 0, fieldErrorf("weekdays only apply to daily goals")
+	}
+	metric, err := normalizeGoalMetric(in.Metric)
+	if err != nil {
+		return 0, err
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	filter := strings.TrimSpace(in.MetricFilter)
+	var weekdays, startsOn interface{}
+	if len(in.Weekdays) > 0 {
+		weekdays = pqIntArray(weekdayIDs(in.Weekdays))
+	}
+	if !in.StartsOn.IsZero() {
+		startsOn = in.StartsOn
+	}
+	var id int64
+	err = inTx(ctx, db, func(tx DBTX) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO goal_recurrences (period, description, target_count, weekdays, starts_on, ends_on, active, job_application_id, coding_problem_id, project_id, contact_id, metric, metric_filter, metric_terms, user_id)
+             VALUES ($1,$2,$3,$4,COALESCE($5::date, CURRENT_DATE),$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING id`,
+			period, strings.TrimSpace(in.Description), max(in.TargetCount, 1), weekdays, startsOn, in.EndsOn, in.Active,
+			in.JobApplication, in.CodingProblem, in.Project, in.Contact, nullIfEmpty(metric), nullIfEmpty(filter), metricTerms(filter), uid,
+		).Scan(&id)
+		if err != nil || !in.Active {
+			return err
+		}
+		rec, err := GetGoalRecurrence(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		_, err = materializeRecurrence(ctx, tx, rec, time.Now())
+		return err
+	})
+	return id, err
+}
+
+// UpdateGoalRecurrence changes a rule. Instances already created keep their
+// values; later ones follow the new rule.
+func UpdateGoalRecurrence(ctx context.Context, db DBTX, id int64, fields map[string]interface{}) error {
+	return updateByID(ctx, db, "goal_recurrences", id, fields)
+}
+
+// DeleteGoalRecurrence removes a rule; the goals it created are kept.
+func DeleteGoalRecurrence(ctx context.Context, db DBTX, id int64) error {
+	return deleteByID(ctx, db, "goal_recurrences", id)
+}
+
+// MaterializeGoals creates the goals of every active rule of the user in ctx
+// up to the day, week or month containing asOf and returns how many were
+// created. Each rule remembers the last period it covered, so running it
+// again (or after an instance was deleted) creates nothing new.
+func MaterializeGoals(ctx context.Context, db DBTX, asOf time.Time) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := db.QueryContext(ctx,
+		recurrenceSelect+` WHERE user_id = $2 AND active AND starts_on <= $1
+           AND (materialized_through IS NULL OR ends_on IS NULL OR materialized_through < ends_on)
+         ORDER BY id`, asOf.Format("2006-01-02"), uid)
+	if err != nil {
+		return 0, err
+	}
+	var due []GoalRecurrence
+	for rows.Next() {
+		r, err := scanGoalRecurrence(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var created int64
+	err = inTx(ctx, db, func(tx DBTX) error {
+		for _, rec := range due {
+			n, err := materializeRecurrence(ctx, tx, rec, asOf)
+			if err != nil {
+				return fmt.Errorf("goal recurrence %d: %w", rec.ID, err)
+			}
+			created += n
+		}
+		return nil
+	})
+	return created, err
+}
+
+func materializeRecurrence(ctx context.Context, db DBTX, rec GoalRecurrence, asOf time.Time) (int64, error) {
+	dates, through := recurrenceDates(rec, asOf)
+	if through.IsZero() {
+		return 0, nil
+	}
+	var created int64
+	for _, date := range dates {
+		res, err := db.ExecContext(ctx,
+			`INSERT INTO goals (period, description, target_date, target_count, job_application_id, coding_problem_id, project_id, contact_id, recurrence_id, metric, metric_filter, metric_terms, user_id)
+             SELECT $1,$2,$3,$4,$5,$6,$7,$8,id, metric, metric_filter, metric_terms, user_id FROM goal_recurrences WHERE id=$9
+             ON CONFLICT (recurrence_id, target_date) DO NOTHING`,
+			rec.Period, rec.Description, date, rec.TargetCount, rec.JobApplication, rec.CodingProblem, rec.Project, rec.Contact, rec.ID)
+		if err != nil {
+			return created, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return created, err
+		}
+		created += n
+	}
+	_, err := db.ExecContext(ctx, `UPDATE goal_recurrences SET materialized_through=$1 WHERE id=$2`, through, rec.ID)
+	return created, err
+}
+
+// recurrenceDates lists the target dates a rule still has to create up to
+// the period containing asOf, and the last period they cover (zero when
+// there is nothing left to do).
+func recurrenceDates(rec GoalRecurrence, asOf time.Time) ([]time.Time, time.Time) {
+	start := periodStart(rec.Period, rec.StartsOn)
+	if rec.MaterializedThrough != nil {
+		start = addPeriods(rec.Period, periodStart(rec.Period, *rec.MaterializedThrough), 1)
+	}
+	end := periodStart(rec.Period, asOf)
+	if rec.EndsOn != nil && rec.EndsOn.Before(end) {
+		end = periodStart(rec.Period, *rec.EndsOn)
+	}
+	var dates []time.Time
+	var through time.Time
+	for date := start; !date.After(end); date = addPeriods(rec.Period, date, 1) {
+		if len(dates) == maxMaterialize {
+			break
+		}
+		through = date
+		if rec.Period == "daily" && !onWeekday(rec.Weekdays, date) {
+			continue
+		}
+		dates = append(dates, date)
+	}
+	return dates, through
+}
+
+func onWeekday(weekdays []int, date time.Time) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	day := isoWeekday(date)
+	for _, d := range weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+var weekdayNames = []string{"", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// weekdayIDs sorts and dedupes ISO weekday numbers.
+func weekdayIDs(days []int) []int64 {
+	seen := map[int]bool{}
+	var ids []int64
+	for _, d := range days {
+		if !seen[d] {
+			seen[d] = true
+			ids = append(ids, int64(d))
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// parseWeekday reads a weekday name ("mon", "Tuesday", "thurs") or ISO
+// number.
+func parseWeekday(raw string) (int, bool) {
+	raw = strings.ToLower(strings.TrimSpace(raw))
+	if n, err := strconv.Atoi(raw); err == nil {
+		return n, n >= 1 && n <= 7
+	}
+	raw = strings.TrimSuffix(raw, "s")
+	if len(raw) < 3 {
+		return 0, false
+	}
+	for day := 1; day <= 7; day++ {
+		if strings.HasPrefix(strings.ToLower(time.Weekday(day%7).String()), raw) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func describeSchedule(period string, weekdays []int) string {
+	switch period {
+	case "weekly":
+		return "every week"
+	case "monthly":
+		return "every month"
+	}
+	ids := weekdayIDs(weekdays)
+	switch pqIntArray(ids) {
+	case "{}", "{1,2,3,4,5,6,7}":
+		return "every day"
+	case "{1,2,3,4,5}":
+		return "every weekday"
+	case "{6,7}":
+		return "every weekend day"
+	}
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, weekdayNames[id])
+	}
+	return "every " + strings.Join(names, ", ")
+}
+
+// ParseGoalSchedule reads a schedule such as "every weekday", "each week",
+// "monthly" or "every Mon, Wed and Fri" into a period and weekdays.
+func ParseGoalSchedule(text string) (string, []int, error) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return r == ',' || r == '/' || unicode.IsSpace(r)
+	})
+	var rest []string
+	for _, w := range words {
+		switch w {
+		case "every", "each", "per", "a", "on", "and", "the", "once":
+			continue
+		}
+		rest = append(rest, w)
+	}
+	if len(rest) == 1 {
+		switch rest[0] {
+		case "day", "days", "daily":
+			return "daily", nil, nil
+		case "weekday", "weekdays", "workday", "workdays":
+			return "daily", []int{1, 2, 3, 4, 5}, nil
+		case "weekend", "weekends":
+			return "daily", []int{6, 7}, nil
+		case "week", "weekly":
+			return "weekly", nil, nil
+		case "month", "monthly":
+			return "monthly", nil, nil
+		}
+	}
+	var days []int
+	for _, w := range rest {
+		day, ok := parseWeekday(w)
+		if !ok {
+			return "", nil, fieldErrorf("unrecognised schedule %q (try \"every weekday\", \"each week\" or \"every Mon, Thu\")", text)
+		}
+		days = append(days, day)
+	}
+	if len(days) == 0 {
+		return "", nil, fieldErrorf("schedule is required")
+	}
+	var sorted []int
+	for _, id := range weekdayIDs(days) {
+		sorted = append(sorted, int(id))
+	}
+	return "daily", sorted, nil
+}
+
+var (
+	scheduleStart = regexp.MustCompile(`(?i)\s+(?:every|each|per|once a|daily$|weekly$|monthly$)`)
+	firstNumber   = regexp.MustCompile(`\b(\d+)\b`)
+)
+
+// ParseGoalRule splits a rule such as "solve 2 problems every weekday" or
+// "message 3 contacts each week" into a recurrence. The schedule is the
+// last "every/each ..." phrase that parses; the first number in the
+// description becomes the target count, and countable descriptions get a
+// metric (see ParseGoalMetric).
+func ParseGoalRule(text string) (GoalRecurrence, error) {
+	text = strings.TrimSpace(text)
+	matches := scheduleStart.FindAllStringIndex(text, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		period, weekdays, err := ParseGoalSchedule(text[matches[i][0]:])
+		if err != nil {
+			continue
+		}
+		desc := strings.TrimSpace(text[:matches[i][0]])
+		if desc == "" {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(desc)
+		rec := GoalRecurrence{
+			Period:      period,
+			Description: string(unicode.ToUpper(r)) + desc[size:],
+			TargetCount: 1,
+			Weekdays:    weekdays,
+			Active:      true,
+		}
+		if m := firstNumber.FindString(desc); m != "" {
+			if n, err := strconv.Atoi(m); err == nil && n > 0 {
+				rec.TargetCount = n
+			}
+		}
+		if metric, filter, count := ParseGoalMetric(desc); metric != "" {
+			rec.Metric, rec.MetricFilter, rec.TargetCount = metric, filter, count
+		}
+		return rec, nil
+	}
+	return GoalRecurrence{}, fieldErrorf("unrecognised rule %q (write it like \"solve 2 problems every weekday\")", text)
+}
+
+// GoalRecurrenceFromRecord builds a rule from a JSON object (a write-request
+// record or a request body): a "rule" sentence, or a period or "schedule"
+// plus description. Explicit fields override what the rule implies.
+func GoalRecurrenceFromRecord(record map[string]interface{}) (GoalRecurrence, error) {
+	rec := GoalRecurrence{Active: true}
+	if rule := getString(record, "rule"); rule != "" {
+		var err error
+		if rec, err = ParseGoalRule(rule); err != nil {
+			return rec, err
+		}
+	}
+	if schedule := getString(record, "schedule"); schedule != "" {
+		var err error
+		if rec.Period, rec.Weekdays, err = ParseGoalSchedule(schedule); err != nil {
+			return rec, err
+		}
+	}
+	if period := getString(record, "period"); period != "" {
+		rec.Period = period
+	}
+	if raw, ok := record["weekdays"]; ok && raw != nil {
+		days, err := weekdaysValue(raw)
+		if err != nil {
+			return rec, err
+		}
+		rec.Weekdays = days
+	}
+	if desc := getString(record, "description"); desc != "" {
+		rec.Description = desc
+	}
+	if n := getIntPtr(record, "target_count"); n != nil {
+		rec.TargetCount = int(*n)
+	}
+	if _, ok := record["metric"]; ok {
+		rec.Metric = getString(record, "metric")
+	}
+	if _, ok := record["metric_filter"]; ok {
+		rec.MetricFilter = getString(record, "metric_filter")
+	}
+	if _, ok := record["active"]; ok {
+		rec.Active = getBool(record, "active")
+	}
+	if d := getDate(record, "starts_on"); d != nil {
+		rec.StartsOn = *d
+	}
+	rec.EndsOn = getDate(record, "ends_on")
+	rec.JobApplication = getIntPtr(record, "job_application_id")
+	rec.CodingProblem = getIntPtr(record, "coding_problem_id")
+	rec.Project = getIntPtr(record, "project_id")
+	rec.Contact = getIntPtr(record, "contact_id")
+	return rec, nil
+}
+
+// weekdaysValue reads weekdays from JSON: an array of names or numbers, or
+// a comma-separated string.
+func weekdaysValue(raw interface{}) ([]int, error) {
+	var parts []string
+	switch v := raw.(type) {
+	case string:
+		parts = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+	default:
+		return nil, fieldErrorf("weekdays: expected a list of days")
+	}
+	var days []int
+	for _, part := range parts {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		day, ok := parseWeekday(part)
+		if !ok {
+			return nil, fieldErrorf("weekdays: invalid day %q", part)
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
@@ -0,0 +1,172 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGoalRule(t *testing.T) {
+	cases := []struct {
+		in       string
+		period   string
+		desc     string
+		count    int
+		weekdays []int
+	}{
+		{"solve 2 problems every weekday", "daily", "Solve 2 problems", 2, []int{1, 2, 3, 4, 5}},
+		{"message 3 contacts each week", "weekly", "Message 3 contacts", 3, nil},
+		{"Review every problem every day", "daily", "Review every problem", 1, nil},
+		{"apply to 10 jobs monthly", "monthly", "Apply to 10 jobs", 10, nil},
+		{"go to a meetup once a month", "monthly", "Go to a meetup", 1, nil},
+		{"mock interview every Tue and Thurs", "daily", "Mock interview", 1, []int{2, 4}},
+	}
+	for _, c := range cases {
+		got, err := ParseGoalRule(c.in)
+		if err != nil {
+			t.Errorf("ParseGoalRule(%q): %v", c.in, err)
+			continue
+		}
+		if got.Period != c.period || got.Description != c.desc || got.TargetCount != c.count || !reflect.DeepEqual(got.Weekdays, c.weekdays) {
+			t.Errorf("ParseGoalRule(%q) = %s %q x%d %v, want %s %q x%d %v", c.in,
+				got.Period, got.Description, got.TargetCount, got.Weekdays, c.period, c.desc, c.count, c.weekdays)
+		}
+	}
+
+	for _, in := range []string{"solve 2 problems", "every weekday", "stretch every fortnight"} {
+		if _, err := ParseGoalRule(in); err == nil {
+			t.Errorf("ParseGoalRule(%q): expected an error", in)
+		}
+	}
+}
+
+func TestDescribeSchedule(t *testing.T) {
+	cases := []struct {
+		period   string
+		weekdays []int
+		want     string
+	}{
+		{"daily", nil, "every day"},
+		{"daily", []int{5, 4, 3, 2, 1}, "every weekday"},
+		{"daily", []int{7, 6}, "every weekend day"},
+		{"daily", []int{1, 3, 5}, "every Mon, Wed, Fri"},
+		{"weekly", nil, "every week"},
+		{"monthly", nil, "every month"},
+	}
+	for _, c := range cases {
+		if got := describeSchedule(c.period, c.weekdays); got != c.want {
+			t.Errorf("describeSchedule(%s, %v) = %q, want %q", c.period, c.weekdays, got, c.want)
+		}
+	}
+}
+
+func formatDates(dates []time.Time) string {
+	parts := make([]string, 0, len(dates))
+	for _, d := range dates {
+		parts = append(parts, d.Format("2006-01-02"))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestRecurrenceDates(t *testing.T) {
+	ptr := func(s string) *time.Time {
+		d := day(s)
+		return &d
+	}
+	cases := []struct {
+		name    string
+		rec     GoalRecurrence
+		asOf    string
+		want    string
+		through string
+	}{
+		{
+			name:    "weekdays skip the weekend",
+			rec:     GoalRecurrence{Period: "daily", Weekdays: []int{1, 2, 3, 4, 5}, StartsOn: day("2026-10-15")},
+			asOf:    "2026-10-19",
+			want:    "2026-10-15 2026-10-16 2026-10-19",
+			through: "2026-10-19",
+		},
+		{
+			name:    "resumes after the last materialized day",
+			rec:     GoalRecurrence{Period: "daily", StartsOn: day("2026-10-01"), MaterializedThrough: ptr("2026-10-15")},
+			asOf:    "2026-10-16",
+			want:    "2026-10-16",
+			through: "2026-10-16",
+		},
+		{
+			name:    "nothing left today",
+			rec:     GoalRecurrence{Period: "daily", StartsOn: day("2026-10-01"), MaterializedThrough: ptr("2026-10-16")},
+			asOf:    "2026-10-16",
+			want:    "",
+			through: "",
+		},
+		{
+			name:    "weekly goals land on Mondays",
+			rec:     GoalRecurrence{Period: "weekly", StartsOn: day("2026-10-01")},
+			asOf:    "2026-10-16",
+			want:    "2026-09-28 2026-10-05 2026-10-12",
+			through: "2026-10-12",
+		},
+		{
+			name:    "stops at ends_on",
+			rec:     GoalRecurrence{Period: "monthly", StartsOn: day("2026-07-15"), EndsOn: ptr("2026-08-31")},
+			asOf:    "2026-10-16",
+			want:    "2026-07-01 2026-08-01",
+			through: "2026-08-01",
+		},
+		{
+			name:    "not started yet",
+			rec:     GoalRecurrence{Period: "daily", StartsOn: day("2026-11-01")},
+			asOf:    "2026-10-16",
+			want:    "",
+			through: "",
+		},
+	}
+	for _, c := range cases {
+		dates, through := recurrenceDates(c.rec, day(c.asOf))
+		if got := formatDates(dates); got != c.want {
+			t.Errorf("%s: dates = %q, want %q", c.name, got, c.want)
+		}
+		gotThrough := ""
+		if !through.IsZero() {
+			gotThrough = through.Format("2006-01-02")
+		}
+		if gotThrough != c.through {
+			t.Errorf("%s: through = %q, want %q", c.name, gotThrough, c.through)
+		}
+	}
+}
+
+func TestRecurrenceDatesCapsBackfill(t *testing.T) {
+	rec := GoalRecurrence{Period: "daily", StartsOn: day("2020-01-01")}
+	dates, through := recurrenceDates(rec, day("2026-10-16"))
+	if len(dates) != maxMaterialize {
+		t.Fatalf("got %d dates, want %d", len(dates), maxMaterialize)
+	}
+	if !through.Equal(dates[len(dates)-1]) {
+		t.Fatalf("through = %s, want the last created date %s", through, dates[len(dates)-1])
+	}
+}
+
+func TestGoalRecurrenceFromRecord(t *testing.T) {
+	rec, err := GoalRecurrenceFromRecord(map[string]interface{}{
+		"rule":              "solve 2 problems every weekday",
+		"weekdays":          []interface{}{"mon", "wed"},
+		"coding_problem_id": "7",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Period != "daily" || rec.TargetCount != 2 || !reflect.DeepEqual(rec.Weekdays, []int{1, 3}) || !rec.Active {
+		t.Fatalf("unexpected recurrence %+v", rec)
+	}
+	if rec.CodingProblem == nil || *rec.CodingProblem != 7 {
+		t.Fatalf("coding_problem_id = %v, want 7", rec.CodingProblem)
+	}
+
+	if _, err := GoalRecurrenceFromRecord(map[string]interface{}{"weekdays": "mon,funday"}); err == nil {
+		t.Fatal("expected an error for an unknown weekday")
+	}
+}
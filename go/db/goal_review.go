@@ -0,0 +1,331 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GoalPeriod is the goals of one day, week or month.
+type GoalPeriod struct {
+	Start     time.Time `json:"start"`
+	Total     int       `json:"total"`
+	Completed int       `json:"completed"`
+}
+
+// Done reports whether every goal of the period was completed.
+func (p GoalPeriod) Done() bool {
+	return p.Total > 0 && p.Completed == p.Total
+}
+
+type GoalStats struct {
+	Type           string       `json:"type"`
+	From           time.Time    `json:"from"`
+	To             time.Time    `json:"to"`
+	Total          int          `json:"total"`
+	Completed      int          `json:"completed"`
+	CompletionRate float64      `json:"completion_rate"`
+	CurrentStreak  int          `json:"current_streak"`
+	LongestStreak  int          `json:"longest_streak"`
+	Periods        []GoalPeriod `json:"periods"`
+}
+
+// defaultStatsPeriods is how far back GetGoalStats looks per goal type.
+var defaultStatsPeriods = map[string]int{"daily": 30, "weekly": 12, "monthly": 6}
+
+// periodStart truncates t to the start of its day, ISO week or month.
+func periodStart(goalType string, t time.Time) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch goalType {
+	case "weekly":
+		return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	case "monthly":
+		return t.AddDate(0, 0, 1-t.Day())
+	}
+	return t
+}
+
+func addPeriods(goalType string, t time.Time, n int) time.Time {
+	switch goalType {
+	case "weekly":
+		return t.AddDate(0, 0, 7*n)
+	case "monthly":
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// GetGoalStats summarises the last periods (days, weeks or months) of goals
+// up to and including the one containing asOf.
+func GetGoalStats(ctx context.Context, db DBTX, goalType string, asOf time.Time, periods int) (GoalStats, error) {
+	goalType, err := goalPeriodOf(goalType)
+	if err != nil {
+		return GoalStats{}, fieldErrorf("goal type must be daily, weekly or monthly")
+	}
+	if periods <= 0 {
+		periods = defaultStatsPeriods[goalType]
+	}
+	to := periodStart(goalType, asOf)
+	from := addPeriods(goalType, to, -(periods - 1))
+	trunc := map[string]string{"daily": "day", "weekly": "week", "monthly": "month"}[goalType]
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return GoalStats{}, err
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT date_trunc('%s', target_date)::date, count(*), count(*) FILTER (WHERE completed)
+         FROM goals WHERE user_id = $4 AND period = $1 AND target_date >= $2 AND target_date < $3 GROUP BY 1 ORDER BY 1`,
+		trunc), goalType, from, addPeriods(goalType, to, 1), uid)
+	if err != nil {
+		return GoalStats{}, err
+	}
+	defer rows.Close()
+	var data []GoalPeriod
+	for rows.Next() {
+		var p GoalPeriod
+		if err := rows.Scan(&p.Start, &p.Total, &p.Completed); err != nil {
+			return GoalStats{}, err
+		}
+		data = append(data, p)
+	}
+	if err := rows.Err(); err != nil {
+		return GoalStats{}, err
+	}
+	stats := buildGoalStats(goalType, from, to, data)
+	return stats, nil
+}
+
+// buildGoalStats fills in empty periods and computes streaks. A period
+// extends a streak when all its goals were completed; periods without goals
+// are skipped rather than breaking it, and the current period only counts
+// once it is done, so an unfinished today doesn't reset the streak.
+func buildGoalStats(goalType string, from, to time.Time, data []GoalPeriod) GoalStats {
+	stats := GoalStats{Type: goalType, From: from, To: to, Periods: []GoalPeriod{}}
+	byStart := map[time.Time]GoalPeriod{}
+	for _, p := range data {
+		byStart[periodStart(goalType, p.Start)] = p
+	}
+	for start := from; !start.After(to); start = addPeriods(goalType, start, 1) {
+		p := byStart[start]
+		p.Start = start
+		stats.Periods = append(stats.Periods, p)
+		stats.Total += p.Total
+		stats.Completed += p.Completed
+	}
+	stats.CompletionRate = ratio(stats.Completed, stats.Total)
+
+	run := 0
+	for _, p := range stats.Periods {
+		switch {
+		case p.Total == 0:
+		case p.Done():
+			run++
+			stats.LongestStreak = max(stats.LongestStreak, run)
+		default:
+			run = 0
+		}
+	}
+	for i := len(stats.Periods) - 1; i >= 0; i-- {
+		p := stats.Periods[i]
+		if p.Total == 0 || (i == len(stats.Periods)-1 && !p.Done()) {
+			continue
+		}
+		if !p.Done() {
+			break
+		}
+		stats.CurrentStreak++
+	}
+	return stats
+}
+
+// ListOverdueDailyGoals returns incomplete daily goals dated before asOf.
+// Goals from a recurrence are left out: each day gets a fresh instance, so
+// they are never carried over.
+func ListOverdueDailyGoals(ctx context.Context, db DBTX, asOf time.Time) ([]Goal, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		goalSelect+` WHERE user_id = $2 AND period = 'daily' AND recurrence_id IS NULL AND NOT completed AND target_date < $1 ORDER BY target_date, id`,
+		asOf.Format("2006-01-02"), uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []Goal{}
+	for rows.Next() {
+		g, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, g)
+	}
+	return res, rows.Err()
+}
+
+// RollOverDailyGoals moves every incomplete one-off daily goal dated before
+// asOf to asOf and returns how many moved.
+func RollOverDailyGoals(ctx context.Context, db DBTX, asOf time.Time) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx,
+		`UPDATE goals SET target_date=$1 WHERE user_id = $2 AND period = 'daily' AND recurrence_id IS NULL AND NOT completed AND target_date < $1`,
+		asOf.Format("2006-01-02"), uid)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CompleteMetContactGoals completes the open goals linked to a contact once
+// a meeting with them, on or after the goal's date, has taken place by asOf,
+// and returns how many it completed. The meeting triggers skip meetings
+// still ahead; this picks them up once they have passed.
+func CompleteMetContactGoals(ctx context.Context, db DBTX, asOf time.Time) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx,
+		`UPDATE goals g SET completed = true
+         WHERE g.user_id = $2 AND g.contact_id IS NOT NULL AND g.metric IS NULL AND NOT g.completed
+           AND EXISTS (
+               SELECT 1 FROM meetings m LEFT JOIN meeting_contacts mc ON mc.meeting_id = m.id
+               WHERE m.user_id = $2 AND (m.contact_id = g.contact_id OR mc.contact_id = g.contact_id)
+                 AND m.session_time <= $1 AND m.session_time::date >= g.target_date)`,
+		asOf, uid)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GoalSuggestion is a proposed goal with the reason it was picked.
+type GoalSuggestion struct {
+	Goal
+	Reason string `json:"reason"`
+}
+
+// Caps on each source of weekly goal suggestions.
+const (
+	suggestFollowUps = 3
+	suggestReviews   = 3
+	suggestProjects  = 2
+	suggestContacts  = 2
+)
+
+// SuggestWeeklyGoals proposes goals for the week starting weekOf from the
+// linked records: stale applications to follow up on, interviews that week,
+// coding problems due for review, the least practiced pattern, active
+// projects and contacts not met in a while. Records that already have a
+// weekly goal that week are left out.
+func SuggestWeeklyGoals(ctx context.Context, db DBTX, weekOf time.Time) ([]GoalSuggestion, error) {
+	weekOf = periodStart("weekly", weekOf)
+	weekEnd := weekOf.AddDate(0, 0, 7)
+	existing, err := ListGoalsPage(ctx, db, "weekly", ListParams{
+		Filters: map[string]string{"from": weekOf.Format("2006-01-02"), "to": weekOf.AddDate(0, 0, 6).Format("2006-01-02")},
+		Limit:   maxPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	linked := map[string]bool{}
+	for _, g := range existing.Items {
+		for kind, id := range map[string]*int64{"job": g.JobApplication, "coding": g.CodingProblem, "project": g.Project, "contact": g.Contact} {
+			if id != nil {
+				linked[fmt.Sprintf("%s:%d", kind, *id)] = true
+			}
+		}
+	}
+
+	var res []GoalSuggestion
+	add := func(kind string, id int64, description, reason string) {
+		key := fmt.Sprintf("%s:%d", kind, id)
+		if linked[key] {
+			return
+		}
+		linked[key] = true
+		g := Goal{Description: description, TargetDate: weekOf}
+		ref := id
+		switch kind {
+		case "job":
+			g.JobApplication = &ref
+		case "coding":
+			g.CodingProblem = &ref
+		case "project":
+			g.Project = &ref
+		case "contact":
+			g.Contact = &ref
+		}
+		res = append(res, GoalSuggestion{Goal: g, Reason: reason})
+	}
+
+	interviews, err := ListUpcomingInterviews(ctx, db, 0, weekOf, 20)
+	if err != nil {
+		return nil, err
+	}
+	for _, iv := range interviews {
+		if !iv.SessionTime.Before(weekEnd) || iv.JobApplication == nil {
+			continue
+		}
+		add("job", *iv.JobApplication, fmt.Sprintf("Prepare for %s (%s)", iv.SessionName, iv.JobTitle),
+			"interview on "+iv.SessionTime.Format("Mon Jan 2"))
+	}
+
+	stale, err := ListStaleJobApplications(ctx, db, 14)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(stale) && i < suggestFollowUps; i++ {
+		s := stale[i]
+		add("job", s.ID, fmt.Sprintf("Follow up on %s at %s", s.JobTitle, s.Company),
+			fmt.Sprintf("no update in %d days (%s)", s.DaysIdle, s.Status))
+	}
+
+	due, err := ListDueReviews(ctx, db, weekEnd.AddDate(0, 0, -1), suggestReviews)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range due {
+		add("coding", d.Problem.ID, "Review "+problemName(d.Problem), "due for spaced-repetition review")
+	}
+
+	report, err := GetCodingPatternReport(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.LeastPracticed) > 0 {
+		key := report.LeastPracticed[0]
+		res = append(res, GoalSuggestion{
+			Goal:   Goal{Description: "Solve 3 " + patternLabel(key) + " problems", TargetDate: weekOf},
+			Reason: "least practiced coding pattern",
+		})
+	}
+
+	projects, err := ListProjectsPage(ctx, db, ListParams{Filters: map[string]string{"active": "true"}, Limit: suggestProjects})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range projects.Items {
+		add("project", p.ID, "Ship one improvement to "+p.Name, "active project")
+	}
+
+	contacts, err := ListContactMeetings(ctx, db, 0, "", weekOf, suggestContacts)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range contacts {
+		reason := "never met"
+		if c.LastMeeting != nil {
+			reason = "last met " + c.LastMeeting.SessionTime.Format("Jan 2, 2006")
+		}
+		add("contact", c.Contact.ID, "Reconnect with "+c.Contact.PersonName, reason)
+	}
+	if res == nil {
+		res = []GoalSuggestion{}
+	}
+	return res, nil
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestPeriodStart(t *testing.T) {
+	cases := []struct {
+		goalType, in, want string
+	}{
+		{"daily", "2026-10-16", "2026-10-16"},
+		{"weekly", "2026-10-16", "2026-10-12"}, // Friday -> Monday
+		{"weekly", "2026-10-18", "2026-10-12"}, // Sunday -> Monday
+		{"weekly", "2026-10-12", "2026-10-12"},
+		{"monthly", "2026-10-16", "2026-10-01"},
+	}
+	for _, c := range cases {
+		if got := periodStart(c.goalType, day(c.in)); !got.Equal(day(c.want)) {
+			t.Errorf("periodStart(%s, %s) = %s, want %s", c.goalType, c.in, got.Format("2006-01-02"), c.want)
+		}
+	}
+}
+
+func TestBuildGoalStatsStreaks(t *testing.T) {
+	data := []GoalPeriod{
+		{Start: day("2026-10-10"), Total: 2, Completed: 2},
+		{Start: day("2026-10-11"), Total: 1, Completed: 0}, // breaks the run
+		{Start: day("2026-10-12"), Total: 3, Completed: 3},
+		{Start: day("2026-10-13"), Total: 1, Completed: 1},
+		// 2026-10-14 has no goals and is skipped.
+		{Start: day("2026-10-15"), Total: 2, Completed: 2},
+		{Start: day("2026-10-16"), Total: 2, Completed: 1}, // today, in progress
+	}
+	stats := buildGoalStats("daily", day("2026-10-10"), day("2026-10-16"), data)
+	if len(stats.Periods) != 7 {
+		t.Fatalf("expected 7 periods, got %d", len(stats.Periods))
+	}
+	if stats.Total != 11 || stats.Completed != 9 {
+		t.Errorf("totals = %d/%d", stats.Completed, stats.Total)
+	}
+	if stats.CurrentStreak != 3 || stats.LongestStreak != 3 {
+		t.Errorf("streaks current=%d longest=%d, want 3 and 3", stats.CurrentStreak, stats.LongestStreak)
+	}
+
+	// Finishing today extends the current streak.
+	data[len(data)-1].Completed = 2
+	stats = buildGoalStats("daily", day("2026-10-10"), day("2026-10-16"), data)
+	if stats.CurrentStreak != 4 || stats.LongestStreak != 4 {
+		t.Errorf("streaks current=%d longest=%d, want 4 and 4", stats.CurrentStreak, stats.LongestStreak)
+	}
+}
+
+func TestBuildGoalStatsWeeklyBuckets(t *testing.T) {
+	// A week_of stored mid-week still lands in its Monday bucket.
+	data := []GoalPeriod{{Start: day("2026-10-14"), Total: 1, Completed: 1}}
+	stats := buildGoalStats("weekly", day("2026-10-05"), day("2026-10-12"), data)
+	if len(stats.Periods) != 2 || stats.Periods[1].Total != 1 {
+		t.Fatalf("unexpected periods %+v", stats.Periods)
+	}
+}
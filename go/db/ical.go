@@ -0,0 +1,517 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Minimal RFC 5545 support: enough to publish meetings as a calendar feed and
+// to read the VEVENTs (and their time zones) out of a calendar export.
+
+const (
+	icsDateTimeUTC   = "20060102T150405Z"
+	icsDateTimeLocal = "20060102T150405"
+	icsDate          = "20060102"
+	icsMaxLineOctets = 75
+)
+
+// icsWriter writes CRLF terminated, folded content lines.
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newICSWriter(w io.Writer) *icsWriter {
+	return &icsWriter{w: bufio.NewWriter(w)}
+}
+
+func (iw *icsWriter) line(name, value string) {
+	iw.raw(name + ":" + value)
+}
+
+func (iw *icsWriter) text(name, value string) {
+	if value != "" {
+		iw.line(name, icsEscape(value))
+	}
+}
+
+func (iw *icsWriter) raw(line string) {
+	if iw.err != nil {
+		return
+	}
+	_, iw.err = iw.w.WriteString(icsFold(line) + "\r\n")
+}
+
+func (iw *icsWriter) flush() error {
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// icsFold splits a content line into chunks of at most 75 octets, never inside
+// a UTF-8 sequence; continuation lines start with a single space.
+func icsFold(line string) string {
+	if len(line) <= icsMaxLineOctets {
+		return line
+	}
+	var b strings.Builder
+	limit := icsMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the next line's length.
+		limit = icsMaxLineOctets - 1
+	}
+	b.WriteString(line)
+	return b.String()
+}
+
+func icsEscape(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func icsUnescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// icsParamValue quotes a parameter value when it contains separators.
+func icsParamValue(s string) string {
+	s = strings.ReplaceAll(s, `"`, "'")
+	if strings.ContainsAny(s, ";:,") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// icsOffset formats a UTC offset as +HHMM (or +HHMMSS).
+func icsOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	out := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		out += fmt.Sprintf("%02d", seconds%60)
+	}
+	return out
+}
+
+func parseICSOffset(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) != 5 && len(raw) != 7 || (raw[0] != '+' && raw[0] != '-') {
+		return 0, fmt.Errorf("invalid utc offset %q", raw)
+	}
+	var parts [3]int
+	for i := 0; 1+2*i < len(raw); i++ {
+		n, err := strconv.Atoi(raw[1+2*i : 3+2*i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid utc offset %q", raw)
+		}
+		parts[i] = n
+	}
+	seconds := parts[0]*3600 + parts[1]*60 + parts[2]
+	if raw[0] == '-' {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
+
+// zoneTransition is an instant at which a location's UTC offset changes.
+type zoneTransition struct {
+	At         time.Time
+	OffsetFrom int
+	OffsetTo   int
+	Name       string
+	DST        bool
+}
+
+// zoneTransitions finds the offset changes of loc in [from, to). Go does not
+// expose the zone rules, so days are probed and each change is narrowed down
+// to the second.
+func zoneTransitions(loc *time.Location, from, to time.Time) []zoneTransition {
+	var res []zoneTransition
+	_, prev := from.In(loc).Zone()
+	for t := from; t.Before(to); t = t.Add(24 * time.Hour) {
+		next := t.Add(24 * time.Hour)
+		_, offset := next.In(loc).Zone()
+		if offset == prev {
+			continue
+		}
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(loc).Zone(); o == prev {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		// Transitions fall on whole seconds.
+		hi = hi.Truncate(time.Second)
+		name, _ := hi.In(loc).Zone()
+		res = append(res, zoneTransition{At: hi, OffsetFrom: prev, OffsetTo: offset, Name: name, DST: hi.In(loc).IsDST()})
+		prev = offset
+	}
+	return res
+}
+
+// vtimezone describes loc between from and to as explicit observances,
+// starting with the offset in effect at from.
+func (iw *icsWriter) vtimezone(loc *time.Location, from, to time.Time) {
+	iw.line("BEGIN", "VTIMEZONE")
+	iw.line("TZID", loc.String())
+	observance := func(start time.Time, offsetFrom, offsetTo int, name string, dst bool) {
+		kind := "STANDARD"
+		if dst {
+			kind = "DAYLIGHT"
+		}
+		iw.line("BEGIN", kind)
+		// DTSTART of an observance is the wall clock time before the change.
+		iw.line("DTSTART", start.UTC().Add(time.Duration(offsetFrom)*time.Second).Format(icsDateTimeLocal))
+		iw.line("TZOFFSETFROM", icsOffset(offsetFrom))
+		iw.line("TZOFFSETTO", icsOffset(offsetTo))
+		if name != "" {
+			iw.text("TZNAME", name)
+		}
+		iw.line("END", kind)
+	}
+	name, offset := from.In(loc).Zone()
+	observance(from, offset, offset, name, from.In(loc).IsDST())
+	for _, tr := range zoneTransitions(loc, from, to) {
+		observance(tr.At, tr.OffsetFrom, tr.OffsetTo, tr.Name, tr.DST)
+	}
+	iw.line("END", "VTIMEZONE")
+}
+
+type icsProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+func (p icsProperty) param(name string) string {
+	return p.Params[name]
+}
+
+type icsComponent struct {
+	Name     string
+	Props    []icsProperty
+	Children []*icsComponent
+}
+
+func (c *icsComponent) prop(name string) (icsProperty, bool) {
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return icsProperty{}, false
+}
+
+func (c *icsComponent) text(name string) string {
+	p, ok := c.prop(name)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(icsUnescape(p.Value))
+}
+
+// walk calls fn for every nested component with the given name.
+func (c *icsComponent) walk(name string, fn func(*icsComponent)) {
+	for _, child := range c.Children {
+		if child.Name == name {
+			fn(child)
+		}
+		child.walk(name, fn)
+	}
+}
+
+// parseICS reads an iCalendar stream into a component tree rooted at a
+// synthetic component holding every top level VCALENDAR.
+func parseICS(r io.Reader) (*icsComponent, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+	root := &icsComponent{}
+	stack := []*icsComponent{root}
+	for i, raw := range lines {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		prop, err := parseICSLine(raw)
+		if err != nil {
+			return nil, fieldErrorf("ics line %d: %v", i+1, err)
+		}
+		top := stack[len(stack)-1]
+		switch prop.Name {
+		case "BEGIN":
+			child := &icsComponent{Name: strings.ToUpper(prop.Value)}
+			top.Children = append(top.Children, child)
+			stack = append(stack, child)
+		case "END":
+			if len(stack) == 1 || top.Name != strings.ToUpper(prop.Value) {
+				return nil, fieldErrorf("ics line %d: unexpected END:%s", i+1, prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			top.Props = append(top.Props, prop)
+		}
+	}
+	if len(stack) != 1 {
+		return nil, fieldErrorf("ics: %s is not closed", stack[len(stack)-1].Name)
+	}
+	var calendars int
+	root.walk("VCALENDAR", func(*icsComponent) { calendars++ })
+	if calendars == 0 {
+		return nil, fieldErrorf("ics: no VCALENDAR found")
+	}
+	return root, nil
+}
+
+func unfoldICS(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxICSLine)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fieldErrorf("ics: %v", err)
+	}
+	return lines, nil
+}
+
+const maxICSLine = 1 << 20
+
+// parseICSLine splits `NAME;PARAM=a;PARAM="b:c":value`.
+func parseICSLine(line string) (icsProperty, error) {
+	prop := icsProperty{Params: map[string]string{}}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return prop, fmt.Errorf("malformed content line %q", truncate(line, 40))
+	}
+	prop.Name = strings.ToUpper(line[:i])
+	for line[i] == ';' {
+		rest := line[i+1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return prop, fmt.Errorf("malformed parameter in %s", prop.Name)
+		}
+		name := strings.ToUpper(rest[:eq])
+		j := i + 1 + eq + 1
+		var value strings.Builder
+		quoted := false
+		for ; j < len(line); j++ {
+			c := line[j]
+			if c == '"' {
+				quoted = !quoted
+				continue
+			}
+			if !quoted && (c == ';' || c == ':') {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if j == len(line) {
+			return prop, fmt.Errorf("%s has no value", prop.Name)
+		}
+		prop.Params[name] = value.String()
+		i = j
+	}
+	prop.Value = line[i+1:]
+	return prop, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// icsZone is a VTIMEZONE from an imported file, used when its TZID is not an
+// IANA name (Outlook writes e.g. "Eastern Standard Time").
+type icsZone struct {
+	observances []icsObservance
+}
+
+type icsObservance struct {
+	start    time.Time // wall clock, in UTC for comparison only
+	offsetTo int
+	rule     map[string]string
+}
+
+func parseICSZone(c *icsComponent) icsZone {
+	var zone icsZone
+	for _, child := range c.Children {
+		if child.Name != "STANDARD" && child.Name != "DAYLIGHT" {
+			continue
+		}
+		start, err := time.Parse(icsDateTimeLocal, child.text("DTSTART"))
+		if err != nil {
+			continue
+		}
+		offset, err := parseICSOffset(child.text("TZOFFSETTO"))
+		if err != nil {
+			continue
+		}
+		obs := icsObservance{start: start, offsetTo: offset}
+		if rrule := child.text("RRULE"); rrule != "" {
+			obs.rule = map[string]string{}
+			for _, part := range strings.Split(rrule, ";") {
+				if k, v, ok := strings.Cut(part, "="); ok {
+					obs.rule[strings.ToUpper(k)] = strings.ToUpper(v)
+				}
+			}
+		}
+		zone.observances = append(zone.observances, obs)
+	}
+	return zone
+}
+
+// offsetAt returns the UTC offset for a wall clock time: the offset of the
+// observance that most recently took effect.
+func (z icsZone) offsetAt(wall time.Time) (int, bool) {
+	var best time.Time
+	offset, found := 0, false
+	for _, obs := range z.observances {
+		for _, onset := range obs.onsets(wall.Year()) {
+			if onset.After(wall) || (found && !onset.After(best)) {
+				continue
+			}
+			best, offset, found = onset, obs.offsetTo, true
+		}
+	}
+	return offset, found
+}
+
+// onsets lists when the observance took effect in year and the year before.
+// Only the yearly BYMONTH/BYDAY rules calendar apps emit are understood;
+// anything else falls back to DTSTART.
+func (obs icsObservance) onsets(year int) []time.Time {
+	if obs.rule == nil || obs.rule["FREQ"] != "YEARLY" {
+		return []time.Time{obs.start}
+	}
+	month, err := strconv.Atoi(obs.rule["BYMONTH"])
+	if err != nil || month < 1 || month > 12 {
+		return []time.Time{obs.start}
+	}
+	var res []time.Time
+	for _, y := range []int{year - 1, year} {
+		if y < obs.start.Year() {
+			continue
+		}
+		day, ok := nthWeekday(y, time.Month(month), obs.rule["BYDAY"])
+		if !ok {
+			return []time.Time{obs.start}
+		}
+		res = append(res, time.Date(y, time.Month(month), day, obs.start.Hour(), obs.start.Minute(), obs.start.Second(), 0, time.UTC))
+	}
+	return res
+}
+
+var icsWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// nthWeekday resolves a BYDAY value such as "2SU" or "-1SU" in a month.
+func nthWeekday(year int, month time.Month, byday string) (int, bool) {
+	if len(byday) < 3 {
+		return 0, false
+	}
+	weekday, ok := icsWeekdays[byday[len(byday)-2:]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(byday[:len(byday)-2])
+	if err != nil || n == 0 || n < -5 || n > 5 {
+		return 0, false
+	}
+	if n > 0 {
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		day := 1 + (int(weekday)-int(first.Weekday())+7)%7 + 7*(n-1)
+		return day, day <= daysIn(year, month)
+	}
+	lastDay := daysIn(year, month)
+	last := time.Date(year, month, lastDay, 0, 0, 0, 0, time.UTC)
+	day := lastDay - (int(last.Weekday())-int(weekday)+7)%7 + 7*(n+1)
+	return day, day >= 1
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// parseICSTime reads a DATE or DATE-TIME property. Floating times and dates
+// are interpreted in loc; TZID parameters are resolved as IANA names first,
+// then against the file's own VTIMEZONEs.
+func parseICSTime(p icsProperty, zones map[string]icsZone, loc *time.Location) (time.Time, bool, error) {
+	value := strings.TrimSpace(p.Value)
+	if strings.EqualFold(p.param("VALUE"), "DATE") || len(value) == len(icsDate) {
+		t, err := time.ParseInLocation(icsDate, value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(icsDateTimeUTC, value)
+		return t, false, err
+	}
+	tzid := strings.TrimPrefix(p.param("TZID"), "/")
+	if tzid == "" {
+		t, err := time.ParseInLocation(icsDateTimeLocal, value, loc)
+		return t, false, err
+	}
+	if zoneLoc, err := time.LoadLocation(tzid); err == nil {
+		t, err := time.ParseInLocation(icsDateTimeLocal, value, zoneLoc)
+		return t, false, err
+	}
+	wall, err := time.Parse(icsDateTimeLocal, value)
+	if err != nil {
+		return wall, false, err
+	}
+	zone, ok := zones[tzid]
+	if !ok {
+		return wall, false, fmt.Errorf("unknown time zone %q", tzid)
+	}
+	offset, ok := zone.offsetAt(wall)
+	if !ok {
+		return wall, false, fmt.Errorf("time zone %q does not cover %s", tzid, value)
+	}
+	return wall.Add(-time.Duration(offset) * time.Second), false, nil
+}
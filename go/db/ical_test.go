@@ -0,0 +1,143 @@
+package db
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICSFoldKeepsLinesShort(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("café ", 40)
+	folded := icsFold(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		if len(part) > icsMaxLineOctets {
+			t.Fatalf("line has %d octets: %q", len(part), part)
+		}
+	}
+	lines, err := unfoldICS(strings.NewReader(folded + "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 || lines[0] != line {
+		t.Fatalf("unfold mismatch: %q", lines)
+	}
+}
+
+func TestICSEscapeRoundTrip(t *testing.T) {
+	in := "Prep: system design; bring notes, laptop\nRoom 4\\B"
+	if got := icsUnescape(icsEscape(in)); got != in {
+		t.Fatalf("got %q, want %q", got, in)
+	}
+}
+
+func TestParseICSLineQuotedParams(t *testing.T) {
+	prop, err := parseICSLine(`ORGANIZER;CN="Doe, Jane";ROLE=CHAIR:mailto:jane@example.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prop.Name != "ORGANIZER" || prop.param("CN") != "Doe, Jane" || prop.param("ROLE") != "CHAIR" || prop.Value != "mailto:jane@example.com" {
+		t.Fatalf("unexpected property %+v", prop)
+	}
+}
+
+func TestZoneTransitionsNewYork(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	trs := zoneTransitions(loc, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(trs) != 2 {
+		t.Fatalf("expected 2 transitions, got %+v", trs)
+	}
+	if want := time.Date(2026, 3, 8, 7, 0, 0, 0, time.UTC); !trs[0].At.Equal(want) || !trs[0].DST || trs[0].OffsetTo != -4*3600 {
+		t.Errorf("spring forward: %+v", trs[0])
+	}
+	if want := time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC); !trs[1].At.Equal(want) || trs[1].DST || trs[1].OffsetTo != -5*3600 {
+		t.Errorf("fall back: %+v", trs[1])
+	}
+}
+
+func TestMeetingsCalendarRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	meetings := []calendarMeeting{
+		{Meeting: Meeting{ID: 7, SessionName: "Onsite, Acme", SessionType: "in-person", SessionTime: time.Date(2026, 11, 3, 15, 0, 0, 0, time.UTC),
+			Location: "1 Main St", Organizer: "recruiter@acme.com", Company: "Acme", Notes: "Bring ID;\nask about team"}},
+		{Meeting: Meeting{ID: 8, SessionName: "Coffee chat", SessionType: "virtual", SessionTime: time.Date(2026, 7, 1, 13, 30, 0, 0, time.UTC)}},
+	}
+	for i := range meetings {
+		meetings[i].UID = meetingUID(meetings[i].ID, "")
+	}
+	var buf bytes.Buffer
+	opts := CalendarOptions{Location: loc, AlarmMinutes: 30, Now: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)}
+	if err := writeMeetingsICS(&buf, meetings, opts); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"BEGIN:VTIMEZONE\r\n", "TZID:America/New_York\r\n", "DTSTART;TZID=America/New_York:20261103T100000\r\n",
+		"UID:meeting-7@career-koala\r\n", "TRIGGER:-PT30M\r\n", "ORGANIZER:mailto:recruiter@acme.com\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed missing %q", want)
+		}
+	}
+
+	events, err := ParseCalendarEvents(strings.NewReader(out), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(meetings) {
+		t.Fatalf("got %d events", len(events))
+	}
+	for i, ev := range events {
+		m := meetings[i]
+		if ev.UID != m.UID || ev.SessionName != m.SessionName || ev.SessionType != m.SessionType || !ev.SessionTime.Equal(m.SessionTime) ||
+			ev.Location != m.Location || ev.Organizer != m.Organizer || ev.Company != m.Company || ev.Notes != m.Notes {
+			t.Errorf("event %d = %+v, want %+v", i, ev, m)
+		}
+	}
+}
+
+// Outlook style invite: Windows zone name with a yearly recurring VTIMEZONE.
+const outlookInvite = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n" +
+	"BEGIN:VTIMEZONE\r\nTZID:Pacific Standard Time\r\n" +
+	"BEGIN:STANDARD\r\nDTSTART:16010101T020000\r\nTZOFFSETFROM:-0700\r\nTZOFFSETTO:-0800\r\nRRULE:FREQ=YEARLY;BYDAY=1SU;BYMONTH=11\r\nEND:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\nDTSTART:16010101T020000\r\nTZOFFSETFROM:-0800\r\nTZOFFSETTO:-0700\r\nRRULE:FREQ=YEARLY;BYDAY=2SU;BYMONTH=3\r\nEND:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\nUID:040000008200E00074C5B7101A82E008\r\nSUMMARY:Technical interview\r\n" +
+	"DTSTART;TZID=Pacific Standard Time:20261020T090000\r\nLOCATION:https://zoom.us/j/123\r\n" +
+	"ORGANIZER;CN=Sam Lee:mailto:sam@example.com\r\nEND:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\nUID:winter\r\nSUMMARY:Coffee\r\nDTSTART;TZID=Pacific Standard Time:20261210T090000\r\nLOCATION:Blue Bottle\r\nSTATUS:CANCELLED\r\nEND:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseCalendarEventsOutlookZone(t *testing.T) {
+	events, err := ParseCalendarEvents(strings.NewReader(outlookInvite), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events", len(events))
+	}
+	interview, coffee := events[0], events[1]
+	if want := time.Date(2026, 10, 20, 16, 0, 0, 0, time.UTC); !interview.SessionTime.Equal(want) {
+		t.Errorf("interview at %s, want %s", interview.SessionTime, want)
+	}
+	if interview.SessionType != "virtual" || interview.Organizer != "Sam Lee" {
+		t.Errorf("interview = %+v", interview)
+	}
+	if want := time.Date(2026, 12, 10, 17, 0, 0, 0, time.UTC); !coffee.SessionTime.Equal(want) {
+		t.Errorf("coffee at %s, want %s", coffee.SessionTime, want)
+	}
+	if !coffee.Cancelled || coffee.SessionType != "in-person" {
+		t.Errorf("coffee = %+v", coffee)
+	}
+}
+
+func TestParseICSRejectsUnbalanced(t *testing.T) {
+	_, err := ParseCalendarEvents(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n"), nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+}
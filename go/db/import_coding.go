@@ -0,0 +1,339 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// codingCSVHeaders lists the accepted header names for each import field.
+var codingCSVHeaders = map[string][]string{
+	"number":     {"number", "leetcode_number", "leetcode", "lc", "#", "no", "problem_number", "id"},
+	"title":      {"title", "name", "problem", "question"},
+	"pattern":    {"pattern", "category", "topic", "tags"},
+	"link":       {"link", "url", "problem_link"},
+	"difficulty": {"difficulty", "level"},
+	"solved":     {"solved", "already_solved", "done", "completed"},
+	"notes":      {"notes", "note", "comments"},
+}
+
+type CodingImportOptions struct {
+	DryRun bool
+	// Mapping overrides header detection: import field -> CSV header.
+	Mapping map[string]string
+	// GoalType "daily" or "weekly" creates goals linked to the imported
+	// problems, PerGoal problems per day/week starting at GoalStart.
+	GoalType  string
+	GoalStart time.Time
+	PerGoal   int
+}
+
+type ImportRowError struct {
+	Row int    `json:"row"`
+	Err string `json:"error"`
+}
+
+type CodingImportResult struct {
+	DryRun     bool             `json:"dry_run"`
+	Rows       int              `json:"rows"`
+	Imported   int              `json:"imported"`
+	Duplicates []ImportRowError `json:"duplicates"`
+	Errors     []ImportRowError `json:"errors"`
+	ProblemIDs []int64          `json:"problem_ids"`
+	Goals      int              `json:"goals_created"`
+}
+
+type codingCSVRow struct {
+	Line    int
+	Problem CodingProblem
+}
+
+// ParseCodingCSV reads a problem list. Header names are matched
+// case-insensitively against known aliases (or opts.Mapping); rows that fail
+// validation are reported rather than aborting the parse.
+func ParseCodingCSV(r io.Reader, mapping map[string]string) ([]codingCSVRow, []ImportRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fieldErrorf("csv is empty")
+	}
+	if err != nil {
+		return nil, nil, fieldErrorf("csv header: %v", err)
+	}
+	columns, err := mapCodingColumns(header, mapping)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []codingCSVRow
+	var rowErrs []ImportRowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			rowErrs = append(rowErrs, ImportRowError{Row: parseErr.Line, Err: parseErr.Err.Error()})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(name string) string {
+			idx, ok := columns[name]
+			if !ok || idx >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[idx])
+		}
+		p, err := codingProblemFromCSV(field)
+		if err != nil {
+			rowErrs = append(rowErrs, ImportRowError{Row: line, Err: err.Error()})
+			continue
+		}
+		rows = append(rows, codingCSVRow{Line: line, Problem: p})
+	}
+	return rows, rowErrs, nil
+}
+
+func mapCodingColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	index := map[string]int{}
+	for i, h := range header {
+		key := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))
+		index[strings.ReplaceAll(key, " ", "_")] = i
+	}
+	columns := map[string]int{}
+	for field, headerName := range mapping {
+		if _, ok := codingCSVHeaders[field]; !ok {
+			return nil, fieldErrorf("unknown import field %q", field)
+		}
+		key := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(headerName)), " ", "_")
+		idx, ok := index[key]
+		if !ok {
+			return nil, fieldErrorf("csv has no column %q for %s", headerName, field)
+		}
+		columns[field] = idx
+	}
+	for field, aliases := range codingCSVHeaders {
+		if _, ok := columns[field]; ok {
+			continue
+		}
+		for _, alias := range aliases {
+			if idx, ok := index[alias]; ok {
+				columns[field] = idx
+				break
+			}
+		}
+	}
+	_, hasNumber := columns["number"]
+	_, hasTitle := columns["title"]
+	if !hasNumber && !hasTitle {
+		return nil, fieldErrorf("csv needs a number or title column (got %s)", strings.Join(header, ", "))
+	}
+	return columns, nil
+}
+
+func codingProblemFromCSV(field func(string) string) (CodingProblem, error) {
+	p := CodingProblem{
+		Title:       field("title"),
+		Pattern:     field("pattern"),
+		ProblemLink: field("link"),
+		Difficulty:  normalizeDifficulty(field("difficulty")),
+		Notes:       field("notes"),
+	}
+	if raw := strings.TrimPrefix(field("number"), "#"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			return p, fmt.Errorf("invalid number %q", raw)
+		}
+		p.LeetCodeNumber = n
+	}
+	if p.LeetCodeNumber == 0 && p.Title == "" {
+		return p, fmt.Errorf("number or title is required")
+	}
+	if raw := field("solved"); raw != "" {
+		solved, ok := parseCSVBool(raw)
+		if !ok {
+			return p, fmt.Errorf("invalid solved value %q", raw)
+		}
+		p.AlreadySolved = solved
+	}
+	return p, nil
+}
+
+func normalizeDifficulty(raw string) string {
+	switch strings.ToLower(raw) {
+	case "e", "easy":
+		return "Easy"
+	case "m", "med", "medium":
+		return "Medium"
+	case "h", "hard":
+		return "Hard"
+	}
+	return raw
+}
+
+func parseCSVBool(raw string) (bool, bool) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "1", "true", "t", "yes", "y", "x", "✓", "✔", "solved", "done":
+		return true, true
+	case "0", "false", "f", "no", "n", "", "-", "todo", "unsolved":
+		return false, true
+	}
+	return false, false
+}
+
+// ImportCodingProblems imports a CSV problem list in one transaction.
+// Problems whose leetcode_number already exists (in the database or earlier
+// in the file) are skipped as duplicates. With DryRun everything is rolled
+// back but the result still reports what would have happened.
+func ImportCodingProblems(ctx context.Context, dbConn *sql.DB, r io.Reader, opts CodingImportOptions) (CodingImportResult, error) {
+	res := CodingImportResult{DryRun: opts.DryRun, Duplicates: []ImportRowError{}, Errors: []ImportRowError{}, ProblemIDs: []int64{}}
+	goalType := strings.ToLower(strings.TrimSpace(opts.GoalType))
+	if goalType != "" && goalType != "daily" && goalType != "weekly" {
+		return res, fieldErrorf("goal type must be daily or weekly")
+	}
+	rows, rowErrs, err := ParseCodingCSV(r, opts.Mapping)
+	if err != nil {
+		return res, err
+	}
+	res.Rows = len(rows) + len(rowErrs)
+	res.Errors = append(res.Errors, rowErrs...)
+
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	seen, err := existingLeetCodeNumbers(ctx, tx)
+	if err != nil {
+		return res, err
+	}
+	var imported []CodingProblem
+	for _, row := range rows {
+		p := row.Problem
+		if p.LeetCodeNumber > 0 {
+			if seen[p.LeetCodeNumber] {
+				res.Duplicates = append(res.Duplicates, ImportRowError{Row: row.Line, Err: fmt.Sprintf("leetcode_number %d already exists", p.LeetCodeNumber)})
+				continue
+			}
+			seen[p.LeetCodeNumber] = true
+		}
+		id, err := InsertCodingProblem(ctx, tx, p)
+		if err != nil {
+			return res, fmt.Errorf("row %d: %w", row.Line, err)
+		}
+		p.ID = id
+		imported = append(imported, p)
+		res.ProblemIDs = append(res.ProblemIDs, id)
+	}
+	res.Imported = len(imported)
+
+	if goalType != "" {
+		goals := planImportGoals(goalType, opts.GoalStart, opts.PerGoal, imported)
+		for _, g := range goals {
+			if _, err := InsertGoal(ctx, tx, goalType, g); err != nil {
+				return res, err
+			}
+		}
+		res.Goals = len(goals)
+	}
+
+	if opts.DryRun {
+		// Ids allocated inside the rolled back transaction mean nothing.
+		res.ProblemIDs = []int64{}
+		return res, nil
+	}
+	return res, tx.Commit()
+}
+
+func existingLeetCodeNumbers(ctx context.Context, q DBTX) (map[int]bool, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx, `SELECT DISTINCT leetcode_number FROM coding_problems WHERE user_id = $1 AND leetcode_number IS NOT NULL`, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	seen := map[int]bool{}
+	for rows.Next() {
+		var n int
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		seen[n] = true
+	}
+	return seen, rows.Err()
+}
+
+// planImportGoals spreads the imported problems over daily or weekly goals,
+// perGoal problems each, one goal per problem linked via coding_problem_id.
+func planImportGoals(goalType string, start time.Time, perGoal int, problems []CodingProblem) []Goal {
+	if start.IsZero() {
+		start = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if goalType == "weekly" {
+		// week_of is the Monday of the week.
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+		if perGoal <= 0 {
+			perGoal = 5
+		}
+	} else if perGoal <= 0 {
+		perGoal = 1
+	}
+	goals := make([]Goal, 0, len(problems))
+	for i, p := range problems {
+		period := i / perGoal
+		target := start.AddDate(0, 0, period)
+		if goalType == "weekly" {
+			target = start.AddDate(0, 0, 7*period)
+		}
+		id := p.ID
+		goals = append(goals, Goal{
+			Description:   "Solve " + problemName(p),
+			TargetDate:    target,
+			CodingProblem: &id,
+		})
+	}
+	return goals
+}
+
+func problemName(p CodingProblem) string {
+	switch {
+	case p.LeetCodeNumber > 0 && p.Title != "":
+		return fmt.Sprintf("#%d %s", p.LeetCodeNumber, p.Title)
+	case p.LeetCodeNumber > 0:
+		return fmt.Sprintf("#%d", p.LeetCodeNumber)
+	default:
+		return p.Title
+	}
+}
+
+// ParseImportMapping parses "field=Header,field=Header" mapping overrides.
+func ParseImportMapping(raw string) (map[string]string, error) {
+	mapping := map[string]string{}
+	if strings.TrimSpace(raw) == "" {
+		return mapping, nil
+	}
+	for _, part := range strings.Split(raw, ",") {
+		field, header, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(field) == "" || strings.TrimSpace(header) == "" {
+			return nil, errors.New("mapping must look like field=Header,field=Header")
+		}
+		mapping[strings.ToLower(strings.TrimSpace(field))] = strings.TrimSpace(header)
+	}
+	return mapping, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCodingCSV(t *testing.T) {
+	input := "\ufeffLC #,Name,Topic,URL,Level,Done,Notes\n" +
+		"1,Two Sum,Arrays,https://leetcode.com/problems/two-sum,E,yes,\n" +
+		"abc,Broken,,,,,\n" +
+		",Untitled number,,,,,\n" +
+		"15,3Sum,Two Pointers,,medium,maybe,\n" +
+		",,,,,,\n"
+	rows, rowErrs, err := ParseCodingCSV(strings.NewReader(input), map[string]string{"number": "LC #"})
+	if err != nil {
+		t.Fatalf("ParseCodingCSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 valid rows, got %+v", rows)
+	}
+	first := rows[0].Problem
+	if first.LeetCodeNumber != 1 || first.Title != "Two Sum" || first.Difficulty != "Easy" || !first.AlreadySolved || first.Pattern != "Arrays" {
+		t.Fatalf("unexpected first row: %+v", first)
+	}
+	if rows[0].Line != 2 || rows[1].Line != 4 {
+		t.Fatalf("unexpected line numbers: %d, %d", rows[0].Line, rows[1].Line)
+	}
+	if len(rowErrs) != 3 || rowErrs[0].Row != 3 || rowErrs[1].Row != 5 || rowErrs[2].Row != 6 {
+		t.Fatalf("unexpected row errors: %+v", rowErrs)
+	}
+}
+
+func TestParseCodingCSVNeedsNumberOrTitle(t *testing.T) {
+	if _, _, err := ParseCodingCSV(strings.NewReader("pattern,difficulty\nDP,Hard\n"), nil); err == nil {
+		t.Fatalf("expected header error")
+	}
+	if _, _, err := ParseCodingCSV(strings.NewReader("title\nx\n"), map[string]string{"number": "missing"}); err == nil {
+		t.Fatalf("expected error for unknown mapped header")
+	}
+}
+
+func TestPlanImportGoals(t *testing.T) {
+	problems := []CodingProblem{{ID: 1, LeetCodeNumber: 1, Title: "Two Sum"}, {ID: 2, Title: "LRU"}, {ID: 3, LeetCodeNumber: 3}}
+	start := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC) // a Wednesday
+
+	daily := planImportGoals("daily", start, 2, problems)
+	if len(daily) != 3 || !daily[1].TargetDate.Equal(start) || !daily[2].TargetDate.Equal(start.AddDate(0, 0, 1)) {
+		t.Fatalf("unexpected daily plan: %+v", daily)
+	}
+	if daily[0].Description != "Solve #1 Two Sum" || *daily[1].CodingProblem != 2 {
+		t.Fatalf("unexpected goal: %+v", daily[0])
+	}
+
+	weekly := planImportGoals("weekly", start, 0, problems)
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	for _, g := range weekly {
+		if !g.TargetDate.Equal(monday) {
+			t.Fatalf("expected weekly goals on %v, got %v", monday, g.TargetDate)
+		}
+	}
+}
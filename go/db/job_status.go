@@ -0,0 +1,271 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Job application pipeline stages.
+const (
+	JobSaved           = "saved"
+	JobApplied         = "applied"
+	JobRecruiterScreen = "recruiter_screen"
+	JobTechnical       = "technical"
+	JobOnsite          = "onsite"
+	JobOffer           = "offer"
+	JobAccepted        = "accepted"
+	JobRejected        = "rejected"
+	JobWithdrawn       = "withdrawn"
+	JobGhosted         = "ghosted"
+)
+
+// JobStatuses lists the pipeline stages in order.
+var JobStatuses = []string{
+	JobSaved, JobApplied, JobRecruiterScreen, JobTechnical, JobOnsite,
+	JobOffer, JobAccepted, JobRejected, JobWithdrawn, JobGhosted,
+}
+
+// ErrInvalidTransition is returned when a status change is not allowed by the
+// pipeline.
+var ErrInvalidTransition = errors.New("invalid job status transition")
+
+// jobTransitions lists the statuses each stage may move to. Accepted,
+// rejected and withdrawn are final; a ghosted application can come back to
+// life if the company replies after all.
+var jobTransitions = map[string][]string{
+	JobSaved:           {JobApplied, JobWithdrawn},
+	JobApplied:         {JobRecruiterScreen, JobTechnical, JobOnsite, JobOffer, JobRejected, JobWithdrawn, JobGhosted},
+	JobRecruiterScreen: {JobTechnical, JobOnsite, JobOffer, JobRejected, JobWithdrawn, JobGhosted},
+	JobTechnical:       {JobOnsite, JobOffer, JobRejected, JobWithdrawn, JobGhosted},
+	JobOnsite:          {JobOffer, JobRejected, JobWithdrawn, JobGhosted},
+	JobOffer:           {JobAccepted, JobRejected, JobWithdrawn},
+	JobGhosted:         {JobRecruiterScreen, JobTechnical, JobOnsite, JobOffer, JobRejected, JobWithdrawn},
+	JobAccepted:        {},
+	JobRejected:        {},
+	JobWithdrawn:       {},
+}
+
+var jobStatusAliases = map[string]string{
+	"wishlist":            JobSaved,
+	"interested":          JobSaved,
+	"submitted":           JobApplied,
+	"pending":             JobApplied,
+	"screen":              JobRecruiterScreen,
+	"phone_screen":        JobRecruiterScreen,
+	"recruiter_call":      JobRecruiterScreen,
+	"interview":           JobTechnical,
+	"interviewing":        JobTechnical,
+	"technical_interview": JobTechnical,
+	"tech_screen":         JobTechnical,
+	"on_site":             JobOnsite,
+	"final_round":         JobOnsite,
+	"offered":             JobOffer,
+	"offer_received":      JobOffer,
+	"reject":              JobRejected,
+	"declined":            JobRejected,
+	"withdrew":            JobWithdrawn,
+	"no_response":         JobGhosted,
+}
+
+// NormalizeJobStatus maps free-text statuses ("Phone screen", "On-site") to a
+// pipeline stage. It reports false for statuses it does not recognise.
+func NormalizeJobStatus(status string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(status))
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	if _, ok := jobTransitions[key]; ok {
+		return key, true
+	}
+	if canonical, ok := jobStatusAliases[key]; ok {
+		return canonical, true
+	}
+	if strings.Contains(key, "reject") {
+		return JobRejected, true
+	}
+	return "", false
+}
+
+// CheckJobTransition reports whether a job may move from one status to
+// another. Unset or unrecognised legacy statuses may move anywhere, and
+// staying in the same stage is always allowed.
+func CheckJobTransition(from, to string) error {
+	next, ok := NormalizeJobStatus(to)
+	if !ok {
+		return unknownJobStatus(to)
+	}
+	to = next
+	from, ok = NormalizeJobStatus(from)
+	if !ok || from == to {
+		return nil
+	}
+	for _, next := range jobTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
+
+// jobSubmitted reports whether reaching status means the application was
+// sent, which stamps applied_date. Withdrawing a saved job doesn't, and an
+// application only gets ghosted or rejected once it was sent.
+func jobSubmitted(status string) bool {
+	switch status {
+	case JobApplied, JobRecruiterScreen, JobTechnical, JobOnsite, JobOffer, JobAccepted:
+		return true
+	}
+	return false
+}
+
+// jobOutcome reports whether reaching status means the company responded
+// with a result, which stamps result_date.
+func jobOutcome(status string) bool {
+	return status == JobOffer || status == JobAccepted || status == JobRejected
+}
+
+func unknownJobStatus(status string) error {
+	return fieldErrorf("unknown job status %q (use one of: %s)", status, strings.Join(JobStatuses, ", "))
+}
+
+// defaultJobStatus normalizes the status of a new application, defaulting to
+// applied or saved depending on whether it has an applied date.
+func defaultJobStatus(in JobApplication) (string, error) {
+	if strings.TrimSpace(in.Status) == "" {
+		if in.Applied != nil {
+			return JobApplied, nil
+		}
+		return JobSaved, nil
+	}
+	status, ok := NormalizeJobStatus(in.Status)
+	if !ok {
+		return "", unknownJobStatus(in.Status)
+	}
+	return status, nil
+}
+
+// JobStatusChange is one row of job_status_history.
+type JobStatusChange struct {
+	ID               int64     `json:"id"`
+	JobApplicationID int64     `json:"job_application_id"`
+	FromStatus       string    `json:"from_status"`
+	ToStatus         string    `json:"to_status"`
+	ChangedAt        time.Time `json:"changed_at"`
+}
+
+// UpdateJobStatus moves a job to a new pipeline stage. Illegal transitions
+// return ErrInvalidTransition; the change is recorded in job_status_history
+// by the job_applications trigger. The dates are stamped as in a generic
+// update of the status (see normalizeJobSet).
+func UpdateJobStatus(ctx context.Context, db DBTX, id int64, status string) error {
+	if strings.TrimSpace(status) == "" {
+		return fmt.Errorf("status is required")
+	}
+	to, ok := NormalizeJobStatus(status)
+	if !ok {
+		return unknownJobStatus(status)
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, db, func(tx DBTX) error {
+		var from string
+		err := tx.QueryRowContext(ctx, `SELECT COALESCE(status,'') FROM job_applications WHERE id=$1 AND user_id=$2 FOR UPDATE`, id, uid).Scan(&from)
+		if err != nil {
+			return err
+		}
+		if err := CheckJobTransition(from, to); err != nil {
+			return err
+		}
+		setSQL, args, err := buildSet(tableSpecs["job_applications"], map[string]interface{}{"status": to}, 1)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			fmt.Sprintf(`UPDATE job_applications SET %s WHERE id=$%d AND user_id=$%d`, setSQL, len(args)+1, len(args)+2),
+			append(args, id, uid)...,
+		)
+		return err
+	})
+}
+
+// ListJobStatusHistory returns the status transitions of a job, oldest first.
+func ListJobStatusHistory(ctx context.Context, db DBTX, jobID int64) ([]JobStatusChange, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, job_application_id, COALESCE(from_status,''), to_status, changed_at
+         FROM job_status_history WHERE job_application_id=$1 AND user_id=$2 ORDER BY changed_at, id`, jobID, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []JobStatusChange{}
+	for rows.Next() {
+		var c JobStatusChange
+		if err := rows.Scan(&c.ID, &c.JobApplicationID, &c.FromStatus, &c.ToStatus, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+// checkJobUpdate vets a generic update of a job row against the pipeline.
+func checkJobUpdate(row, set map[string]interface{}) error {
+	to, ok := set["status"].(string)
+	if !ok {
+		return nil
+	}
+	return CheckJobTransition(getString(row, "status"), to)
+}
+
+// normalizeJobSet canonicalizes a status in a generic SET map and stamps
+// applied_date when the application was sent and result_date when it
+// reaches an outcome, unless they are set already.
+func normalizeJobSet(set map[string]interface{}) error {
+	val, ok := set["status"]
+	if !ok {
+		return nil
+	}
+	raw, _ := val.(string)
+	if raw == "" {
+		return fieldErrorf("status cannot be cleared")
+	}
+	status, ok := NormalizeJobStatus(raw)
+	if !ok {
+		return unknownJobStatus(raw)
+	}
+	set["status"] = status
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if _, ok := set["applied_date"]; !ok && jobSubmitted(status) {
+		set["applied_date"] = ifNull{&today}
+	}
+	if _, ok := set["result_date"]; !ok && jobOutcome(status) {
+		set["result_date"] = ifNull{&today}
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, reusing the caller's transaction when db is
+// already one.
+func inTx(ctx context.Context, db DBTX, fn func(tx DBTX) error) error {
+	conn, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
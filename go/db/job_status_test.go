@@ -0,0 +1,137 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNormalizeJobStatus(t *testing.T) {
+	cases := map[string]string{
+		"Applied":          JobApplied,
+		"Phone screen":     JobRecruiterScreen,
+		"recruiter-screen": JobRecruiterScreen,
+		"On-site":          JobOnsite,
+		"Rejected by HM":   JobRejected,
+		"offered":          JobOffer,
+	}
+	for in, want := range cases {
+		got, ok := NormalizeJobStatus(in)
+		if !ok || got != want {
+			t.Fatalf("NormalizeJobStatus(%q) = %q, %v; want %q", in, got, ok, want)
+		}
+	}
+	if _, ok := NormalizeJobStatus("thinking about it"); ok {
+		t.Fatalf("expected unknown status to be rejected")
+	}
+}
+
+func TestCheckJobTransition(t *testing.T) {
+	allowed := [][2]string{
+		{"saved", "applied"},
+		{"applied", "Phone screen"},
+		{"technical", "offer"},
+		{"ghosted", "onsite"},
+		{"offer", "accepted"},
+		{"rejected", "rejected"},
+		{"", "onsite"},
+		{"waiting", "applied"},
+	}
+	for _, tc := range allowed {
+		if err := CheckJobTransition(tc[0], tc[1]); err != nil {
+			t.Fatalf("%s -> %s: unexpected error %v", tc[0], tc[1], err)
+		}
+	}
+	illegal := [][2]string{
+		{"rejected", "applied"},
+		{"accepted", "withdrawn"},
+		{"onsite", "recruiter_screen"},
+		{"saved", "offer"},
+	}
+	for _, tc := range illegal {
+		if err := CheckJobTransition(tc[0], tc[1]); !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("%s -> %s: expected ErrInvalidTransition, got %v", tc[0], tc[1], err)
+		}
+	}
+	var fieldErr *FieldError
+	if err := CheckJobTransition("applied", "maybe"); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *FieldError for unknown status, got %v", err)
+	}
+}
+
+func TestGenericJobUpdateChecksTransition(t *testing.T) {
+	spec := tableSpecs["job_applications"]
+	set, err := convertSet(spec, map[string]interface{}{"status": "Phone Screen"})
+	if err != nil {
+		t.Fatalf("convertSet: %v", err)
+	}
+	if set["status"] != JobRecruiterScreen {
+		t.Fatalf("expected normalized status, got %v", set["status"])
+	}
+	if err := spec.checkUpdate(map[string]interface{}{"status": "rejected"}, set); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition, got %v", err)
+	}
+	if _, err := convertSet(spec, map[string]interface{}{"status": nil}); err == nil {
+		t.Fatalf("expected error when clearing status")
+	}
+}
+
+// The status endpoint and generic updates (PATCH /jobs/{id}, chat write
+// requests) stamp the dates by the same rule.
+func TestJobStatusStampsDates(t *testing.T) {
+	spec := tableSpecs["job_applications"]
+	cases := []struct {
+		status string
+		want   string
+	}{
+		{"applied", "applied_date=COALESCE(applied_date, $1), status=$2"},
+		{"Phone screen", "applied_date=COALESCE(applied_date, $1), status=$2"},
+		{"offer", "applied_date=COALESCE(applied_date, $1), result_date=COALESCE(result_date, $2), status=$3"},
+		{"rejected", "result_date=COALESCE(result_date, $1), status=$2"},
+		{"withdrawn", "status=$1"},
+		{"ghosted", "status=$1"},
+		{"saved", "status=$1"},
+	}
+	for _, tc := range cases {
+		got, _, err := buildSet(spec, map[string]interface{}{"status": tc.status}, 1)
+		if err != nil || got != tc.want {
+			t.Errorf("status %q: SET %q, %v; want %q", tc.status, got, err, tc.want)
+		}
+	}
+	// An explicit date wins over the stamp.
+	got, args, err := buildSet(spec, map[string]interface{}{"status": "applied", "applied_date": "2026-10-01"}, 1)
+	if err != nil || got != "applied_date=$1, status=$2" || args[0].(*time.Time).Format("2006-01-02") != "2026-10-01" {
+		t.Fatalf("SET %q %v, %v", got, args, err)
+	}
+}
+
+func TestJobStatusPreviewShowsOnlyNewStamps(t *testing.T) {
+	spec := tableSpecs["job_applications"]
+	set, err := convertSet(spec, map[string]interface{}{"status": "technical"})
+	if err != nil {
+		t.Fatalf("convertSet: %v", err)
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	for _, tc := range []struct {
+		applied interface{}
+		want    []string
+	}{
+		{"2026-09-30", []string{"status"}},
+		{nil, []string{"applied_date", "status"}},
+	} {
+		row := map[string]interface{}{"id": json.Number("1"), "status": "saved", "applied_date": tc.applied}
+		var cols []string
+		for _, f := range rowChange(spec, "update", row, set).Fields {
+			cols = append(cols, f.Column)
+			if f.Column == "applied_date" && f.After != strconv.Quote(today) {
+				t.Fatalf("applied_date stamped %s, want %q", f.After, today)
+			}
+		}
+		if !reflect.DeepEqual(cols, tc.want) {
+			t.Fatalf("applied_date %v: changed %v, want %v", tc.applied, cols, tc.want)
+		}
+	}
+}
@@ -0,0 +1,413 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+// ListParams describes one page of a list query. Filters are keyed by the
+// filter names of the entity (see the *ListFilters docs in list endpoints);
+// Sort is a sort key, optionally prefixed with "-" for descending order.
+type ListParams struct {
+	Filters map[string]string
+	Sort    string
+	Limit   int
+	Cursor  string
+}
+
+// Page is one page of results. NextCursor is empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type filterOp int
+
+const (
+	filterEq filterOp = iota // case-insensitive for text
+	filterContains
+	filterGTE
+	filterLTE
+	filterBool
+	filterAny // value is an element of an array column
+)
+
+type filterDef struct {
+	Column string
+	Op     filterOp
+	Kind   columnKind
+}
+
+type sortDef struct {
+	// Expr must be non-null so keyset comparisons are well defined.
+	Expr string
+	Type string
+}
+
+type listSpec struct {
+	Select  string
+	Filters map[string]filterDef
+	Sorts   map[string]sortDef
+	// Where is an optional fixed condition (no placeholders).
+	Where string
+}
+
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+var idSort = sortDef{Expr: "id", Type: "bigint"}
+
+var jobListSpec = listSpec{
+	Select: jobSelect,
+	Filters: map[string]filterDef{
+		"status":       {Column: "status", Op: filterEq, Kind: kindText},
+		"company":      {Column: "company", Op: filterContains, Kind: kindText},
+		"title":        {Column: "job_title", Op: filterContains, Kind: kindText},
+		"applied_from": {Column: "applied_date", Op: filterGTE, Kind: kindDate},
+		"applied_to":   {Column: "applied_date", Op: filterLTE, Kind: kindDate},
+		"result_from":  {Column: "result_date", Op: filterGTE, Kind: kindDate},
+		"result_to":    {Column: "result_date", Op: filterLTE, Kind: kindDate},
+	},
+	Sorts: map[string]sortDef{
+		"id":           idSort,
+		"applied_date": {Expr: "COALESCE(applied_date, DATE '0001-01-01')", Type: "date"},
+		"result_date":  {Expr: "COALESCE(result_date, DATE '0001-01-01')", Type: "date"},
+		"company":      {Expr: "LOWER(COALESCE(company,''))", Type: "text"},
+		"status":       {Expr: "LOWER(COALESCE(status,''))", Type: "text"},
+		"job_title":    {Expr: "LOWER(job_title)", Type: "text"},
+	},
+}
+
+var codingListSpec = listSpec{
+	Select: codingSelect,
+	Filters: map[string]filterDef{
+		"difficulty":      {Column: "difficulty", Op: filterEq, Kind: kindText},
+		"pattern":         {Column: "pattern", Op: filterContains, Kind: kindText},
+		"title":           {Column: "title", Op: filterContains, Kind: kindText},
+		"solved":          {Column: "already_solved", Op: filterBool, Kind: kindBool},
+		"leetcode_number": {Column: "leetcode_number", Op: filterEq, Kind: kindInt},
+	},
+	Sorts: map[string]sortDef{
+		"id":              idSort,
+		"leetcode_number": {Expr: "COALESCE(leetcode_number, 0)", Type: "int"},
+		"title":           {Expr: "LOWER(COALESCE(title,''))", Type: "text"},
+		"difficulty":      {Expr: "LOWER(COALESCE(difficulty,''))", Type: "text"},
+		"pattern":         {Expr: "LOWER(COALESCE(pattern,''))", Type: "text"},
+	},
+}
+
+var projectListSpec = listSpec{
+	Select: projectSelect,
+	Filters: map[string]filterDef{
+		"active": {Column: "active", Op: filterBool, Kind: kindBool},
+		"name":   {Column: "name", Op: filterContains, Kind: kindText},
+		"tech":   {Column: "tech_stack", Op: filterAny, Kind: kindText},
+	},
+	Sorts: map[string]sortDef{
+		"id":   idSort,
+		"name": {Expr: "LOWER(name)", Type: "text"},
+	},
+}
+
+var contactListSpec = listSpec{
+	Select: contactSelect,
+	Filters: map[string]filterDef{
+		"company":            {Column: "company", Op: filterContains, Kind: kindText},
+		"name":               {Column: "person_name", Op: filterContains, Kind: kindText},
+		"linkedin_connected": {Column: "linkedin_connected", Op: filterBool, Kind: kindBool},
+	},
+	Sorts: map[string]sortDef{
+		"id":          idSort,
+		"person_name": {Expr: "LOWER(person_name)", Type: "text"},
+		"company":     {Expr: "LOWER(COALESCE(company,''))", Type: "text"},
+	},
+}
+
+var meetingListSpec = listSpec{
+	Select: meetingSelect,
+	Filters: map[string]filterDef{
+		"session_type":       {Column: "session_type", Op: filterEq, Kind: kindText},
+		"company":            {Column: "company", Op: filterContains, Kind: kindText},
+		"job_application_id": {Column: "job_application_id", Op: filterEq, Kind: kindRef},
+		"contact_id":         {Column: "contact_id", Op: filterEq, Kind: kindRef},
+		"from":               {Column: "session_time", Op: filterGTE, Kind: kindTimestamp},
+		"to":                 {Column: "session_time", Op: filterLTE, Kind: kindTimestamp},
+	},
+	Sorts: map[string]sortDef{
+		"id":           idSort,
+		"session_time": {Expr: "COALESCE(session_time, TIMESTAMPTZ '0001-01-01T00:00:00Z')", Type: "timestamptz"},
+	},
+}
+
+func goalListSpec(period string) listSpec {
+	return listSpec{
+		Select: goalSelect,
+		Where:  "period = '" + period + "'",
+		Filters: map[string]filterDef{
+			"completed":          {Column: "completed", Op: filterBool, Kind: kindBool},
+			"from":               {Column: "target_date", Op: filterGTE, Kind: kindDate},
+			"to":                 {Column: "target_date", Op: filterLTE, Kind: kindDate},
+			"job_application_id": {Column: "job_application_id", Op: filterEq, Kind: kindRef},
+			"coding_problem_id":  {Column: "coding_problem_id", Op: filterEq, Kind: kindRef},
+			"project_id":         {Column: "project_id", Op: filterEq, Kind: kindRef},
+			"contact_id":         {Column: "contact_id", Op: filterEq, Kind: kindRef},
+			"recurrence_id":      {Column: "recurrence_id", Op: filterEq, Kind: kindRef},
+		},
+		Sorts: map[string]sortDef{
+			"id":          idSort,
+			"target_date": {Expr: "target_date", Type: "date"},
+		},
+	}
+}
+
+func ListJobApplicationsPage(ctx context.Context, db DBTX, params ListParams) (Page[JobApplication], error) {
+	return listPage(ctx, db, jobListSpec, params, scanJobApplication)
+}
+
+func ListCodingProblemsPage(ctx context.Context, db DBTX, params ListParams) (Page[CodingProblem], error) {
+	return listPage(ctx, db, codingListSpec, params, scanCodingProblem)
+}
+
+func ListProjectsPage(ctx context.Context, db DBTX, params ListParams) (Page[Project], error) {
+	return listPage(ctx, db, projectListSpec, params, scanProject)
+}
+
+func ListNetworkingContactsPage(ctx context.Context, db DBTX, params ListParams) (Page[NetworkingContact], error) {
+	return listPage(ctx, db, contactListSpec, params, scanNetworkingContact)
+}
+
+func ListMeetingsPage(ctx context.Context, db DBTX, params ListParams) (Page[Meeting], error) {
+	page, err := listPage(ctx, db, meetingListSpec, params, scanMeeting)
+	if err != nil {
+		return page, err
+	}
+	return page, attachMeetingContacts(ctx, db, page.Items)
+}
+
+func ListGoalsPage(ctx context.Context, db DBTX, goalType string, params ListParams) (Page[Goal], error) {
+	period, err := goalPeriodOf(goalType)
+	if err != nil {
+		return Page[Goal]{}, err
+	}
+	return listPage(ctx, db, goalListSpec(period), params, scanGoal)
+}
+
+// keyedScanner appends the cursor columns (sort key, id) to every Scan call so
+// the entity scan functions can be reused for keyset pagination.
+type keyedScanner struct {
+	rows *sql.Rows
+	key  *string
+	id   *int64
+}
+
+func (k keyedScanner) Scan(dest ...any) error {
+	return k.rows.Scan(append(dest, k.key, k.id)...)
+}
+
+func listPage[T any](ctx context.Context, db DBTX, spec listSpec, params ListParams, scan func(rowScanner) (T, error)) (Page[T], error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	query, args, sortKey, err := buildListQuery(spec, params, uid)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	limit := pageSize(params.Limit)
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Page[T]{}, err
+	}
+	defer rows.Close()
+
+	page := Page[T]{Items: []T{}}
+	var lastKey string
+	var lastID int64
+	for rows.Next() {
+		var key string
+		var id int64
+		item, err := scan(keyedScanner{rows: rows, key: &key, id: &id})
+		if err != nil {
+			return Page[T]{}, err
+		}
+		if len(page.Items) == limit {
+			page.NextCursor = encodeCursor(pageCursor{Sort: sortKey, Value: lastKey, ID: lastID})
+			break
+		}
+		page.Items = append(page.Items, item)
+		lastKey, lastID = key, id
+	}
+	return page, rows.Err()
+}
+
+// buildListQuery builds the page query for the rows of userID.
+func buildListQuery(spec listSpec, params ListParams, userID int64) (string, []interface{}, string, error) {
+	sortKey := strings.TrimSpace(params.Sort)
+	if sortKey == "" {
+		sortKey = "-id"
+	}
+	desc := strings.HasPrefix(sortKey, "-")
+	sortDef, ok := spec.Sorts[strings.TrimPrefix(sortKey, "-")]
+	if !ok {
+		return "", nil, "", fieldErrorf("unsupported sort %q (use one of: %s)", sortKey, strings.Join(sortedSortKeys(spec), ", "))
+	}
+
+	var conds []string
+	var args []interface{}
+	if spec.Where != "" {
+		conds = append(conds, spec.Where)
+	}
+	names := make([]string, 0, len(params.Filters))
+	for name := range params.Filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		raw := strings.TrimSpace(params.Filters[name])
+		if raw == "" {
+			continue
+		}
+		def, ok := spec.Filters[name]
+		if !ok {
+			return "", nil, "", fieldErrorf("unsupported filter %q", name)
+		}
+		cond, arg, err := filterCondition(def, name, raw, len(args)+1)
+		if err != nil {
+			return "", nil, "", err
+		}
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+
+	if params.Cursor != "" {
+		cur, err := decodeCursor(params.Cursor)
+		if err != nil || cur.Sort != sortKey {
+			return "", nil, "", fieldErrorf("invalid cursor")
+		}
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		conds = append(conds, fmt.Sprintf("(%s, id) %s (CAST($%d AS %s), $%d)", sortDef.Expr, op, len(args)+1, sortDef.Type, len(args)+2))
+		args = append(args, cur.Value, cur.ID)
+	}
+	conds = append(conds, fmt.Sprintf("user_id = $%d", len(args)+1))
+	args = append(args, userID)
+
+	// The cursor columns are appended after the entity columns.
+	query := strings.Replace(spec.Select, " FROM ", fmt.Sprintf(", (%s)::text, id FROM ", sortDef.Expr), 1)
+	query += " WHERE " + strings.Join(conds, " AND ")
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT %d", sortDef.Expr, dir, dir, pageSize(params.Limit)+1)
+	return query, args, sortKey, nil
+}
+
+func filterCondition(def filterDef, name, raw string, n int) (string, interface{}, error) {
+	switch def.Op {
+	case filterContains:
+		return fmt.Sprintf("%s ILIKE $%d", def.Column, n), "%" + escapeLike(raw) + "%", nil
+	case filterAny:
+		return fmt.Sprintf("$%d = ANY(%s)", n, def.Column), raw, nil
+	case filterBool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", nil, fieldErrorf("%s: invalid boolean %q", name, raw)
+		}
+		return fmt.Sprintf("COALESCE(%s,false) = $%d", def.Column, n), b, nil
+	}
+
+	var arg interface{}
+	switch def.Kind {
+	case kindDate:
+		tm, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return "", nil, fieldErrorf("%s: invalid date %q (want YYYY-MM-DD)", name, raw)
+		}
+		arg = tm
+	case kindTimestamp:
+		tm := getTimestamp(map[string]interface{}{name: raw}, name)
+		if tm == nil {
+			if d, err := time.Parse("2006-01-02", raw); err == nil {
+				tm = &d
+			} else {
+				return "", nil, fieldErrorf("%s: invalid time %q (want RFC 3339 or YYYY-MM-DD)", name, raw)
+			}
+		}
+		arg = *tm
+	case kindInt, kindRef:
+		i, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return "", nil, fieldErrorf("%s: invalid integer %q", name, raw)
+		}
+		arg = i
+	default:
+		if def.Op == filterEq {
+			return fmt.Sprintf("LOWER(%s) = LOWER($%d)", def.Column, n), raw, nil
+		}
+		arg = raw
+	}
+	switch def.Op {
+	case filterGTE:
+		return fmt.Sprintf("%s >= $%d", def.Column, n), arg, nil
+	case filterLTE:
+		return fmt.Sprintf("%s <= $%d", def.Column, n), arg, nil
+	default:
+		return fmt.Sprintf("%s = $%d", def.Column, n), arg, nil
+	}
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+func sortedSortKeys(spec listSpec) []string {
+	keys := make([]string, 0, len(spec.Sorts))
+	for k := range spec.Sorts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(data, &c)
+	return c, err
+}
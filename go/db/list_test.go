@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuildListQueryFiltersAndSort(t *testing.T) {
+	query, args, sortKey, err := buildListQuery(jobListSpec, ListParams{
+		Filters: map[string]string{"status": "Applied", "company": "50%", "applied_from": "2026-01-01"},
+		Sort:    "-applied_date",
+		Limit:   10,
+	}, 1)
+	if err != nil {
+		t.Fatalf("buildListQuery: %v", err)
+	}
+	if sortKey != "-applied_date" {
+		t.Fatalf("unexpected sort key %q", sortKey)
+	}
+	for _, want := range []string{
+		"applied_date >= $1",
+		"company ILIKE $2",
+		"LOWER(status) = LOWER($3)",
+		"user_id = $4",
+		"ORDER BY COALESCE(applied_date, DATE '0001-01-01') DESC, id DESC LIMIT 11",
+	} {
+		if !strings.Contains(query, want) {
+			t.Fatalf("query missing %q:\n%s", want, query)
+		}
+	}
+	if len(args) != 4 || args[1] != `%50\%%` || args[3] != int64(1) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestBuildListQueryCursor(t *testing.T) {
+	cursor := encodeCursor(pageCursor{Sort: "name", Value: "koala", ID: 7})
+	query, args, _, err := buildListQuery(projectListSpec, ListParams{Sort: "name", Cursor: cursor}, 1)
+	if err != nil {
+		t.Fatalf("buildListQuery: %v", err)
+	}
+	if !strings.Contains(query, "(LOWER(name), id) > (CAST($1 AS text), $2)") {
+		t.Fatalf("unexpected keyset condition:\n%s", query)
+	}
+	if len(args) != 3 || args[0] != "koala" || args[1] != int64(7) {
+		t.Fatalf("unexpected args: %v", args)
+	}
+
+	// A cursor is only valid for the sort it was issued for.
+	if _, _, _, err := buildListQuery(projectListSpec, ListParams{Sort: "-id", Cursor: cursor}, 1); err == nil {
+		t.Fatalf("expected error for cursor with different sort")
+	}
+}
+
+func TestBuildListQueryRejectsBadInput(t *testing.T) {
+	cases := []ListParams{
+		{Sort: "salary"},
+		{Filters: map[string]string{"salary": "1"}},
+		{Filters: map[string]string{"solved": "maybe"}},
+		{Cursor: "not-a-cursor"},
+	}
+	for _, params := range cases {
+		_, _, _, err := buildListQuery(codingListSpec, params, 1)
+		var fieldErr *FieldError
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("params %+v: expected *FieldError, got %v", params, err)
+		}
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	if pageSize(0) != defaultPageSize || pageSize(1000) != maxPageSize || pageSize(5) != 5 {
+		t.Fatalf("unexpected page sizes")
+	}
+}
@@ -0,0 +1,393 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsProdID identifies the feed producer; meetings without an imported UID
+// get a UID derived from their id under icsUIDDomain.
+const (
+	icsProdID    = "-//career-koala//meetings//EN"
+	icsUIDDomain = "career-koala"
+)
+
+var ownMeetingUID = regexp.MustCompile(`^meeting-(\d+)@` + regexp.QuoteMeta(icsUIDDomain) + `$`)
+
+// Custom properties that carry meeting columns with no iCalendar equivalent,
+// so a feed can be imported back without losing them.
+const (
+	icsPropCompany     = "X-CAREER-KOALA-COMPANY"
+	icsPropSessionType = "X-CAREER-KOALA-SESSION-TYPE"
+	icsPropOrganizer   = "X-CAREER-KOALA-ORGANIZER"
+)
+
+type CalendarOptions struct {
+	// Location is the time zone events are written in; nil writes UTC.
+	Location *time.Location
+	// AlarmMinutes adds a VALARM that many minutes before each meeting.
+	AlarmMinutes int
+	// DurationMinutes is the assumed meeting length (default 60).
+	DurationMinutes int
+	Now             time.Time
+}
+
+type calendarMeeting struct {
+	Meeting
+	UID string
+}
+
+// meetingUID keeps imported meetings under their original UID and gives the
+// rest a stable one based on the row id.
+func meetingUID(id int64, icalUID string) string {
+	if icalUID != "" {
+		return icalUID
+	}
+	return fmt.Sprintf("meeting-%d@%s", id, icsUIDDomain)
+}
+
+// WriteMeetingsCalendar writes every scheduled meeting of the user in ctx as
+// an RFC 5545 calendar.
+func WriteMeetingsCalendar(ctx context.Context, db DBTX, w io.Writer, opts CalendarOptions) error {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, session_name, session_type, session_time, COALESCE(location,''), COALESCE(organizer,''),
+                COALESCE(company,''), COALESCE(notes,''), COALESCE(ical_uid,'')
+         FROM meetings WHERE user_id = $1 AND session_time IS NOT NULL ORDER BY session_time, id`, uid)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var meetings []calendarMeeting
+	for rows.Next() {
+		var m calendarMeeting
+		var icalUID string
+		if err := rows.Scan(&m.ID, &m.SessionName, &m.SessionType, &m.SessionTime, &m.Location, &m.Organizer, &m.Company, &m.Notes, &icalUID); err != nil {
+			return err
+		}
+		m.UID = meetingUID(m.ID, icalUID)
+		meetings = append(meetings, m)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return writeMeetingsICS(w, meetings, opts)
+}
+
+func writeMeetingsICS(w io.Writer, meetings []calendarMeeting, opts CalendarOptions) error {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	duration := time.Duration(opts.DurationMinutes) * time.Minute
+	if duration <= 0 {
+		duration = time.Hour
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	iw := newICSWriter(w)
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", icsProdID)
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.line("METHOD", "PUBLISH")
+	iw.text("X-WR-CALNAME", "Career Koala meetings")
+	if loc != time.UTC {
+		iw.text("X-WR-TIMEZONE", loc.String())
+		// Cover every year with an event so each DTSTART falls inside a
+		// described observance.
+		from, to := now, now
+		if len(meetings) > 0 {
+			from, to = meetings[0].SessionTime, meetings[len(meetings)-1].SessionTime.Add(duration)
+		}
+		iw.vtimezone(loc,
+			time.Date(from.In(loc).Year(), 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(to.In(loc).Year()+1, 1, 1, 0, 0, 0, 0, time.UTC))
+	}
+
+	stamp := now.UTC().Format(icsDateTimeUTC)
+	formatTime := func(name string, t time.Time) {
+		if loc == time.UTC {
+			iw.line(name, t.UTC().Format(icsDateTimeUTC))
+			return
+		}
+		iw.line(name+";TZID="+icsParamValue(loc.String()), t.In(loc).Format(icsDateTimeLocal))
+	}
+	for _, m := range meetings {
+		iw.line("BEGIN", "VEVENT")
+		iw.text("UID", m.UID)
+		iw.line("DTSTAMP", stamp)
+		formatTime("DTSTART", m.SessionTime)
+		formatTime("DTEND", m.SessionTime.Add(duration))
+		iw.text("SUMMARY", m.SessionName)
+		iw.text("LOCATION", m.Location)
+		iw.text("DESCRIPTION", m.Notes)
+		if strings.Contains(m.Organizer, "@") && !strings.ContainsAny(m.Organizer, " <>") {
+			iw.line("ORGANIZER", "mailto:"+m.Organizer)
+		}
+		if m.SessionType != "" {
+			iw.text("CATEGORIES", m.SessionType)
+		}
+		iw.text(icsPropSessionType, m.SessionType)
+		iw.text(icsPropCompany, m.Company)
+		iw.text(icsPropOrganizer, m.Organizer)
+		if opts.AlarmMinutes > 0 {
+			iw.line("BEGIN", "VALARM")
+			iw.line("ACTION", "DISPLAY")
+			iw.text("DESCRIPTION", "Reminder: "+m.SessionName)
+			iw.line("TRIGGER", fmt.Sprintf("-PT%dM", opts.AlarmMinutes))
+			iw.line("END", "VALARM")
+		}
+		iw.line("END", "VEVENT")
+	}
+	iw.line("END", "VCALENDAR")
+	return iw.flush()
+}
+
+// CalendarEvent is a VEVENT read from an imported calendar, already mapped
+// to meeting columns.
+type CalendarEvent struct {
+	UID         string    `json:"uid"`
+	SessionName string    `json:"session_name"`
+	SessionType string    `json:"session_type"`
+	SessionTime time.Time `json:"session_time"`
+	Location    string    `json:"location"`
+	Organizer   string    `json:"organizer"`
+	Company     string    `json:"company"`
+	Notes       string    `json:"notes"`
+	Cancelled   bool      `json:"cancelled"`
+}
+
+// ParseCalendarEvents reads the VEVENTs of an .ics file. Floating times and
+// all-day events are interpreted in loc (UTC if nil).
+func ParseCalendarEvents(r io.Reader, loc *time.Location) ([]CalendarEvent, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	root, err := parseICS(r)
+	if err != nil {
+		return nil, err
+	}
+	zones := map[string]icsZone{}
+	root.walk("VTIMEZONE", func(c *icsComponent) {
+		zones[strings.TrimPrefix(c.text("TZID"), "/")] = parseICSZone(c)
+	})
+
+	var events []CalendarEvent
+	var parseErr error
+	root.walk("VEVENT", func(c *icsComponent) {
+		if parseErr != nil {
+			return
+		}
+		ev, err := calendarEventFrom(c, zones, loc)
+		if err != nil {
+			parseErr = err
+			return
+		}
+		events = append(events, ev)
+	})
+	return events, parseErr
+}
+
+func calendarEventFrom(c *icsComponent, zones map[string]icsZone, loc *time.Location) (CalendarEvent, error) {
+	ev := CalendarEvent{
+		UID:         c.text("UID"),
+		SessionName: c.text("SUMMARY"),
+		Location:    c.text("LOCATION"),
+		Notes:       c.text("DESCRIPTION"),
+		Company:     c.text(icsPropCompany),
+		Organizer:   c.text(icsPropOrganizer),
+		Cancelled:   strings.EqualFold(c.text("STATUS"), "CANCELLED"),
+	}
+	if ev.UID == "" {
+		return ev, fieldErrorf("event %q has no UID", ev.SessionName)
+	}
+	start, ok := c.prop("DTSTART")
+	if !ok {
+		return ev, fieldErrorf("event %s has no DTSTART", ev.UID)
+	}
+	t, _, err := parseICSTime(start, zones, loc)
+	if err != nil {
+		return ev, fieldErrorf("event %s: DTSTART: %v", ev.UID, err)
+	}
+	ev.SessionTime = t.UTC()
+	if ev.SessionName == "" {
+		ev.SessionName = "Untitled event"
+	}
+	if ev.Organizer == "" {
+		if org, ok := c.prop("ORGANIZER"); ok {
+			ev.Organizer = org.param("CN")
+			if ev.Organizer == "" {
+				ev.Organizer = strings.TrimPrefix(strings.TrimPrefix(org.Value, "mailto:"), "MAILTO:")
+			}
+		}
+	}
+	ev.SessionType = c.text(icsPropSessionType)
+	if ev.SessionType != "virtual" && ev.SessionType != "in-person" {
+		ev.SessionType = guessSessionType(ev.Location, ev.Notes, c.text("URL"))
+	}
+	return ev, nil
+}
+
+var virtualMeetingHints = []string{"zoom.us", "meet.google", "teams.microsoft", "teams.live", "webex", "whereby", "http://", "https://", "virtual", "online", "video call", "phone"}
+
+// guessSessionType treats events without a physical location, or with a
+// video link, as virtual.
+func guessSessionType(location, description, url string) string {
+	if strings.TrimSpace(location) == "" {
+		return "virtual"
+	}
+	text := strings.ToLower(location + " " + description + " " + url)
+	for _, hint := range virtualMeetingHints {
+		if strings.Contains(text, hint) {
+			return "virtual"
+		}
+	}
+	return "in-person"
+}
+
+type CalendarImportOptions struct {
+	DryRun bool
+	// Location interprets floating times and all-day events.
+	Location *time.Location
+	// Keywords, when set, limits the import to events whose summary or
+	// description mentions one of them (case-insensitive).
+	Keywords []string
+}
+
+type CalendarImportSkip struct {
+	UID     string `json:"uid"`
+	Summary string `json:"summary"`
+	Reason  string `json:"reason"`
+}
+
+type CalendarImportResult struct {
+	DryRun     bool                 `json:"dry_run"`
+	Events     int                  `json:"events"`
+	Created    int                  `json:"created"`
+	Updated    int                  `json:"updated"`
+	Skipped    []CalendarImportSkip `json:"skipped"`
+	MeetingIDs []int64              `json:"meeting_ids"`
+}
+
+// ImportMeetingsCalendar upserts the events of an .ics file into meetings.
+// An event matches an existing meeting by its UID: either one recorded by an
+// earlier import or one this server generated in its own feed. Cancelled
+// events and events filtered out by Keywords are skipped.
+func ImportMeetingsCalendar(ctx context.Context, dbConn *sql.DB, r io.Reader, opts CalendarImportOptions) (CalendarImportResult, error) {
+	res := CalendarImportResult{DryRun: opts.DryRun, Skipped: []CalendarImportSkip{}, MeetingIDs: []int64{}}
+	events, err := ParseCalendarEvents(r, opts.Location)
+	if err != nil {
+		return res, err
+	}
+	res.Events = len(events)
+	userID, err := currentUser(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return res, err
+	}
+	defer tx.Rollback()
+
+	for _, ev := range events {
+		skip := func(reason string) {
+			res.Skipped = append(res.Skipped, CalendarImportSkip{UID: ev.UID, Summary: ev.SessionName, Reason: reason})
+		}
+		if ev.Cancelled {
+			skip("cancelled")
+			continue
+		}
+		if !matchesKeywords(ev, opts.Keywords) {
+			skip("no keyword match")
+			continue
+		}
+		id, found, err := findCalendarMeeting(ctx, tx, userID, ev.UID)
+		if err != nil {
+			return res, err
+		}
+		if found {
+			_, err = tx.ExecContext(ctx,
+				`UPDATE meetings SET session_name=$2, session_type=$3, session_time=$4, location=$5,
+                        organizer=COALESCE(NULLIF($6,''), organizer), company=COALESCE(NULLIF($7,''), company),
+                        notes=COALESCE(NULLIF($8,''), notes)
+                 WHERE id=$1 AND user_id=$9`,
+				id, ev.SessionName, ev.SessionType, ev.SessionTime, ev.Location, ev.Organizer, ev.Company, ev.Notes, userID)
+			if err != nil {
+				return res, fmt.Errorf("update meeting %d: %w", id, err)
+			}
+			res.Updated++
+		} else {
+			var icalUID interface{} = ev.UID
+			if ownMeetingUID.MatchString(ev.UID) {
+				// A feed of a meeting that no longer exists; don't claim
+				// the generated UID for a new row.
+				icalUID = nil
+			}
+			err = tx.QueryRowContext(ctx,
+				`INSERT INTO meetings (session_name, session_type, session_time, location, organizer, company, notes, ical_uid, user_id)
+                 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING id`,
+				ev.SessionName, ev.SessionType, ev.SessionTime, ev.Location, ev.Organizer, ev.Company, ev.Notes, icalUID, userID,
+			).Scan(&id)
+			if err != nil {
+				return res, fmt.Errorf("insert %s: %w", ev.UID, err)
+			}
+			res.Created++
+		}
+		res.MeetingIDs = append(res.MeetingIDs, id)
+	}
+
+	if opts.DryRun {
+		res.MeetingIDs = []int64{}
+		return res, nil
+	}
+	return res, tx.Commit()
+}
+
+func findCalendarMeeting(ctx context.Context, q DBTX, userID int64, uid string) (int64, bool, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, `SELECT id FROM meetings WHERE ical_uid=$1 AND user_id=$2`, uid, userID).Scan(&id)
+	if err == nil {
+		return id, true, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, err
+	}
+	m := ownMeetingUID.FindStringSubmatch(uid)
+	if m == nil {
+		return 0, false, nil
+	}
+	own, _ := strconv.ParseInt(m[1], 10, 64)
+	err = q.QueryRowContext(ctx, `SELECT id FROM meetings WHERE id=$1 AND ical_uid IS NULL AND user_id=$2`, own, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return id, err == nil, err
+}
+
+func matchesKeywords(ev CalendarEvent, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	text := strings.ToLower(ev.SessionName + " " + ev.Notes)
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" && strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
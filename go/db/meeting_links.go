@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SetMeetingContacts replaces the attendees of a meeting.
+func SetMeetingContacts(ctx context.Context, db DBTX, meetingID int64, contactIDs []int64) error {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	return inTx(ctx, db, func(tx DBTX) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM meeting_contacts WHERE meeting_id=$1 AND user_id=$2`, meetingID, uid); err != nil {
+			return err
+		}
+		for _, contactID := range contactIDs {
+			if err := AddMeetingContact(ctx, tx, meetingID, contactID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddMeetingContact records one attendee; adding the same person twice is a
+// no-op. The meeting and contact must both belong to the user in ctx.
+func AddMeetingContact(ctx context.Context, db DBTX, meetingID, contactID int64) error {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx,
+		`INSERT INTO meeting_contacts (meeting_id, contact_id, user_id) VALUES ($1,$2,$3) ON CONFLICT (meeting_id, contact_id) DO NOTHING`,
+		meetingID, contactID, uid)
+	return linkError(err)
+}
+
+// attachMeetingContacts fills ContactIDs for a batch of meetings.
+func attachMeetingContacts(ctx context.Context, db DBTX, meetings []Meeting) error {
+	if len(meetings) == 0 {
+		return nil
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	index := make(map[int64]int, len(meetings))
+	ids := make([]int64, 0, len(meetings))
+	for i, m := range meetings {
+		index[m.ID] = i
+		ids = append(ids, m.ID)
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT meeting_id, contact_id FROM meeting_contacts WHERE meeting_id = ANY($1::int[]) AND user_id = $2 ORDER BY meeting_id, contact_id`,
+		pqIntArray(ids), uid)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var meetingID, contactID int64
+		if err := rows.Scan(&meetingID, &contactID); err != nil {
+			return err
+		}
+		if i, ok := index[meetingID]; ok {
+			meetings[i].ContactIDs = append(meetings[i].ContactIDs, contactID)
+		}
+	}
+	return rows.Err()
+}
+
+func listMeetingsByID(ctx context.Context, db DBTX, ids []int64) (map[int64]Meeting, error) {
+	res := map[int64]Meeting{}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx, meetingSelect+` WHERE id = ANY($1::int[]) AND user_id = $2`, pqIntArray(ids), uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var meetings []Meeting
+	for rows.Next() {
+		m, err := scanMeeting(rows)
+		if err != nil {
+			return nil, err
+		}
+		meetings = append(meetings, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := attachMeetingContacts(ctx, db, meetings); err != nil {
+		return nil, err
+	}
+	for _, m := range meetings {
+		res[m.ID] = m
+	}
+	return res, nil
+}
+
+// pqIntArray formats ids as a Postgres array literal.
+func pqIntArray(ids []int64) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.FormatInt(id, 10))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// intSlice converts a decoded JSON array of ids.
+func intSlice(raw interface{}) ([]int64, error) {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an array of ids")
+	}
+	ids := make([]int64, 0, len(items))
+	for i := range items {
+		id := getIntPtr(map[string]interface{}{"id": items[i]}, "id")
+		if id == nil {
+			return nil, fmt.Errorf("invalid id %v", items[i])
+		}
+		ids = append(ids, *id)
+	}
+	return ids, nil
+}
+
+// Interview is a meeting linked to a job application.
+type Interview struct {
+	Meeting
+	JobTitle  string `json:"job_title"`
+	JobStatus string `json:"job_status"`
+}
+
+// ListUpcomingInterviews returns meetings linked to job applications that
+// start at or after from, soonest first. jobID 0 covers every application.
+func ListUpcomingInterviews(ctx context.Context, db DBTX, jobID int64, from time.Time, limit int) ([]Interview, error) {
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT m.id, j.job_title, COALESCE(j.status,'')
+         FROM meetings m JOIN job_applications j ON j.id = m.job_application_id
+         WHERE m.user_id = $4 AND m.session_time >= $1 AND ($2 = 0 OR j.id = $2)
+         ORDER BY m.session_time, m.id LIMIT $3`, from, jobID, limit, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []Interview{}
+	var ids []int64
+	for rows.Next() {
+		var iv Interview
+		if err := rows.Scan(&iv.ID, &iv.JobTitle, &iv.JobStatus); err != nil {
+			return nil, err
+		}
+		ids = append(ids, iv.ID)
+		res = append(res, iv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	meetings, err := listMeetingsByID(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range res {
+		res[i].Meeting = meetings[res[i].ID]
+	}
+	return res, nil
+}
+
+// ContactMeetings summarises the meetings with one contact, counting both
+// meetings where they are the main contact and ones they attended.
+type ContactMeetings struct {
+	Contact      NetworkingContact `json:"contact"`
+	MeetingCount int               `json:"meeting_count"`
+	LastMeeting  *Meeting          `json:"last_meeting"`
+	NextMeeting  *Meeting          `json:"next_meeting"`
+}
+
+// ListContactMeetings returns contacts with their last meeting before asOf
+// and next one after it, the longest unseen first. contactID or name (a
+// case-insensitive substring) narrow the result.
+func ListContactMeetings(ctx context.Context, db DBTX, contactID int64, name string, asOf time.Time, limit int) ([]ContactMeetings, error) {
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`WITH met AS (
+             SELECT contact_id, id AS meeting_id, session_time FROM meetings WHERE user_id = $5 AND contact_id IS NOT NULL AND session_time IS NOT NULL
+             UNION
+             SELECT mc.contact_id, m.id, m.session_time FROM meeting_contacts mc JOIN meetings m ON m.id = mc.meeting_id WHERE m.user_id = $5 AND m.session_time IS NOT NULL
+         )
+         SELECT c.id, c.person_name, COALESCE(c.how_met,''), COALESCE(c.linkedin_connected,false), COALESCE(c.company,''), COALESCE(c.position,''), COALESCE(c.notes,''),
+                (SELECT count(*) FROM met WHERE met.contact_id = c.id AND met.session_time <= $1),
+                last.meeting_id, next.meeting_id
+         FROM networking_contacts c
+         LEFT JOIN LATERAL (SELECT meeting_id, session_time FROM met WHERE met.contact_id = c.id AND met.session_time <= $1
+                            ORDER BY session_time DESC LIMIT 1) last ON true
+         LEFT JOIN LATERAL (SELECT meeting_id FROM met WHERE met.contact_id = c.id AND met.session_time > $1
+                            ORDER BY session_time LIMIT 1) next ON true
+         WHERE c.user_id = $5 AND ($2 = 0 OR c.id = $2) AND ($3 = '' OR c.person_name ILIKE '%' || $3 || '%')
+         ORDER BY last.session_time NULLS FIRST, c.id
+         LIMIT $4`, asOf, contactID, escapeLike(strings.TrimSpace(name)), limit, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []ContactMeetings{}
+	var lastIDs, nextIDs []*int64
+	var ids []int64
+	for rows.Next() {
+		var cm ContactMeetings
+		var last, next *int64
+		c := &cm.Contact
+		if err := rows.Scan(&c.ID, &c.PersonName, &c.HowMet, &c.LinkedInConnected, &c.Company, &c.Position, &c.Notes, &cm.MeetingCount, &last, &next); err != nil {
+			return nil, err
+		}
+		for _, id := range []*int64{last, next} {
+			if id != nil {
+				ids = append(ids, *id)
+			}
+		}
+		lastIDs, nextIDs = append(lastIDs, last), append(nextIDs, next)
+		res = append(res, cm)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	meetings, err := listMeetingsByID(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	pick := func(id *int64) *Meeting {
+		if id == nil {
+			return nil
+		}
+		m := meetings[*id]
+		return &m
+	}
+	for i := range res {
+		res[i].LastMeeting = pick(lastIDs[i])
+		res[i].NextMeeting = pick(nextIDs[i])
+	}
+	return res, nil
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIntSlice(t *testing.T) {
+	ids, err := intSlice([]interface{}{json.Number("3"), float64(5), "7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{3, 5, 7}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	for _, bad := range []interface{}{"1,2", []interface{}{"x"}, []interface{}{true}} {
+		if _, err := intSlice(bad); err == nil {
+			t.Errorf("intSlice(%#v): expected error", bad)
+		}
+	}
+}
+
+func TestMeetingLinksAreWritable(t *testing.T) {
+	spec, err := lookupTableSpec("meetings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, col := range []string{"job_application_id", "contact_id"} {
+		if spec.Columns[col] != kindRef {
+			t.Errorf("meetings.%s should be a ref column", col)
+		}
+	}
+	failures := validateWriteRequests(WritePayload{WriteRequests: []WriteRequest{
+		{Action: "insert", Table: "meeting_contacts"},
+		{Action: "delete", Table: "meeting_contacts"},
+	}})
+	if len(failures) != 0 {
+		t.Fatalf("unexpected failures: %v", failures)
+	}
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"sort"
+)
+
+// MeetingPrep gathers what is worth reviewing before a meeting: the linked
+// application and its pipeline history, notes on the people attending, and
+// earlier meetings with the same application, people or company.
+type MeetingPrep struct {
+	Meeting          Meeting             `json:"meeting"`
+	Job              *JobApplication     `json:"job_application"`
+	JobHistory       []JobStatusChange   `json:"job_history"`
+	Contacts         []NetworkingContact `json:"contacts"`
+	PreviousMeetings []Meeting           `json:"previous_meetings"`
+}
+
+const prepPreviousMeetings = 5
+
+func GetMeetingPrep(ctx context.Context, db DBTX, meetingID int64) (MeetingPrep, error) {
+	prep := MeetingPrep{JobHistory: []JobStatusChange{}, Contacts: []NetworkingContact{}, PreviousMeetings: []Meeting{}}
+	m, err := GetMeeting(ctx, db, meetingID)
+	if err != nil {
+		return prep, err
+	}
+	prep.Meeting = m
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return prep, err
+	}
+
+	company := m.Company
+	if m.JobApplication != nil {
+		job, err := GetJobApplication(ctx, db, *m.JobApplication)
+		if err != nil {
+			return prep, err
+		}
+		prep.Job = &job
+		if company == "" {
+			company = job.Company
+		}
+		if prep.JobHistory, err = ListJobStatusHistory(ctx, db, job.ID); err != nil {
+			return prep, err
+		}
+	}
+
+	contactIDs := append([]int64{}, m.ContactIDs...)
+	if m.Contact != nil {
+		contactIDs = append([]int64{*m.Contact}, contactIDs...)
+	}
+	if len(contactIDs) > 0 {
+		rows, err := db.QueryContext(ctx, contactSelect+` WHERE id = ANY($1::int[]) AND user_id = $2 ORDER BY id`, pqIntArray(contactIDs), uid)
+		if err != nil {
+			return prep, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			c, err := scanNetworkingContact(rows)
+			if err != nil {
+				return prep, err
+			}
+			prep.Contacts = append(prep.Contacts, c)
+		}
+		if err := rows.Err(); err != nil {
+			return prep, err
+		}
+	}
+
+	rows, err := db.QueryContext(ctx,
+		`SELECT m.id FROM meetings m
+         WHERE m.user_id = $7 AND m.id <> $1 AND m.session_time < $2 AND (
+               ($3::int IS NOT NULL AND m.job_application_id = $3)
+            OR ($4 <> '' AND LOWER(m.company) = LOWER($4))
+            OR m.contact_id = ANY($5::int[])
+            OR EXISTS (SELECT 1 FROM meeting_contacts mc WHERE mc.meeting_id = m.id AND mc.contact_id = ANY($5::int[])))
+         ORDER BY m.session_time DESC LIMIT $6`,
+		m.ID, m.SessionTime, m.JobApplication, company, pqIntArray(contactIDs), prepPreviousMeetings, uid)
+	if err != nil {
+		return prep, err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return prep, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return prep, err
+	}
+	previous, err := listMeetingsByID(ctx, db, ids)
+	if err != nil {
+		return prep, err
+	}
+	for _, id := range ids {
+		prep.PreviousMeetings = append(prep.PreviousMeetings, previous[id])
+	}
+	sort.SliceStable(prep.PreviousMeetings, func(i, j int) bool {
+		return prep.PreviousMeetings[i].SessionTime.After(prep.PreviousMeetings[j].SessionTime)
+	})
+	return prep, nil
+}
@@ -0,0 +1,350 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Notification kinds recorded by the scheduled tasks.
+const (
+	NotifyGoalsRolledOver   = "goals_rolled_over"
+	NotifyStaleApplication  = "stale_application"
+	NotifyStaleContact      = "stale_contact"
+	NotifyUpcomingMeeting   = "upcoming_meeting"
+	NotifyDailyDigest       = "daily_digest"
+	notificationSelect      = `SELECT id, kind, title, body, job_application_id, contact_id, meeting_id, created_at, read_at, delivered_at, array_to_string(delivered_via, ',') FROM notifications`
+	defaultNotificationPage = 50
+)
+
+// Notification is a reminder or nudge. Key identifies what it is about (the
+// stale stretch, meeting or day), so a task that runs again doesn't repeat
+// it.
+type Notification struct {
+	ID             int64      `json:"id"`
+	Kind           string     `json:"kind"`
+	Title          string     `json:"title"`
+	Body           string     `json:"body"`
+	Key            string     `json:"-"`
+	JobApplication *int64     `json:"job_application_id,omitempty"`
+	Contact        *int64     `json:"contact_id,omitempty"`
+	Meeting        *int64     `json:"meeting_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	// DeliveredAt is when it had been sent on every channel (email,
+	// webhook); DeliveredVia lists the channels it went out on so far.
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty"`
+	DeliveredVia []string   `json:"delivered_via,omitempty"`
+}
+
+func scanNotification(row rowScanner) (Notification, error) {
+	var n Notification
+	var via string
+	err := row.Scan(&n.ID, &n.Kind, &n.Title, &n.Body, &n.JobApplication, &n.Contact, &n.Meeting, &n.CreatedAt, &n.ReadAt, &n.DeliveredAt, &via)
+	if via != "" {
+		n.DeliveredVia = strings.Split(via, ",")
+	}
+	return n, err
+}
+
+// InsertNotifications records notifications whose key is new and returns
+// them with their ids; ones already recorded are skipped.
+func InsertNotifications(ctx context.Context, db DBTX, in []Notification) ([]Notification, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var created []Notification
+	for _, n := range in {
+		row := db.QueryRowContext(ctx,
+			`INSERT INTO notifications (kind, title, body, key, job_application_id, contact_id, meeting_id, delivered_at, delivered_via, user_id)
+             VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+             ON CONFLICT (user_id, key) DO NOTHING
+             RETURNING id, created_at`,
+			n.Kind, n.Title, n.Body, n.Key, n.JobApplication, n.Contact, n.Meeting, n.DeliveredAt, pqStringArray(n.DeliveredVia), uid)
+		switch err := row.Scan(&n.ID, &n.CreatedAt); {
+		case err == nil:
+			created = append(created, n)
+		case errors.Is(err, sql.ErrNoRows):
+		default:
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// ListNotifications returns the newest notifications first, optionally only
+// unread ones, plus the total unread count.
+func ListNotifications(ctx context.Context, db DBTX, unreadOnly bool, limit int) ([]Notification, int, error) {
+	if limit <= 0 || limit > 200 {
+		limit = defaultNotificationPage
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.QueryContext(ctx,
+		notificationSelect+` WHERE user_id = $3 AND (read_at IS NULL OR NOT $1) ORDER BY created_at DESC, id DESC LIMIT $2`, unreadOnly, limit, uid)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	res := []Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		res = append(res, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	var unread int
+	err = db.QueryRowContext(ctx, `SELECT count(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, uid).Scan(&unread)
+	return res, unread, err
+}
+
+// MarkNotificationsRead marks the given notifications (all unread ones when
+// ids is empty) as read and returns how many changed.
+func MarkNotificationsRead(ctx context.Context, db DBTX, ids []int64) (int64, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	res, err := db.ExecContext(ctx,
+		`UPDATE notifications SET read_at = now() WHERE user_id = $2 AND read_at IS NULL AND (cardinality($1::int[]) = 0 OR id = ANY($1::int[]))`,
+		pqIntArray(ids), uid)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// GetNotificationByKey returns the notification recorded with key, or
+// sql.ErrNoRows.
+func GetNotificationByKey(ctx context.Context, db DBTX, key string) (Notification, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return Notification{}, err
+	}
+	return scanNotification(db.QueryRowContext(ctx, notificationSelect+` WHERE key = $1 AND user_id = $2`, key, uid))
+}
+
+// ListUndeliveredNotifications returns notifications of the given kinds
+// created since and not sent yet, oldest first.
+func ListUndeliveredNotifications(ctx context.Context, db DBTX, kinds []string, since time.Time) ([]Notification, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		notificationSelect+` WHERE user_id = $3 AND delivered_at IS NULL AND kind = ANY($1) AND created_at >= $2 ORDER BY created_at, id`,
+		pqStringArray(kinds), since, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []Notification{}
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, n)
+	}
+	return res, rows.Err()
+}
+
+// RecordNotificationDelivery saves the channels a notification has gone
+// out on, and marks it delivered once that is all of them.
+func RecordNotificationDelivery(ctx context.Context, db DBTX, id int64, via []string, delivered bool) error {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx,
+		`UPDATE notifications SET delivered_via = $1, delivered_at = CASE WHEN $2 THEN now() END WHERE id = $3 AND user_id = $4`,
+		pqStringArray(via), delivered, id, uid)
+	return err
+}
+
+// NotifyGoalRollover rolls unfinished one-off daily goals over to asOf and
+// records one notification per day that had any.
+func NotifyGoalRollover(ctx context.Context, db DBTX, asOf time.Time) ([]Notification, error) {
+	moved, err := RollOverDailyGoals(ctx, db, asOf)
+	if err != nil || moved == 0 {
+		return nil, err
+	}
+	day := asOf.Format("2006-01-02")
+	return InsertNotifications(ctx, db, []Notification{{
+		Kind:  NotifyGoalsRolledOver,
+		Title: fmt.Sprintf("%d unfinished daily %s moved to today", moved, pluralize(int(moved), "goal")),
+		Key:   NotifyGoalsRolledOver + ":" + day,
+	}})
+}
+
+// NotifyStaleApplications flags open applications with no status change in
+// days, once per stale stretch.
+func NotifyStaleApplications(ctx context.Context, db DBTX, days int) ([]Notification, error) {
+	stale, err := ListStaleJobApplications(ctx, db, days)
+	if err != nil {
+		return nil, err
+	}
+	in := make([]Notification, 0, len(stale))
+	for _, s := range stale {
+		id := s.ID
+		title := s.JobTitle
+		if s.Company != "" {
+			title += " at " + s.Company
+		}
+		in = append(in, Notification{
+			Kind:           NotifyStaleApplication,
+			Title:          fmt.Sprintf("No update on %s for %d days", title, s.DaysIdle),
+			Body:           fmt.Sprintf("Still %s since %s. Follow up or update its status.", s.Status, s.LastUpdate.Format("Jan 2")),
+			Key:            fmt.Sprintf("%s:%d:%s", NotifyStaleApplication, s.ID, s.LastUpdate.UTC().Format(time.RFC3339)),
+			JobApplication: &id,
+		})
+	}
+	return InsertNotifications(ctx, db, in)
+}
+
+// StaleContact is a contact not touched (edited or met) in a while.
+type StaleContact struct {
+	ID          int64     `json:"id"`
+	PersonName  string    `json:"person_name"`
+	Company     string    `json:"company"`
+	LastTouched time.Time `json:"last_touched"`
+	DaysIdle    int       `json:"days_idle"`
+}
+
+// ListStaleContacts returns contacts whose last edit and last meeting are
+// both more than weeks ago, the longest untouched first.
+func ListStaleContacts(ctx context.Context, db DBTX, weeks int) ([]StaleContact, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`WITH met AS (
+             SELECT contact_id, session_time FROM meetings WHERE user_id = $2 AND contact_id IS NOT NULL AND session_time <= now()
+             UNION ALL
+             SELECT mc.contact_id, m.session_time FROM meeting_contacts mc JOIN meetings m ON m.id = mc.meeting_id WHERE m.user_id = $2 AND m.session_time <= now()
+         )
+         SELECT c.id, c.person_name, COALESCE(c.company,''), t.last_touched
+         FROM networking_contacts c
+         CROSS JOIN LATERAL (
+             SELECT GREATEST(MAX(met.session_time), c.updated_at) AS last_touched
+             FROM met WHERE met.contact_id = c.id
+         ) t
+         WHERE c.user_id = $2 AND t.last_touched < now() - make_interval(weeks => $1)
+         ORDER BY t.last_touched, c.id`, weeks, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	now := time.Now()
+	res := []StaleContact{}
+	for rows.Next() {
+		var s StaleContact
+		if err := rows.Scan(&s.ID, &s.PersonName, &s.Company, &s.LastTouched); err != nil {
+			return nil, err
+		}
+		s.DaysIdle = int(now.Sub(s.LastTouched).Hours() / 24)
+		res = append(res, s)
+	}
+	return res, rows.Err()
+}
+
+// NotifyStaleContacts nudges about contacts not touched in weeks, once per
+// quiet stretch.
+func NotifyStaleContacts(ctx context.Context, db DBTX, weeks int) ([]Notification, error) {
+	stale, err := ListStaleContacts(ctx, db, weeks)
+	if err != nil {
+		return nil, err
+	}
+	in := make([]Notification, 0, len(stale))
+	for _, s := range stale {
+		id := s.ID
+		name := s.PersonName
+		if s.Company != "" {
+			name += " (" + s.Company + ")"
+		}
+		in = append(in, Notification{
+			Kind:    NotifyStaleContact,
+			Title:   fmt.Sprintf("Reconnect with %s", name),
+			Body:    fmt.Sprintf("Last touched %d days ago.", s.DaysIdle),
+			Key:     fmt.Sprintf("%s:%d:%s", NotifyStaleContact, s.ID, s.LastTouched.UTC().Format(time.RFC3339)),
+			Contact: &id,
+		})
+	}
+	return InsertNotifications(ctx, db, in)
+}
+
+// NotifyUpcomingMeetings reminds about meetings starting between now and
+// now+within, once per meeting time.
+func NotifyUpcomingMeetings(ctx context.Context, db DBTX, now time.Time, within time.Duration) ([]Notification, error) {
+	meetings, err := listMeetingsBetween(ctx, db, now, now.Add(within))
+	if err != nil {
+		return nil, err
+	}
+	in := make([]Notification, 0, len(meetings))
+	for _, m := range meetings {
+		meetingID := m.ID
+		var details []string
+		for _, s := range []string{m.SessionType, m.Company, m.Location} {
+			if s != "" {
+				details = append(details, s)
+			}
+		}
+		in = append(in, Notification{
+			Kind:           NotifyUpcomingMeeting,
+			Title:          fmt.Sprintf("%s at %s", m.SessionName, m.SessionTime.In(now.Location()).Format("Mon Jan 2 15:04 MST")),
+			Body:           strings.Join(details, ", "),
+			Key:            fmt.Sprintf("%s:%d:%s", NotifyUpcomingMeeting, m.ID, m.SessionTime.UTC().Format(time.RFC3339)),
+			JobApplication: m.JobApplication,
+			Contact:        m.Contact,
+			Meeting:        &meetingID,
+		})
+	}
+	return InsertNotifications(ctx, db, in)
+}
+
+// listMeetingsBetween returns the meetings starting after from and up to
+// to, soonest first.
+func listMeetingsBetween(ctx context.Context, db DBTX, from, to time.Time) ([]Meeting, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT id FROM meetings WHERE user_id = $3 AND session_time > $1 AND session_time <= $2 ORDER BY session_time, id`, from, to, uid)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	byID, err := listMeetingsByID(ctx, db, ids)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Meeting, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, byID[id])
+	}
+	return res, nil
+}
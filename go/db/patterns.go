@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PatternOther collects problems whose pattern is empty or unrecognised.
+const PatternOther = "other"
+
+// CodingPattern is one entry of the pattern taxonomy.
+type CodingPattern struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// CodingPatterns is the normalized pattern taxonomy, roughly in the order
+// they are usually studied.
+var CodingPatterns = []CodingPattern{
+	{"arrays_hashing", "Arrays & Hashing"},
+	{"two_pointers", "Two Pointers"},
+	{"sliding_window", "Sliding Window"},
+	{"prefix_sum", "Prefix Sum"},
+	{"stack", "Stack"},
+	{"binary_search", "Binary Search"},
+	{"linked_list", "Linked List"},
+	{"trees", "Trees"},
+	{"tries", "Tries"},
+	{"heap", "Heap / Priority Queue"},
+	{"backtracking", "Backtracking"},
+	{"bfs_dfs", "Graphs (BFS / DFS)"},
+	{"union_find", "Union Find"},
+	{"dynamic_programming", "Dynamic Programming"},
+	{"greedy", "Greedy"},
+	{"intervals", "Intervals"},
+	{"math", "Math & Geometry"},
+	{"bit_manipulation", "Bit Manipulation"},
+}
+
+// patternAliases maps normalized free text to a taxonomy key. Lookups try
+// the whole text first, then each comma/slash separated part.
+var patternAliases = map[string]string{
+	"array": "arrays_hashing", "arrays": "arrays_hashing", "hashing": "arrays_hashing",
+	"hash map": "arrays_hashing", "hashmap": "arrays_hashing", "hash table": "arrays_hashing",
+	"arrays hashing": "arrays_hashing", "arrays and hashing": "arrays_hashing", "string": "arrays_hashing", "strings": "arrays_hashing",
+	"two pointer": "two_pointers", "two pointers": "two_pointers", "2 pointers": "two_pointers", "fast slow pointers": "two_pointers",
+	"sliding window": "sliding_window", "window": "sliding_window",
+	"prefix sum": "prefix_sum", "prefix sums": "prefix_sum",
+	"stack": "stack", "monotonic stack": "stack", "queue": "stack",
+	"binary search": "binary_search", "bisect": "binary_search",
+	"linked list": "linked_list", "linked lists": "linked_list",
+	"tree": "trees", "trees": "trees", "binary tree": "trees", "bst": "trees", "binary search tree": "trees",
+	"trie": "tries", "tries": "tries", "prefix tree": "tries",
+	"heap": "heap", "heaps": "heap", "priority queue": "heap", "top k": "heap",
+	"backtracking": "backtracking", "recursion": "backtracking",
+	"bfs": "bfs_dfs", "dfs": "bfs_dfs", "bfs dfs": "bfs_dfs", "graph": "bfs_dfs", "graphs": "bfs_dfs",
+	"flood fill": "bfs_dfs", "topological sort": "bfs_dfs", "matrix traversal": "bfs_dfs",
+	"union find": "union_find", "disjoint set": "union_find", "dsu": "union_find",
+	"dp": "dynamic_programming", "dynamic programming": "dynamic_programming", "1d dp": "dynamic_programming",
+	"2d dp": "dynamic_programming", "memoization": "dynamic_programming", "knapsack": "dynamic_programming",
+	"greedy": "greedy", "interval": "intervals", "intervals": "intervals", "merge intervals": "intervals",
+	"math": "math", "geometry": "math", "math geometry": "math", "math and geometry": "math",
+	"bit manipulation": "bit_manipulation", "bits": "bit_manipulation", "bitmask": "bit_manipulation",
+}
+
+// NormalizePattern maps a stored free-text pattern to a taxonomy key, or
+// PatternOther if nothing matches.
+func NormalizePattern(raw string) string {
+	if key, ok := patternAliases[cleanPattern(raw)]; ok {
+		return key
+	}
+	parts := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == '/' || r == ';' || r == '+' })
+	for _, part := range parts {
+		if key, ok := patternAliases[cleanPattern(part)]; ok {
+			return key
+		}
+	}
+	return PatternOther
+}
+
+func cleanPattern(raw string) string {
+	s := strings.ToLower(raw)
+	s = strings.NewReplacer("&", " and ", "-", " ", "_", " ", "(", " ", ")", " ").Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func patternLabel(key string) string {
+	for _, p := range CodingPatterns {
+		if p.Key == key {
+			return p.Label
+		}
+	}
+	return "Other"
+}
+
+type DifficultyCounts struct {
+	Solved   int `json:"solved"`
+	Unsolved int `json:"unsolved"`
+}
+
+type PatternStats struct {
+	Pattern        string                      `json:"pattern"`
+	Label          string                      `json:"label"`
+	Solved         int                         `json:"solved"`
+	Unsolved       int                         `json:"unsolved"`
+	ByDifficulty   map[string]DifficultyCounts `json:"by_difficulty"`
+	Attempts       int                         `json:"attempts"`
+	SolvedAttempts int                         `json:"solved_attempts"`
+	SuccessRate    float64                     `json:"success_rate"`
+	LastPracticed  *time.Time                  `json:"last_practiced"`
+}
+
+type CodingPatternReport struct {
+	Patterns       []PatternStats `json:"patterns"`
+	LeastPracticed []string       `json:"least_practiced"`
+}
+
+type patternRow struct {
+	Pattern        string
+	Difficulty     string
+	Solved         bool
+	Attempts       int
+	SolvedAttempts int
+	LastAttempt    *time.Time
+}
+
+// GetCodingPatternReport aggregates problems and attempts by pattern.
+func GetCodingPatternReport(ctx context.Context, db DBTX) (CodingPatternReport, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return CodingPatternReport{}, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT COALESCE(p.pattern,''), COALESCE(p.difficulty,''), COALESCE(p.already_solved,false),
+                count(a.id), count(a.id) FILTER (WHERE a.outcome = 'solved'), max(a.attempted_on)
+         FROM coding_problems p
+         LEFT JOIN coding_attempts a ON a.coding_problem_id = p.id
+         WHERE p.user_id = $1
+         GROUP BY p.id`, uid)
+	if err != nil {
+		return CodingPatternReport{}, err
+	}
+	defer rows.Close()
+	var data []patternRow
+	for rows.Next() {
+		var r patternRow
+		if err := rows.Scan(&r.Pattern, &r.Difficulty, &r.Solved, &r.Attempts, &r.SolvedAttempts, &r.LastAttempt); err != nil {
+			return CodingPatternReport{}, err
+		}
+		data = append(data, r)
+	}
+	if err := rows.Err(); err != nil {
+		return CodingPatternReport{}, err
+	}
+	return buildPatternReport(data), nil
+}
+
+// leastPracticedCount is how many patterns the report flags for practice.
+const leastPracticedCount = 5
+
+func buildPatternReport(data []patternRow) CodingPatternReport {
+	stats := map[string]*PatternStats{}
+	order := make([]string, 0, len(CodingPatterns)+1)
+	for _, p := range CodingPatterns {
+		stats[p.Key] = &PatternStats{Pattern: p.Key, Label: p.Label, ByDifficulty: map[string]DifficultyCounts{}}
+		order = append(order, p.Key)
+	}
+	for _, r := range data {
+		key := NormalizePattern(r.Pattern)
+		s, ok := stats[key]
+		if !ok {
+			s = &PatternStats{Pattern: key, Label: patternLabel(key), ByDifficulty: map[string]DifficultyCounts{}}
+			stats[key] = s
+			order = append(order, key)
+		}
+		difficulty := strings.ToLower(strings.TrimSpace(r.Difficulty))
+		switch difficulty {
+		case "easy", "medium", "hard":
+		default:
+			difficulty = "unknown"
+		}
+		counts := s.ByDifficulty[difficulty]
+		if r.Solved {
+			s.Solved++
+			counts.Solved++
+		} else {
+			s.Unsolved++
+			counts.Unsolved++
+		}
+		s.ByDifficulty[difficulty] = counts
+		s.Attempts += r.Attempts
+		s.SolvedAttempts += r.SolvedAttempts
+		if r.LastAttempt != nil && (s.LastPracticed == nil || r.LastAttempt.After(*s.LastPracticed)) {
+			last := *r.LastAttempt
+			s.LastPracticed = &last
+		}
+	}
+
+	report := CodingPatternReport{Patterns: make([]PatternStats, 0, len(order))}
+	for _, key := range order {
+		s := stats[key]
+		s.SuccessRate = ratio(s.SolvedAttempts, s.Attempts)
+		report.Patterns = append(report.Patterns, *s)
+	}
+
+	// Least practiced: fewest solved problems plus attempts, then the
+	// longest since last practice (never practiced first).
+	ranked := make([]PatternStats, 0, len(report.Patterns))
+	for _, s := range report.Patterns {
+		if s.Pattern != PatternOther {
+			ranked = append(ranked, s)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		pi, pj := ranked[i].Solved+ranked[i].Attempts, ranked[j].Solved+ranked[j].Attempts
+		if pi != pj {
+			return pi < pj
+		}
+		li, lj := ranked[i].LastPracticed, ranked[j].LastPracticed
+		if li == nil || lj == nil {
+			return li == nil && lj != nil
+		}
+		return li.Before(*lj)
+	})
+	for i := 0; i < len(ranked) && i < leastPracticedCount; i++ {
+		report.LeastPracticed = append(report.LeastPracticed, ranked[i].Pattern)
+	}
+	return report
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizePattern(t *testing.T) {
+	cases := map[string]string{
+		"Two Pointers":        "two_pointers",
+		"two-pointer":         "two_pointers",
+		"Sliding window":      "sliding_window",
+		"BFS/DFS":             "bfs_dfs",
+		"DP":                  "dynamic_programming",
+		"1D-DP":               "dynamic_programming",
+		"Arrays & Hashing":    "arrays_hashing",
+		"Heap, Sorting":       "heap",
+		"Priority Queue":      "heap",
+		"":                    PatternOther,
+		"something I made up": PatternOther,
+	}
+	for in, want := range cases {
+		if got := NormalizePattern(in); got != want {
+			t.Fatalf("NormalizePattern(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestBuildPatternReport(t *testing.T) {
+	last := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	report := buildPatternReport([]patternRow{
+		{Pattern: "two pointers", Difficulty: "Easy", Solved: true, Attempts: 3, SolvedAttempts: 2, LastAttempt: &last},
+		{Pattern: "Two-Pointers", Difficulty: "Medium", Solved: false, Attempts: 1},
+		{Pattern: "dp", Difficulty: "hard", Solved: false},
+		{Pattern: "weird stuff", Difficulty: "", Solved: true},
+	})
+
+	var twoPointers, other *PatternStats
+	for i := range report.Patterns {
+		switch report.Patterns[i].Pattern {
+		case "two_pointers":
+			twoPointers = &report.Patterns[i]
+		case PatternOther:
+			other = &report.Patterns[i]
+		}
+	}
+	if twoPointers == nil || twoPointers.Solved != 1 || twoPointers.Unsolved != 1 || twoPointers.Attempts != 4 {
+		t.Fatalf("unexpected two pointers stats: %+v", twoPointers)
+	}
+	if twoPointers.SuccessRate != 0.5 || twoPointers.ByDifficulty["easy"].Solved != 1 || twoPointers.ByDifficulty["medium"].Unsolved != 1 {
+		t.Fatalf("unexpected two pointers breakdown: %+v", twoPointers)
+	}
+	if other == nil || other.ByDifficulty["unknown"].Solved != 1 {
+		t.Fatalf("expected unrecognised pattern under other, got %+v", other)
+	}
+	if len(report.LeastPracticed) != leastPracticedCount {
+		t.Fatalf("expected %d least practiced patterns, got %v", leastPracticedCount, report.LeastPracticed)
+	}
+	for _, key := range report.LeastPracticed {
+		if key == "two_pointers" || key == PatternOther {
+			t.Fatalf("%s should not be least practiced: %v", key, report.LeastPracticed)
+		}
+	}
+}
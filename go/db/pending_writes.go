@@ -0,0 +1,150 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	PendingWriteOpen     = "pending"
+	PendingWriteApplied  = "applied"
+	PendingWriteDeclined = "declined"
+	PendingWriteExpired  = "expired"
+	PendingWriteFailed   = "failed"
+)
+
+// PendingWrite is a chat write request waiting for the user's yes/no.
+type PendingWrite struct {
+	ID        int64        `json:"id"`
+	UserID    string       `json:"user_id"`
+	SessionID string       `json:"session_id"`
+	Payload   WritePayload `json:"payload"`
+	RawJSON   string       `json:"raw_json"`
+	Summary   string       `json:"summary"`
+	Status    string       `json:"status"`
+	CreatedAt time.Time    `json:"created_at"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// PendingWriteStore keeps pending chat writes in the pending_writes table so
+// a confirmation can be answered by any API replica, even after a restart.
+type PendingWriteStore struct {
+	DB *sql.DB
+}
+
+func NewPendingWriteStore(dbConn *sql.DB) *PendingWriteStore {
+	return &PendingWriteStore{DB: dbConn}
+}
+
+// Get returns the open pending write for the session, or nil if there is
+// none. Open writes past their expiry are marked expired first.
+func (s *PendingWriteStore) Get(ctx context.Context, userID, sessionID string) (*PendingWrite, error) {
+	if _, err := s.DB.ExecContext(ctx,
+		`UPDATE pending_writes SET status=$1, resolved_at=now()
+         WHERE user_id=$2 AND session_id=$3 AND status=$4 AND expires_at <= now()`,
+		PendingWriteExpired, userID, sessionID, PendingWriteOpen,
+	); err != nil {
+		return nil, err
+	}
+	var pw PendingWrite
+	var payload []byte
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT id, user_id, session_id, payload, raw_json, summary, status, created_at, expires_at
+         FROM pending_writes WHERE user_id=$1 AND session_id=$2 AND status=$3`,
+		userID, sessionID, PendingWriteOpen,
+	).Scan(&pw.ID, &pw.UserID, &pw.SessionID, &payload, &pw.RawJSON, &pw.Summary, &pw.Status, &pw.CreatedAt, &pw.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeWritePayload(payload, &pw.Payload); err != nil {
+		return nil, err
+	}
+	return &pw, nil
+}
+
+// Put stores pw as the open pending write for its session, expiring any
+// previous one.
+func (s *PendingWriteStore) Put(ctx context.Context, pw PendingWrite) error {
+	payload, err := json.Marshal(pw.Payload)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE pending_writes SET status=$1, resolved_at=now() WHERE user_id=$2 AND session_id=$3 AND status=$4`,
+		PendingWriteExpired, pw.UserID, pw.SessionID, PendingWriteOpen,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO pending_writes (user_id, session_id, payload, raw_json, summary, status, expires_at)
+         VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+		pw.UserID, pw.SessionID, payload, pw.RawJSON, pw.Summary, PendingWriteOpen, pw.ExpiresAt,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Resolve moves an open pending write to status. It reports false if the
+// write was no longer open (e.g. another replica resolved it first).
+func (s *PendingWriteStore) Resolve(ctx context.Context, id int64, status string) (bool, error) {
+	res, err := s.DB.ExecContext(ctx,
+		`UPDATE pending_writes SET status=$1, resolved_at=now() WHERE id=$2 AND status=$3`,
+		status, id, PendingWriteOpen,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ErrPendingWriteResolved is returned by Apply for a write that is no longer
+// open, e.g. because a retried "yes" or another replica applied it first.
+var ErrPendingWriteResolved = errors.New("pending write was already resolved")
+
+// Apply marks pw applied and applies its payload on dbConn, which must hold
+// the pending_writes table, in one transaction. The status update comes
+// first and locks the row, so a concurrent Apply waits and then finds the
+// write resolved; if the writes fail or the process dies the claim rolls
+// back with them and the write is still open.
+func (s *PendingWriteStore) Apply(ctx context.Context, pw *PendingWrite, dbConn *sql.DB) (string, error) {
+	return applyWriteRequests(ctx, dbConn, pw.Payload, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE pending_writes SET status=$1, resolved_at=now() WHERE id=$2 AND status=$3`,
+			PendingWriteApplied, pw.ID, PendingWriteOpen,
+		)
+		if err != nil {
+			return err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrPendingWriteResolved
+		}
+		return nil
+	})
+}
+
+func decodeWritePayload(raw []byte, out *WritePayload) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Coding attempt outcomes.
+const (
+	AttemptSolved = "solved"
+	AttemptHints  = "hints"
+	AttemptFailed = "failed"
+)
+
+type CodingAttempt struct {
+	ID              int64     `json:"id"`
+	CodingProblemID int64     `json:"coding_problem_id"`
+	AttemptedOn     time.Time `json:"attempted_on"`
+	Outcome         string    `json:"outcome"`
+	MinutesSpent    *int      `json:"minutes_spent"`
+	Confidence      *int      `json:"confidence"`
+	Notes           string    `json:"notes"`
+}
+
+// ReviewState is the SM-2 scheduling state of one problem.
+type ReviewState struct {
+	Repetitions  int       `json:"repetitions"`
+	EaseFactor   float64   `json:"ease_factor"`
+	IntervalDays int       `json:"interval_days"`
+	NextReview   time.Time `json:"next_review"`
+}
+
+// DueReview is a problem whose next review date has arrived.
+type DueReview struct {
+	Problem     CodingProblem `json:"problem"`
+	Attempts    int           `json:"attempts"`
+	LastAttempt *time.Time    `json:"last_attempt"`
+	ReviewState
+	OverdueDays int `json:"overdue_days"`
+}
+
+const initialEaseFactor = 2.5
+
+// NormalizeAttemptOutcome accepts a few spellings of the attempt outcomes.
+func NormalizeAttemptOutcome(outcome string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(outcome)) {
+	case "solved", "pass", "passed", "ok":
+		return AttemptSolved, true
+	case "hints", "hint", "with hints", "solved_with_hints", "partial":
+		return AttemptHints, true
+	case "failed", "fail", "stuck", "unsolved":
+		return AttemptFailed, true
+	}
+	return "", false
+}
+
+// attemptQuality maps an attempt to the SM-2 0-5 recall quality. Confidence
+// (0-5) refines the grade within the range allowed by the outcome.
+func attemptQuality(a CodingAttempt) int {
+	conf := -1
+	if a.Confidence != nil {
+		conf = *a.Confidence
+	}
+	switch a.Outcome {
+	case AttemptSolved:
+		if conf < 0 {
+			return 4
+		}
+		return min(max(conf, 3), 5)
+	case AttemptHints:
+		return 3
+	default:
+		if conf < 0 {
+			return 1
+		}
+		return min(max(conf, 0), 2)
+	}
+}
+
+// ScheduleReview replays attempts (oldest first) through SM-2 and returns the
+// resulting state. A quality below 3 restarts the repetition sequence.
+func ScheduleReview(attempts []CodingAttempt) ReviewState {
+	state := ReviewState{EaseFactor: initialEaseFactor}
+	for _, a := range attempts {
+		q := attemptQuality(a)
+		if q < 3 {
+			state.Repetitions = 0
+			state.IntervalDays = 1
+		} else {
+			state.Repetitions++
+			switch state.Repetitions {
+			case 1:
+				state.IntervalDays = 1
+			case 2:
+				state.IntervalDays = 6
+			default:
+				state.IntervalDays = int(math.Round(float64(state.IntervalDays) * state.EaseFactor))
+			}
+		}
+		miss := float64(5 - q)
+		state.EaseFactor = math.Max(1.3, state.EaseFactor+0.1-miss*(0.08+miss*0.02))
+		state.NextReview = a.AttemptedOn.AddDate(0, 0, state.IntervalDays)
+	}
+	return state
+}
+
+func InsertCodingAttempt(ctx context.Context, db DBTX, in CodingAttempt) (int64, error) {
+	outcome, ok := NormalizeAttemptOutcome(in.Outcome)
+	if !ok {
+		return 0, fieldErrorf("outcome must be solved, hints or failed")
+	}
+	if in.Confidence != nil && (*in.Confidence < 0 || *in.Confidence > 5) {
+		return 0, fieldErrorf("confidence must be between 0 and 5")
+	}
+	if in.MinutesSpent != nil && *in.MinutesSpent < 0 {
+		return 0, fieldErrorf("minutes_spent must not be negative")
+	}
+	if in.AttemptedOn.IsZero() {
+		in.AttemptedOn = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	err = inTx(ctx, db, func(tx DBTX) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO coding_attempts (coding_problem_id, attempted_on, outcome, minutes_spent, confidence, notes, user_id)
+             VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING id`,
+			in.CodingProblemID, in.AttemptedOn, outcome, in.MinutesSpent, in.Confidence, in.Notes, uid,
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if outcome == AttemptFailed {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE coding_problems SET already_solved=true WHERE id=$1 AND user_id=$2`, in.CodingProblemID, uid)
+		return err
+	})
+	return id, err
+}
+
+func ListCodingAttempts(ctx context.Context, db DBTX, problemID int64) ([]CodingAttempt, error) {
+	return queryCodingAttempts(ctx, db, ` AND coding_problem_id=$2`, problemID)
+}
+
+// queryCodingAttempts returns the user's attempts matching cond, whose
+// placeholders start at $2 ($1 is the user).
+func queryCodingAttempts(ctx context.Context, db DBTX, cond string, args ...interface{}) ([]CodingAttempt, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT id, coding_problem_id, attempted_on, outcome, minutes_spent, confidence, COALESCE(notes,'')
+         FROM coding_attempts WHERE user_id=$1`+cond+` ORDER BY coding_problem_id, attempted_on, id`, append([]interface{}{uid}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := []CodingAttempt{}
+	for rows.Next() {
+		var a CodingAttempt
+		if err := rows.Scan(&a.ID, &a.CodingProblemID, &a.AttemptedOn, &a.Outcome, &a.MinutesSpent, &a.Confidence, &a.Notes); err != nil {
+			return nil, err
+		}
+		res = append(res, a)
+	}
+	return res, rows.Err()
+}
+
+// ListDueReviews returns problems due for review on or before asOf, most
+// overdue first. Solved problems without any recorded attempt are due
+// immediately so they enter the rotation.
+func ListDueReviews(ctx context.Context, db DBTX, asOf time.Time, limit int) ([]DueReview, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := queryCodingAttempts(ctx, db, "")
+	if err != nil {
+		return nil, err
+	}
+	byProblem := map[int64][]CodingAttempt{}
+	for _, a := range attempts {
+		byProblem[a.CodingProblemID] = append(byProblem[a.CodingProblemID], a)
+	}
+
+	rows, err := db.QueryContext(ctx, codingSelect+` WHERE user_id=$1`, uid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	asOf = asOf.Truncate(24 * time.Hour)
+	due := []DueReview{}
+	for rows.Next() {
+		p, err := scanCodingProblem(rows)
+		if err != nil {
+			return nil, err
+		}
+		history := byProblem[p.ID]
+		review := DueReview{Problem: p, Attempts: len(history)}
+		if len(history) == 0 {
+			if !p.AlreadySolved {
+				continue
+			}
+			review.ReviewState = ReviewState{EaseFactor: initialEaseFactor, NextReview: asOf}
+		} else {
+			last := history[len(history)-1].AttemptedOn
+			review.LastAttempt = &last
+			review.ReviewState = ScheduleReview(history)
+		}
+		if review.NextReview.After(asOf) {
+			continue
+		}
+		review.OverdueDays = int(asOf.Sub(review.NextReview).Hours() / 24)
+		due = append(due, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		if !due[i].NextReview.Equal(due[j].NextReview) {
+			return due[i].NextReview.Before(due[j].NextReview)
+		}
+		return due[i].Problem.ID < due[j].Problem.ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	return due, nil
+}
+
+func attemptFromRecord(record map[string]interface{}) (CodingAttempt, error) {
+	id := getIntPtr(record, "coding_problem_id")
+	if id == nil {
+		return CodingAttempt{}, fmt.Errorf("coding_problem_id is required")
+	}
+	a := CodingAttempt{
+		CodingProblemID: *id,
+		Outcome:         getString(record, "outcome"),
+		Notes:           getString(record, "notes"),
+	}
+	if d := getDatePtr(record, "attempted_on"); d != nil {
+		a.AttemptedOn = *d
+	}
+	if v := getIntPtr(record, "minutes_spent"); v != nil {
+		n := int(*v)
+		a.MinutesSpent = &n
+	}
+	if v := getIntPtr(record, "confidence"); v != nil {
+		n := int(*v)
+		a.Confidence = &n
+	}
+	return a, nil
+}
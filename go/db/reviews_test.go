@@ -0,0 +1,85 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func attempt(day int, outcome string, confidence int) CodingAttempt {
+	return CodingAttempt{
+		AttemptedOn: time.Date(2026, 1, day, 0, 0, 0, 0, time.UTC),
+		Outcome:     outcome,
+		Confidence:  &confidence,
+	}
+}
+
+func TestScheduleReviewIntervals(t *testing.T) {
+	history := []CodingAttempt{attempt(1, AttemptSolved, 4)}
+	state := ScheduleReview(history)
+	if state.Repetitions != 1 || state.IntervalDays != 1 || state.EaseFactor != 2.5 {
+		t.Fatalf("unexpected state after first solve: %+v", state)
+	}
+
+	history = append(history, attempt(2, AttemptSolved, 5))
+	state = ScheduleReview(history)
+	if state.IntervalDays != 6 || !state.NextReview.Equal(time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected state after second solve: %+v", state)
+	}
+
+	history = append(history, attempt(8, AttemptSolved, 5))
+	state = ScheduleReview(history)
+	if state.Repetitions != 3 || state.IntervalDays != 16 {
+		t.Fatalf("unexpected state after third solve: %+v", state)
+	}
+}
+
+func TestScheduleReviewFailureResets(t *testing.T) {
+	state := ScheduleReview([]CodingAttempt{
+		attempt(1, AttemptSolved, 5),
+		attempt(2, AttemptSolved, 5),
+		attempt(8, AttemptFailed, 1),
+	})
+	if state.Repetitions != 0 || state.IntervalDays != 1 {
+		t.Fatalf("expected reset after failure, got %+v", state)
+	}
+	if state.EaseFactor >= 2.5 {
+		t.Fatalf("expected ease factor to drop, got %v", state.EaseFactor)
+	}
+
+	state = ScheduleReview(repeatFailures(10))
+	if state.EaseFactor != 1.3 {
+		t.Fatalf("expected ease factor floor of 1.3, got %v", state.EaseFactor)
+	}
+}
+
+func repeatFailures(n int) []CodingAttempt {
+	res := make([]CodingAttempt, n)
+	for i := range res {
+		res[i] = attempt(i+1, AttemptFailed, 0)
+	}
+	return res
+}
+
+func TestAttemptQuality(t *testing.T) {
+	if q := attemptQuality(CodingAttempt{Outcome: AttemptSolved}); q != 4 {
+		t.Fatalf("solved without confidence: got %d", q)
+	}
+	if q := attemptQuality(attempt(1, AttemptSolved, 1)); q != 3 {
+		t.Fatalf("solved with low confidence should still pass: got %d", q)
+	}
+	if q := attemptQuality(attempt(1, AttemptFailed, 5)); q != 2 {
+		t.Fatalf("failed attempt must not pass: got %d", q)
+	}
+	if q := attemptQuality(attempt(1, AttemptHints, 5)); q != 3 {
+		t.Fatalf("hints: got %d", q)
+	}
+}
+
+func TestListDueReviewsNeedsUser(t *testing.T) {
+	// A nil DBTX panics if queried, so this must fail up front.
+	if _, err := ListDueReviews(context.Background(), nil, time.Now(), 0); !errors.Is(err, ErrNoUser) {
+		t.Fatalf("expected ErrNoUser, got %v", err)
+	}
+}
@@ -0,0 +1,472 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/adk/session"
+)
+
+// ErrSessionNotFound is returned by SessionService.Get for unknown sessions.
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionService is a Postgres-backed session.Service. Sessions, their
+// events and the app/user/session state live in the chat_* tables so chat
+// history survives restarts and is shared across API replicas.
+type SessionService struct {
+	DB *sql.DB
+}
+
+func NewSessionService(dbConn *sql.DB) *SessionService {
+	return &SessionService{DB: dbConn}
+}
+
+var _ session.Service = (*SessionService)(nil)
+
+func (s *SessionService) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+	if req.AppName == "" || req.UserID == "" {
+		return nil, fmt.Errorf("app_name and user_id are required, got app_name: %q, user_id: %q", req.AppName, req.UserID)
+	}
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = uuid.NewString()
+	}
+	appDelta, userDelta, sessionState := splitStateDelta(req.State)
+	stateJSON, err := json.Marshal(sessionState)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var updatedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO chat_sessions (app_name, user_id, id, state) VALUES ($1,$2,$3,$4)
+         ON CONFLICT DO NOTHING RETURNING updated_at`,
+		req.AppName, req.UserID, sessionID, stateJSON,
+	).Scan(&updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session %s already exists", sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	appState, err := mergeAppState(ctx, tx, req.AppName, appDelta)
+	if err != nil {
+		return nil, err
+	}
+	userState, err := mergeUserState(ctx, tx, req.AppName, req.UserID, userDelta)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &session.CreateResponse{Session: &pgSession{
+		appName:   req.AppName,
+		userID:    req.UserID,
+		id:        sessionID,
+		state:     mergeStates(appState, userState, sessionState),
+		updatedAt: updatedAt,
+	}}, nil
+}
+
+func (s *SessionService) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+	sess, err := s.loadSession(ctx, req.AppName, req.UserID, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT event FROM chat_events WHERE app_name=$1 AND user_id=$2 AND session_id=$3`
+	args := []interface{}{req.AppName, req.UserID, req.SessionID}
+	if !req.After.IsZero() {
+		args = append(args, req.After)
+		query += fmt.Sprintf(" AND event_time >= $%d", len(args))
+	}
+	query += " ORDER BY seq DESC"
+	if req.NumRecentEvents > 0 {
+		args = append(args, req.NumRecentEvents)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []*session.Event
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var event session.Event
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, fmt.Errorf("decode event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Fetched newest first so LIMIT keeps the most recent; restore order.
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	sess.events = events
+	return &session.GetResponse{Session: sess}, nil
+}
+
+func (s *SessionService) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
+	if req.AppName == "" {
+		return nil, fmt.Errorf("app_name is required, got app_name: %q", req.AppName)
+	}
+	query := `SELECT user_id, id, state, updated_at FROM chat_sessions WHERE app_name=$1`
+	args := []interface{}{req.AppName}
+	if req.UserID != "" {
+		query += " AND user_id=$2"
+		args = append(args, req.UserID)
+	}
+	rows, err := s.DB.QueryContext(ctx, query+" ORDER BY updated_at DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var found []*pgSession
+	for rows.Next() {
+		sess := &pgSession{appName: req.AppName}
+		var stateJSON []byte
+		if err := rows.Scan(&sess.userID, &sess.id, &stateJSON, &sess.updatedAt); err != nil {
+			return nil, err
+		}
+		if sess.state, err = decodeState(stateJSON); err != nil {
+			return nil, err
+		}
+		found = append(found, sess)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	appState, err := loadState(ctx, s.DB, `SELECT state FROM chat_app_states WHERE app_name=$1`, req.AppName)
+	if err != nil {
+		return nil, err
+	}
+	userStates := map[string]map[string]any{}
+	sessions := make([]session.Session, 0, len(found))
+	for _, sess := range found {
+		userState, ok := userStates[sess.userID]
+		if !ok {
+			userState, err = loadState(ctx, s.DB, `SELECT state FROM chat_user_states WHERE app_name=$1 AND user_id=$2`, req.AppName, sess.userID)
+			if err != nil {
+				return nil, err
+			}
+			userStates[sess.userID] = userState
+		}
+		sess.state = mergeStates(appState, userState, sess.state)
+		sessions = append(sessions, sess)
+	}
+	return &session.ListResponse{Sessions: sessions}, nil
+}
+
+func (s *SessionService) Delete(ctx context.Context, req *session.DeleteRequest) error {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+	_, err := s.DB.ExecContext(ctx,
+		`DELETE FROM chat_sessions WHERE app_name=$1 AND user_id=$2 AND id=$3`,
+		req.AppName, req.UserID, req.SessionID,
+	)
+	return err
+}
+
+func (s *SessionService) AppendEvent(ctx context.Context, cur session.Session, event *session.Event) error {
+	if cur == nil {
+		return fmt.Errorf("session is nil")
+	}
+	if event == nil {
+		return fmt.Errorf("event is nil")
+	}
+	if event.Partial {
+		return nil
+	}
+	sess, ok := cur.(*pgSession)
+	if !ok {
+		return fmt.Errorf("unexpected session type %T", cur)
+	}
+	dropTempState(event)
+	appDelta, userDelta, sessionDelta := splitStateDelta(event.Actions.StateDelta)
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	deltaJSON, err := json.Marshal(sessionDelta)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE chat_sessions SET state = state || $1::jsonb, updated_at=$2
+         WHERE app_name=$3 AND user_id=$4 AND id=$5`,
+		deltaJSON, event.Timestamp, sess.appName, sess.userID, sess.id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("session not found, cannot apply event")
+	}
+	if _, err := mergeAppState(ctx, tx, sess.appName, appDelta); err != nil {
+		return err
+	}
+	if _, err := mergeUserState(ctx, tx, sess.appName, sess.userID, userDelta); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO chat_events (id, app_name, user_id, session_id, invocation_id, author, event_time, event)
+         VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`,
+		event.ID, sess.appName, sess.userID, sess.id, event.InvocationID, event.Author, event.Timestamp, eventJSON,
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	for key, val := range event.Actions.StateDelta {
+		sess.state[key] = val
+	}
+	sess.events = append(sess.events, event)
+	sess.updatedAt = event.Timestamp
+	return nil
+}
+
+func (s *SessionService) loadSession(ctx context.Context, appName, userID, sessionID string) (*pgSession, error) {
+	sess := &pgSession{appName: appName, userID: userID, id: sessionID}
+	var stateJSON []byte
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT state, updated_at FROM chat_sessions WHERE app_name=$1 AND user_id=$2 AND id=$3`,
+		appName, userID, sessionID,
+	).Scan(&stateJSON, &sess.updatedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sessionState, err := decodeState(stateJSON)
+	if err != nil {
+		return nil, err
+	}
+	appState, err := loadState(ctx, s.DB, `SELECT state FROM chat_app_states WHERE app_name=$1`, appName)
+	if err != nil {
+		return nil, err
+	}
+	userState, err := loadState(ctx, s.DB, `SELECT state FROM chat_user_states WHERE app_name=$1 AND user_id=$2`, appName, userID)
+	if err != nil {
+		return nil, err
+	}
+	sess.state = mergeStates(appState, userState, sessionState)
+	return sess, nil
+}
+
+func mergeAppState(ctx context.Context, q DBTX, appName string, delta map[string]any) (map[string]any, error) {
+	if len(delta) == 0 {
+		return loadState(ctx, q, `SELECT state FROM chat_app_states WHERE app_name=$1`, appName)
+	}
+	deltaJSON, err := json.Marshal(delta)
+	if err != nil {
+		return nil, err
+	}
+	return loadState(ctx, q,
+		`INSERT INTO chat_app_states (app_name, state) VALUES ($1,$2)
+         ON CONFLICT (app_name) DO UPDATE SET state = chat_app_states.state || EXCLUDED.state, updated_at=now()
+         RETURNING state`,
+		appName, deltaJSON,
+	)
+}
+
+func mergeUserState(ctx context.Context, q DBTX, appName, userID string, delta map[string]any) (map[string]any, error) {
+	if len(delta) == 0 {
+		return loadState(ctx, q, `SELECT state FROM chat_user_states WHERE app_name=$1 AND user_id=$2`, appName, userID)
+	}
+	deltaJSON, err := json.Marshal(delta)
+	if err != nil {
+		return nil, err
+	}
+	return loadState(ctx, q,
+		`INSERT INTO chat_user_states (app_name, user_id, state) VALUES ($1,$2,$3)
+         ON CONFLICT (app_name, user_id) DO UPDATE SET state = chat_user_states.state || EXCLUDED.state, updated_at=now()
+         RETURNING state`,
+		appName, userID, deltaJSON,
+	)
+}
+
+func loadState(ctx context.Context, q DBTX, query string, args ...interface{}) (map[string]any, error) {
+	var raw []byte
+	err := q.QueryRowContext(ctx, query, args...).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeState(raw)
+}
+
+func decodeState(raw []byte) (map[string]any, error) {
+	state := map[string]any{}
+	if len(raw) == 0 {
+		return state, nil
+	}
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("decode state: %w", err)
+	}
+	return state, nil
+}
+
+// splitStateDelta separates a state delta into app-, user- and session-scoped
+// parts, stripping the scope prefixes and dropping temp: keys.
+func splitStateDelta(delta map[string]any) (app, user, sess map[string]any) {
+	app, user, sess = map[string]any{}, map[string]any{}, map[string]any{}
+	for key, val := range delta {
+		if k, ok := strings.CutPrefix(key, session.KeyPrefixApp); ok {
+			app[k] = val
+		} else if k, ok := strings.CutPrefix(key, session.KeyPrefixUser); ok {
+			user[k] = val
+		} else if !strings.HasPrefix(key, session.KeyPrefixTemp) {
+			sess[key] = val
+		}
+	}
+	return app, user, sess
+}
+
+func mergeStates(app, user, sess map[string]any) map[string]any {
+	merged := make(map[string]any, len(app)+len(user)+len(sess))
+	maps.Copy(merged, sess)
+	for key, val := range app {
+		merged[session.KeyPrefixApp+key] = val
+	}
+	for key, val := range user {
+		merged[session.KeyPrefixUser+key] = val
+	}
+	return merged
+}
+
+func dropTempState(event *session.Event) {
+	for key := range event.Actions.StateDelta {
+		if strings.HasPrefix(key, session.KeyPrefixTemp) {
+			delete(event.Actions.StateDelta, key)
+		}
+	}
+}
+
+type pgSession struct {
+	appName   string
+	userID    string
+	id        string
+	mu        sync.RWMutex
+	state     map[string]any
+	events    []*session.Event
+	updatedAt time.Time
+}
+
+func (s *pgSession) ID() string      { return s.id }
+func (s *pgSession) AppName() string { return s.appName }
+func (s *pgSession) UserID() string  { return s.userID }
+
+func (s *pgSession) State() session.State {
+	return &pgState{mu: &s.mu, state: s.state}
+}
+
+func (s *pgSession) Events() session.Events {
+	return pgEvents(s.events)
+}
+
+func (s *pgSession) LastUpdateTime() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+type pgState struct {
+	mu    *sync.RWMutex
+	state map[string]any
+}
+
+func (s *pgState) Get(key string) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.state[key]
+	if !ok {
+		return nil, session.ErrStateKeyNotExist
+	}
+	return val, nil
+}
+
+func (s *pgState) Set(key string, val any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state[key] = val
+	return nil
+}
+
+func (s *pgState) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		s.mu.RLock()
+		snapshot := maps.Clone(s.state)
+		s.mu.RUnlock()
+		for k, v := range snapshot {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+type pgEvents []*session.Event
+
+func (e pgEvents) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for _, event := range e {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+func (e pgEvents) Len() int { return len(e) }
+
+func (e pgEvents) At(i int) *session.Event {
+	if i >= 0 && i < len(e) {
+		return e[i]
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"testing"
+
+	"google.golang.org/adk/session"
+)
+
+func TestSplitAndMergeState(t *testing.T) {
+	app, user, sess := splitStateDelta(map[string]any{
+		"app:theme":  "dark",
+		"user:name":  "Koala",
+		"temp:draft": "x",
+		"topic":      "coding",
+	})
+	if app["theme"] != "dark" || user["name"] != "Koala" || sess["topic"] != "coding" {
+		t.Fatalf("unexpected split: app=%v user=%v session=%v", app, user, sess)
+	}
+	if _, ok := sess["temp:draft"]; ok {
+		t.Fatalf("temp state should be dropped")
+	}
+
+	merged := mergeStates(app, user, sess)
+	if merged["app:theme"] != "dark" || merged["user:name"] != "Koala" || merged["topic"] != "coding" {
+		t.Fatalf("unexpected merge: %v", merged)
+	}
+}
+
+func TestPgSessionState(t *testing.T) {
+	s := &pgSession{appName: "a", userID: "u", id: "s", state: map[string]any{"k": 1}}
+	if _, err := s.State().Get("missing"); err != session.ErrStateKeyNotExist {
+		t.Fatalf("expected ErrStateKeyNotExist, got %v", err)
+	}
+	if err := s.State().Set("k", 2); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if v, _ := s.State().Get("k"); v != 2 {
+		t.Fatalf("unexpected value: %v", v)
+	}
+	if s.Events().Len() != 0 || s.Events().At(0) != nil {
+		t.Fatalf("expected no events")
+	}
+}
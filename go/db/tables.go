@@ -0,0 +1,580 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type columnKind int
+
+const (
+	kindText columnKind = iota
+	kindInt
+	kindBool
+	kindDate
+	kindTimestamp
+	kindTextArray
+	kindRef
+	// kindWeekdays is a SMALLINT[] of ISO weekdays, given as names or numbers.
+	kindWeekdays
+)
+
+// tableSpec whitelists the columns of a table that can be matched or changed
+// through generic (map-based) updates and deletes.
+type tableSpec struct {
+	Name    string
+	Label   []string
+	Columns map[string]columnKind
+	// beforeUpdate may normalize values in, or add derived columns to, a
+	// converted SET map.
+	beforeUpdate func(set map[string]interface{}) error
+	// checkUpdate, if set, vets an update against each matched row before it
+	// is applied.
+	checkUpdate func(row, set map[string]interface{}) error
+}
+
+func goalColumns(dateColumn string) map[string]columnKind {
+	return map[string]columnKind{
+		"description":        kindText,
+		dateColumn:           kindDate,
+		"completed":          kindBool,
+		"job_application_id": kindRef,
+		"coding_problem_id":  kindRef,
+		"project_id":         kindRef,
+		"contact_id":         kindRef,
+	}
+}
+
+var tableSpecs = map[string]tableSpec{
+	"job_applications": {
+		Name:  "job_applications",
+		Label: []string{"job_title", "company"},
+		Columns: map[string]columnKind{
+			"job_title":    kindText,
+			"company":      kindText,
+			"job_link":     kindText,
+			"applied_date": kindDate,
+			"result_date":  kindDate,
+			"status":       kindText,
+			"notes":        kindText,
+		},
+		beforeUpdate: normalizeJobSet,
+		checkUpdate:  checkJobUpdate,
+	},
+	"coding_problems": {
+		Name:  "coding_problems",
+		Label: []string{"leetcode_number", "title"},
+		Columns: map[string]columnKind{
+			"leetcode_number": kindInt,
+			"title":           kindText,
+			"pattern":         kindText,
+			"problem_link":    kindText,
+			"difficulty":      kindText,
+			"already_solved":  kindBool,
+			"notes":           kindText,
+		},
+	},
+	"coding_attempts": {
+		Name:  "coding_attempts",
+		Label: []string{"coding_problem_id", "attempted_on"},
+		Columns: map[string]columnKind{
+			"coding_problem_id": kindRef,
+			"attempted_on":      kindDate,
+			"outcome":           kindText,
+			"minutes_spent":     kindInt,
+			"confidence":        kindInt,
+			"notes":             kindText,
+		},
+		beforeUpdate: func(set map[string]interface{}) error {
+			raw, ok := set["outcome"].(string)
+			if !ok {
+				return nil
+			}
+			outcome, ok := NormalizeAttemptOutcome(raw)
+			if !ok {
+				return fieldErrorf("outcome must be solved, hints or failed")
+			}
+			set["outcome"] = outcome
+			return nil
+		},
+	},
+	"projects": {
+		Name:  "projects",
+		Label: []string{"name"},
+		Columns: map[string]columnKind{
+			"name":       kindText,
+			"repo_url":   kindText,
+			"active":     kindBool,
+			"tech_stack": kindTextArray,
+			"summary":    kindText,
+		},
+	},
+	"networking_contacts": {
+		Name:  "networking_contacts",
+		Label: []string{"person_name", "company"},
+		Columns: map[string]columnKind{
+			"person_name":        kindText,
+			"how_met":            kindText,
+			"linkedin_connected": kindBool,
+			"company":            kindText,
+			"position":           kindText,
+			"notes":              kindText,
+		},
+	},
+	"meetings": {
+		Name:  "meetings",
+		Label: []string{"session_name", "company"},
+		Columns: map[string]columnKind{
+			"session_name":       kindText,
+			"session_type":       kindText,
+			"session_time":       kindTimestamp,
+			"location":           kindText,
+			"organizer":          kindText,
+			"company":            kindText,
+			"notes":              kindText,
+			"job_application_id": kindRef,
+			"contact_id":         kindRef,
+		},
+	},
+	"meeting_contacts": {
+		Name:  "meeting_contacts",
+		Label: []string{"meeting_id", "contact_id"},
+		Columns: map[string]columnKind{
+			"meeting_id": kindRef,
+			"contact_id": kindRef,
+		},
+	},
+	"daily_goals": {
+		Name:    "daily_goals",
+		Label:   []string{"description", "target_date"},
+		Columns: goalColumns("target_date"),
+	},
+	"weekly_goals": {
+		Name:    "weekly_goals",
+		Label:   []string{"description", "week_of"},
+		Columns: goalColumns("week_of"),
+	},
+	"monthly_goals": {
+		Name:    "monthly_goals",
+		Label:   []string{"description", "month_of"},
+		Columns: goalColumns("month_of"),
+	},
+	"goals": {
+		Name:  "goals",
+		Label: []string{"description", "target_date"},
+		Columns: map[string]columnKind{
+			"description":        kindText,
+			"target_date":        kindDate,
+			"completed":          kindBool,
+			"target_count":       kindInt,
+			"metric":             kindText,
+			"metric_filter":      kindText,
+			"job_application_id": kindRef,
+			"coding_problem_id":  kindRef,
+			"project_id":         kindRef,
+			"contact_id":         kindRef,
+		},
+		beforeUpdate: normalizeGoalMetricSet,
+	},
+	"goal_recurrences": {
+		Name:  "goal_recurrences",
+		Label: []string{"description"},
+		Columns: map[string]columnKind{
+			"description":        kindText,
+			"target_count":       kindInt,
+			"weekdays":           kindWeekdays,
+			"starts_on":          kindDate,
+			"ends_on":            kindDate,
+			"active":             kindBool,
+			"metric":             kindText,
+			"metric_filter":      kindText,
+			"job_application_id": kindRef,
+			"coding_problem_id":  kindRef,
+			"project_id":         kindRef,
+			"contact_id":         kindRef,
+		},
+		beforeUpdate: normalizeGoalMetricSet,
+	},
+}
+
+// FieldError reports a column name or value rejected before reaching SQL,
+// so callers can tell bad input apart from database failures.
+type FieldError struct {
+	Msg string
+}
+
+func (e *FieldError) Error() string {
+	return e.Msg
+}
+
+func fieldErrorf(format string, args ...interface{}) error {
+	return &FieldError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// linkError reports a foreign_key_violation, a link to a row that doesn't
+// exist or belongs to another user, as a *FieldError naming the link
+// column. Other errors are returned as they are.
+func linkError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23503" {
+		return err
+	}
+	col := strings.TrimSuffix(strings.TrimPrefix(pgErr.ConstraintName, pgErr.TableName+"_"), "_fkey")
+	return fieldErrorf("%s does not refer to one of your records", col)
+}
+
+func lookupTableSpec(table string) (tableSpec, error) {
+	spec, ok := tableSpecs[strings.ToLower(strings.TrimSpace(table))]
+	if !ok {
+		return tableSpec{}, fmt.Errorf("unsupported table: %s", table)
+	}
+	return spec, nil
+}
+
+// convertColumn turns a decoded JSON value into a SQL argument for the column.
+func convertColumn(kind columnKind, column string, val interface{}) (interface{}, error) {
+	if val == nil {
+		switch kind {
+		case kindText:
+			return "", nil
+		case kindBool:
+			return false, nil
+		case kindTextArray:
+			return pqStringArray(nil), nil
+		default:
+			return nil, nil
+		}
+	}
+	record := map[string]interface{}{column: val}
+	switch kind {
+	case kindText:
+		return getString(record, column), nil
+	case kindInt:
+		id := getIntPtr(record, column)
+		if id == nil {
+			return nil, fieldErrorf("%s: invalid integer %v", column, val)
+		}
+		return int(*id), nil
+	case kindRef:
+		id := getIntPtr(record, column)
+		if id == nil {
+			return nil, fieldErrorf("%s: invalid id %v", column, val)
+		}
+		return id, nil
+	case kindBool:
+		switch val.(type) {
+		case bool, string, json.Number:
+			return getBool(record, column), nil
+		}
+		return nil, fieldErrorf("%s: invalid boolean %v", column, val)
+	case kindDate:
+		date := getDate(record, column)
+		if date == nil {
+			return nil, fieldErrorf("%s: invalid date %v (want YYYY-MM-DD)", column, val)
+		}
+		return date, nil
+	case kindTimestamp:
+		ts := getTimestamp(record, column)
+		if ts == nil {
+			return nil, fieldErrorf("%s: invalid timestamp %v (want RFC 3339)", column, val)
+		}
+		return ts, nil
+	case kindTextArray:
+		return pqStringArray(getStringSlice(record, column)), nil
+	case kindWeekdays:
+		days, err := weekdaysValue(val)
+		if err != nil || len(days) == 0 {
+			return nil, err
+		}
+		return pqIntArray(weekdayIDs(days)), nil
+	}
+	return nil, fieldErrorf("%s: unsupported column", column)
+}
+
+func getTimestamp(record map[string]interface{}, key string) *time.Time {
+	val := strings.TrimSpace(getString(record, key))
+	if val == "" {
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04"} {
+		if tm, err := time.Parse(layout, val); err == nil {
+			return &tm
+		}
+	}
+	return nil
+}
+
+// convertSet validates and converts a column->value map for an UPDATE,
+// including any derived columns added by the table's beforeUpdate hook.
+func convertSet(spec tableSpec, fields map[string]interface{}) (map[string]interface{}, error) {
+	if len(fields) == 0 {
+		return nil, fieldErrorf("no fields to update")
+	}
+	set := make(map[string]interface{}, len(fields))
+	for col, raw := range fields {
+		kind, ok := spec.Columns[col]
+		if !ok {
+			return nil, fieldErrorf("unknown column %s.%s", spec.Name, col)
+		}
+		val, err := convertColumn(kind, col, raw)
+		if err != nil {
+			return nil, err
+		}
+		set[col] = val
+	}
+	if spec.beforeUpdate != nil {
+		if err := spec.beforeUpdate(set); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// ifNull is a SET value that only fills a column that is NULL, such as a
+// date stamped the first time a job reaches a stage.
+type ifNull struct{ val interface{} }
+
+// buildSet converts a column->value map into a SET clause. Placeholders start
+// at $argStart.
+func buildSet(spec tableSpec, fields map[string]interface{}, argStart int) (string, []interface{}, error) {
+	set, err := convertSet(spec, fields)
+	if err != nil {
+		return "", nil, err
+	}
+	cols := sortedKeys(set)
+	parts := make([]string, 0, len(cols))
+	args := make([]interface{}, 0, len(cols))
+	for i, col := range cols {
+		if v, ok := set[col].(ifNull); ok {
+			parts = append(parts, fmt.Sprintf("%s=COALESCE(%s, $%d)", col, col, argStart+i))
+			args = append(args, v.val)
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=$%d", col, argStart+i))
+		args = append(args, set[col])
+	}
+	return strings.Join(parts, ", "), args, nil
+}
+
+// buildMatch converts a column->value map into a WHERE clause. Text columns
+// compare case-insensitively; null values match NULL.
+func buildMatch(spec tableSpec, match map[string]interface{}, argStart int) (string, []interface{}, error) {
+	if len(match) == 0 {
+		return "", nil, fieldErrorf("match is empty")
+	}
+	parts := make([]string, 0, len(match))
+	args := []interface{}{}
+	for _, col := range sortedKeys(match) {
+		raw := match[col]
+		kind, ok := spec.Columns[col]
+		if col == "id" {
+			kind, ok = kindRef, true
+		}
+		if !ok {
+			return "", nil, fieldErrorf("unknown column %s.%s", spec.Name, col)
+		}
+		if raw == nil {
+			parts = append(parts, col+" IS NULL")
+			continue
+		}
+		val, err := convertColumn(kind, col, raw)
+		if err != nil {
+			return "", nil, err
+		}
+		n := argStart + len(args)
+		if kind == kindText {
+			parts = append(parts, fmt.Sprintf("LOWER(%s)=LOWER($%d)", col, n))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s=$%d", col, n))
+		}
+		args = append(args, val)
+	}
+	return strings.Join(parts, " AND "), args, nil
+}
+
+// scopeToUser limits a WHERE clause built by buildMatch, whose arguments are
+// args, to the rows of the user in ctx.
+func scopeToUser(ctx context.Context, whereSQL string, args []interface{}) (string, []interface{}, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("user_id=$%d AND %s", len(args)+1, whereSQL), append(args, uid), nil
+}
+
+// updateRows applies fields to every row matching where and returns the
+// number of affected rows.
+func updateRows(ctx context.Context, q DBTX, spec tableSpec, where map[string]interface{}, fields map[string]interface{}) (int64, error) {
+	setSQL, args, err := buildSet(spec, fields, 1)
+	if err != nil {
+		return 0, err
+	}
+	whereSQL, whereArgs, err := buildMatch(spec, where, len(args)+1)
+	if err != nil {
+		return 0, err
+	}
+	whereSQL, args, err = scopeToUser(ctx, whereSQL, append(args, whereArgs...))
+	if err != nil {
+		return 0, err
+	}
+	if spec.checkUpdate != nil {
+		if err := checkUpdateRows(ctx, q, spec, where, fields); err != nil {
+			return 0, err
+		}
+	}
+	res, err := q.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s WHERE %s", spec.Name, setSQL, whereSQL), args...)
+	if err != nil {
+		return 0, linkError(err)
+	}
+	return res.RowsAffected()
+}
+
+func deleteRows(ctx context.Context, q DBTX, spec tableSpec, where map[string]interface{}) (int64, error) {
+	whereSQL, args, err := buildMatch(spec, where, 1)
+	if err != nil {
+		return 0, err
+	}
+	whereSQL, args, err = scopeToUser(ctx, whereSQL, args)
+	if err != nil {
+		return 0, err
+	}
+	res, err := q.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", spec.Name, whereSQL), args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// checkUpdateRows locks the matched rows and runs the table's checkUpdate
+// hook against each of them.
+func checkUpdateRows(ctx context.Context, q DBTX, spec tableSpec, where map[string]interface{}, fields map[string]interface{}) error {
+	set, err := convertSet(spec, fields)
+	if err != nil {
+		return err
+	}
+	rows, err := queryRows(ctx, q, spec, where, true)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := spec.checkUpdate(row, set); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectRows returns matching rows as generic JSON objects (via to_jsonb).
+func selectRows(ctx context.Context, q DBTX, spec tableSpec, where map[string]interface{}) ([]map[string]interface{}, error) {
+	return queryRows(ctx, q, spec, where, false)
+}
+
+func queryRows(ctx context.Context, q DBTX, spec tableSpec, where map[string]interface{}, lock bool) ([]map[string]interface{}, error) {
+	whereSQL, args, err := buildMatch(spec, where, 1)
+	if err != nil {
+		return nil, err
+	}
+	whereSQL, args, err = scopeToUser(ctx, whereSQL, args)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT to_jsonb(t) FROM %s t WHERE %s ORDER BY id", spec.Name, whereSQL)
+	if lock {
+		query += " FOR UPDATE"
+	}
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []map[string]interface{}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(strings.NewReader(string(raw)))
+		decoder.UseNumber()
+		row := map[string]interface{}{}
+		if err := decoder.Decode(&row); err != nil {
+			return nil, err
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+// displayColumn renders a converted column value the way to_jsonb would, so
+// before/after values can be compared textually.
+func displayColumn(kind columnKind, val interface{}) string {
+	switch kind {
+	case kindTextArray:
+		if v, ok := val.(string); ok {
+			return formatTextArray(v)
+		}
+	case kindWeekdays:
+		if v, ok := val.(string); ok {
+			return strings.NewReplacer("{", "[", "}", "]").Replace(v)
+		}
+	case kindDate, kindTimestamp:
+		if v, ok := val.(*time.Time); ok && v != nil {
+			if kind == kindDate {
+				return strconv.Quote(v.Format("2006-01-02"))
+			}
+			return strconv.Quote(v.Format(time.RFC3339))
+		}
+	}
+	return displayValue(val)
+}
+
+// displayValue renders a decoded JSON (or plain Go) value for diffs.
+func displayValue(val interface{}) string {
+	switch v := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case *int64:
+		if v == nil {
+			return "null"
+		}
+		return strconv.FormatInt(*v, 10)
+	case *time.Time:
+		if v == nil {
+			return "null"
+		}
+		return strconv.Quote(v.Format(time.RFC3339))
+	case []interface{}, map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func formatTextArray(v string) string {
+	inner := strings.TrimSuffix(strings.TrimPrefix(v, "{"), "}")
+	items := []string{}
+	if inner != "" {
+		items = strings.Split(inner, ",")
+	}
+	data, _ := json.Marshal(items)
+	return string(data)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
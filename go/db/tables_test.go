@@ -0,0 +1,110 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestBuildSetAndMatch(t *testing.T) {
+	spec := tableSpecs["coding_problems"]
+	setSQL, setArgs, err := buildSet(spec, map[string]interface{}{
+		"already_solved": true,
+		"notes":          "revisit",
+	}, 1)
+	if err != nil {
+		t.Fatalf("buildSet: %v", err)
+	}
+	if setSQL != "already_solved=$1, notes=$2" || len(setArgs) != 2 {
+		t.Fatalf("unexpected set: %q %v", setSQL, setArgs)
+	}
+
+	whereSQL, whereArgs, err := buildMatch(spec, map[string]interface{}{
+		"leetcode_number": json.Number("42"),
+		"title":           "Trapping Rain Water",
+		"pattern":         nil,
+	}, 3)
+	if err != nil {
+		t.Fatalf("buildMatch: %v", err)
+	}
+	want := "leetcode_number=$3 AND pattern IS NULL AND LOWER(title)=LOWER($4)"
+	if whereSQL != want || len(whereArgs) != 2 {
+		t.Fatalf("unexpected where: %q %v", whereSQL, whereArgs)
+	}
+}
+
+func TestBuildSetRejectsUnknownAndInvalid(t *testing.T) {
+	spec := tableSpecs["job_applications"]
+	if _, _, err := buildSet(spec, map[string]interface{}{"id": json.Number("1")}, 1); err == nil {
+		t.Fatalf("expected error for id column")
+	}
+	if _, _, err := buildSet(spec, map[string]interface{}{"applied_date": "last week"}, 1); err == nil {
+		t.Fatalf("expected error for invalid date")
+	}
+	_, _, err := buildMatch(spec, map[string]interface{}{"salary": "1"}, 1)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *FieldError for unknown match column, got %v", err)
+	}
+}
+
+func TestJobRejectionStampsResultDate(t *testing.T) {
+	set, err := convertSet(tableSpecs["job_applications"], map[string]interface{}{"status": "Rejected"})
+	if err != nil {
+		t.Fatalf("convertSet: %v", err)
+	}
+	stamp, ok := set["result_date"].(ifNull)
+	if date, isDate := stamp.val.(*time.Time); !ok || !isDate || date == nil {
+		t.Fatalf("expected result_date to be set, got %v", set["result_date"])
+	}
+}
+
+func TestFormatRowChanges(t *testing.T) {
+	spec := tableSpecs["projects"]
+	set, err := convertSet(spec, map[string]interface{}{
+		"active":     false,
+		"tech_stack": []interface{}{"go", "sql"},
+	})
+	if err != nil {
+		t.Fatalf("convertSet: %v", err)
+	}
+	row := map[string]interface{}{
+		"id":         json.Number("3"),
+		"name":       "Koala",
+		"active":     true,
+		"tech_stack": []interface{}{"go", "sql"},
+	}
+	change := RowChange{Table: spec.Name, Action: "update", ID: getString(row, "id"), Label: rowLabel(spec, row)}
+	for _, col := range sortedKeys(set) {
+		before := displayValue(row[col])
+		after := displayColumn(spec.Columns[col], set[col])
+		if before != after {
+			change.Fields = append(change.Fields, FieldChange{Column: col, Before: before, After: after})
+		}
+	}
+	got := FormatRowChanges([]RowChange{change, {Table: "coding_problems", Action: "delete", ID: "5", Label: "42, Trapping Rain Water"}})
+	want := "update projects #3 (Koala)\n  active: true -> false\ndelete coding_problems #5 (42, Trapping Rain Water)"
+	if got != want {
+		t.Fatalf("unexpected diff:\n%s", got)
+	}
+	if strings.Contains(got, "tech_stack") {
+		t.Fatalf("unchanged column reported: %s", got)
+	}
+}
+
+func TestLinkError(t *testing.T) {
+	err := fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23503", TableName: "meetings", ConstraintName: "meetings_job_application_id_fkey"})
+	var fieldErr *FieldError
+	if !errors.As(linkError(err), &fieldErr) || fieldErr.Msg != "job_application_id does not refer to one of your records" {
+		t.Fatalf("linkError = %v", linkError(err))
+	}
+	err = &pgconn.PgError{Code: "23505", TableName: "meetings", ConstraintName: "meetings_ical_uid_idx"}
+	if got := linkError(err); got != error(err) {
+		t.Fatalf("expected other errors unchanged, got %v", got)
+	}
+}
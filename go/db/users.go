@@ -0,0 +1,236 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultUserName is the account that owns the data from before users
+// existed, and the caller when none is given.
+const DefaultUserName = "demo_user"
+
+// ErrNoUser is returned by the query helpers when ctx carries no user (see
+// WithUser). Every query on user data is scoped to that user, so a missing
+// one is a bug in the caller rather than a reason to see everyone's rows.
+var ErrNoUser = errors.New("db: no user in context")
+
+// User is one account. Name is the external identity (the chat user_id, a
+// token's subject); ID scopes the rows of every other table.
+type User struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	// NotifyEmail and NotifyWebhookURL are where the user's alerts and
+	// daily digest go; empty when not set.
+	NotifyEmail      string `json:"notify_email"`
+	NotifyWebhookURL string `json:"notify_webhook_url"`
+}
+
+const userColumns = `id, name, created_at, COALESCE(notify_email, ''), COALESCE(notify_webhook_url, '')`
+
+func scanUser(row rowScanner) (User, error) {
+	var u User
+	err := row.Scan(&u.ID, &u.Name, &u.CreatedAt, &u.NotifyEmail, &u.NotifyWebhookURL)
+	return u, err
+}
+
+type userKey struct{}
+
+// WithUser returns a copy of ctx whose queries are scoped to the user.
+func WithUser(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// UserFromContext returns the user set by WithUser.
+func UserFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(userKey{}).(int64)
+	return id, ok && id > 0
+}
+
+// currentUser is UserFromContext for the query helpers.
+func currentUser(ctx context.Context) (int64, error) {
+	id, ok := UserFromContext(ctx)
+	if !ok {
+		return 0, ErrNoUser
+	}
+	return id, nil
+}
+
+func normalizeUserName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fieldErrorf("user name is required")
+	}
+	if len(name) > maxUserNameLen {
+		return "", fieldErrorf("user name is too long")
+	}
+	if strings.HasPrefix(name, identityNamePrefix) {
+		return "", fieldErrorf("user names starting with %q are reserved", identityNamePrefix)
+	}
+	return name, nil
+}
+
+const maxUserNameLen = 200
+
+// identityNamePrefix starts the names of users created for an external
+// identity. Other users can't be given such a name, so no API token or
+// caller-named user can end up sharing an account with a JWT identity.
+const identityNamePrefix = "oidc:"
+
+// EnsureUser returns the user called name, creating it on first use.
+func EnsureUser(ctx context.Context, db DBTX, name string) (User, error) {
+	name, err := normalizeUserName(name)
+	if err != nil {
+		return User{}, err
+	}
+	// The no-op update makes RETURNING yield the existing row too.
+	return scanUser(db.QueryRowContext(ctx,
+		`INSERT INTO users (name) VALUES ($1)
+         ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+         RETURNING `+userColumns, name))
+}
+
+// EnsureIdentityUser returns the user linked to the external identity
+// (issuer, subject), creating both on first use. Identities are matched
+// through user_identities only, never by name, so a subject that equals an
+// existing user name (say demo_user) still gets an account of its own.
+func EnsureIdentityUser(ctx context.Context, db *sql.DB, issuer, subject string) (User, error) {
+	issuer, subject = strings.TrimSpace(issuer), strings.TrimSpace(subject)
+	if issuer == "" || subject == "" {
+		return User{}, fieldErrorf("identity needs an issuer and a subject")
+	}
+	lookup := func() (User, error) {
+		return scanUser(db.QueryRowContext(ctx,
+			`SELECT `+userColumns+` FROM users
+             WHERE id = (SELECT user_id FROM user_identities WHERE issuer=$1 AND subject=$2)`, issuer, subject))
+	}
+	u, err := lookup()
+	if !errors.Is(err, sql.ErrNoRows) {
+		return u, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return User{}, err
+	}
+	defer tx.Rollback()
+	u, err = scanUser(tx.QueryRowContext(ctx,
+		`INSERT INTO users (name) VALUES ($1) ON CONFLICT (name) DO NOTHING RETURNING `+userColumns,
+		identityUserName(issuer, subject)))
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another issuer on the same host has this subject too.
+		u, err = scanUser(tx.QueryRowContext(ctx,
+			`INSERT INTO users (name) VALUES ($1) RETURNING `+userColumns, identityNamePrefix+uuid.NewString()))
+	}
+	if err != nil {
+		return User{}, err
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1,$2,$3) ON CONFLICT DO NOTHING`,
+		issuer, subject, u.ID)
+	if err != nil {
+		return User{}, err
+	}
+	if rows, err := res.RowsAffected(); err != nil {
+		return User{}, err
+	} else if rows == 0 {
+		// A concurrent first request linked the identity; use its user.
+		tx.Rollback()
+		return lookup()
+	}
+	return u, tx.Commit()
+}
+
+// identityUserName is the readable name of a new identity's user:
+// "oidc:<subject>@<issuer host>".
+func identityUserName(issuer, subject string) string {
+	host := issuer
+	if u, err := url.Parse(issuer); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	name := identityNamePrefix + subject + "@" + host
+	if len(name) > maxUserNameLen {
+		return identityNamePrefix + uuid.NewString()
+	}
+	return name
+}
+
+// GetUserByName returns sql.ErrNoRows if there is no such user.
+func GetUserByName(ctx context.Context, db DBTX, name string) (User, error) {
+	return scanUser(db.QueryRowContext(ctx,
+		`SELECT `+userColumns+` FROM users WHERE name=$1`, strings.TrimSpace(name)))
+}
+
+// GetCurrentUser returns the user ctx is scoped to.
+func GetCurrentUser(ctx context.Context, db DBTX) (User, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return User{}, err
+	}
+	return scanUser(db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id=$1`, uid))
+}
+
+// SetNotifyTargets sets where the current user's alerts and digest are
+// delivered; an empty value turns that channel off.
+func SetNotifyTargets(ctx context.Context, db DBTX, email, webhookURL string) (User, error) {
+	uid, err := currentUser(ctx)
+	if err != nil {
+		return User{}, err
+	}
+	email, webhookURL = strings.TrimSpace(email), strings.TrimSpace(webhookURL)
+	if email != "" {
+		addr, err := mail.ParseAddress(email)
+		if err != nil || addr.Address != email {
+			return User{}, fieldErrorf("notify_email %q is not an email address", email)
+		}
+	}
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return User{}, fieldErrorf("notify_webhook_url %q is not an https URL", webhookURL)
+		}
+	}
+	return scanUser(db.QueryRowContext(ctx,
+		`UPDATE users SET notify_email = NULLIF($1, ''), notify_webhook_url = NULLIF($2, '')
+         WHERE id = $3 RETURNING `+userColumns, email, webhookURL, uid))
+}
+
+// ListUsers returns every user, oldest first. Background tasks use it to
+// run once per user.
+func ListUsers(ctx context.Context, db DBTX) ([]User, error) {
+	rows, err := db.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, u)
+	}
+	return res, rows.Err()
+}
+
+// ForEachUser runs fn with ctx scoped to each of users in turn. A failure
+// doesn't stop the users after it: the errors are joined, each naming the
+// user it failed for.
+func ForEachUser(ctx context.Context, users []User, fn func(ctx context.Context, u User) error) error {
+	var errs []error
+	for _, u := range users {
+		if err := fn(WithUser(ctx, u.ID), u); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", u.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestUserContext(t *testing.T) {
+	ctx := context.Background()
+	if _, err := currentUser(ctx); !errors.Is(err, ErrNoUser) {
+		t.Fatalf("expected ErrNoUser without a user, got %v", err)
+	}
+	if _, err := currentUser(WithUser(ctx, 0)); !errors.Is(err, ErrNoUser) {
+		t.Fatalf("expected ErrNoUser for id 0, got %v", err)
+	}
+	id, err := currentUser(WithUser(ctx, 7))
+	if err != nil || id != 7 {
+		t.Fatalf("currentUser = %d, %v; want 7", id, err)
+	}
+}
+
+func TestScopeToUser(t *testing.T) {
+	spec := tableSpecs["job_applications"]
+	whereSQL, args, err := buildMatch(spec, map[string]interface{}{"company": "Stripe"}, 2)
+	if err != nil {
+		t.Fatalf("buildMatch: %v", err)
+	}
+	args = append([]interface{}{"rejected"}, args...)
+	if _, _, err := scopeToUser(context.Background(), whereSQL, args); !errors.Is(err, ErrNoUser) {
+		t.Fatalf("expected ErrNoUser, got %v", err)
+	}
+	whereSQL, args, err = scopeToUser(WithUser(context.Background(), 3), whereSQL, args)
+	if err != nil {
+		t.Fatalf("scopeToUser: %v", err)
+	}
+	if whereSQL != "user_id=$3 AND LOWER(company)=LOWER($2)" || len(args) != 3 || args[2] != int64(3) {
+		t.Fatalf("unexpected scoped where: %q %v", whereSQL, args)
+	}
+}
+
+func TestNormalizeUserName(t *testing.T) {
+	if name, err := normalizeUserName("  ana  "); err != nil || name != "ana" {
+		t.Fatalf("normalizeUserName = %q, %v", name, err)
+	}
+	for _, bad := range []string{"", "   ", strings.Repeat("x", 201), "oidc:ana@idp.example.com"} {
+		var fieldErr *FieldError
+		if _, err := normalizeUserName(bad); !errors.As(err, &fieldErr) {
+			t.Fatalf("expected *FieldError for %q, got %v", bad, err)
+		}
+	}
+}
+
+func TestIdentityUserName(t *testing.T) {
+	if got := identityUserName("https://idp.example.com/realms/koala", "demo_user"); got != "oidc:demo_user@idp.example.com" {
+		t.Fatalf("identityUserName = %q", got)
+	}
+	// Identity names are reserved, so no caller can claim one by name.
+	if _, err := normalizeUserName(identityUserName("https://idp.example.com", "ana")); err == nil {
+		t.Fatal("expected an identity's user name to be refused as a plain name")
+	}
+	if got := identityUserName("https://idp.example.com", strings.Repeat("x", 300)); !strings.HasPrefix(got, "oidc:") || len(got) > maxUserNameLen {
+		t.Fatalf("long subject gave %q", got)
+	}
+	var fieldErr *FieldError
+	if _, err := EnsureIdentityUser(context.Background(), nil, "", "ana"); !errors.As(err, &fieldErr) {
+		t.Fatalf("expected *FieldError without an issuer, got %v", err)
+	}
+}
+
+func TestSetNotifyTargetsValidates(t *testing.T) {
+	ctx := WithUser(context.Background(), 1)
+	// A nil DBTX panics if queried, so these must fail up front.
+	for _, tc := range []struct{ email, webhook string }{
+		{"not an address", ""},
+		{"Ana <ana@example.com>", ""},
+		{"", "ftp://hooks.example.com"},
+		{"", "http://hooks.example.com"},
+		{"", "https://"},
+	} {
+		var fieldErr *FieldError
+		if _, err := SetNotifyTargets(ctx, nil, tc.email, tc.webhook); !errors.As(err, &fieldErr) {
+			t.Errorf("SetNotifyTargets(%q, %q) = %v, want a *FieldError", tc.email, tc.webhook, err)
+		}
+	}
+}
+
+func TestForEachUserKeepsGoing(t *testing.T) {
+	users := []User{{ID: 1, Name: "ana"}, {ID: 2, Name: "bo"}, {ID: 3, Name: "cy"}}
+	var ran []int64
+	err := ForEachUser(context.Background(), users, func(ctx context.Context, u User) error {
+		id, _ := currentUser(ctx)
+		ran = append(ran, id)
+		if u.Name != "bo" {
+			return errors.New("webhook returned 500")
+		}
+		return nil
+	})
+	if len(ran) != 3 || ran[0] != 1 || ran[2] != 3 {
+		t.Fatalf("ran for %v, want every user", ran)
+	}
+	if err == nil || err.Error() != "user ana: webhook returned 500\nuser cy: webhook returned 500" {
+		t.Fatalf("err = %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// FieldChange is one column of a row whose value an update would change.
+type FieldChange struct {
+	Column string
+	Before string
+	After  string
+}
+
+// RowChange describes the effect of an update/delete request on one row.
+type RowChange struct {
+	Table  string
+	Action string
+	ID     string
+	Label  string
+	Fields []FieldChange
+}
+
+// PreviewWriteRequests resolves the rows targeted by the update and delete
+// requests of a payload and computes their before/after values. Inserts have
+// no existing rows and are skipped. A selector that matches nothing is
+// reported as an *ApplyError, since applying it would fail.
+func PreviewWriteRequests(ctx context.Context, q DBTX, payload WritePayload) ([]RowChange, error) {
+	var changes []RowChange
+	var failures []WriteFailure
+	for i, req := range payload.WriteRequests {
+		action := strings.ToLower(strings.TrimSpace(req.Action))
+		if action != "update" && action != "delete" {
+			continue
+		}
+		table := strings.ToLower(strings.TrimSpace(req.Table))
+		spec, err := lookupTableSpec(table)
+		if err != nil {
+			failures = append(failures, WriteFailure{Request: i + 1, Table: table, Err: err})
+			continue
+		}
+		for j, record := range req.Records {
+			rowChanges, err := previewRecord(ctx, q, spec, action, record)
+			if err != nil {
+				failures = append(failures, WriteFailure{Request: i + 1, Record: j + 1, Table: table, Err: err})
+				continue
+			}
+			changes = append(changes, rowChanges...)
+		}
+	}
+	if len(failures) > 0 {
+		return nil, &ApplyError{Failures: failures}
+	}
+	return changes, nil
+}
+
+func previewRecord(ctx context.Context, q DBTX, spec tableSpec, action string, record map[string]interface{}) ([]RowChange, error) {
+	where, err := recordSelector(record)
+	if err != nil {
+		return nil, err
+	}
+	var set map[string]interface{}
+	if action == "update" {
+		fields, err := recordSet(record)
+		if err != nil {
+			return nil, err
+		}
+		if set, err = convertSet(spec, fields); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := selectRows(ctx, q, spec, where)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errNoRowsMatched
+	}
+	changes := make([]RowChange, 0, len(rows))
+	for _, row := range rows {
+		if set != nil && spec.checkUpdate != nil {
+			if err := spec.checkUpdate(row, set); err != nil {
+				return nil, err
+			}
+		}
+		changes = append(changes, rowChange(spec, action, row, set))
+	}
+	return changes, nil
+}
+
+// rowChange lists the columns set would change in row. A stamp that only
+// fills NULL columns (see ifNull) shows only where the column is empty.
+func rowChange(spec tableSpec, action string, row, set map[string]interface{}) RowChange {
+	change := RowChange{
+		Table:  spec.Name,
+		Action: action,
+		ID:     getString(row, "id"),
+		Label:  rowLabel(spec, row),
+	}
+	for _, col := range sortedKeys(set) {
+		val := set[col]
+		if v, ok := val.(ifNull); ok {
+			if row[col] != nil {
+				continue
+			}
+			val = v.val
+		}
+		before := displayValue(row[col])
+		after := displayColumn(spec.Columns[col], val)
+		if before != after {
+			change.Fields = append(change.Fields, FieldChange{Column: col, Before: before, After: after})
+		}
+	}
+	return change
+}
+
+func rowLabel(spec tableSpec, row map[string]interface{}) string {
+	parts := make([]string, 0, len(spec.Label))
+	for _, col := range spec.Label {
+		if val := getString(row, col); val != "" {
+			parts = append(parts, val)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatRowChanges renders changes as a plain-text before/after diff.
+func FormatRowChanges(changes []RowChange) string {
+	var b strings.Builder
+	for i, c := range changes {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s %s #%s", c.Action, c.Table, c.ID)
+		if c.Label != "" {
+			fmt.Fprintf(&b, " (%s)", c.Label)
+		}
+		if c.Action == "update" {
+			if len(c.Fields) == 0 {
+				b.WriteString("\n  (no changes)")
+			}
+			for _, f := range c.Fields {
+				fmt.Fprintf(&b, "\n  %s: %s -> %s", f.Column, f.Before, f.After)
+			}
+		}
+	}
+	return b.String()
+}
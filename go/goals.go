@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	ckdb "career-koala/db"
+)
+
+var goalRecurrenceResource = resource[ckdb.GoalRecurrence]{
+	name:   "goal recurrence",
+	get:    ckdb.GetGoalRecurrence,
+	update: ckdb.UpdateGoalRecurrence,
+	remove: ckdb.DeleteGoalRecurrence,
+}
+
+// goalRecurrencesHandler lists (GET, ?active=true for active rules only) and
+// creates (POST) recurrence rules. A POST body takes either a "rule" such as
+// "solve 2 problems every weekday" or period/schedule plus description.
+func goalRecurrencesHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		switch r.Method {
+		case http.MethodGet:
+			activeOnly, err := queryBool(r, "active")
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			rules, err := ckdb.ListGoalRecurrences(ctx, dbConn, activeOnly)
+			if err != nil {
+				writeItemError(w, err, "goal recurrences", "list")
+				return
+			}
+			writeJSON(w, map[string]any{"items": rules})
+		case http.MethodPost:
+			decoder := json.NewDecoder(r.Body)
+			decoder.UseNumber()
+			var record map[string]interface{}
+			if err := decoder.Decode(&record); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid json")
+				return
+			}
+			rule, err := ckdb.GoalRecurrenceFromRecord(record)
+			if err != nil {
+				writeItemError(w, err, "goal recurrence", "create")
+				return
+			}
+			id, err := ckdb.InsertGoalRecurrence(ctx, dbConn, rule)
+			if err != nil {
+				writeItemError(w, err, "goal recurrence", "create")
+				return
+			}
+			created, err := ckdb.GetGoalRecurrence(ctx, dbConn, id)
+			if err != nil {
+				writeItemError(w, err, "goal recurrence", "fetch")
+				return
+			}
+			writeJSON(w, created)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ckdb "career-koala/db"
+)
+
+const maxImportBytes = 10 << 20
+
+// importBody returns the uploaded file: either the "file" part of a
+// multipart form or the raw request body.
+func importBody(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.New("multipart upload needs a \"file\" field")
+		}
+		return file, nil
+	}
+	return r.Body, nil
+}
+
+func queryBool(r *http.Request, name string) (bool, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return false, nil
+	}
+	val, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, errors.New("invalid " + name)
+	}
+	return val, nil
+}
+
+// codingImportHandler imports a CSV problem list. Query parameters: dry_run,
+// map (field=Header,...), goals (daily|weekly), goal_start (YYYY-MM-DD) and
+// per_goal.
+func codingImportHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		query := r.URL.Query()
+		var opts ckdb.CodingImportOptions
+		var err error
+		if opts.DryRun, err = queryBool(r, "dry_run"); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if opts.Mapping, err = ckdb.ParseImportMapping(query.Get("map")); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		opts.GoalType = query.Get("goals")
+		if raw := query.Get("goal_start"); raw != "" {
+			if opts.GoalStart, err = time.Parse("2006-01-02", raw); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid goal_start")
+				return
+			}
+		}
+		var ok bool
+		if opts.PerGoal, ok = queryInt(w, r, "per_goal"); !ok {
+			return
+		}
+
+		body, err := importBody(w, r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer body.Close()
+		ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+		defer cancel()
+		result, err := ckdb.ImportCodingProblems(ctx, dbConn, body, opts)
+		if err != nil {
+			var fieldErr *ckdb.FieldError
+			if errors.As(err, &fieldErr) {
+				writeError(w, http.StatusBadRequest, fieldErr.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to import coding problems")
+			return
+		}
+		writeJSON(w, result)
+	}
+}
+
+func exportHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+		defer cancel()
+		archive, err := ckdb.ExportArchive(ctx, dbConn)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to export data")
+			return
+		}
+		name := "career-koala-" + archive.ExportedAt.Format("20060102") + ".json"
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		writeJSON(w, archive)
+	}
+}
+
+// exportTableHandler serves /export/{table}.csv.
+func exportTableHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		table, ok := strings.CutSuffix(r.PathValue("file"), ".csv")
+		if !ok {
+			writeError(w, http.StatusNotFound, "use /export/{table}.csv")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+		defer cancel()
+		var buf bytes.Buffer
+		if err := ckdb.WriteTableCSV(ctx, dbConn, table, &buf); err != nil {
+			var fieldErr *ckdb.FieldError
+			if errors.As(err, &fieldErr) {
+				writeError(w, http.StatusNotFound, fieldErr.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to export "+table)
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+table+`.csv"`)
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+// archiveImportHandler restores a JSON archive produced by /export.
+func archiveImportHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		dryRun, err := queryBool(r, "dry_run")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		body, err := importBody(w, r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer body.Close()
+		var archive ckdb.Archive
+		if err := json.NewDecoder(body).Decode(&archive); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid archive json")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+		defer cancel()
+		result, err := ckdb.ImportArchive(ctx, dbConn, archive, dryRun)
+		if err != nil {
+			var fieldErr *ckdb.FieldError
+			if errors.As(err, &fieldErr) {
+				writeError(w, http.StatusBadRequest, fieldErr.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to import archive: "+err.Error())
+			return
+		}
+		writeJSON(w, result)
+	}
+}
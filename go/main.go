@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,7 +16,11 @@ import (
 	"time"
 
 	"career-koala/agents"
+	"career-koala/agents/router"
+	"career-koala/auth"
 	ckdb "career-koala/db"
+	"career-koala/notify"
+	"career-koala/scheduler"
 
 	"golang.org/x/oauth2/google"
 	"google.golang.org/adk/agent"
@@ -35,9 +40,27 @@ func main() {
 		log.Fatalf("db init (postgres): %v", err)
 	}
 	defer conn.Close()
+	if boolFromEnv("SCHEDULER_ENABLED", true) {
+		delivery, err := notify.FromEnv(os.Getenv)
+		if err != nil {
+			log.Fatalf("notifications: %v", err)
+		}
+		cfg := schedulerConfigFromEnv()
+		sched := &scheduler.Scheduler{DB: conn, Tasks: backgroundTasks(conn, cfg, delivery), Tick: cfg.Tick}
+		go sched.Run(ctx)
+	}
+
+	authn, err := auth.FromEnv(os.Getenv, lookupAPIToken(conn))
+	if err != nil {
+		log.Fatalf("auth: %v", err)
+	}
+	if !authn.Required {
+		log.Printf("auth: AUTH_REQUIRED=false, requests without a token act as the user they name")
+	}
 
 	var rnr *runner.Runner
 	var sessSvc session.Service
+	var routes *router.Router
 	if enableAI {
 		// Allow overriding the model via env (MODEL_NAME); validate and normalize.
 		modelName := os.Getenv("MODEL_NAME")
@@ -91,12 +114,28 @@ func main() {
 		if err != nil {
 			log.Fatalf("networking agent: %v", err)
 		}
-		root, err := agents.NewRootAgent(model, []agent.Agent{jobAgent, codingAgent, projectAgent, networkingAgent})
+		meetingsAgent, err := agents.NewMeetingsAgent(model, conn)
+		if err != nil {
+			log.Fatalf("meetings agent: %v", err)
+		}
+		goalsAgent, err := agents.NewGoalsAgent(model, conn)
+		if err != nil {
+			log.Fatalf("goals agent: %v", err)
+		}
+		routes, err = router.FromEnv(os.Getenv)
+		if err != nil {
+			log.Fatalf("agent routes: %v", err)
+		}
+		root, err := agents.NewRootAgent(model, []agent.Agent{jobAgent, codingAgent, projectAgent, networkingAgent, meetingsAgent, goalsAgent})
 		if err != nil {
 			log.Fatalf("root agent: %v", err)
 		}
 
-		sessSvc = session.InMemoryService()
+		agents.SetPendingWriteStore(ckdb.NewPendingWriteStore(conn))
+		sessSvc, err = newSessionService(conn)
+		if err != nil {
+			log.Fatalf("session store: %v", err)
+		}
 		rnr, err = runner.New(runner.Config{
 			AppName:        "career_koala",
 			Agent:          root,
@@ -110,24 +149,55 @@ func main() {
 	mux := http.NewServeMux()
 	// API only; Next.js UI runs separately.
 	if enableAI {
-		mux.HandleFunc("/chat", chatHandler(rnr, sessSvc, conn))
+		mux.HandleFunc("/chat", chatHandler(rnr, sessSvc, conn, routes))
+		mux.HandleFunc("/chat/stream", chatStreamHandler(rnr, sessSvc, conn, routes))
 	} else {
 		mux.HandleFunc("/chat", chatDisabledHandler())
+		mux.HandleFunc("/chat/stream", chatDisabledHandler())
 	}
 	mux.HandleFunc("/meta", metaHandler(enableAI))
 	mux.HandleFunc("/data", dataHandler(conn))
-	mux.HandleFunc("/jobs", jobCreateHandler(conn))
+	mux.HandleFunc("/analytics/jobs", jobAnalyticsHandler(conn))
+	mux.HandleFunc("/analytics/coding", codingAnalyticsHandler(conn))
+	mux.HandleFunc("/jobs", collectionHandler(listHandler(conn, "jobs", ckdb.ListJobApplicationsPage), jobCreateHandler(conn)))
 	mux.HandleFunc("/jobs/status", jobStatusUpdateHandler(conn))
-	mux.HandleFunc("/coding", codingCreateHandler(conn))
-	mux.HandleFunc("/projects", projectCreateHandler(conn))
-	mux.HandleFunc("/networking", networkingCreateHandler(conn))
-	mux.HandleFunc("/goals", goalUpdateHandler(conn))
+	mux.HandleFunc("/jobs/{id}", itemHandler(conn, jobResource))
+	mux.HandleFunc("/jobs/{id}/history", jobHistoryHandler(conn))
+	mux.HandleFunc("/coding", collectionHandler(listHandler(conn, "coding problems", ckdb.ListCodingProblemsPage), codingCreateHandler(conn)))
+	mux.HandleFunc("/coding/due", codingDueHandler(conn))
+	mux.HandleFunc("/coding/{id}", itemHandler(conn, codingResource))
+	mux.HandleFunc("/coding/{id}/attempts", codingAttemptsHandler(conn))
+	mux.HandleFunc("/projects", collectionHandler(listHandler(conn, "projects", ckdb.ListProjectsPage), projectCreateHandler(conn)))
+	mux.HandleFunc("/projects/{id}", itemHandler(conn, projectResource))
+	mux.HandleFunc("/networking", collectionHandler(listHandler(conn, "contacts", ckdb.ListNetworkingContactsPage), networkingCreateHandler(conn)))
+	mux.HandleFunc("/contacts", collectionHandler(listHandler(conn, "contacts", ckdb.ListNetworkingContactsPage), networkingCreateHandler(conn)))
+	mux.HandleFunc("/contacts/{id}", itemHandler(conn, contactResource))
+	mux.HandleFunc("/meetings", collectionHandler(listHandler(conn, "meetings", ckdb.ListMeetingsPage), meetingCreateHandler(conn)))
+	mux.HandleFunc("/meetings.ics", meetingsCalendarHandler(conn))
+	mux.HandleFunc("/meetings/import", meetingsCalendarImportHandler(conn))
+	mux.HandleFunc("/meetings/{id}", itemHandler(conn, meetingResource))
+	mux.HandleFunc("/import/coding", codingImportHandler(conn))
+	mux.HandleFunc("/import", archiveImportHandler(conn))
+	mux.HandleFunc("/export", exportHandler(conn))
+	mux.HandleFunc("/export/{file}", exportTableHandler(conn))
+	mux.HandleFunc("/goals", goalsHandler(conn))
+	mux.HandleFunc("/goals/recurrences", goalRecurrencesHandler(conn))
+	mux.HandleFunc("/goals/recurrences/{id}", itemHandler(conn, goalRecurrenceResource))
+	mux.HandleFunc("/goals/{type}", goalListHandler(conn))
+	mux.HandleFunc("/goals/{type}/{id}", goalItemHandler(conn))
+	mux.HandleFunc("/me", meHandler(conn))
+	mux.HandleFunc("/notifications", notificationsHandler(conn))
+	mux.HandleFunc("/notifications/read", notificationsReadHandler(conn))
 
 	addr := ":8080"
-	log.Printf("CareerKoala API listening on %s (ai=%t).", addr, enableAI)
-	log.Fatal(http.ListenAndServe(addr, withCORS(mux)))
+	log.Printf("CareerKoala API listening on %s (ai=%t, auth_required=%t, jwt=%t).", addr, enableAI, authn.Required, authn.JWT != nil)
+	handler := withCORS(corsOriginsFromEnv(), authn.Middleware(withUser(conn, !authn.Required, mux)))
+	log.Fatal(http.ListenAndServe(addr, handler))
 }
 
+// chatTimeout bounds one agent run.
+const chatTimeout = 60 * time.Second
+
 type chatRequest struct {
 	UserID    string `json:"user_id"`
 	SessionID string `json:"session_id"`
@@ -137,109 +207,160 @@ type chatRequest struct {
 type chatResponse struct {
 	SessionID string   `json:"session_id"`
 	Replies   []string `json:"replies"`
-	Error     string   `json:"error,omitempty"`
+	// AnsweredBy is the agent that wrote the last reply; Trace lists the
+	// replies, tool calls and transfers of the run in order.
+	AnsweredBy string      `json:"answered_by,omitempty"`
+	Trace      []traceStep `json:"trace,omitempty"`
+	Error      string      `json:"error,omitempty"`
 }
 
 type errorResponse struct {
 	Error string `json:"error"`
 }
 
-func chatHandler(rnr *runner.Runner, sessSvc session.Service, dbConn *sql.DB) http.HandlerFunc {
+func chatHandler(rnr *runner.Runner, sessSvc session.Service, dbConn *sql.DB, routes *router.Router) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			return
 		}
-		var req chatRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			writeError(w, http.StatusBadRequest, "invalid json")
-			return
-		}
-		if req.Message == "" {
-			writeError(w, http.StatusBadRequest, "message is required")
+		req, userCtx, ok := prepareChat(w, r, sessSvc, dbConn, routes)
+		if !ok {
 			return
 		}
-		req.Message = normalizeAgentHint(req.Message)
-		log.Printf("chat request user=%s session=%s msg=%q", req.UserID, req.SessionID, req.Message)
-		if req.UserID == "" {
-			req.UserID = "demo_user"
-		}
-
-		if req.SessionID == "" {
-			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-			defer cancel()
-			createResp, err := sessSvc.Create(ctx, &session.CreateRequest{
-				AppName: "career_koala",
-				UserID:  req.UserID,
-			})
-			if err != nil {
-				writeError(w, http.StatusInternalServerError, "failed to create session")
-				return
-			}
-			req.SessionID = createResp.Session.ID()
-		} else {
-			ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-			defer cancel()
-			_, err := sessSvc.Get(ctx, &session.GetRequest{
-				AppName:   "career_koala",
-				UserID:    req.UserID,
-				SessionID: req.SessionID,
-			})
-			if err != nil {
-				createResp, cerr := sessSvc.Create(ctx, &session.CreateRequest{
-					AppName:   "career_koala",
-					UserID:    req.UserID,
-					SessionID: req.SessionID,
-				})
-				if cerr != nil {
-					writeError(w, http.StatusInternalServerError, "failed to create session")
-					return
-				}
-				req.SessionID = createResp.Session.ID()
-			}
-		}
 
-		if reply, handled := agents.HandlePendingWrite(r.Context(), req.UserID, req.SessionID, req.Message, dbConn); handled {
+		if reply, handled := agents.HandlePendingWrite(userCtx, req.UserID, req.SessionID, req.Message, dbConn); handled {
 			writeJSON(w, chatResponse{SessionID: req.SessionID, Replies: []string{reply}})
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+		ctx, cancel := context.WithTimeout(userCtx, chatTimeout)
 		defer cancel()
+		trace := newChatTrace(time.Now())
 		seq := rnr.Run(ctx, req.UserID, req.SessionID, &genai.Content{
 			Parts: []*genai.Part{{Text: req.Message}},
 		}, agent.RunConfig{})
 
-		var replies []string
 		for event, err := range seq {
 			if err != nil {
 				log.Printf("chat error user=%s session=%s: %v", req.UserID, req.SessionID, err)
 				writeError(w, http.StatusInternalServerError, err.Error())
 				return
 			}
-			if event == nil || event.LLMResponse.Content == nil {
-				continue
-			}
-			if event.Author == "user" {
-				continue
-			}
-			for _, part := range event.LLMResponse.Content.Parts {
-				if part == nil {
-					continue
-				}
-				if part.Text != "" {
-					replies = append(replies, part.Text)
-				}
-			}
+			trace.observe(event, time.Now())
 		}
 
-		if prompt, ok := agents.MaybeCaptureWrite(req.UserID, req.SessionID, replies); ok {
-			writeJSON(w, chatResponse{SessionID: req.SessionID, Replies: []string{prompt}})
-			return
+		if prompt, ok := agents.MaybeCaptureWrite(userCtx, req.UserID, req.SessionID, trace.replies, dbConn); ok {
+			trace.confirm(prompt, time.Now())
 		}
+		log.Printf("chat user=%s session=%s: %s", req.UserID, req.SessionID, trace.summary())
+		writeJSON(w, trace.response(req.SessionID))
+	}
+}
 
-		writeJSON(w, chatResponse{SessionID: req.SessionID, Replies: replies})
+// prepareChat decodes a chat request, prefixes the message with the hint of
+// the agent the router picks, resolves its user and loads or creates its
+// session. It writes the error response itself and returns false when the
+// request can't go ahead; otherwise ctx is scoped to the user.
+func prepareChat(w http.ResponseWriter, r *http.Request, sessSvc session.Service, dbConn *sql.DB, routes *router.Router) (chatRequest, context.Context, bool) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid json")
+		return req, nil, false
+	}
+	if req.Message == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return req, nil, false
+	}
+	// Below the routing threshold the root agent picks the agent itself.
+	if route, ok := routes.Route(req.Message); ok {
+		log.Printf("chat route %s confidence=%.2f matched=%q", route.Agent, route.Confidence, route.Matched)
+		req.Message = route.Hint(req.Message)
 	}
+	log.Printf("chat request user=%s session=%s msg=%q", req.UserID, req.SessionID, req.Message)
+	// An authenticated caller always chats as themselves; user_id in the
+	// body only names the user when auth is disabled.
+	var u ckdb.User
+	var err error
+	if _, ok := auth.FromContext(r.Context()); ok || req.UserID == "" || !namesTrusted(r.Context()) {
+		u, err = requestUser(r.Context(), dbConn, r)
+	} else {
+		qctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		u, err = ckdb.EnsureUser(qctx, dbConn, req.UserID)
+		cancel()
+	}
+	if err != nil {
+		var fieldErr *ckdb.FieldError
+		if errors.As(err, &fieldErr) {
+			writeError(w, http.StatusBadRequest, "user_id: "+fieldErr.Error())
+			return req, nil, false
+		}
+		log.Printf("resolve user=%s: %v", req.UserID, err)
+		writeError(w, http.StatusInternalServerError, "failed to resolve user")
+		return req, nil, false
+	}
+	req.UserID = u.Name
+	userCtx := ckdb.WithUser(r.Context(), u.ID)
+
+	if req.SessionID == "" {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		createResp, err := sessSvc.Create(ctx, &session.CreateRequest{
+			AppName: "career_koala",
+			UserID:  req.UserID,
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to create session")
+			return req, nil, false
+		}
+		req.SessionID = createResp.Session.ID()
+	} else {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		_, err := sessSvc.Get(ctx, &session.GetRequest{
+			AppName:         "career_koala",
+			UserID:          req.UserID,
+			SessionID:       req.SessionID,
+			NumRecentEvents: 1,
+		})
+		if err != nil {
+			if !isSessionNotFound(err) {
+				log.Printf("load session user=%s session=%s: %v", req.UserID, req.SessionID, err)
+				writeError(w, http.StatusInternalServerError, "failed to load session")
+				return req, nil, false
+			}
+			createResp, cerr := sessSvc.Create(ctx, &session.CreateRequest{
+				AppName:   "career_koala",
+				UserID:    req.UserID,
+				SessionID: req.SessionID,
+			})
+			if cerr != nil {
+				writeError(w, http.StatusInternalServerError, "failed to create session")
+				return req, nil, false
+			}
+			req.SessionID = createResp.Session.ID()
+		}
+	}
+	return req, userCtx, true
+}
+
+// newSessionService picks the ADK session store from SESSION_STORE:
+// "postgres" keeps chat history in the database, "memory" (default) keeps it
+// in-process only.
+func newSessionService(dbConn *sql.DB) (session.Service, error) {
+	switch store := strings.TrimSpace(strings.ToLower(os.Getenv("SESSION_STORE"))); store {
+	case "", "memory":
+		return session.InMemoryService(), nil
+	case "postgres", "db":
+		return ckdb.NewSessionService(dbConn), nil
+	default:
+		return nil, fmt.Errorf("unknown SESSION_STORE %q (use memory|postgres)", store)
+	}
+}
+
+func isSessionNotFound(err error) bool {
+	// The in-memory service has no sentinel error, only a "not found" message.
+	return errors.Is(err, ckdb.ErrSessionNotFound) || strings.Contains(err.Error(), "not found")
 }
 
 func chatDisabledHandler() http.HandlerFunc {
@@ -299,11 +420,29 @@ func dataHandler(dbConn *sql.DB) http.HandlerFunc {
 	}
 }
 
-func withCORS(next http.Handler) http.Handler {
+// corsOriginsFromEnv reads CORS_ALLOWED_ORIGINS, a comma-separated list of
+// origins allowed to call the API (default "*", any origin).
+func corsOriginsFromEnv() []string {
+	var origins []string
+	for _, o := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	if len(origins) == 0 {
+		return []string{"*"}
+	}
+	return origins
+}
+
+func withCORS(origins []string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		if allowed := corsOrigin(origins, r.Header.Get("Origin")); allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+		}
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, "+userHeader)
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -312,40 +451,18 @@ func withCORS(next http.Handler) http.Handler {
 	})
 }
 
-func normalizeAgentHint(msg string) string {
-	trim := strings.TrimSpace(strings.ToLower(msg))
-	// If user already sent an explicit hint, leave as-is.
-	if strings.Contains(trim, "[agent:") || strings.Contains(trim, "option 1") || strings.Contains(trim, "option 2") || strings.Contains(trim, "option 3") || strings.Contains(trim, "option 4") {
-		return msg
-	}
-
-	// Short direct responses.
-	switch trim {
-	case "1", "jobs", "job", "job applications", "job application":
-		return "Option 1 (Jobs): " + msg
-	case "2", "coding", "code", "leetcode", "problem":
-		return "Option 2 (Coding): " + msg
-	case "3", "projects", "project":
-		return "Option 3 (Projects): " + msg
-	case "4", "networking", "network":
-		return "Option 4 (Networking): " + msg
-	}
-
-	// Keyword-based routing for full sentences.
-	if strings.Contains(trim, "job") || strings.Contains(trim, "application") || strings.Contains(trim, "resume") {
-		return "Option 1 (Jobs): " + msg
-	}
-	if strings.Contains(trim, "code") || strings.Contains(trim, "leetcode") || strings.Contains(trim, "problem") {
-		return "Option 2 (Coding): " + msg
-	}
-	if strings.Contains(trim, "project") || strings.Contains(trim, "repo") || strings.Contains(trim, "portfolio") {
-		return "Option 3 (Projects): " + msg
-	}
-	if strings.Contains(trim, "network") || strings.Contains(trim, "contact") || strings.Contains(trim, "coffee chat") {
-		return "Option 4 (Networking): " + msg
+// corsOrigin returns the Access-Control-Allow-Origin value for a request
+// from origin, or "" when it isn't allowed.
+func corsOrigin(origins []string, origin string) string {
+	for _, o := range origins {
+		if o == "*" {
+			return "*"
+		}
+		if origin != "" && strings.EqualFold(o, origin) {
+			return origin
+		}
 	}
-
-	return msg
+	return ""
 }
 
 func boolFromEnv(key string, defaultVal bool) bool {
@@ -529,7 +646,7 @@ func jobCreateHandler(dbConn *sql.DB) http.HandlerFunc {
 			ResultDate: resultDate,
 		})
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, "failed to create job")
+			writeItemError(w, err, "job", "create")
 			return
 		}
 		writeJSON(w, map[string]any{"id": id})
@@ -558,15 +675,7 @@ func jobStatusUpdateHandler(dbConn *sql.DB) http.HandlerFunc {
 		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
 		defer cancel()
 		if err := ckdb.UpdateJobStatus(ctx, dbConn, req.ID, req.Status); err != nil {
-			if err == sql.ErrNoRows {
-				writeError(w, http.StatusNotFound, "job not found")
-				return
-			}
-			if strings.Contains(err.Error(), "status is required") {
-				writeError(w, http.StatusBadRequest, "status is required")
-				return
-			}
-			writeError(w, http.StatusInternalServerError, "failed to update job status")
+			writeItemError(w, err, "job", "update status of")
 			return
 		}
 		writeJSON(w, map[string]any{"status": "updated"})
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS pending_writes (
+    id SERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    raw_json TEXT NOT NULL,
+    summary TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','applied','declined','expired','failed')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ
+);
+
+-- At most one open confirmation per chat session.
+CREATE UNIQUE INDEX IF NOT EXISTS pending_writes_open_idx
+    ON pending_writes (user_id, session_id)
+    WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS pending_writes;
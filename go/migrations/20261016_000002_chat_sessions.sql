@@ -0,0 +1,45 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS chat_sessions (
+    app_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    id TEXT NOT NULL,
+    state JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_name, user_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS chat_events (
+    seq BIGSERIAL PRIMARY KEY,
+    id TEXT NOT NULL,
+    app_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    invocation_id TEXT,
+    author TEXT,
+    event_time TIMESTAMPTZ NOT NULL,
+    event JSONB NOT NULL,
+    FOREIGN KEY (app_name, user_id, session_id) REFERENCES chat_sessions(app_name, user_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS chat_events_session_idx ON chat_events (app_name, user_id, session_id, event_time);
+
+CREATE TABLE IF NOT EXISTS chat_app_states (
+    app_name TEXT PRIMARY KEY,
+    state JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS chat_user_states (
+    app_name TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    state JSONB NOT NULL DEFAULT '{}'::jsonb,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (app_name, user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS chat_user_states;
+DROP TABLE IF EXISTS chat_app_states;
+DROP TABLE IF EXISTS chat_events;
+DROP TABLE IF EXISTS chat_sessions;
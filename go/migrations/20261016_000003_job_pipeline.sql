@@ -0,0 +1,69 @@
+-- +goose Up
+-- Map free-text statuses onto the pipeline stages; anything unrecognised
+-- falls back to applied/saved depending on whether it has an applied date.
+UPDATE job_applications SET status = CASE
+    WHEN lower(status) LIKE '%reject%' OR lower(status) = 'declined' THEN 'rejected'
+    WHEN lower(status) IN ('saved','wishlist','interested') THEN 'saved'
+    WHEN lower(status) IN ('applied','submitted','pending') THEN 'applied'
+    WHEN lower(status) LIKE '%screen%' OR lower(status) = 'recruiter call' THEN 'recruiter_screen'
+    WHEN lower(status) IN ('technical','interview','interviewing','technical interview') THEN 'technical'
+    WHEN lower(status) IN ('onsite','on-site','on site','final round') THEN 'onsite'
+    WHEN lower(status) IN ('offer','offered','offer received') THEN 'offer'
+    WHEN lower(status) = 'accepted' THEN 'accepted'
+    WHEN lower(status) IN ('withdrawn','withdrew') THEN 'withdrawn'
+    WHEN lower(status) IN ('ghosted','no response') THEN 'ghosted'
+    WHEN applied_date IS NOT NULL THEN 'applied'
+    ELSE 'saved'
+END;
+
+ALTER TABLE job_applications
+    ALTER COLUMN status SET DEFAULT 'saved',
+    ALTER COLUMN status SET NOT NULL,
+    ADD CONSTRAINT job_applications_status_check CHECK (status IN (
+        'saved','applied','recruiter_screen','technical','onsite',
+        'offer','accepted','rejected','withdrawn','ghosted'));
+
+CREATE TABLE IF NOT EXISTS job_status_history (
+    id BIGSERIAL PRIMARY KEY,
+    job_application_id INT NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+    from_status TEXT,
+    to_status TEXT NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS job_status_history_job_idx
+    ON job_status_history (job_application_id, changed_at);
+
+-- Existing applications start their history at their current stage.
+INSERT INTO job_status_history (job_application_id, from_status, to_status, changed_at)
+SELECT id, NULL, status, COALESCE(applied_date::timestamptz, now())
+FROM job_applications;
+
+-- Every insert and status change is recorded, whichever code path made it.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_job_status_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO job_status_history (job_application_id, from_status, to_status)
+        VALUES (NEW.id, NULL, NEW.status);
+    ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO job_status_history (job_application_id, from_status, to_status)
+        VALUES (NEW.id, OLD.status, NEW.status);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER job_applications_status_history
+    AFTER INSERT OR UPDATE OF status ON job_applications
+    FOR EACH ROW EXECUTE FUNCTION record_job_status_change();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS job_applications_status_history ON job_applications;
+DROP FUNCTION IF EXISTS record_job_status_change();
+DROP TABLE IF EXISTS job_status_history;
+ALTER TABLE job_applications
+    DROP CONSTRAINT IF EXISTS job_applications_status_check,
+    ALTER COLUMN status DROP NOT NULL,
+    ALTER COLUMN status DROP DEFAULT;
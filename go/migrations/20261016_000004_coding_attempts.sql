@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS coding_attempts (
+    id SERIAL PRIMARY KEY,
+    coding_problem_id INT NOT NULL REFERENCES coding_problems(id) ON DELETE CASCADE,
+    attempted_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    outcome TEXT NOT NULL CHECK (outcome IN ('solved','hints','failed')),
+    minutes_spent INT CHECK (minutes_spent >= 0),
+    confidence INT CHECK (confidence BETWEEN 0 AND 5),
+    notes TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS coding_attempts_problem_idx
+    ON coding_attempts (coding_problem_id, attempted_on);
+
+-- +goose Down
+DROP TABLE IF EXISTS coding_attempts;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- UID of the calendar event a meeting was imported from, so re-importing the
+-- same .ics file updates the meeting instead of duplicating it.
+ALTER TABLE meetings ADD COLUMN IF NOT EXISTS ical_uid TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS meetings_ical_uid_idx
+    ON meetings (ical_uid)
+    WHERE ical_uid IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS meetings_ical_uid_idx;
+ALTER TABLE meetings DROP COLUMN IF EXISTS ical_uid;
@@ -0,0 +1,31 @@
+-- +goose Up
+ALTER TABLE meetings
+    ADD COLUMN IF NOT EXISTS job_application_id INT REFERENCES job_applications(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS contact_id INT REFERENCES networking_contacts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS meetings_job_application_idx
+    ON meetings (job_application_id, session_time)
+    WHERE job_application_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS meetings_contact_idx
+    ON meetings (contact_id, session_time)
+    WHERE contact_id IS NOT NULL;
+
+-- Additional attendees; meetings.contact_id is the main person met.
+CREATE TABLE IF NOT EXISTS meeting_contacts (
+    id SERIAL PRIMARY KEY,
+    meeting_id INT NOT NULL REFERENCES meetings(id) ON DELETE CASCADE,
+    contact_id INT NOT NULL REFERENCES networking_contacts(id) ON DELETE CASCADE,
+    UNIQUE (meeting_id, contact_id)
+);
+
+CREATE INDEX IF NOT EXISTS meeting_contacts_contact_idx
+    ON meeting_contacts (contact_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS meeting_contacts;
+DROP INDEX IF EXISTS meetings_contact_idx;
+DROP INDEX IF EXISTS meetings_job_application_idx;
+ALTER TABLE meetings
+    DROP COLUMN IF EXISTS contact_id,
+    DROP COLUMN IF EXISTS job_application_id;
@@ -0,0 +1,138 @@
+-- +goose Up
+CREATE TYPE goal_period AS ENUM ('daily', 'weekly', 'monthly');
+
+-- A rule that creates one goal per matching period, e.g. "solve 2 problems
+-- every weekday". weekdays (ISO, 1 = Monday) only applies to daily rules;
+-- NULL means every day. materialized_through is the last period already
+-- generated, so deleted instances are not recreated.
+CREATE TABLE IF NOT EXISTS goal_recurrences (
+    id SERIAL PRIMARY KEY,
+    period goal_period NOT NULL,
+    description TEXT NOT NULL,
+    target_count INT NOT NULL DEFAULT 1 CHECK (target_count > 0),
+    weekdays SMALLINT[] CHECK (weekdays <@ ARRAY[1,2,3,4,5,6,7]::SMALLINT[]),
+    starts_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    ends_on DATE,
+    active BOOLEAN NOT NULL DEFAULT true,
+    job_application_id INT REFERENCES job_applications(id) ON DELETE SET NULL,
+    coding_problem_id INT REFERENCES coding_problems(id) ON DELETE SET NULL,
+    project_id INT REFERENCES projects(id) ON DELETE SET NULL,
+    contact_id INT REFERENCES networking_contacts(id) ON DELETE SET NULL,
+    materialized_through DATE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- target_date is the day, or the first day of the week or month, the goal
+-- belongs to.
+CREATE TABLE IF NOT EXISTS goals (
+    id SERIAL PRIMARY KEY,
+    period goal_period NOT NULL,
+    description TEXT NOT NULL,
+    target_date DATE NOT NULL,
+    completed BOOLEAN NOT NULL DEFAULT false,
+    target_count INT NOT NULL DEFAULT 1 CHECK (target_count > 0),
+    job_application_id INT REFERENCES job_applications(id) ON DELETE SET NULL,
+    coding_problem_id INT REFERENCES coding_problems(id) ON DELETE SET NULL,
+    project_id INT REFERENCES projects(id) ON DELETE SET NULL,
+    contact_id INT REFERENCES networking_contacts(id) ON DELETE SET NULL,
+    recurrence_id INT REFERENCES goal_recurrences(id) ON DELETE SET NULL,
+    UNIQUE (recurrence_id, target_date)
+);
+
+CREATE INDEX IF NOT EXISTS goals_period_date_idx ON goals (period, target_date);
+
+-- Daily goals keep their ids; weekly and monthly goals are renumbered after
+-- them since the three tables had overlapping ids.
+INSERT INTO goals (id, period, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id)
+SELECT id, 'daily', description, target_date, COALESCE(completed, false), job_application_id, coding_problem_id, project_id, contact_id
+FROM daily_goals;
+
+SELECT setval(pg_get_serial_sequence('goals', 'id'), COALESCE(max(id), 0) + 1, false) FROM goals;
+
+INSERT INTO goals (period, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id)
+SELECT 'weekly', description, week_of, COALESCE(completed, false), job_application_id, coding_problem_id, project_id, contact_id
+FROM weekly_goals ORDER BY id;
+
+INSERT INTO goals (period, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id)
+SELECT 'monthly', description, month_of, COALESCE(completed, false), job_application_id, coding_problem_id, project_id, contact_id
+FROM monthly_goals ORDER BY id;
+
+DROP TABLE daily_goals;
+DROP TABLE weekly_goals;
+DROP TABLE monthly_goals;
+
+-- Compatibility views with the old table shapes. They are simple enough to
+-- be updatable, and the period default makes inserts land in the right
+-- period.
+CREATE VIEW daily_goals AS
+    SELECT id, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id, period
+    FROM goals WHERE period = 'daily'
+    WITH CASCADED CHECK OPTION;
+ALTER VIEW daily_goals ALTER COLUMN period SET DEFAULT 'daily';
+
+CREATE VIEW weekly_goals AS
+    SELECT id, description, target_date AS week_of, completed, job_application_id, coding_problem_id, project_id, contact_id, period
+    FROM goals WHERE period = 'weekly'
+    WITH CASCADED CHECK OPTION;
+ALTER VIEW weekly_goals ALTER COLUMN period SET DEFAULT 'weekly';
+
+CREATE VIEW monthly_goals AS
+    SELECT id, description, target_date AS month_of, completed, job_application_id, coding_problem_id, project_id, contact_id, period
+    FROM goals WHERE period = 'monthly'
+    WITH CASCADED CHECK OPTION;
+ALTER VIEW monthly_goals ALTER COLUMN period SET DEFAULT 'monthly';
+
+-- +goose Down
+DROP VIEW IF EXISTS monthly_goals;
+DROP VIEW IF EXISTS weekly_goals;
+DROP VIEW IF EXISTS daily_goals;
+
+CREATE TABLE IF NOT EXISTS daily_goals (
+    id SERIAL PRIMARY KEY,
+    description TEXT NOT NULL,
+    target_date DATE NOT NULL,
+    completed BOOLEAN DEFAULT false,
+    job_application_id INT REFERENCES job_applications(id) ON DELETE SET NULL,
+    coding_problem_id INT REFERENCES coding_problems(id) ON DELETE SET NULL,
+    project_id INT REFERENCES projects(id) ON DELETE SET NULL,
+    contact_id INT REFERENCES networking_contacts(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS weekly_goals (
+    id SERIAL PRIMARY KEY,
+    description TEXT NOT NULL,
+    week_of DATE NOT NULL,
+    completed BOOLEAN DEFAULT false,
+    job_application_id INT REFERENCES job_applications(id) ON DELETE SET NULL,
+    coding_problem_id INT REFERENCES coding_problems(id) ON DELETE SET NULL,
+    project_id INT REFERENCES projects(id) ON DELETE SET NULL,
+    contact_id INT REFERENCES networking_contacts(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS monthly_goals (
+    id SERIAL PRIMARY KEY,
+    description TEXT NOT NULL,
+    month_of DATE NOT NULL,
+    completed BOOLEAN DEFAULT false,
+    job_application_id INT REFERENCES job_applications(id) ON DELETE SET NULL,
+    coding_problem_id INT REFERENCES coding_problems(id) ON DELETE SET NULL,
+    project_id INT REFERENCES projects(id) ON DELETE SET NULL,
+    contact_id INT REFERENCES networking_contacts(id) ON DELETE SET NULL
+);
+
+INSERT INTO daily_goals (id, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id)
+SELECT id, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id
+FROM goals WHERE period = 'daily';
+SELECT setval(pg_get_serial_sequence('daily_goals', 'id'), COALESCE(max(id), 0) + 1, false) FROM daily_goals;
+
+INSERT INTO weekly_goals (description, week_of, completed, job_application_id, coding_problem_id, project_id, contact_id)
+SELECT description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id
+FROM goals WHERE period = 'weekly' ORDER BY id;
+
+INSERT INTO monthly_goals (description, month_of, completed, job_application_id, coding_problem_id, project_id, contact_id)
+SELECT description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id
+FROM goals WHERE period = 'monthly' ORDER BY id;
+
+DROP TABLE IF EXISTS goals;
+DROP TABLE IF EXISTS goal_recurrences;
+DROP TYPE IF EXISTS goal_period;
@@ -0,0 +1,192 @@
+-- +goose Up
+-- A goal with a metric counts its progress from the other tables over its
+-- day, week or month, e.g. "apply to 5 jobs this week". metric_filter is the
+-- user's qualifier ("DP", "backend"); metric_terms is its lowercase
+-- expansion (pattern aliases included) matched as substrings.
+ALTER TABLE goals
+    ADD COLUMN IF NOT EXISTS metric TEXT CHECK (metric IN ('jobs_applied','problems_solved','problems_attempted','meetings','contacts_met')),
+    ADD COLUMN IF NOT EXISTS metric_filter TEXT,
+    ADD COLUMN IF NOT EXISTS metric_terms TEXT[];
+
+ALTER TABLE goal_recurrences
+    ADD COLUMN IF NOT EXISTS metric TEXT CHECK (metric IN ('jobs_applied','problems_solved','problems_attempted','meetings','contacts_met')),
+    ADD COLUMN IF NOT EXISTS metric_filter TEXT,
+    ADD COLUMN IF NOT EXISTS metric_terms TEXT[];
+
+CREATE INDEX IF NOT EXISTS goals_open_metric_idx
+    ON goals (metric)
+    WHERE metric IS NOT NULL AND NOT completed;
+
+CREATE OR REPLACE FUNCTION goal_period_end(period goal_period, target_date DATE) RETURNS DATE AS $$
+    SELECT (target_date + CASE period
+        WHEN 'daily' THEN interval '1 day'
+        WHEN 'weekly' THEN interval '7 days'
+        ELSE interval '1 month'
+    END)::date
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION matches_goal_terms(val TEXT, terms TEXT[]) RETURNS BOOLEAN AS $$
+    SELECT terms IS NULL OR cardinality(terms) = 0
+        OR EXISTS (SELECT 1 FROM unnest(terms) t WHERE lower(COALESCE(val, '')) LIKE '%' || t || '%')
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Progress of a metric goal within [target_date, goal_period_end); NULL for
+-- goals without a metric.
+CREATE OR REPLACE FUNCTION goal_progress(g goals) RETURNS INT AS $$
+    SELECT (CASE g.metric
+        WHEN 'jobs_applied' THEN (
+            SELECT count(*) FROM job_applications j
+            WHERE j.applied_date >= g.target_date AND j.applied_date < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', j.job_title, j.company), g.metric_terms))
+        WHEN 'problems_solved' THEN (
+            SELECT count(DISTINCT a.coding_problem_id) FROM coding_attempts a JOIN coding_problems p ON p.id = a.coding_problem_id
+            WHERE a.outcome = 'solved'
+              AND a.attempted_on >= g.target_date AND a.attempted_on < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', p.pattern, p.difficulty), g.metric_terms))
+        WHEN 'problems_attempted' THEN (
+            SELECT count(*) FROM coding_attempts a JOIN coding_problems p ON p.id = a.coding_problem_id
+            WHERE a.attempted_on >= g.target_date AND a.attempted_on < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', p.pattern, p.difficulty), g.metric_terms))
+        WHEN 'meetings' THEN (
+            SELECT count(*) FROM meetings m
+            WHERE m.session_time >= g.target_date AND m.session_time < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', m.session_name, m.session_type, m.company), g.metric_terms))
+        WHEN 'contacts_met' THEN (
+            SELECT count(DISTINCT met.contact_id)
+            FROM (SELECT contact_id, session_time FROM meetings WHERE contact_id IS NOT NULL
+                  UNION ALL
+                  SELECT mc.contact_id, m.session_time FROM meeting_contacts mc JOIN meetings m ON m.id = mc.meeting_id) met
+            JOIN networking_contacts c ON c.id = met.contact_id
+            WHERE met.session_time >= g.target_date AND met.session_time < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', c.person_name, c.company, c.position), g.metric_terms))
+    END)::int
+$$ LANGUAGE sql STABLE;
+
+-- Metric goals complete themselves once the count reaches target_count,
+-- whichever code path wrote the rows being counted.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION complete_reached_goals() RETURNS trigger AS $$
+BEGIN
+    UPDATE goals g SET completed = true
+    WHERE g.metric IS NOT NULL AND NOT g.completed AND goal_progress(g) >= g.target_count;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION complete_goal_if_reached() RETURNS trigger AS $$
+BEGIN
+    IF NEW.metric IS NOT NULL AND NOT NEW.completed AND goal_progress(NEW) >= NEW.target_count THEN
+        NEW.completed := true;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER goals_complete_if_reached
+    BEFORE INSERT OR UPDATE OF metric, metric_terms, target_count, target_date ON goals
+    FOR EACH ROW EXECUTE FUNCTION complete_goal_if_reached();
+
+CREATE TRIGGER job_applications_goal_progress
+    AFTER INSERT OR UPDATE ON job_applications
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER coding_problems_goal_progress
+    AFTER UPDATE ON coding_problems
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER coding_attempts_goal_progress
+    AFTER INSERT OR UPDATE ON coding_attempts
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER meetings_goal_progress
+    AFTER INSERT OR UPDATE ON meetings
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER meeting_contacts_goal_progress
+    AFTER INSERT ON meeting_contacts
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+-- Goals linked to a record (without a metric) complete when that record
+-- changes state: the problem is solved, the application moves stage, or a
+-- meeting with the contact is logged. Only goals whose period has started
+-- by then are completed, so next week's review of a problem stays open.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION complete_linked_goals() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'coding_problems' THEN
+        UPDATE goals SET completed = true
+        WHERE coding_problem_id = NEW.id AND metric IS NULL AND NOT completed AND target_date <= CURRENT_DATE;
+    ELSIF TG_TABLE_NAME = 'coding_attempts' THEN
+        UPDATE goals SET completed = true
+        WHERE coding_problem_id = NEW.coding_problem_id AND metric IS NULL AND NOT completed AND target_date <= NEW.attempted_on;
+    ELSIF TG_TABLE_NAME = 'job_applications' THEN
+        UPDATE goals SET completed = true
+        WHERE job_application_id = NEW.id AND metric IS NULL AND NOT completed AND target_date <= CURRENT_DATE;
+    ELSIF TG_TABLE_NAME = 'meetings' THEN
+        UPDATE goals SET completed = true
+        WHERE contact_id = NEW.contact_id AND metric IS NULL AND NOT completed AND target_date <= NEW.session_time::date;
+    ELSIF TG_TABLE_NAME = 'meeting_contacts' THEN
+        UPDATE goals g SET completed = true
+        FROM meetings m
+        WHERE m.id = NEW.meeting_id AND m.session_time IS NOT NULL
+          AND g.contact_id = NEW.contact_id AND g.metric IS NULL AND NOT g.completed AND g.target_date <= m.session_time::date;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER coding_problems_complete_goals
+    AFTER UPDATE OF already_solved ON coding_problems
+    FOR EACH ROW WHEN (NEW.already_solved AND NOT COALESCE(OLD.already_solved, false))
+    EXECUTE FUNCTION complete_linked_goals();
+
+CREATE TRIGGER coding_attempts_complete_goals
+    AFTER INSERT ON coding_attempts
+    FOR EACH ROW WHEN (NEW.outcome = 'solved')
+    EXECUTE FUNCTION complete_linked_goals();
+
+CREATE TRIGGER job_applications_complete_goals
+    AFTER UPDATE OF status ON job_applications
+    FOR EACH ROW WHEN (NEW.status IS DISTINCT FROM OLD.status)
+    EXECUTE FUNCTION complete_linked_goals();
+
+CREATE TRIGGER meetings_complete_goals
+    AFTER INSERT OR UPDATE OF contact_id, session_time ON meetings
+    FOR EACH ROW WHEN (NEW.contact_id IS NOT NULL AND NEW.session_time IS NOT NULL)
+    EXECUTE FUNCTION complete_linked_goals();
+
+CREATE TRIGGER meeting_contacts_complete_goals
+    AFTER INSERT ON meeting_contacts
+    FOR EACH ROW EXECUTE FUNCTION complete_linked_goals();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS meeting_contacts_complete_goals ON meeting_contacts;
+DROP TRIGGER IF EXISTS meetings_complete_goals ON meetings;
+DROP TRIGGER IF EXISTS job_applications_complete_goals ON job_applications;
+DROP TRIGGER IF EXISTS coding_attempts_complete_goals ON coding_attempts;
+DROP TRIGGER IF EXISTS coding_problems_complete_goals ON coding_problems;
+DROP FUNCTION IF EXISTS complete_linked_goals();
+DROP TRIGGER IF EXISTS meeting_contacts_goal_progress ON meeting_contacts;
+DROP TRIGGER IF EXISTS meetings_goal_progress ON meetings;
+DROP TRIGGER IF EXISTS coding_attempts_goal_progress ON coding_attempts;
+DROP TRIGGER IF EXISTS coding_problems_goal_progress ON coding_problems;
+DROP TRIGGER IF EXISTS job_applications_goal_progress ON job_applications;
+DROP TRIGGER IF EXISTS goals_complete_if_reached ON goals;
+DROP FUNCTION IF EXISTS complete_goal_if_reached();
+DROP FUNCTION IF EXISTS complete_reached_goals();
+DROP FUNCTION IF EXISTS goal_progress(goals);
+DROP FUNCTION IF EXISTS matches_goal_terms(TEXT, TEXT[]);
+DROP FUNCTION IF EXISTS goal_period_end(goal_period, DATE);
+DROP INDEX IF EXISTS goals_open_metric_idx;
+ALTER TABLE goal_recurrences
+    DROP COLUMN IF EXISTS metric_terms,
+    DROP COLUMN IF EXISTS metric_filter,
+    DROP COLUMN IF EXISTS metric;
+ALTER TABLE goals
+    DROP COLUMN IF EXISTS metric_terms,
+    DROP COLUMN IF EXISTS metric_filter,
+    DROP COLUMN IF EXISTS metric;
@@ -0,0 +1,50 @@
+-- +goose Up
+-- Reminders and nudges recorded by the background scheduler. key dedupes
+-- repeated runs: one notification per stale stretch, meeting or day.
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT NOT NULL DEFAULT '',
+    key TEXT NOT NULL UNIQUE,
+    job_application_id INT REFERENCES job_applications(id) ON DELETE CASCADE,
+    contact_id INT REFERENCES networking_contacts(id) ON DELETE CASCADE,
+    meeting_id INT REFERENCES meetings(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (created_at DESC) WHERE read_at IS NULL;
+
+-- When each scheduled task last ran, shared by all replicas so a new leader
+-- picks up where the previous one stopped.
+CREATE TABLE IF NOT EXISTS scheduler_runs (
+    task TEXT PRIMARY KEY,
+    last_run_at TIMESTAMPTZ NOT NULL,
+    duration_ms INT NOT NULL DEFAULT 0,
+    last_error TEXT
+);
+
+-- Contacts had no timestamps; updated_at (or a meeting with them) is when a
+-- contact was last touched.
+ALTER TABLE networking_contacts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER networking_contacts_touch
+    BEFORE UPDATE ON networking_contacts
+    FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS networking_contacts_touch ON networking_contacts;
+DROP FUNCTION IF EXISTS touch_updated_at();
+ALTER TABLE networking_contacts DROP COLUMN IF EXISTS updated_at;
+DROP TABLE IF EXISTS scheduler_runs;
+DROP TABLE IF EXISTS notifications;
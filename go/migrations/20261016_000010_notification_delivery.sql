@@ -0,0 +1,9 @@
+-- +goose Up
+-- When a notification was sent out (email/webhook); NULL until then.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS notifications_undelivered_idx ON notifications (created_at) WHERE delivered_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS notifications_undelivered_idx;
+ALTER TABLE notifications DROP COLUMN IF EXISTS delivered_at;
@@ -0,0 +1,358 @@
+-- +goose Up
+-- Accounts sharing one deployment. name is the external identity (the chat
+-- user_id, later a token subject); every domain row belongs to one user.
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Everything recorded so far belongs to the single user the app had.
+INSERT INTO users (id, name) VALUES (1, 'demo_user');
+SELECT setval(pg_get_serial_sequence('users', 'id'), 1);
+
+-- The default only backfills existing rows; it is dropped below so a write
+-- that forgets the owner fails instead of landing on the demo user.
+ALTER TABLE job_applications ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE job_status_history ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE coding_problems ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE coding_attempts ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE projects ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE networking_contacts ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE meetings ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE meeting_contacts ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE goal_recurrences ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE goals ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+ALTER TABLE notifications ADD COLUMN user_id INT NOT NULL DEFAULT 1 REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE job_applications ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE job_status_history ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE coding_problems ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE coding_attempts ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE projects ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE networking_contacts ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE meetings ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE meeting_contacts ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE goal_recurrences ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE goals ALTER COLUMN user_id DROP DEFAULT;
+ALTER TABLE notifications ALTER COLUMN user_id DROP DEFAULT;
+
+-- (user_id, id) keys let the links between tables include the owner, so a
+-- row can only point at rows of the same user. They also serve as the
+-- per-user index of each table.
+ALTER TABLE job_applications ADD CONSTRAINT job_applications_user_id_id_key UNIQUE (user_id, id);
+ALTER TABLE coding_problems ADD CONSTRAINT coding_problems_user_id_id_key UNIQUE (user_id, id);
+ALTER TABLE projects ADD CONSTRAINT projects_user_id_id_key UNIQUE (user_id, id);
+ALTER TABLE networking_contacts ADD CONSTRAINT networking_contacts_user_id_id_key UNIQUE (user_id, id);
+ALTER TABLE meetings ADD CONSTRAINT meetings_user_id_id_key UNIQUE (user_id, id);
+ALTER TABLE goal_recurrences ADD CONSTRAINT goal_recurrences_user_id_id_key UNIQUE (user_id, id);
+
+-- SET NULL only clears the link column; user_id stays.
+ALTER TABLE job_status_history
+    DROP CONSTRAINT job_status_history_job_application_id_fkey,
+    ADD CONSTRAINT job_status_history_job_application_id_fkey FOREIGN KEY (user_id, job_application_id)
+        REFERENCES job_applications (user_id, id) ON DELETE CASCADE;
+
+ALTER TABLE coding_attempts
+    DROP CONSTRAINT coding_attempts_coding_problem_id_fkey,
+    ADD CONSTRAINT coding_attempts_coding_problem_id_fkey FOREIGN KEY (user_id, coding_problem_id)
+        REFERENCES coding_problems (user_id, id) ON DELETE CASCADE;
+
+ALTER TABLE meetings
+    DROP CONSTRAINT meetings_job_application_id_fkey,
+    DROP CONSTRAINT meetings_contact_id_fkey,
+    ADD CONSTRAINT meetings_job_application_id_fkey FOREIGN KEY (user_id, job_application_id)
+        REFERENCES job_applications (user_id, id) ON DELETE SET NULL (job_application_id),
+    ADD CONSTRAINT meetings_contact_id_fkey FOREIGN KEY (user_id, contact_id)
+        REFERENCES networking_contacts (user_id, id) ON DELETE SET NULL (contact_id);
+
+ALTER TABLE meeting_contacts
+    DROP CONSTRAINT meeting_contacts_meeting_id_fkey,
+    DROP CONSTRAINT meeting_contacts_contact_id_fkey,
+    ADD CONSTRAINT meeting_contacts_meeting_id_fkey FOREIGN KEY (user_id, meeting_id)
+        REFERENCES meetings (user_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT meeting_contacts_contact_id_fkey FOREIGN KEY (user_id, contact_id)
+        REFERENCES networking_contacts (user_id, id) ON DELETE CASCADE;
+
+ALTER TABLE goal_recurrences
+    DROP CONSTRAINT goal_recurrences_job_application_id_fkey,
+    DROP CONSTRAINT goal_recurrences_coding_problem_id_fkey,
+    DROP CONSTRAINT goal_recurrences_project_id_fkey,
+    DROP CONSTRAINT goal_recurrences_contact_id_fkey,
+    ADD CONSTRAINT goal_recurrences_job_application_id_fkey FOREIGN KEY (user_id, job_application_id)
+        REFERENCES job_applications (user_id, id) ON DELETE SET NULL (job_application_id),
+    ADD CONSTRAINT goal_recurrences_coding_problem_id_fkey FOREIGN KEY (user_id, coding_problem_id)
+        REFERENCES coding_problems (user_id, id) ON DELETE SET NULL (coding_problem_id),
+    ADD CONSTRAINT goal_recurrences_project_id_fkey FOREIGN KEY (user_id, project_id)
+        REFERENCES projects (user_id, id) ON DELETE SET NULL (project_id),
+    ADD CONSTRAINT goal_recurrences_contact_id_fkey FOREIGN KEY (user_id, contact_id)
+        REFERENCES networking_contacts (user_id, id) ON DELETE SET NULL (contact_id);
+
+ALTER TABLE goals
+    DROP CONSTRAINT goals_job_application_id_fkey,
+    DROP CONSTRAINT goals_coding_problem_id_fkey,
+    DROP CONSTRAINT goals_project_id_fkey,
+    DROP CONSTRAINT goals_contact_id_fkey,
+    DROP CONSTRAINT goals_recurrence_id_fkey,
+    ADD CONSTRAINT goals_job_application_id_fkey FOREIGN KEY (user_id, job_application_id)
+        REFERENCES job_applications (user_id, id) ON DELETE SET NULL (job_application_id),
+    ADD CONSTRAINT goals_coding_problem_id_fkey FOREIGN KEY (user_id, coding_problem_id)
+        REFERENCES coding_problems (user_id, id) ON DELETE SET NULL (coding_problem_id),
+    ADD CONSTRAINT goals_project_id_fkey FOREIGN KEY (user_id, project_id)
+        REFERENCES projects (user_id, id) ON DELETE SET NULL (project_id),
+    ADD CONSTRAINT goals_contact_id_fkey FOREIGN KEY (user_id, contact_id)
+        REFERENCES networking_contacts (user_id, id) ON DELETE SET NULL (contact_id),
+    ADD CONSTRAINT goals_recurrence_id_fkey FOREIGN KEY (user_id, recurrence_id)
+        REFERENCES goal_recurrences (user_id, id) ON DELETE SET NULL (recurrence_id);
+
+ALTER TABLE notifications
+    DROP CONSTRAINT notifications_job_application_id_fkey,
+    DROP CONSTRAINT notifications_contact_id_fkey,
+    DROP CONSTRAINT notifications_meeting_id_fkey,
+    ADD CONSTRAINT notifications_job_application_id_fkey FOREIGN KEY (user_id, job_application_id)
+        REFERENCES job_applications (user_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT notifications_contact_id_fkey FOREIGN KEY (user_id, contact_id)
+        REFERENCES networking_contacts (user_id, id) ON DELETE CASCADE,
+    ADD CONSTRAINT notifications_meeting_id_fkey FOREIGN KEY (user_id, meeting_id)
+        REFERENCES meetings (user_id, id) ON DELETE CASCADE;
+
+-- Calendar UIDs and notification keys are unique per user.
+DROP INDEX IF EXISTS meetings_ical_uid_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS meetings_ical_uid_idx
+    ON meetings (user_id, ical_uid)
+    WHERE ical_uid IS NOT NULL;
+
+ALTER TABLE notifications
+    DROP CONSTRAINT notifications_key_key,
+    ADD CONSTRAINT notifications_user_id_key_key UNIQUE (user_id, key);
+
+DROP INDEX IF EXISTS notifications_unread_idx;
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (user_id, created_at DESC) WHERE read_at IS NULL;
+
+DROP INDEX IF EXISTS goals_period_date_idx;
+CREATE INDEX IF NOT EXISTS goals_period_date_idx ON goals (user_id, period, target_date);
+
+-- The compatibility views expose the owner so generic updates can scope on it.
+CREATE OR REPLACE VIEW daily_goals AS
+    SELECT id, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id, period, user_id
+    FROM goals WHERE period = 'daily'
+    WITH CASCADED CHECK OPTION;
+
+CREATE OR REPLACE VIEW weekly_goals AS
+    SELECT id, description, target_date AS week_of, completed, job_application_id, coding_problem_id, project_id, contact_id, period, user_id
+    FROM goals WHERE period = 'weekly'
+    WITH CASCADED CHECK OPTION;
+
+CREATE OR REPLACE VIEW monthly_goals AS
+    SELECT id, description, target_date AS month_of, completed, job_application_id, coding_problem_id, project_id, contact_id, period, user_id
+    FROM goals WHERE period = 'monthly'
+    WITH CASCADED CHECK OPTION;
+
+-- History rows belong to the application's owner.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_job_status_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO job_status_history (user_id, job_application_id, from_status, to_status)
+        VALUES (NEW.user_id, NEW.id, NULL, NEW.status);
+    ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO job_status_history (user_id, job_application_id, from_status, to_status)
+        VALUES (NEW.user_id, NEW.id, OLD.status, NEW.status);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- A goal only counts its owner's rows.
+CREATE OR REPLACE FUNCTION goal_progress(g goals) RETURNS INT AS $$
+    SELECT (CASE g.metric
+        WHEN 'jobs_applied' THEN (
+            SELECT count(*) FROM job_applications j
+            WHERE j.user_id = g.user_id
+              AND j.applied_date >= g.target_date AND j.applied_date < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', j.job_title, j.company), g.metric_terms))
+        WHEN 'problems_solved' THEN (
+            SELECT count(DISTINCT a.coding_problem_id) FROM coding_attempts a JOIN coding_problems p ON p.id = a.coding_problem_id
+            WHERE a.user_id = g.user_id AND a.outcome = 'solved'
+              AND a.attempted_on >= g.target_date AND a.attempted_on < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', p.pattern, p.difficulty), g.metric_terms))
+        WHEN 'problems_attempted' THEN (
+            SELECT count(*) FROM coding_attempts a JOIN coding_problems p ON p.id = a.coding_problem_id
+            WHERE a.user_id = g.user_id
+              AND a.attempted_on >= g.target_date AND a.attempted_on < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', p.pattern, p.difficulty), g.metric_terms))
+        WHEN 'meetings' THEN (
+            SELECT count(*) FROM meetings m
+            WHERE m.user_id = g.user_id
+              AND m.session_time >= g.target_date AND m.session_time < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', m.session_name, m.session_type, m.company), g.metric_terms))
+        WHEN 'contacts_met' THEN (
+            SELECT count(DISTINCT met.contact_id)
+            FROM (SELECT contact_id, session_time FROM meetings WHERE contact_id IS NOT NULL AND user_id = g.user_id
+                  UNION ALL
+                  SELECT mc.contact_id, m.session_time FROM meeting_contacts mc JOIN meetings m ON m.id = mc.meeting_id
+                  WHERE mc.user_id = g.user_id) met
+            JOIN networking_contacts c ON c.id = met.contact_id
+            WHERE met.session_time >= g.target_date AND met.session_time < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', c.person_name, c.company, c.position), g.metric_terms))
+    END)::int
+$$ LANGUAGE sql STABLE;
+
+-- +goose Down
+CREATE OR REPLACE FUNCTION goal_progress(g goals) RETURNS INT AS $$
+    SELECT (CASE g.metric
+        WHEN 'jobs_applied' THEN (
+            SELECT count(*) FROM job_applications j
+            WHERE j.applied_date >= g.target_date AND j.applied_date < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', j.job_title, j.company), g.metric_terms))
+        WHEN 'problems_solved' THEN (
+            SELECT count(DISTINCT a.coding_problem_id) FROM coding_attempts a JOIN coding_problems p ON p.id = a.coding_problem_id
+            WHERE a.outcome = 'solved'
+              AND a.attempted_on >= g.target_date AND a.attempted_on < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', p.pattern, p.difficulty), g.metric_terms))
+        WHEN 'problems_attempted' THEN (
+            SELECT count(*) FROM coding_attempts a JOIN coding_problems p ON p.id = a.coding_problem_id
+            WHERE a.attempted_on >= g.target_date AND a.attempted_on < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', p.pattern, p.difficulty), g.metric_terms))
+        WHEN 'meetings' THEN (
+            SELECT count(*) FROM meetings m
+            WHERE m.session_time >= g.target_date AND m.session_time < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', m.session_name, m.session_type, m.company), g.metric_terms))
+        WHEN 'contacts_met' THEN (
+            SELECT count(DISTINCT met.contact_id)
+            FROM (SELECT contact_id, session_time FROM meetings WHERE contact_id IS NOT NULL
+                  UNION ALL
+                  SELECT mc.contact_id, m.session_time FROM meeting_contacts mc JOIN meetings m ON m.id = mc.meeting_id) met
+            JOIN networking_contacts c ON c.id = met.contact_id
+            WHERE met.session_time >= g.target_date AND met.session_time < goal_period_end(g.period, g.target_date)
+              AND matches_goal_terms(concat_ws(' ', c.person_name, c.company, c.position), g.metric_terms))
+    END)::int
+$$ LANGUAGE sql STABLE;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION record_job_status_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO job_status_history (job_application_id, from_status, to_status)
+        VALUES (NEW.id, NULL, NEW.status);
+    ELSIF NEW.status IS DISTINCT FROM OLD.status THEN
+        INSERT INTO job_status_history (job_application_id, from_status, to_status)
+        VALUES (NEW.id, OLD.status, NEW.status);
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP VIEW IF EXISTS daily_goals;
+DROP VIEW IF EXISTS weekly_goals;
+DROP VIEW IF EXISTS monthly_goals;
+
+CREATE VIEW daily_goals AS
+    SELECT id, description, target_date, completed, job_application_id, coding_problem_id, project_id, contact_id, period
+    FROM goals WHERE period = 'daily'
+    WITH CASCADED CHECK OPTION;
+ALTER VIEW daily_goals ALTER COLUMN period SET DEFAULT 'daily';
+
+CREATE VIEW weekly_goals AS
+    SELECT id, description, target_date AS week_of, completed, job_application_id, coding_problem_id, project_id, contact_id, period
+    FROM goals WHERE period = 'weekly'
+    WITH CASCADED CHECK OPTION;
+ALTER VIEW weekly_goals ALTER COLUMN period SET DEFAULT 'weekly';
+
+CREATE VIEW monthly_goals AS
+    SELECT id, description, target_date AS month_of, completed, job_application_id, coding_problem_id, project_id, contact_id, period
+    FROM goals WHERE period = 'monthly'
+    WITH CASCADED CHECK OPTION;
+ALTER VIEW monthly_goals ALTER COLUMN period SET DEFAULT 'monthly';
+
+DROP INDEX IF EXISTS goals_period_date_idx;
+CREATE INDEX IF NOT EXISTS goals_period_date_idx ON goals (period, target_date);
+
+DROP INDEX IF EXISTS notifications_unread_idx;
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (created_at DESC) WHERE read_at IS NULL;
+
+-- Keys and UIDs may collide once the users' rows are merged; keep the first.
+DELETE FROM notifications n USING notifications o WHERE n.key = o.key AND n.id > o.id;
+ALTER TABLE notifications
+    DROP CONSTRAINT notifications_user_id_key_key,
+    ADD CONSTRAINT notifications_key_key UNIQUE (key);
+
+UPDATE meetings m SET ical_uid = NULL
+FROM meetings o WHERE m.ical_uid = o.ical_uid AND m.id > o.id;
+DROP INDEX IF EXISTS meetings_ical_uid_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS meetings_ical_uid_idx
+    ON meetings (ical_uid)
+    WHERE ical_uid IS NOT NULL;
+
+ALTER TABLE notifications
+    DROP CONSTRAINT notifications_job_application_id_fkey,
+    DROP CONSTRAINT notifications_contact_id_fkey,
+    DROP CONSTRAINT notifications_meeting_id_fkey,
+    ADD CONSTRAINT notifications_job_application_id_fkey FOREIGN KEY (job_application_id) REFERENCES job_applications(id) ON DELETE CASCADE,
+    ADD CONSTRAINT notifications_contact_id_fkey FOREIGN KEY (contact_id) REFERENCES networking_contacts(id) ON DELETE CASCADE,
+    ADD CONSTRAINT notifications_meeting_id_fkey FOREIGN KEY (meeting_id) REFERENCES meetings(id) ON DELETE CASCADE;
+
+ALTER TABLE goals
+    DROP CONSTRAINT goals_job_application_id_fkey,
+    DROP CONSTRAINT goals_coding_problem_id_fkey,
+    DROP CONSTRAINT goals_project_id_fkey,
+    DROP CONSTRAINT goals_contact_id_fkey,
+    DROP CONSTRAINT goals_recurrence_id_fkey,
+    ADD CONSTRAINT goals_job_application_id_fkey FOREIGN KEY (job_application_id) REFERENCES job_applications(id) ON DELETE SET NULL,
+    ADD CONSTRAINT goals_coding_problem_id_fkey FOREIGN KEY (coding_problem_id) REFERENCES coding_problems(id) ON DELETE SET NULL,
+    ADD CONSTRAINT goals_project_id_fkey FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL,
+    ADD CONSTRAINT goals_contact_id_fkey FOREIGN KEY (contact_id) REFERENCES networking_contacts(id) ON DELETE SET NULL,
+    ADD CONSTRAINT goals_recurrence_id_fkey FOREIGN KEY (recurrence_id) REFERENCES goal_recurrences(id) ON DELETE SET NULL;
+
+ALTER TABLE goal_recurrences
+    DROP CONSTRAINT goal_recurrences_job_application_id_fkey,
+    DROP CONSTRAINT goal_recurrences_coding_problem_id_fkey,
+    DROP CONSTRAINT goal_recurrences_project_id_fkey,
+    DROP CONSTRAINT goal_recurrences_contact_id_fkey,
+    ADD CONSTRAINT goal_recurrences_job_application_id_fkey FOREIGN KEY (job_application_id) REFERENCES job_applications(id) ON DELETE SET NULL,
+    ADD CONSTRAINT goal_recurrences_coding_problem_id_fkey FOREIGN KEY (coding_problem_id) REFERENCES coding_problems(id) ON DELETE SET NULL,
+    ADD CONSTRAINT goal_recurrences_project_id_fkey FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL,
+    ADD CONSTRAINT goal_recurrences_contact_id_fkey FOREIGN KEY (contact_id) REFERENCES networking_contacts(id) ON DELETE SET NULL;
+
+ALTER TABLE meeting_contacts
+    DROP CONSTRAINT meeting_contacts_meeting_id_fkey,
+    DROP CONSTRAINT meeting_contacts_contact_id_fkey,
+    ADD CONSTRAINT meeting_contacts_meeting_id_fkey FOREIGN KEY (meeting_id) REFERENCES meetings(id) ON DELETE CASCADE,
+    ADD CONSTRAINT meeting_contacts_contact_id_fkey FOREIGN KEY (contact_id) REFERENCES networking_contacts(id) ON DELETE CASCADE;
+
+ALTER TABLE meetings
+    DROP CONSTRAINT meetings_job_application_id_fkey,
+    DROP CONSTRAINT meetings_contact_id_fkey,
+    ADD CONSTRAINT meetings_job_application_id_fkey FOREIGN KEY (job_application_id) REFERENCES job_applications(id) ON DELETE SET NULL,
+    ADD CONSTRAINT meetings_contact_id_fkey FOREIGN KEY (contact_id) REFERENCES networking_contacts(id) ON DELETE SET NULL;
+
+ALTER TABLE coding_attempts
+    DROP CONSTRAINT coding_attempts_coding_problem_id_fkey,
+    ADD CONSTRAINT coding_attempts_coding_problem_id_fkey FOREIGN KEY (coding_problem_id) REFERENCES coding_problems(id) ON DELETE CASCADE;
+
+ALTER TABLE job_status_history
+    DROP CONSTRAINT job_status_history_job_application_id_fkey,
+    ADD CONSTRAINT job_status_history_job_application_id_fkey FOREIGN KEY (job_application_id) REFERENCES job_applications(id) ON DELETE CASCADE;
+
+ALTER TABLE goal_recurrences DROP CONSTRAINT goal_recurrences_user_id_id_key;
+ALTER TABLE meetings DROP CONSTRAINT meetings_user_id_id_key;
+ALTER TABLE networking_contacts DROP CONSTRAINT networking_contacts_user_id_id_key;
+ALTER TABLE projects DROP CONSTRAINT projects_user_id_id_key;
+ALTER TABLE coding_problems DROP CONSTRAINT coding_problems_user_id_id_key;
+ALTER TABLE job_applications DROP CONSTRAINT job_applications_user_id_id_key;
+
+ALTER TABLE notifications DROP COLUMN IF EXISTS user_id;
+ALTER TABLE goals DROP COLUMN IF EXISTS user_id;
+ALTER TABLE goal_recurrences DROP COLUMN IF EXISTS user_id;
+ALTER TABLE meeting_contacts DROP COLUMN IF EXISTS user_id;
+ALTER TABLE meetings DROP COLUMN IF EXISTS user_id;
+ALTER TABLE networking_contacts DROP COLUMN IF EXISTS user_id;
+ALTER TABLE projects DROP COLUMN IF EXISTS user_id;
+ALTER TABLE coding_attempts DROP COLUMN IF EXISTS user_id;
+ALTER TABLE coding_problems DROP COLUMN IF EXISTS user_id;
+ALTER TABLE job_status_history DROP COLUMN IF EXISTS user_id;
+ALTER TABLE job_applications DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS users;
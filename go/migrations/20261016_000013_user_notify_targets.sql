@@ -0,0 +1,10 @@
+-- +goose Up
+-- Where each user's alerts and daily digest are delivered. NULL means the
+-- user gets no email or webhook (the default user falls back to
+-- NOTIFY_EMAIL_TO and NOTIFY_WEBHOOK_URL).
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_email TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notify_webhook_url TEXT;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS notify_webhook_url;
+ALTER TABLE users DROP COLUMN IF EXISTS notify_email;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- The channels (smtp, webhook) a notification has gone out on. delivered_at
+-- is only set once every channel has it, so a failing channel is retried on
+-- its own instead of resending through the others.
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS delivered_via TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE notifications DROP COLUMN IF EXISTS delivered_via;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- External (OIDC/JWT) identities, keyed by issuer and subject. A JWT caller
+-- is matched to a user only through this table, never by users.name, so a
+-- subject that equals an existing name can't take over that account.
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_identities;
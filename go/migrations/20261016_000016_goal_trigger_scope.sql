@@ -0,0 +1,147 @@
+-- +goose Up
+-- Metric goals are re-checked per written row and only for its owner, and
+-- only the metrics that read the table. Updates fire only for the columns
+-- those metrics read, so editing notes re-checks nothing.
+DROP INDEX IF EXISTS goals_open_metric_idx;
+CREATE INDEX IF NOT EXISTS goals_open_metric_idx
+    ON goals (user_id, metric)
+    WHERE metric IS NOT NULL AND NOT completed;
+
+DROP TRIGGER IF EXISTS job_applications_goal_progress ON job_applications;
+DROP TRIGGER IF EXISTS coding_problems_goal_progress ON coding_problems;
+DROP TRIGGER IF EXISTS coding_attempts_goal_progress ON coding_attempts;
+DROP TRIGGER IF EXISTS meetings_goal_progress ON meetings;
+DROP TRIGGER IF EXISTS meeting_contacts_goal_progress ON meeting_contacts;
+
+-- The trigger arguments are the metrics to re-check.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION complete_reached_goals() RETURNS trigger AS $$
+BEGIN
+    UPDATE goals g SET completed = true
+    WHERE g.user_id = NEW.user_id AND g.metric = ANY (TG_ARGV) AND NOT g.completed
+      AND goal_progress(g) >= g.target_count;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER job_applications_goal_progress
+    AFTER INSERT OR UPDATE OF applied_date, job_title, company ON job_applications
+    FOR EACH ROW WHEN (NEW.applied_date IS NOT NULL)
+    EXECUTE FUNCTION complete_reached_goals('jobs_applied');
+
+CREATE TRIGGER coding_problems_goal_progress
+    AFTER UPDATE OF pattern, difficulty ON coding_problems
+    FOR EACH ROW EXECUTE FUNCTION complete_reached_goals('problems_solved', 'problems_attempted');
+
+CREATE TRIGGER coding_attempts_goal_progress
+    AFTER INSERT OR UPDATE OF coding_problem_id, outcome, attempted_on ON coding_attempts
+    FOR EACH ROW EXECUTE FUNCTION complete_reached_goals('problems_solved', 'problems_attempted');
+
+CREATE TRIGGER meetings_goal_progress
+    AFTER INSERT OR UPDATE OF session_time, session_name, session_type, company, contact_id ON meetings
+    FOR EACH ROW WHEN (NEW.session_time IS NOT NULL)
+    EXECUTE FUNCTION complete_reached_goals('meetings', 'contacts_met');
+
+CREATE TRIGGER meeting_contacts_goal_progress
+    AFTER INSERT ON meeting_contacts
+    FOR EACH ROW EXECUTE FUNCTION complete_reached_goals('contacts_met');
+
+-- A goal linked to a contact completes once a meeting with them has taken
+-- place, not when one is scheduled. Meetings logged ahead of time are
+-- picked up by the complete_met_contact_goals task when their time comes.
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION complete_linked_goals() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'coding_problems' THEN
+        UPDATE goals SET completed = true
+        WHERE coding_problem_id = NEW.id AND metric IS NULL AND NOT completed AND target_date <= CURRENT_DATE;
+    ELSIF TG_TABLE_NAME = 'coding_attempts' THEN
+        UPDATE goals SET completed = true
+        WHERE coding_problem_id = NEW.coding_problem_id AND metric IS NULL AND NOT completed AND target_date <= NEW.attempted_on;
+    ELSIF TG_TABLE_NAME = 'job_applications' THEN
+        UPDATE goals SET completed = true
+        WHERE job_application_id = NEW.id AND metric IS NULL AND NOT completed AND target_date <= CURRENT_DATE;
+    ELSIF TG_TABLE_NAME = 'meetings' THEN
+        UPDATE goals SET completed = true
+        WHERE user_id = NEW.user_id AND contact_id = NEW.contact_id AND metric IS NULL AND NOT completed
+          AND target_date <= NEW.session_time::date AND NEW.session_time <= now();
+    ELSIF TG_TABLE_NAME = 'meeting_contacts' THEN
+        UPDATE goals g SET completed = true
+        FROM meetings m
+        WHERE m.id = NEW.meeting_id AND m.session_time IS NOT NULL AND m.session_time <= now()
+          AND g.user_id = NEW.user_id AND g.contact_id = NEW.contact_id AND g.metric IS NULL AND NOT g.completed
+          AND g.target_date <= m.session_time::date;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION complete_linked_goals() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'coding_problems' THEN
+        UPDATE goals SET completed = true
+        WHERE coding_problem_id = NEW.id AND metric IS NULL AND NOT completed AND target_date <= CURRENT_DATE;
+    ELSIF TG_TABLE_NAME = 'coding_attempts' THEN
+        UPDATE goals SET completed = true
+        WHERE coding_problem_id = NEW.coding_problem_id AND metric IS NULL AND NOT completed AND target_date <= NEW.attempted_on;
+    ELSIF TG_TABLE_NAME = 'job_applications' THEN
+        UPDATE goals SET completed = true
+        WHERE job_application_id = NEW.id AND metric IS NULL AND NOT completed AND target_date <= CURRENT_DATE;
+    ELSIF TG_TABLE_NAME = 'meetings' THEN
+        UPDATE goals SET completed = true
+        WHERE contact_id = NEW.contact_id AND metric IS NULL AND NOT completed AND target_date <= NEW.session_time::date;
+    ELSIF TG_TABLE_NAME = 'meeting_contacts' THEN
+        UPDATE goals g SET completed = true
+        FROM meetings m
+        WHERE m.id = NEW.meeting_id AND m.session_time IS NOT NULL
+          AND g.contact_id = NEW.contact_id AND g.metric IS NULL AND NOT g.completed AND g.target_date <= m.session_time::date;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS meeting_contacts_goal_progress ON meeting_contacts;
+DROP TRIGGER IF EXISTS meetings_goal_progress ON meetings;
+DROP TRIGGER IF EXISTS coding_attempts_goal_progress ON coding_attempts;
+DROP TRIGGER IF EXISTS coding_problems_goal_progress ON coding_problems;
+DROP TRIGGER IF EXISTS job_applications_goal_progress ON job_applications;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION complete_reached_goals() RETURNS trigger AS $$
+BEGIN
+    UPDATE goals g SET completed = true
+    WHERE g.metric IS NOT NULL AND NOT g.completed AND goal_progress(g) >= g.target_count;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER job_applications_goal_progress
+    AFTER INSERT OR UPDATE ON job_applications
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER coding_problems_goal_progress
+    AFTER UPDATE ON coding_problems
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER coding_attempts_goal_progress
+    AFTER INSERT OR UPDATE ON coding_attempts
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER meetings_goal_progress
+    AFTER INSERT OR UPDATE ON meetings
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+CREATE TRIGGER meeting_contacts_goal_progress
+    AFTER INSERT ON meeting_contacts
+    FOR EACH STATEMENT EXECUTE FUNCTION complete_reached_goals();
+
+DROP INDEX IF EXISTS goals_open_metric_idx;
+CREATE INDEX IF NOT EXISTS goals_open_metric_idx
+    ON goals (metric)
+    WHERE metric IS NOT NULL AND NOT completed;
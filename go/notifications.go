@@ -0,0 +1,366 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	ckdb "career-koala/db"
+	"career-koala/notify"
+	"career-koala/scheduler"
+)
+
+// schedulerConfig holds the background task settings, read from the
+// environment.
+type schedulerConfig struct {
+	Tick                 time.Duration // SCHEDULER_TICK, default 1m
+	StaleApplicationDays int           // STALE_APPLICATION_DAYS, default 14
+	StaleContactWeeks    int           // STALE_CONTACT_WEEKS, default 6
+	UpcomingMeetings     time.Duration // UPCOMING_MEETING_WINDOW, default 24h
+	DigestHour           int           // NOTIFY_DIGEST_HOUR (local time), default 8; -1 disables the digest
+	AlertKinds           []string      // NOTIFY_ALERTS, notification kinds sent immediately; default upcoming_meeting
+}
+
+func schedulerConfigFromEnv() schedulerConfig {
+	cfg := schedulerConfig{
+		Tick:                 durationFromEnv("SCHEDULER_TICK", time.Minute),
+		StaleApplicationDays: intFromEnv("STALE_APPLICATION_DAYS", 14),
+		StaleContactWeeks:    intFromEnv("STALE_CONTACT_WEEKS", 6),
+		UpcomingMeetings:     durationFromEnv("UPCOMING_MEETING_WINDOW", 24*time.Hour),
+		DigestHour:           8,
+		AlertKinds:           []string{ckdb.NotifyUpcomingMeeting},
+	}
+	if raw := strings.TrimSpace(os.Getenv("NOTIFY_DIGEST_HOUR")); raw != "" {
+		if hour, err := strconv.Atoi(raw); err == nil && hour >= -1 && hour < 24 {
+			cfg.DigestHour = hour
+		} else {
+			log.Printf("NOTIFY_DIGEST_HOUR: invalid hour %q, using %d", raw, cfg.DigestHour)
+		}
+	}
+	if raw, ok := os.LookupEnv("NOTIFY_ALERTS"); ok {
+		cfg.AlertKinds = nil
+		for _, kind := range strings.Split(raw, ",") {
+			if kind = strings.TrimSpace(kind); kind != "" {
+				cfg.AlertKinds = append(cfg.AlertKinds, kind)
+			}
+		}
+	}
+	return cfg
+}
+
+// backgroundTasks are the periodic jobs run by the scheduler leader, each
+// once per user. All of them are idempotent, so a task that runs twice (or
+// late) records nothing new. The delivery tasks are only added when
+// delivery is configured, and only run for users with a target.
+func backgroundTasks(dbConn *sql.DB, cfg schedulerConfig, delivery *notify.Config) []scheduler.Task {
+	users := func(ctx context.Context) ([]ckdb.User, error) { return ckdb.ListUsers(ctx, dbConn) }
+	notified := func(kind string) func([]ckdb.Notification, error) (string, error) {
+		return func(created []ckdb.Notification, err error) (string, error) {
+			if err != nil || len(created) == 0 {
+				return "", err
+			}
+			return fmt.Sprintf("%d %s notifications", len(created), kind), nil
+		}
+	}
+	tasks := []scheduler.Task{
+		{
+			// New days, weeks and months of recurring goals appear within an
+			// hour of starting.
+			Name:  "materialize_goals",
+			Every: time.Hour,
+			Run: perUser(users, func(ctx context.Context) (string, error) {
+				created, err := ckdb.MaterializeGoals(ctx, dbConn, time.Now())
+				if err != nil || created == 0 {
+					return "", err
+				}
+				return fmt.Sprintf("materialized %d recurring goals", created), nil
+			}),
+		},
+		{
+			Name:  "roll_over_daily_goals",
+			Every: time.Hour,
+			Run: perUser(users, func(ctx context.Context) (string, error) {
+				return notified("rollover")(ckdb.NotifyGoalRollover(ctx, dbConn, time.Now()))
+			}),
+		},
+		{
+			// Goals to meet a contact complete once a meeting logged ahead
+			// of time has happened.
+			Name:  "complete_met_contact_goals",
+			Every: 15 * time.Minute,
+			Run: perUser(users, func(ctx context.Context) (string, error) {
+				completed, err := ckdb.CompleteMetContactGoals(ctx, dbConn, time.Now())
+				if err != nil || completed == 0 {
+					return "", err
+				}
+				return fmt.Sprintf("completed %d contact goals", completed), nil
+			}),
+		},
+		{
+			Name:  "stale_applications",
+			Every: 6 * time.Hour,
+			Run: perUser(users, func(ctx context.Context) (string, error) {
+				return notified("stale application")(ckdb.NotifyStaleApplications(ctx, dbConn, cfg.StaleApplicationDays))
+			}),
+		},
+		{
+			Name:  "stale_contacts",
+			Every: 24 * time.Hour,
+			Run: perUser(users, func(ctx context.Context) (string, error) {
+				return notified("stale contact")(ckdb.NotifyStaleContacts(ctx, dbConn, cfg.StaleContactWeeks))
+			}),
+		},
+		{
+			Name:  "upcoming_meetings",
+			Every: 15 * time.Minute,
+			Run: perUser(users, func(ctx context.Context) (string, error) {
+				return notified("meeting")(ckdb.NotifyUpcomingMeetings(ctx, dbConn, time.Now(), cfg.UpcomingMeetings))
+			}),
+		},
+	}
+	if delivery == nil {
+		return tasks
+	}
+	if len(cfg.AlertKinds) > 0 {
+		tasks = append(tasks, scheduler.Task{
+			Name:  "deliver_alerts",
+			Every: time.Minute,
+			Run: deliverPerUser(users, delivery, func(ctx context.Context, notifier notify.Notifier) (string, error) {
+				return deliverAlerts(ctx, dbConn, notifier, cfg.AlertKinds)
+			}),
+		})
+	}
+	if cfg.DigestHour >= 0 {
+		tasks = append(tasks, scheduler.Task{
+			Name:  "daily_digest",
+			Every: 15 * time.Minute,
+			Run: deliverPerUser(users, delivery, func(ctx context.Context, notifier notify.Notifier) (string, error) {
+				return sendDailyDigest(ctx, dbConn, notifier, cfg, time.Now())
+			}),
+		})
+	}
+	return tasks
+}
+
+// perUser runs a task once for every user, with its queries scoped to that
+// user, and joins the summaries of the runs that did something.
+func perUser(users userLister, run func(ctx context.Context) (string, error)) func(ctx context.Context) (string, error) {
+	return forUsers(users, func(ctx context.Context, _ ckdb.User) (string, error) {
+		return run(ctx)
+	})
+}
+
+// deliverPerUser runs a delivery task once for every user with a target,
+// passing the notifier that delivers to it. Users without one are skipped,
+// so nobody's alerts end up in someone else's inbox.
+func deliverPerUser(users userLister, delivery *notify.Config, run func(ctx context.Context, notifier notify.Notifier) (string, error)) func(ctx context.Context) (string, error) {
+	return forUsers(users, func(ctx context.Context, u ckdb.User) (string, error) {
+		notifier := delivery.For(userTarget(u, delivery))
+		if notifier == nil {
+			return "", nil
+		}
+		return run(ctx, notifier)
+	})
+}
+
+// userTarget is where u's messages go: the user's own email and webhook,
+// or for the default user without any, the ones in the environment.
+func userTarget(u ckdb.User, delivery *notify.Config) notify.Target {
+	if u.NotifyEmail == "" && u.NotifyWebhookURL == "" {
+		if u.Name == ckdb.DefaultUserName {
+			return delivery.Default
+		}
+		return notify.Target{}
+	}
+	t := notify.Target{WebhookURL: u.NotifyWebhookURL, UserSupplied: true}
+	if u.NotifyEmail != "" {
+		t.Email = []string{u.NotifyEmail}
+	}
+	return t
+}
+
+// userLister lists the users the background tasks run for.
+type userLister func(ctx context.Context) ([]ckdb.User, error)
+
+// forUsers runs a task for every user listed. A user whose run fails (say,
+// a webhook that keeps answering 500) doesn't hold up the users after them;
+// the task reports all the failures together.
+func forUsers(users userLister, run func(ctx context.Context, u ckdb.User) (string, error)) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		list, err := users(ctx)
+		if err != nil {
+			return "", err
+		}
+		var done []string
+		err = ckdb.ForEachUser(ctx, list, func(ctx context.Context, u ckdb.User) error {
+			summary, err := run(ctx, u)
+			if summary != "" {
+				done = append(done, u.Name+": "+summary)
+			}
+			return err
+		})
+		return strings.Join(done, "; "), err
+	}
+}
+
+// deliverAlerts sends the notifications of the alert kinds recorded in the
+// last day that haven't gone out on every channel yet. Each channel is
+// tracked on its own: one that fails is retried on the next run without
+// resending through the others.
+func deliverAlerts(ctx context.Context, dbConn *sql.DB, notifier notify.Notifier, kinds []string) (string, error) {
+	pending, err := ckdb.ListUndeliveredNotifications(ctx, dbConn, kinds, time.Now().Add(-24*time.Hour))
+	if err != nil || len(pending) == 0 {
+		return "", err
+	}
+	sent := 0
+	var sendErr error
+	for _, n := range pending {
+		msg := notify.Message{Kind: n.Kind, Subject: n.Title, Text: n.Body}
+		via, err := notify.SendPending(ctx, notifier, msg, n.DeliveredVia)
+		delivered := notify.Delivered(notifier, via)
+		if len(via) > len(n.DeliveredVia) || delivered {
+			if err := ckdb.RecordNotificationDelivery(ctx, dbConn, n.ID, via, delivered); err != nil {
+				return "", err
+			}
+		}
+		if delivered {
+			sent++
+		}
+		if err != nil {
+			// A channel is down; leave the rest for the next run.
+			sendErr = fmt.Errorf("notification %d: %w", n.ID, err)
+			break
+		}
+	}
+	return fmt.Sprintf("sent %d of %d alerts via %s", sent, len(pending), notifier.Name()), sendErr
+}
+
+// sendDailyDigest sends the digest once a day, on the first run at or after
+// the digest hour. The digest is recorded as a notification before it goes
+// out, with the channels it was delivered on, so other replicas and
+// restarts neither send it again nor skip a channel that failed.
+func sendDailyDigest(ctx context.Context, dbConn *sql.DB, notifier notify.Notifier, cfg schedulerConfig, now time.Time) (string, error) {
+	if now.Hour() < cfg.DigestHour {
+		return "", nil
+	}
+	key := ckdb.NotifyDailyDigest + ":" + now.Format("2006-01-02")
+	n, err := ckdb.GetNotificationByKey(ctx, dbConn, key)
+	switch {
+	case err == nil:
+		if n.DeliveredAt != nil {
+			return "", nil
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		digest, err := ckdb.GetDailyDigest(ctx, dbConn, now, cfg.UpcomingMeetings, cfg.StaleApplicationDays)
+		if err != nil {
+			return "", err
+		}
+		n = ckdb.Notification{Kind: ckdb.NotifyDailyDigest, Title: digest.Subject(), Body: digest.Text(now.Location()), Key: key}
+		if digest.Empty() {
+			n.DeliveredAt = &now
+		}
+		created, err := ckdb.InsertNotifications(ctx, dbConn, []ckdb.Notification{n})
+		if err != nil || len(created) == 0 || digest.Empty() {
+			// Nothing to send, or another run recorded it first.
+			return "", err
+		}
+		n = created[0]
+	default:
+		return "", err
+	}
+
+	msg := notify.Message{Kind: ckdb.NotifyDailyDigest, Subject: n.Title, Text: n.Body}
+	via, sendErr := notify.SendPending(ctx, notifier, msg, n.DeliveredVia)
+	delivered := notify.Delivered(notifier, via)
+	if err := ckdb.RecordNotificationDelivery(ctx, dbConn, n.ID, via, delivered); err != nil {
+		return "", err
+	}
+	if !delivered {
+		return "", sendErr
+	}
+	return "sent the daily digest via " + notifier.Name(), sendErr
+}
+
+// notificationsHandler lists notifications, newest first. Query parameters:
+// unread (true for unread only) and limit (default 50).
+func notificationsHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		unreadOnly, err := queryBool(r, "unread")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit, ok := queryInt(w, r, "limit")
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		items, unread, err := ckdb.ListNotifications(ctx, dbConn, unreadOnly, limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to list notifications")
+			return
+		}
+		writeJSON(w, map[string]any{"items": items, "unread": unread})
+	}
+}
+
+// notificationsReadHandler marks notifications read: {"ids": [1, 2]}, or
+// every unread one with an empty body or ids.
+func notificationsReadHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			IDs []int64 `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		updated, err := ckdb.MarkNotificationsRead(ctx, dbConn, req.IDs)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to update notifications")
+			return
+		}
+		writeJSON(w, map[string]any{"updated": updated})
+	}
+}
+
+func intFromEnv(key string, defaultVal int) int {
+	n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || n <= 0 {
+		return defaultVal
+	}
+	return n
+}
+
+func durationFromEnv(key string, defaultVal time.Duration) time.Duration {
+	raw := strings.TrimSpace(os.Getenv(key))
+	if raw == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		log.Printf("%s: invalid duration %q, using %s", key, raw, defaultVal)
+		return defaultVal
+	}
+	return d
+}
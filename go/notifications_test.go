@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ckdb "career-koala/db"
+	"career-koala/notify"
+)
+
+func TestDeliveryKeepsGoingPastAFailingUser(t *testing.T) {
+	got := make(chan string, 2)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.URL.Path
+	}))
+	defer hook.Close()
+
+	// The first user's webhook can't be delivered to (it isn't public); the
+	// default user's goes to the operator's webhook.
+	users := func(context.Context) ([]ckdb.User, error) {
+		return []ckdb.User{
+			{ID: 1, Name: "ana", NotifyWebhookURL: "https://127.0.0.1:1/hook"},
+			{ID: 2, Name: ckdb.DefaultUserName},
+		}, nil
+	}
+	delivery := &notify.Config{Default: notify.Target{WebhookURL: hook.URL + "/alerts"}}
+	run := deliverPerUser(users, delivery, func(ctx context.Context, notifier notify.Notifier) (string, error) {
+		if err := notifier.Send(ctx, notify.Message{Kind: ckdb.NotifyUpcomingMeeting, Subject: "Meeting soon"}); err != nil {
+			return "", err
+		}
+		return "sent 1 of 1 alerts via " + notifier.Name(), nil
+	})
+
+	summary, err := run(context.Background())
+	if err == nil || !strings.HasPrefix(err.Error(), "user ana: ") {
+		t.Fatalf("expected ana's failure to be reported, got %v", err)
+	}
+	if summary != ckdb.DefaultUserName+": sent 1 of 1 alerts via webhook" || len(got) != 1 || <-got != "/alerts" {
+		t.Fatalf("summary %q; want the second user's alert sent", summary)
+	}
+}
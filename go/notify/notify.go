@@ -0,0 +1,173 @@
+// Package notify delivers reminders outside the UI: by email over SMTP or
+// as a JSON POST to a webhook.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Message is one delivery: the daily digest or an immediate alert.
+type Message struct {
+	// Kind is "daily_digest" or the kind of the notification being alerted.
+	Kind    string    `json:"kind"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// Notifier sends messages to one destination.
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, m Message) error
+}
+
+// Multi sends every message to each of its notifiers and reports all the
+// failures together.
+type Multi []Notifier
+
+func (m Multi) Name() string {
+	names := make([]string, 0, len(m))
+	for _, n := range m {
+		names = append(names, n.Name())
+	}
+	return strings.Join(names, "+")
+}
+
+func (m Multi) Send(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Channels returns the notifiers n sends through: the members of a Multi,
+// or n itself.
+func Channels(n Notifier) []Notifier {
+	if m, ok := n.(Multi); ok {
+		return m
+	}
+	return []Notifier{n}
+}
+
+// SendPending sends msg through each channel of n not named in done and
+// returns done plus the channels that took it. Failed channels are left
+// out, so the caller can retry just those; err joins their failures.
+func SendPending(ctx context.Context, n Notifier, msg Message, done []string) ([]string, error) {
+	sent := slices.Clone(done)
+	var errs []error
+	for _, c := range Channels(n) {
+		if slices.Contains(done, c.Name()) {
+			continue
+		}
+		if err := c.Send(ctx, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", c.Name(), err))
+			continue
+		}
+		sent = append(sent, c.Name())
+	}
+	return sent, errors.Join(errs...)
+}
+
+// Delivered reports whether every channel of n is named in sent.
+func Delivered(n Notifier, sent []string) bool {
+	for _, c := range Channels(n) {
+		if !slices.Contains(sent, c.Name()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Target is where one user's messages go. Empty fields are skipped.
+type Target struct {
+	Email      []string
+	WebhookURL string
+	// UserSupplied marks a webhook set by the user rather than the
+	// operator; it is sent only to public https endpoints.
+	UserSupplied bool
+}
+
+// Config is the delivery setup shared by every user: the SMTP server and
+// the webhook signing secret. Each user's messages go to their own Target.
+type Config struct {
+	// SMTP is the mail server, sender and credentials, nil when email is
+	// off. Its To is ignored; For fills it from the target.
+	SMTP          *SMTP
+	WebhookSecret string
+	// Default is the target of the default user, who has none of their own
+	// until they set one.
+	Default Target
+}
+
+// For returns the notifier delivering to t, or nil when t has nowhere
+// this setup can deliver to.
+func (c *Config) For(t Target) Notifier {
+	var res Multi
+	if c.SMTP != nil && len(t.Email) > 0 {
+		s := *c.SMTP
+		s.To = t.Email
+		res = append(res, &s)
+	}
+	if t.WebhookURL != "" {
+		res = append(res, &Webhook{URL: t.WebhookURL, Secret: c.WebhookSecret, PublicOnly: t.UserSupplied})
+	}
+	switch len(res) {
+	case 0:
+		return nil
+	case 1:
+		return res[0]
+	}
+	return res
+}
+
+// FromEnv reads the delivery setup from the environment (through getenv,
+// usually os.Getenv):
+//
+//   - NOTIFY_SMTP_ADDR (host:port) and NOTIFY_EMAIL_FROM enable email;
+//     NOTIFY_SMTP_USERNAME and NOTIFY_SMTP_PASSWORD add PLAIN auth.
+//   - NOTIFY_WEBHOOK_SECRET signs each webhook body.
+//   - NOTIFY_EMAIL_TO (comma-separated) and NOTIFY_WEBHOOK_URL are the
+//     default user's target.
+//
+// Delivery is on when email or a webhook setting is configured; it returns
+// nil otherwise.
+func FromEnv(getenv func(string) string) (*Config, error) {
+	env := func(key string) string { return strings.TrimSpace(getenv(key)) }
+	c := &Config{WebhookSecret: getenv("NOTIFY_WEBHOOK_SECRET")}
+	for _, to := range strings.Split(env("NOTIFY_EMAIL_TO"), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			c.Default.Email = append(c.Default.Email, to)
+		}
+	}
+	if addr := env("NOTIFY_SMTP_ADDR"); addr != "" {
+		c.SMTP = &SMTP{
+			Addr:     addr,
+			From:     env("NOTIFY_EMAIL_FROM"),
+			Username: env("NOTIFY_SMTP_USERNAME"),
+			Password: getenv("NOTIFY_SMTP_PASSWORD"),
+		}
+		if c.SMTP.From == "" {
+			return nil, errors.New("NOTIFY_SMTP_ADDR needs NOTIFY_EMAIL_FROM")
+		}
+	} else if len(c.Default.Email) > 0 {
+		return nil, errors.New("NOTIFY_EMAIL_TO needs NOTIFY_SMTP_ADDR")
+	}
+	if url := env("NOTIFY_WEBHOOK_URL"); url != "" {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return nil, fmt.Errorf("NOTIFY_WEBHOOK_URL: %q is not an http(s) URL", url)
+		}
+		c.Default.WebhookURL = url
+	}
+	if c.SMTP == nil && c.Default.WebhookURL == "" && c.WebhookSecret == "" {
+		return nil, nil
+	}
+	return c, nil
+}
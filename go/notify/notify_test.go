@@ -0,0 +1,313 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts one session and
+// records the envelope and data.
+type smtpStandIn struct {
+	addr string
+	done chan struct{}
+	from string
+	to   []string
+	data string
+}
+
+func startSMTP(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	s := &smtpStandIn{addr: ln.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			switch upper := strings.ToUpper(cmd); {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				reply("250-localhost")
+				reply("250 8BITMIME")
+			case strings.HasPrefix(upper, "MAIL FROM:"):
+				s.from = angleAddr(cmd)
+				reply("250 OK")
+			case strings.HasPrefix(upper, "RCPT TO:"):
+				s.to = append(s.to, angleAddr(cmd))
+				reply("250 OK")
+			case upper == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				s.data = data.String()
+				reply("250 queued")
+			case upper == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	return s
+}
+
+// angleAddr returns the address in "MAIL FROM:<a@b> BODY=8BITMIME".
+func angleAddr(cmd string) string {
+	start, end := strings.Index(cmd, "<"), strings.Index(cmd, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return cmd[start+1 : end]
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := startSMTP(t)
+	n := &SMTP{Addr: srv.addr, From: "koala@example.com", To: []string{"me@example.com", "you@example.com"}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := n.Send(ctx, Message{Kind: "daily_digest", Subject: "Your day: 3 goals", Text: "Goals\n- Apply to 5 jobs (2/5)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-srv.done
+	if srv.from != "koala@example.com" || !reflect.DeepEqual(srv.to, []string{"me@example.com", "you@example.com"}) {
+		t.Fatalf("envelope = %s -> %v", srv.from, srv.to)
+	}
+	for _, want := range []string{"Subject: Your day: 3 goals\r\n", "X-Career-Koala-Kind: daily_digest\r\n", "Goals\r\n- Apply to 5 jobs (2/5)"} {
+		if !strings.Contains(srv.data, want) {
+			t.Errorf("message missing %q:\n%s", want, srv.data)
+		}
+	}
+}
+
+func TestSMTPRefusesPlainAuthOverCleartext(t *testing.T) {
+	srv := startSMTP(t)
+	// The stand-in listens on 127.0.0.1, which net/smtp does not treat as
+	// localhost, so credentials must not be sent without TLS.
+	n := &SMTP{Addr: srv.addr, From: "a@example.com", To: []string{"b@example.com"}, Username: "u", Password: "p"}
+	if err := n.Send(context.Background(), Message{Subject: "x"}); err == nil {
+		t.Fatal("expected an error sending credentials without TLS")
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	var got Message
+	var signature string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(SignatureHeader)
+		json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	wh := &Webhook{URL: srv.URL, Secret: "s3cret"}
+	if err := wh.Send(context.Background(), Message{Kind: "upcoming_meeting", Subject: "Interview at 10:00", Text: "Acme"}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != "upcoming_meeting" || got.Subject != "Interview at 10:00" || got.SentAt.IsZero() {
+		t.Fatalf("unexpected payload %+v", got)
+	}
+	if signature != Sign("s3cret", body) {
+		t.Fatalf("signature = %q, want %q", signature, Sign("s3cret", body))
+	}
+}
+
+func TestWebhookReportsFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	err := (&Webhook{URL: srv.URL}).Send(context.Background(), Message{Subject: "x"})
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("err = %v, want a 502 error", err)
+	}
+}
+
+func TestPublicOnlyWebhookRefusesInternalAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	// The TLS test server listens on loopback, which a user webhook may not reach.
+	err := (&Webhook{URL: srv.URL, PublicOnly: true}).Send(context.Background(), Message{Subject: "x"})
+	if err == nil || !strings.Contains(err.Error(), "not public") || hit {
+		t.Fatalf("err = %v (hit=%v), want a refused dial", err, hit)
+	}
+	err = (&Webhook{URL: "http://hooks.example.com", PublicOnly: true}).Send(context.Background(), Message{Subject: "x"})
+	if !errors.Is(err, errNotHTTPS) {
+		t.Fatalf("err = %v, want errNotHTTPS", err)
+	}
+}
+
+func TestCheckPublicURL(t *testing.T) {
+	for _, raw := range []string{
+		"http://8.8.8.8/hook",
+		"https://127.0.0.1/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.1.2.3/hook",
+		"https://192.168.0.10/hook",
+		"https://100.64.0.1/hook",
+		"https://[::1]/hook",
+		"https://[fd00::1]/hook",
+		"https://[::ffff:127.0.0.1]/hook",
+		"https://0.0.0.0/hook",
+		"not a url",
+	} {
+		if err := CheckPublicURL(context.Background(), raw); err == nil {
+			t.Errorf("CheckPublicURL(%q) = nil, want an error", raw)
+		}
+	}
+	for _, raw := range []string{"https://8.8.8.8/hook", "https://[2001:4860:4860::8888]/hook"} {
+		if err := CheckPublicURL(context.Background(), raw); err != nil {
+			t.Errorf("CheckPublicURL(%q) = %v", raw, err)
+		}
+	}
+}
+
+func TestConfigForRestrictsUserWebhooks(t *testing.T) {
+	c := &Config{}
+	if wh := c.For(Target{WebhookURL: "https://hooks.example.com"}).(*Webhook); wh.PublicOnly {
+		t.Fatal("operator webhook should not be restricted")
+	}
+	if wh := c.For(Target{WebhookURL: "https://hooks.example.com", UserSupplied: true}).(*Webhook); !wh.PublicOnly {
+		t.Fatal("user webhook should be PublicOnly")
+	}
+}
+
+type fakeNotifier struct {
+	name string
+	err  error
+	got  []Message
+}
+
+func (f *fakeNotifier) Name() string { return f.name }
+
+func (f *fakeNotifier) Send(_ context.Context, m Message) error {
+	f.got = append(f.got, m)
+	return f.err
+}
+
+func TestMultiSendsToAll(t *testing.T) {
+	a, b := &fakeNotifier{name: "a", err: errors.New("down")}, &fakeNotifier{name: "b"}
+	err := Multi{a, b}.Send(context.Background(), Message{Subject: "x"})
+	if err == nil || !strings.Contains(err.Error(), "a: down") {
+		t.Fatalf("err = %v, want a's failure", err)
+	}
+	if len(a.got) != 1 || len(b.got) != 1 {
+		t.Fatalf("deliveries = %d, %d; want 1 each", len(a.got), len(b.got))
+	}
+}
+
+func TestSendPendingRetriesOnlyFailedChannels(t *testing.T) {
+	a, b := &fakeNotifier{name: "smtp"}, &fakeNotifier{name: "webhook", err: errors.New("down")}
+	n := Multi{a, b}
+	via, err := SendPending(context.Background(), n, Message{Subject: "x"}, nil)
+	if err == nil || !reflect.DeepEqual(via, []string{"smtp"}) || Delivered(n, via) {
+		t.Fatalf("first send = %v, %v", via, err)
+	}
+
+	b.err = nil
+	via, err = SendPending(context.Background(), n, Message{Subject: "x"}, via)
+	if err != nil || !reflect.DeepEqual(via, []string{"smtp", "webhook"}) || !Delivered(n, via) {
+		t.Fatalf("retry = %v, %v", via, err)
+	}
+	if len(a.got) != 1 || len(b.got) != 2 {
+		t.Fatalf("deliveries = %d, %d; want smtp once, webhook twice", len(a.got), len(b.got))
+	}
+	if !Delivered(a, []string{"smtp"}) {
+		t.Fatal("a single notifier is its own channel")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	env := func(vars map[string]string) func(string) string {
+		return func(k string) string { return vars[k] }
+	}
+	c, err := FromEnv(env(nil))
+	if err != nil || c != nil {
+		t.Fatalf("empty env = %v, %v; want nil, nil", c, err)
+	}
+
+	c, err = FromEnv(env(map[string]string{
+		"NOTIFY_SMTP_ADDR":   "mail.example.com:587",
+		"NOTIFY_EMAIL_FROM":  "koala@example.com",
+		"NOTIFY_EMAIL_TO":    "me@example.com, you@example.com",
+		"NOTIFY_WEBHOOK_URL": "https://hooks.example.com/x",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := c.For(c.Default)
+	if n.Name() != "smtp+webhook" {
+		t.Fatalf("Name = %q", n.Name())
+	}
+	if to := n.(Multi)[0].(*SMTP).To; !reflect.DeepEqual(to, []string{"me@example.com", "you@example.com"}) {
+		t.Fatalf("To = %v", to)
+	}
+
+	// Other users get their own target; the shared SMTP settings aren't
+	// changed by it.
+	n = c.For(Target{Email: []string{"ana@example.com"}})
+	if s := n.(*SMTP); !reflect.DeepEqual(s.To, []string{"ana@example.com"}) || s.From != "koala@example.com" || c.SMTP.To != nil {
+		t.Fatalf("For(ana) = %+v, shared %+v", s, c.SMTP)
+	}
+	if n := c.For(Target{}); n != nil {
+		t.Fatalf("For(empty target) = %v, want nil", n)
+	}
+
+	// A webhook secret alone turns delivery on for users' own webhooks.
+	c, err = FromEnv(env(map[string]string{"NOTIFY_WEBHOOK_SECRET": "s3cret"}))
+	if err != nil || c == nil || c.For(Target{Email: []string{"ana@example.com"}}) != nil {
+		t.Fatalf("webhook-only = %+v, %v", c, err)
+	}
+	if wh := c.For(Target{WebhookURL: "https://hooks.example.com/ana"}).(*Webhook); wh.Secret != "s3cret" {
+		t.Fatalf("webhook = %+v", wh)
+	}
+
+	for _, vars := range []map[string]string{
+		{"NOTIFY_SMTP_ADDR": "mail.example.com:587"},
+		{"NOTIFY_EMAIL_TO": "me@example.com"},
+		{"NOTIFY_WEBHOOK_URL": "hooks.example.com"},
+	} {
+		if _, err := FromEnv(env(vars)); err == nil {
+			t.Errorf("FromEnv(%v): expected an error", vars)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// SignatureHeader carries "sha256=<hex HMAC of the body>" when the webhook
+// has a secret, so the receiver can check the request came from us.
+const SignatureHeader = "X-Career-Koala-Signature"
+
+// Webhook POSTs each message as JSON to URL.
+type Webhook struct {
+	URL    string
+	Secret string
+	// PublicOnly is for URLs users supply: it sends only over https and
+	// only connects to public addresses, checked on every dial so a DNS
+	// change or redirect can't point it inside the network.
+	PublicOnly bool
+	// Client defaults to one with a 10s timeout.
+	Client *http.Client
+}
+
+func (wh *Webhook) Name() string { return "webhook" }
+
+func (wh *Webhook) Send(ctx context.Context, m Message) error {
+	if m.SentAt.IsZero() {
+		m.SentAt = time.Now().UTC()
+	}
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if wh.PublicOnly && req.URL.Scheme != "https" {
+		return errNotHTTPS
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if wh.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(wh.Secret, body))
+	}
+	client := wh.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+		if wh.PublicOnly {
+			client = publicOnlyClient
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+var errNotHTTPS = errors.New("webhook URL must be https")
+
+// publicOnlyClient refuses to connect to anything but public addresses and
+// to follow redirects off https.
+var publicOnlyClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				addrPort, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				if !isPublic(addrPort.Addr()) {
+					return fmt.Errorf("webhook address %s is not public", addrPort.Addr())
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return errNotHTTPS
+		}
+		if len(via) >= 5 {
+			return errors.New("webhook redirected too many times")
+		}
+		return nil
+	},
+}
+
+// CheckPublicURL reports whether raw is an https URL whose host resolves
+// only to public addresses, the kind of webhook a user may set. Send checks
+// the addresses again when it connects.
+func CheckPublicURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%q is not a URL", raw)
+	}
+	if u.Scheme != "https" {
+		return errNotHTTPS
+	}
+	host := u.Hostname()
+	var addrs []netip.Addr
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else if addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", host); err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return fmt.Errorf("%s resolves to %s, which is not a public address", host, addr)
+		}
+	}
+	return nil
+}
+
+// nonPublic are the special-purpose ranges IsPrivate and friends miss.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can reach any IPv4 address
+}
+
+// isPublic reports whether addr is a globally routable unicast address,
+// not loopback, private, link-local (which includes cloud metadata
+// endpoints) or otherwise reserved.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() || addr.IsUnspecified() {
+		return false
+	}
+	for _, p := range nonPublic {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
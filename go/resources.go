@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ckdb "career-koala/db"
+)
+
+// resource wires the Get/Update/Delete functions of one entity into the
+// /{collection}/{id} item routes.
+type resource[T any] struct {
+	name   string
+	get    func(ctx context.Context, db ckdb.DBTX, id int64) (T, error)
+	update func(ctx context.Context, db ckdb.DBTX, id int64, fields map[string]interface{}) error
+	remove func(ctx context.Context, db ckdb.DBTX, id int64) error
+}
+
+var (
+	jobResource = resource[ckdb.JobApplication]{
+		name:   "job",
+		get:    ckdb.GetJobApplication,
+		update: ckdb.UpdateJobApplication,
+		remove: ckdb.DeleteJobApplication,
+	}
+	codingResource = resource[ckdb.CodingProblem]{
+		name:   "coding problem",
+		get:    ckdb.GetCodingProblem,
+		update: ckdb.UpdateCodingProblem,
+		remove: ckdb.DeleteCodingProblem,
+	}
+	projectResource = resource[ckdb.Project]{
+		name:   "project",
+		get:    ckdb.GetProject,
+		update: ckdb.UpdateProject,
+		remove: ckdb.DeleteProject,
+	}
+	contactResource = resource[ckdb.NetworkingContact]{
+		name:   "contact",
+		get:    ckdb.GetNetworkingContact,
+		update: ckdb.UpdateNetworkingContact,
+		remove: ckdb.DeleteNetworkingContact,
+	}
+	meetingResource = resource[ckdb.Meeting]{
+		name:   "meeting",
+		get:    ckdb.GetMeeting,
+		update: ckdb.UpdateMeeting,
+		remove: ckdb.DeleteMeeting,
+	}
+)
+
+// goalResource binds the goal functions to one goal type.
+func goalResource(goalType string) resource[ckdb.Goal] {
+	return resource[ckdb.Goal]{
+		name: "goal",
+		get: func(ctx context.Context, db ckdb.DBTX, id int64) (ckdb.Goal, error) {
+			return ckdb.GetGoal(ctx, db, goalType, id)
+		},
+		update: func(ctx context.Context, db ckdb.DBTX, id int64, fields map[string]interface{}) error {
+			return ckdb.UpdateGoalFields(ctx, db, goalType, id, fields)
+		},
+		remove: func(ctx context.Context, db ckdb.DBTX, id int64) error {
+			return ckdb.DeleteGoal(ctx, db, goalType, id)
+		},
+	}
+}
+
+func itemHandler[T any](dbConn *sql.DB, res resource[T]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		serveItem(w, r, dbConn, res)
+	}
+}
+
+func goalItemHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goalType := strings.ToLower(strings.TrimSpace(r.PathValue("type")))
+		switch goalType {
+		case "daily", "weekly", "monthly":
+		default:
+			writeError(w, http.StatusBadRequest, "invalid goal type")
+			return
+		}
+		serveItem(w, r, dbConn, goalResource(goalType))
+	}
+}
+
+func serveItem[T any](w http.ResponseWriter, r *http.Request, dbConn *sql.DB, res resource[T]) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	switch r.Method {
+	case http.MethodGet:
+		item, err := res.get(ctx, dbConn, id)
+		if err != nil {
+			writeItemError(w, err, res.name, "fetch")
+			return
+		}
+		writeJSON(w, item)
+	case http.MethodPatch:
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		var fields map[string]interface{}
+		if err := decoder.Decode(&fields); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		delete(fields, "id")
+		if len(fields) == 0 {
+			writeError(w, http.StatusBadRequest, "no fields to update")
+			return
+		}
+		if err := res.update(ctx, dbConn, id, fields); err != nil {
+			writeItemError(w, err, res.name, "update")
+			return
+		}
+		item, err := res.get(ctx, dbConn, id)
+		if err != nil {
+			writeItemError(w, err, res.name, "fetch")
+			return
+		}
+		writeJSON(w, item)
+	case http.MethodDelete:
+		if err := res.remove(ctx, dbConn, id); err != nil {
+			writeItemError(w, err, res.name, "delete")
+			return
+		}
+		writeJSON(w, map[string]any{"status": "deleted"})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func writeItemError(w http.ResponseWriter, err error, name, verb string) {
+	var fieldErr *ckdb.FieldError
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, name+" not found")
+	case errors.As(err, &fieldErr):
+		writeError(w, http.StatusBadRequest, fieldErr.Error())
+	case errors.Is(err, ckdb.ErrInvalidTransition):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "failed to "+verb+" "+name)
+	}
+}
+
+// collectionHandler serves GET (list) and POST (create) on a collection route.
+func collectionHandler(list, create http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			list(w, r)
+			return
+		}
+		create(w, r)
+	}
+}
+
+type listFunc[T any] func(ctx context.Context, db ckdb.DBTX, params ckdb.ListParams) (ckdb.Page[T], error)
+
+func listHandler[T any](dbConn *sql.DB, name string, list listFunc[T]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		params, err := parseListParams(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		page, err := list(ctx, dbConn, params)
+		if err != nil {
+			writeItemError(w, err, name, "list")
+			return
+		}
+		writeJSON(w, page)
+	}
+}
+
+func goalListHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		goalType := strings.ToLower(strings.TrimSpace(r.PathValue("type")))
+		switch goalType {
+		case "daily", "weekly", "monthly":
+		default:
+			writeError(w, http.StatusBadRequest, "invalid goal type")
+			return
+		}
+		listHandler(dbConn, "goals", func(ctx context.Context, db ckdb.DBTX, params ckdb.ListParams) (ckdb.Page[ckdb.Goal], error) {
+			return ckdb.ListGoalsPage(ctx, db, goalType, params)
+		})(w, r)
+	}
+}
+
+// parseListParams reads limit, cursor and sort from the query string; every
+// other query parameter is passed through as a filter.
+func parseListParams(r *http.Request) (ckdb.ListParams, error) {
+	query := r.URL.Query()
+	params := ckdb.ListParams{
+		Filters: map[string]string{},
+		Sort:    query.Get("sort"),
+		Cursor:  query.Get("cursor"),
+	}
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return params, errors.New("invalid limit")
+		}
+		params.Limit = limit
+	}
+	for key := range query {
+		switch key {
+		case "limit", "cursor", "sort":
+		default:
+			params.Filters[key] = query.Get(key)
+		}
+	}
+	return params, nil
+}
+
+func jobHistoryHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		if _, err := ckdb.GetJobApplication(ctx, dbConn, id); err != nil {
+			writeItemError(w, err, "job", "fetch")
+			return
+		}
+		history, err := ckdb.ListJobStatusHistory(ctx, dbConn, id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to fetch job history")
+			return
+		}
+		writeJSON(w, history)
+	}
+}
+
+type meetingCreateRequest struct {
+	SessionName    string  `json:"session_name"`
+	SessionType    string  `json:"session_type"`
+	SessionTime    string  `json:"session_time"`
+	Location       string  `json:"location"`
+	Organizer      string  `json:"organizer"`
+	Company        string  `json:"company"`
+	Notes          string  `json:"notes"`
+	JobApplication *int64  `json:"job_application_id"`
+	Contact        *int64  `json:"contact_id"`
+	ContactIDs     []int64 `json:"contact_ids"`
+}
+
+func meetingCreateHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req meetingCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		if strings.TrimSpace(req.SessionName) == "" {
+			writeError(w, http.StatusBadRequest, "session_name is required")
+			return
+		}
+		if req.SessionType != "virtual" && req.SessionType != "in-person" {
+			writeError(w, http.StatusBadRequest, "session_type must be virtual or in-person")
+			return
+		}
+		sessionTime, err := time.Parse(time.RFC3339, strings.TrimSpace(req.SessionTime))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid session_time")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		id, err := ckdb.InsertMeeting(ctx, dbConn, ckdb.Meeting{
+			SessionName:    req.SessionName,
+			SessionType:    req.SessionType,
+			SessionTime:    sessionTime,
+			Location:       req.Location,
+			Organizer:      req.Organizer,
+			Company:        req.Company,
+			Notes:          req.Notes,
+			JobApplication: req.JobApplication,
+			Contact:        req.Contact,
+			ContactIDs:     req.ContactIDs,
+		})
+		if err != nil {
+			writeItemError(w, err, "meeting", "create")
+			return
+		}
+		writeJSON(w, map[string]any{"id": id})
+	}
+}
+
+// goalCreateRequest creates a goal from type, description and target_date,
+// or from a "rule" such as "apply to 5 jobs this week", which implies all
+// three plus a metric.
+type goalCreateRequest struct {
+	Type           string `json:"type"`
+	Rule           string `json:"rule"`
+	Description    string `json:"description"`
+	TargetDate     string `json:"target_date"`
+	Completed      bool   `json:"completed"`
+	TargetCount    int    `json:"target_count"`
+	Metric         string `json:"metric"`
+	MetricFilter   string `json:"metric_filter"`
+	JobApplication *int64 `json:"job_application_id"`
+	CodingProblem  *int64 `json:"coding_problem_id"`
+	Project        *int64 `json:"project_id"`
+	Contact        *int64 `json:"contact_id"`
+}
+
+func goalsHandler(dbConn *sql.DB) http.HandlerFunc {
+	create := goalCreateHandler(dbConn)
+	update := goalUpdateHandler(dbConn)
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			create(w, r)
+		default:
+			update(w, r)
+		}
+	}
+}
+
+func goalCreateHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var req goalCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+		var goal ckdb.Goal
+		if strings.TrimSpace(req.Rule) != "" {
+			var err error
+			if goal, err = ckdb.ParseGoal(req.Rule, time.Now()); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		if req.Type != "" {
+			goal.Period = req.Type
+		}
+		if strings.TrimSpace(req.Description) != "" {
+			goal.Description = req.Description
+		}
+		if strings.TrimSpace(goal.Description) == "" {
+			writeError(w, http.StatusBadRequest, "description is required")
+			return
+		}
+		if req.TargetDate != "" || goal.TargetDate.IsZero() {
+			target, err := time.Parse("2006-01-02", strings.TrimSpace(req.TargetDate))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid target_date")
+				return
+			}
+			goal.TargetDate = target
+		}
+		if req.TargetCount > 0 {
+			goal.TargetCount = req.TargetCount
+		}
+		if req.Metric != "" {
+			goal.Metric, goal.MetricFilter = req.Metric, req.MetricFilter
+		}
+		goal.Completed = req.Completed
+		goal.JobApplication = req.JobApplication
+		goal.CodingProblem = req.CodingProblem
+		goal.Project = req.Project
+		goal.Contact = req.Contact
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		id, err := ckdb.InsertGoal(ctx, dbConn, goal.Period, goal)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid goal type") {
+				writeError(w, http.StatusBadRequest, "invalid goal type")
+				return
+			}
+			var fieldErr *ckdb.FieldError
+			if errors.As(err, &fieldErr) {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "failed to create goal")
+			return
+		}
+		writeJSON(w, map[string]any{"id": id})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	ckdb "career-koala/db"
+)
+
+type codingAttemptRequest struct {
+	AttemptedOn  string `json:"attempted_on"`
+	Outcome      string `json:"outcome"`
+	MinutesSpent *int   `json:"minutes_spent"`
+	Confidence   *int   `json:"confidence"`
+	Notes        string `json:"notes"`
+}
+
+func codingAttemptsHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "invalid id")
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		if _, err := ckdb.GetCodingProblem(ctx, dbConn, id); err != nil {
+			writeItemError(w, err, "coding problem", "fetch")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			attempts, err := ckdb.ListCodingAttempts(ctx, dbConn, id)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "failed to fetch attempts")
+				return
+			}
+			writeJSON(w, map[string]any{"attempts": attempts, "review": ckdb.ScheduleReview(attempts)})
+		case http.MethodPost:
+			var req codingAttemptRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid json")
+				return
+			}
+			attempt := ckdb.CodingAttempt{
+				CodingProblemID: id,
+				Outcome:         req.Outcome,
+				MinutesSpent:    req.MinutesSpent,
+				Confidence:      req.Confidence,
+				Notes:           req.Notes,
+			}
+			if strings.TrimSpace(req.AttemptedOn) != "" {
+				attempt.AttemptedOn, err = time.Parse("2006-01-02", strings.TrimSpace(req.AttemptedOn))
+				if err != nil {
+					writeError(w, http.StatusBadRequest, "invalid attempted_on")
+					return
+				}
+			}
+			attemptID, err := ckdb.InsertCodingAttempt(ctx, dbConn, attempt)
+			if err != nil {
+				writeItemError(w, err, "attempt", "record")
+				return
+			}
+			writeJSON(w, map[string]any{"id": attemptID})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
+
+func codingDueHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		asOf := time.Now().UTC()
+		if raw := r.URL.Query().Get("date"); raw != "" {
+			tm, err := time.Parse("2006-01-02", raw)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "invalid date")
+				return
+			}
+			asOf = tm
+		}
+		limit, ok := queryInt(w, r, "limit")
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		due, err := ckdb.ListDueReviews(ctx, dbConn, asOf, limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to fetch due reviews")
+			return
+		}
+		writeJSON(w, due)
+	}
+}
@@ -0,0 +1,238 @@
+// Package scheduler runs periodic background tasks in-process. Replicas
+// elect a leader with a Postgres advisory lock so each task fires once no
+// matter how many API instances are running, and the last run of every task
+// is kept in scheduler_runs so a new leader picks up where the old one
+// stopped.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// DefaultLockKey is the advisory lock id the leader holds.
+const DefaultLockKey int64 = 0x6b6f616c61 // "koala"
+
+// Task is one periodic job. Run returns a short summary for the log (empty
+// when there was nothing to do).
+type Task struct {
+	Name  string
+	Every time.Duration
+	Run   func(ctx context.Context) (string, error)
+}
+
+// Lock decides which replica is the leader. AdvisoryLock is the Postgres
+// implementation.
+type Lock interface {
+	// Lead reports whether this replica is the leader, taking the lock if
+	// nobody holds it.
+	Lead(ctx context.Context) (bool, error)
+	// Resign releases the lock if this replica holds it.
+	Resign()
+}
+
+// Scheduler runs Tasks on the replica holding the lock.
+type Scheduler struct {
+	DB    *sql.DB
+	Tasks []Task
+	// Lock defaults to an AdvisoryLock on DB with LockKey, which defaults to
+	// DefaultLockKey; Tick (how often leadership and due tasks are checked)
+	// to a minute; Timeout (per task run) to five minutes.
+	Lock    Lock
+	LockKey int64
+	Tick    time.Duration
+	Timeout time.Duration
+
+	runs runLog
+}
+
+// runLog keeps the last run of every task; dbRunLog is the scheduler_runs
+// implementation.
+type runLog interface {
+	lastRuns(ctx context.Context) (map[string]time.Time, error)
+	record(ctx context.Context, task string, started time.Time, runErr error) error
+}
+
+// Run checks for due tasks every Tick until ctx is done, then releases
+// leadership.
+func (s *Scheduler) Run(ctx context.Context) {
+	if s.LockKey == 0 {
+		s.LockKey = DefaultLockKey
+	}
+	if s.Lock == nil {
+		s.Lock = &AdvisoryLock{DB: s.DB, Key: s.LockKey}
+	}
+	if s.runs == nil {
+		s.runs = dbRunLog{s.DB}
+	}
+	if s.Tick <= 0 {
+		s.Tick = time.Minute
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = 5 * time.Minute
+	}
+	defer s.Lock.Resign()
+	for {
+		if err := s.runOnce(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.Tick):
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, now time.Time) error {
+	leader, err := s.Lock.Lead(ctx)
+	if err != nil || !leader {
+		return err
+	}
+	lastRuns, err := s.runs.lastRuns(ctx)
+	if err != nil {
+		return err
+	}
+	for i, t := range dueTasks(s.Tasks, lastRuns, now) {
+		// A leader that lost the lock mid-tick leaves the rest to the new one.
+		if i > 0 {
+			if leader, err := s.Lock.Lead(ctx); err != nil || !leader {
+				return err
+			}
+		}
+		s.runTask(ctx, t)
+	}
+	return nil
+}
+
+// AdvisoryLock is a Lock held as a Postgres session-level advisory lock.
+type AdvisoryLock struct {
+	DB  *sql.DB
+	Key int64
+
+	conn *sql.Conn
+}
+
+// Lead takes the advisory lock if nobody holds it. The lock lives as long as
+// its session, so the connection is kept out of the pool and checked on
+// every call.
+func (l *AdvisoryLock) Lead(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		log.Printf("scheduler: lost the leader connection, re-electing")
+		l.conn.Close()
+		l.conn = nil
+	}
+	conn, err := l.DB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var got bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.Key).Scan(&got); err != nil || !got {
+		conn.Close()
+		return false, err
+	}
+	log.Printf("scheduler: elected leader")
+	l.conn = conn
+	return true, nil
+}
+
+// Resign unlocks and returns the leader connection.
+func (l *AdvisoryLock) Resign() {
+	if l.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.Key); err != nil {
+		log.Printf("scheduler: release leadership: %v", err)
+	}
+	l.conn.Close()
+	l.conn = nil
+}
+
+type dbRunLog struct {
+	db *sql.DB
+}
+
+func (r dbRunLog) lastRuns(ctx context.Context) (map[string]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT task, last_run_at FROM scheduler_runs`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := map[string]time.Time{}
+	for rows.Next() {
+		var name string
+		var at time.Time
+		if err := rows.Scan(&name, &at); err != nil {
+			return nil, err
+		}
+		res[name] = at
+	}
+	return res, rows.Err()
+}
+
+func (r dbRunLog) record(ctx context.Context, task string, started time.Time, runErr error) error {
+	var lastError *string
+	if runErr != nil {
+		msg := runErr.Error()
+		lastError = &msg
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO scheduler_runs (task, last_run_at, duration_ms, last_error) VALUES ($1,$2,$3,$4)
+         ON CONFLICT (task) DO UPDATE SET last_run_at = EXCLUDED.last_run_at, duration_ms = EXCLUDED.duration_ms, last_error = EXCLUDED.last_error`,
+		task, started, time.Since(started).Milliseconds(), lastError)
+	return err
+}
+
+func (s *Scheduler) runTask(ctx context.Context, t Task) {
+	started := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+	summary, err := safeRun(runCtx, t)
+	cancel()
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		log.Printf("scheduler: %s: %v", t.Name, err)
+	} else if summary != "" {
+		log.Printf("scheduler: %s: %s", t.Name, summary)
+	}
+	if err := s.runs.record(ctx, t.Name, started, err); err != nil {
+		log.Printf("scheduler: record %s run: %v", t.Name, err)
+	}
+}
+
+// safeRun keeps a panicking task from taking the server down.
+func safeRun(ctx context.Context, t Task) (summary string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	if t.Run == nil {
+		return "", errors.New("no Run function")
+	}
+	return t.Run(ctx)
+}
+
+// dueTasks returns the tasks that never ran or last ran at least Every ago.
+// A failed run counts as a run, so a broken task is retried on its normal
+// schedule rather than every tick.
+func dueTasks(tasks []Task, lastRuns map[string]time.Time, now time.Time) []Task {
+	var due []Task
+	for _, t := range tasks {
+		last, ok := lastRuns[t.Name]
+		if !ok || !now.Before(last.Add(t.Every)) {
+			due = append(due, t)
+		}
+	}
+	return due
+}
@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDueTasks(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{Name: "never_ran", Every: time.Hour},
+		{Name: "ran_recently", Every: time.Hour},
+		{Name: "exactly_due", Every: time.Hour},
+		{Name: "overdue", Every: 24 * time.Hour},
+	}
+	lastRuns := map[string]time.Time{
+		"ran_recently": now.Add(-30 * time.Minute),
+		"exactly_due":  now.Add(-time.Hour),
+		"overdue":      now.Add(-48 * time.Hour),
+		"removed_task": now.Add(-72 * time.Hour),
+	}
+	var got []string
+	for _, task := range dueTasks(tasks, lastRuns, now) {
+		got = append(got, task.Name)
+	}
+	want := []string{"never_ran", "exactly_due", "overdue"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("dueTasks = %v, want %v", got, want)
+	}
+}
+
+func TestSafeRunRecoversPanics(t *testing.T) {
+	_, err := safeRun(context.Background(), Task{Name: "boom", Run: func(context.Context) (string, error) {
+		panic("boom")
+	}})
+	if err == nil || err.Error() != "panic: boom" {
+		t.Fatalf("safeRun error = %v, want panic: boom", err)
+	}
+	if _, err := safeRun(context.Background(), Task{Name: "empty"}); err == nil {
+		t.Fatal("expected an error for a task without Run")
+	}
+}
+
+// scriptedLock answers Lead from leads in turn, repeating the last answer.
+type scriptedLock struct {
+	leads    []bool
+	calls    int
+	resigned bool
+}
+
+func (l *scriptedLock) Lead(context.Context) (bool, error) {
+	i := min(l.calls, len(l.leads)-1)
+	l.calls++
+	return l.leads[i], nil
+}
+
+func (l *scriptedLock) Resign() { l.resigned = true }
+
+type memRunLog map[string]time.Time
+
+func (m memRunLog) lastRuns(context.Context) (map[string]time.Time, error) {
+	return maps.Clone(m), nil
+}
+
+func (m memRunLog) record(_ context.Context, task string, started time.Time, _ error) error {
+	m[task] = started
+	return nil
+}
+
+func countingTask(name string, runs *[]string) Task {
+	return Task{Name: name, Every: time.Hour, Run: func(context.Context) (string, error) {
+		*runs = append(*runs, name)
+		return "", nil
+	}}
+}
+
+func TestFollowerNeverRunsTasks(t *testing.T) {
+	var runs []string
+	s := &Scheduler{
+		Tasks:   []Task{countingTask("digest", &runs)},
+		Lock:    &scriptedLock{leads: []bool{false}},
+		Timeout: time.Second,
+		runs:    memRunLog{},
+	}
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := s.runOnce(context.Background(), now.Add(time.Duration(i)*2*time.Hour)); err != nil {
+			t.Fatalf("runOnce: %v", err)
+		}
+	}
+	if len(runs) != 0 {
+		t.Fatalf("follower ran %v", runs)
+	}
+}
+
+func TestLeaderStopsWhenLockIsLost(t *testing.T) {
+	var runs []string
+	lock := &scriptedLock{leads: []bool{true, false, true}}
+	s := &Scheduler{
+		Tasks:   []Task{countingTask("digest", &runs)},
+		Lock:    lock,
+		Timeout: time.Second,
+		runs:    memRunLog{},
+	}
+	ctx := context.Background()
+	now := time.Now()
+	// Leader: runs the due task.
+	if err := s.runOnce(ctx, now); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+	// Lost the lock: the task is due again but another replica owns it.
+	if err := s.runOnce(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+	if !reflect.DeepEqual(runs, []string{"digest"}) {
+		t.Fatalf("runs after losing the lock = %v", runs)
+	}
+	// Re-elected: runs again.
+	if err := s.runOnce(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+	if !reflect.DeepEqual(runs, []string{"digest", "digest"}) {
+		t.Fatalf("runs after re-election = %v", runs)
+	}
+}
+
+func TestLeaderLosingLockMidTickSkipsRemainingTasks(t *testing.T) {
+	var runs []string
+	s := &Scheduler{
+		Tasks:   []Task{countingTask("digest", &runs), countingTask("nudges", &runs)},
+		Lock:    &scriptedLock{leads: []bool{true, false}},
+		Timeout: time.Second,
+		runs:    memRunLog{},
+	}
+	if err := s.runOnce(context.Background(), time.Now()); err != nil {
+		t.Fatalf("runOnce: %v", err)
+	}
+	if !reflect.DeepEqual(runs, []string{"digest"}) {
+		t.Fatalf("runs = %v, want only the task started while leader", runs)
+	}
+}
+
+func TestRunResignsOnShutdown(t *testing.T) {
+	lock := &scriptedLock{leads: []bool{false}}
+	s := &Scheduler{Lock: lock, Tick: time.Millisecond, runs: memRunLog{}}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-done
+	if !lock.resigned {
+		t.Fatal("expected Run to resign on shutdown")
+	}
+	if lock.calls < 2 {
+		t.Fatalf("Lead called %d times, want a retry every tick", lock.calls)
+	}
+}
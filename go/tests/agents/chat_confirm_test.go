@@ -2,8 +2,11 @@ package agenttests
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"strings"
+	"sync"
 	"testing"
 
 	"career-koala/agents"
@@ -14,7 +17,7 @@ func TestMaybeCaptureWriteStoresPending(t *testing.T) {
 	ctx := context.Background()
 	reply := "```json\n{\"write_requests\":[{\"action\":\"insert\",\"table\":\"job_applications\",\"records\":[{\"job_title\":\"Engineer\"}]}]}\n```"
 
-	prompt, ok := agents.MaybeCaptureWrite("u1", "s1", []string{reply})
+	prompt, ok := agents.MaybeCaptureWrite(ctx, "u1", "s1", []string{reply}, nil)
 	if !ok {
 		t.Fatalf("expected write capture")
 	}
@@ -35,7 +38,7 @@ func TestMaybeCaptureWriteStoresPending(t *testing.T) {
 func TestHandlePendingWriteDecline(t *testing.T) {
 	ctx := context.Background()
 	reply := "```json\n{\"write_requests\":[{\"action\":\"insert\",\"table\":\"job_applications\",\"records\":[{\"job_title\":\"Engineer\"}]}]}\n```"
-	if _, ok := agents.MaybeCaptureWrite("u2", "s2", []string{reply}); !ok {
+	if _, ok := agents.MaybeCaptureWrite(ctx, "u2", "s2", []string{reply}, nil); !ok {
 		t.Fatalf("expected write capture")
 	}
 
@@ -56,7 +59,7 @@ func TestHandlePendingWriteDecline(t *testing.T) {
 func TestHandlePendingWritePrompt(t *testing.T) {
 	ctx := context.Background()
 	reply := "```json\n{\"write_requests\":[{\"action\":\"insert\",\"table\":\"job_applications\",\"records\":[{\"job_title\":\"Engineer\"}]}]}\n```"
-	if _, ok := agents.MaybeCaptureWrite("u3", "s3", []string{reply}); !ok {
+	if _, ok := agents.MaybeCaptureWrite(ctx, "u3", "s3", []string{reply}, nil); !ok {
 		t.Fatalf("expected write capture")
 	}
 
@@ -73,7 +76,7 @@ func TestHandlePendingWritePrompt(t *testing.T) {
 func TestHandlePendingWriteApplyWithNilDB(t *testing.T) {
 	ctx := context.Background()
 	reply := "```json\n{\"write_requests\":[{\"action\":\"insert\",\"table\":\"job_applications\",\"records\":[{\"job_title\":\"Engineer\"}]}]}\n```"
-	if _, ok := agents.MaybeCaptureWrite("u4", "s4", []string{reply}); !ok {
+	if _, ok := agents.MaybeCaptureWrite(ctx, "u4", "s4", []string{reply}, nil); !ok {
 		t.Fatalf("expected write capture")
 	}
 
@@ -108,3 +111,112 @@ func TestWritePayloadDecodeJSONNumber(t *testing.T) {
 		t.Fatalf("unexpected payload: %s", string(data))
 	}
 }
+
+type recordingPendingStore struct {
+	agents.PendingWriteStore
+	resolved []string
+}
+
+func (r *recordingPendingStore) Resolve(ctx context.Context, id int64, status string) (bool, error) {
+	r.resolved = append(r.resolved, status)
+	return r.PendingWriteStore.Resolve(ctx, id, status)
+}
+
+func TestPendingWriteStoreRecordsResolution(t *testing.T) {
+	store := &recordingPendingStore{PendingWriteStore: agents.NewMemoryPendingWriteStore()}
+	agents.SetPendingWriteStore(store)
+	defer agents.SetPendingWriteStore(agents.NewMemoryPendingWriteStore())
+
+	ctx := context.Background()
+	reply := "```json\n{\"write_requests\":[{\"action\":\"insert\",\"table\":\"projects\",\"records\":[{\"name\":\"Koala\"}]}]}\n```"
+	if _, ok := agents.MaybeCaptureWrite(ctx, "u5", "s5", []string{reply}, nil); !ok {
+		t.Fatalf("expected write capture")
+	}
+	pending, err := store.Get(ctx, "u5", "s5")
+	if err != nil || pending == nil {
+		t.Fatalf("expected stored pending write, got %v (err=%v)", pending, err)
+	}
+	if pending.ExpiresAt.IsZero() || pending.Summary != "insert 1 -> projects" {
+		t.Fatalf("unexpected pending write: %+v", pending)
+	}
+
+	if _, handled := agents.HandlePendingWrite(ctx, "u5", "s5", "no thanks", nil); !handled {
+		t.Fatalf("expected pending write to be handled")
+	}
+	if len(store.resolved) != 1 || store.resolved[0] != ckdb.PendingWriteDeclined {
+		t.Fatalf("unexpected resolutions: %v", store.resolved)
+	}
+	if _, handled := agents.HandlePendingWrite(ctx, "u5", "s5", "yes", nil); handled {
+		t.Fatalf("expected pending write to be cleared")
+	}
+}
+
+// applyCountingStore applies writes without a database, counting them. Like
+// the real stores it resolves a write before applying it.
+type applyCountingStore struct {
+	agents.PendingWriteStore
+	mu      sync.Mutex
+	applied int
+	err     error
+}
+
+func (s *applyCountingStore) Apply(ctx context.Context, pw *ckdb.PendingWrite, dbConn *sql.DB) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	if ok, err := s.PendingWriteStore.Resolve(ctx, pw.ID, ckdb.PendingWriteApplied); err != nil || !ok {
+		return "", ckdb.ErrPendingWriteResolved
+	}
+	s.applied++
+	return "1 records (1 projects)", nil
+}
+
+func TestHandlePendingWriteAppliesOnce(t *testing.T) {
+	store := &applyCountingStore{PendingWriteStore: agents.NewMemoryPendingWriteStore()}
+	agents.SetPendingWriteStore(store)
+	defer agents.SetPendingWriteStore(agents.NewMemoryPendingWriteStore())
+
+	ctx := context.Background()
+	reply := "```json\n{\"write_requests\":[{\"action\":\"insert\",\"table\":\"projects\",\"records\":[{\"name\":\"Koala\"}]}]}\n```"
+	if _, ok := agents.MaybeCaptureWrite(ctx, "u6", "s6", []string{reply}, nil); !ok {
+		t.Fatalf("expected write capture")
+	}
+
+	// Two "yes" replies racing (a retry, or two replicas): only one applies.
+	var wg sync.WaitGroup
+	replies := make([]string, 2)
+	for i := range replies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replies[i], _ = agents.HandlePendingWrite(ctx, "u6", "s6", "yes", &sql.DB{})
+		}()
+	}
+	wg.Wait()
+	if store.applied != 1 {
+		t.Fatalf("applied %d times, want 1", store.applied)
+	}
+	for _, got := range replies {
+		if !strings.HasPrefix(got, "Applied writes") && got != "That write request was already handled." && got != "" {
+			t.Fatalf("unexpected reply: %q", got)
+		}
+	}
+	if _, handled := agents.HandlePendingWrite(ctx, "u6", "s6", "yes", &sql.DB{}); handled {
+		t.Fatalf("expected the applied write to be cleared")
+	}
+
+	// A write that fails to apply is resolved as failed, not left to be
+	// applied by the next "yes".
+	if _, ok := agents.MaybeCaptureWrite(ctx, "u6", "s6", []string{reply}, nil); !ok {
+		t.Fatalf("expected write capture")
+	}
+	store.err = errors.New("boom")
+	if got, _ := agents.HandlePendingWrite(ctx, "u6", "s6", "yes", &sql.DB{}); !strings.Contains(got, "boom") {
+		t.Fatalf("unexpected reply: %q", got)
+	}
+	if _, handled := agents.HandlePendingWrite(ctx, "u6", "s6", "yes", &sql.DB{}); handled {
+		t.Fatalf("expected the failed write to be cleared")
+	}
+}
@@ -0,0 +1,21 @@
+package agenttests
+
+import (
+	"testing"
+
+	"career-koala/agents"
+)
+
+func TestGoalsAgentMockResponse(t *testing.T) {
+	reply := "mock goals response: 3 of 4 daily goals done this week"
+	model := fakeLLM{reply: reply}
+	goalsAgent, err := agents.NewGoalsAgent(model, nil)
+	if err != nil {
+		t.Fatalf("new goals agent: %v", err)
+	}
+
+	replies := runAgent(t, goalsAgent, "How are my goals going?")
+	if replies[0] != reply {
+		t.Fatalf("unexpected reply: %q", replies[0])
+	}
+}
@@ -0,0 +1,21 @@
+package agenttests
+
+import (
+	"testing"
+
+	"career-koala/agents"
+)
+
+func TestMeetingsAgentMockResponse(t *testing.T) {
+	reply := "mock meetings response: onsite tomorrow at 10:00"
+	model := fakeLLM{reply: reply}
+	meetingsAgent, err := agents.NewMeetingsAgent(model, nil)
+	if err != nil {
+		t.Fatalf("new meetings agent: %v", err)
+	}
+
+	replies := runAgent(t, meetingsAgent, "What meetings do I have this week?")
+	if replies[0] != reply {
+		t.Fatalf("unexpected reply: %q", replies[0])
+	}
+}
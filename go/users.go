@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"career-koala/auth"
+	ckdb "career-koala/db"
+	"career-koala/notify"
+)
+
+// userHeader names the caller of an unauthenticated API request when auth
+// is disabled. Requests without it act as ckdb.DefaultUserName, which owns
+// the data from before users existed.
+const userHeader = "X-User-ID"
+
+type trustNamesKey struct{}
+
+// namesTrusted reports whether a request without a token may name the user
+// it acts for (X-User-ID, the chat user_id), which is only when auth is
+// disabled.
+func namesTrusted(ctx context.Context) bool {
+	trusted, _ := ctx.Value(trustNamesKey{}).(bool)
+	return trusted
+}
+
+// requestUser returns the user a request acts for, creating it on first
+// use: the one its token authenticated, else the one it names if names are
+// trusted, else the default user. A JWT caller is looked up by issuer and
+// subject, never by name.
+func requestUser(ctx context.Context, dbConn *sql.DB, r *http.Request) (ckdb.User, error) {
+	qctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	name := ckdb.DefaultUserName
+	if id, ok := auth.FromContext(r.Context()); ok {
+		if id.Method == "jwt" {
+			return ckdb.EnsureIdentityUser(qctx, dbConn, id.Issuer, id.User)
+		}
+		name = id.User
+	} else if header := strings.TrimSpace(r.Header.Get(userHeader)); header != "" && namesTrusted(r.Context()) {
+		name = header
+	}
+	return ckdb.EnsureUser(qctx, dbConn, name)
+}
+
+// withUser scopes the database queries of every request to its caller,
+// creating the user on first use. trustNames lets requests without a token
+// name their user; it must only be set when auth is disabled.
+func withUser(dbConn *sql.DB, trustNames bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(context.WithValue(r.Context(), trustNamesKey{}, trustNames))
+		u, err := requestUser(r.Context(), dbConn, r)
+		if err != nil {
+			var fieldErr *ckdb.FieldError
+			if errors.As(err, &fieldErr) {
+				writeError(w, http.StatusBadRequest, fieldErr.Error())
+				return
+			}
+			log.Printf("resolve user: %v", err)
+			writeError(w, http.StatusInternalServerError, "failed to resolve user")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ckdb.WithUser(r.Context(), u.ID)))
+	})
+}
+
+// lookupAPIToken resolves personal API tokens for the auth middleware.
+func lookupAPIToken(dbConn *sql.DB) auth.TokenLookup {
+	return func(ctx context.Context, token string) (string, error) {
+		u, err := ckdb.LookupAPIToken(ctx, dbConn, token)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", auth.ErrInvalidToken
+		}
+		return u.Name, err
+	}
+}
+
+// meHandler shows the calling user (GET) and sets where their alerts and
+// digest are delivered (PATCH {"notify_email", "notify_webhook_url"}; a
+// field left out keeps its value, an empty one turns the channel off).
+func meHandler(dbConn *sql.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+		u, err := ckdb.GetCurrentUser(ctx, dbConn)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "failed to fetch user")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, u)
+		case http.MethodPatch:
+			req := struct {
+				NotifyEmail      *string `json:"notify_email"`
+				NotifyWebhookURL *string `json:"notify_webhook_url"`
+			}{&u.NotifyEmail, &u.NotifyWebhookURL}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid json")
+				return
+			}
+			// The scheduler posts to the webhook from inside the network, so
+			// a user may only point it at a public address.
+			if webhookURL := strings.TrimSpace(u.NotifyWebhookURL); webhookURL != "" {
+				if err := notify.CheckPublicURL(ctx, webhookURL); err != nil {
+					writeError(w, http.StatusBadRequest, "notify_webhook_url: "+err.Error())
+					return
+				}
+			}
+			u, err = ckdb.SetNotifyTargets(ctx, dbConn, u.NotifyEmail, u.NotifyWebhookURL)
+			if err != nil {
+				var fieldErr *ckdb.FieldError
+				if errors.As(err, &fieldErr) {
+					writeError(w, http.StatusBadRequest, fieldErr.Error())
+					return
+				}
+				writeError(w, http.StatusInternalServerError, "failed to update user")
+				return
+			}
+			writeJSON(w, u)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}
+}
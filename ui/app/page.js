@@ -48,6 +48,30 @@ const getFallbackApiBase = () => {
   return `${window.location.origin}/api`;
 };
 
+// RECORD_SOURCES maps each records tab to its cursor-paged list endpoint and
+// its total in the /data counts.
+const RECORD_SOURCES = {
+  jobs: { path: "/jobs", countKey: "job_applications" },
+  coding: { path: "/coding", countKey: "coding_problems" },
+  projects: { path: "/projects", countKey: "projects" },
+  networking: { path: "/contacts", countKey: "networking_contacts" },
+  daily_goals: { path: "/goals/daily?sort=-target_date", countKey: "daily_goals" },
+  weekly_goals: { path: "/goals/weekly?sort=-target_date", countKey: "weekly_goals" },
+  monthly_goals: { path: "/goals/monthly?sort=-target_date", countKey: "monthly_goals" },
+  meetings: { path: "/meetings?sort=-session_time", countKey: "meetings" },
+};
+
+const RECORD_PAGE_SIZE = 200;
+
+// The API requires a bearer token unless AUTH_REQUIRED=false; the UI sends
+// the personal API token saved here (see the README for minting one).
+const TOKEN_STORAGE_KEY = "careerKoalaApiToken";
+
+const getSavedToken = () => {
+  if (typeof window === "undefined") return "";
+  return window.localStorage.getItem(TOKEN_STORAGE_KEY) || "";
+};
+
 export default function Page() {
   const [sessionId, setSessionId] = useState("");
   const [apiBase, setApiBase] = useState(getFallbackApiBase);
@@ -58,16 +82,49 @@ export default function Page() {
   const [busy, setBusy] = useState(false);
   const [errors, setErrors] = useState([]);
   const [snapshot, setSnapshot] = useState(null);
+  const [records, setRecords] = useState({});
   const [loadingSnapshot, setLoadingSnapshot] = useState(false);
   const [activeSection, setActiveSection] = useState("jobs");
   const [searchText, setSearchText] = useState("");
   const [searchQuery, setSearchQuery] = useState("");
   const [pagination, setPagination] = useState({});
+  const [apiToken, setApiToken] = useState(getSavedToken);
+  const [tokenInput, setTokenInput] = useState(getSavedToken);
 
   const append = (role, text) => {
     setChatLog((prev) => [...prev, { role, text }]);
   };
 
+  const saveToken = () => {
+    const token = tokenInput.trim();
+    if (token) {
+      window.localStorage.setItem(TOKEN_STORAGE_KEY, token);
+    } else {
+      window.localStorage.removeItem(TOKEN_STORAGE_KEY);
+    }
+    setApiToken(token);
+  };
+
+  const authHeaders = (headers = {}) =>
+    apiToken ? { ...headers, Authorization: `Bearer ${apiToken}` } : headers;
+
+  const readResponse = async (resp) => {
+    let data = {};
+    try {
+      data = await resp.json();
+    } catch (err) {
+    }
+    if (resp.status === 401) {
+      throw new Error(
+        apiToken ? "API token rejected; check the token saved above." : "Sign in: save an API token above."
+      );
+    }
+    if (!resp.ok) {
+      throw new Error(data.error || "Request failed");
+    }
+    return data;
+  };
+
   useEffect(() => {
     let ignore = false;
     const fetchRuntimeConfig = async () => {
@@ -111,18 +168,26 @@ export default function Page() {
     }
     const resp = await fetch(`${apiBase}${path}`, {
       method,
-      headers: { "Content-Type": "application/json" },
+      headers: authHeaders({ "Content-Type": "application/json" }),
       body: JSON.stringify(payload),
     });
-    let data = {};
-    try {
-      data = await resp.json();
-    } catch (err) {
-    }
-    if (!resp.ok) {
-      throw new Error(data.error || "Request failed");
+    return readResponse(resp);
+  };
+
+  const getJSON = async (path) => {
+    if (!apiBase) {
+      throw new Error("API base not set");
     }
-    return data;
+    const resp = await fetch(`${apiBase}${path}`, { headers: authHeaders() });
+    return readResponse(resp);
+  };
+
+  const fetchRecordPage = async (key, cursor = "") => {
+    const { path } = RECORD_SOURCES[key];
+    const sep = path.includes("?") ? "&" : "?";
+    const query = `limit=${RECORD_PAGE_SIZE}${cursor ? `&cursor=${encodeURIComponent(cursor)}` : ""}`;
+    const page = await getJSON(`${path}${sep}${query}`);
+    return { items: page.items || [], nextCursor: page.next_cursor || "" };
   };
 
   const sendMessage = async (message) => {
@@ -144,13 +209,7 @@ export default function Page() {
     setBusy(true);
     try {
       if (!apiBase) throw new Error("API base not set");
-      const resp = await fetch(`${apiBase}/chat`, {
-        method: "POST",
-        headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ message: outgoing, session_id: sessionId }),
-      });
-      const data = await resp.json();
-      if (!resp.ok) throw new Error(data.error || "Request failed");
+      const data = await postJSON("/chat", { message: outgoing, session_id: sessionId });
       if (data.session_id) setSessionId(data.session_id);
       append("me", outgoing);
       if (data.replies?.length) {
@@ -170,10 +229,15 @@ export default function Page() {
     setLoadingSnapshot(true);
     try {
       if (!apiBase) return;
-      const resp = await fetch(`${apiBase}/data`);
-      const data = await resp.json();
-      if (!resp.ok) throw new Error(data.error || "Failed to fetch data");
+      // /data only carries the newest rows and the real counts; the tables
+      // page through the list endpoints.
+      const keys = Object.keys(RECORD_SOURCES);
+      const [data, ...pages] = await Promise.all([
+        getJSON("/data"),
+        ...keys.map((key) => fetchRecordPage(key)),
+      ]);
       setSnapshot(data);
+      setRecords(Object.fromEntries(keys.map((key, idx) => [key, pages[idx]])));
     } catch (err) {
       setErrors((prev) => [...prev, err.message]);
     } finally {
@@ -184,7 +248,23 @@ export default function Page() {
   useEffect(() => {
     if (!apiBase) return;
     fetchSnapshot();
-  }, [apiBase]);
+  }, [apiBase, apiToken]);
+
+  const loadMoreRecords = async (key) => {
+    const current = records[key];
+    if (!current?.nextCursor) return;
+    setRecords((prev) => ({ ...prev, [key]: { ...prev[key], loading: true } }));
+    try {
+      const page = await fetchRecordPage(key, current.nextCursor);
+      setRecords((prev) => ({
+        ...prev,
+        [key]: { items: [...(prev[key]?.items || []), ...page.items], nextCursor: page.nextCursor },
+      }));
+    } catch (err) {
+      setErrors((prev) => [...prev, err.message]);
+      setRecords((prev) => ({ ...prev, [key]: { ...prev[key], loading: false } }));
+    }
+  };
 
   const handleChatSubmit = (e) => {
     e.preventDefault();
@@ -239,11 +319,17 @@ export default function Page() {
 
   const baseTabs = useMemo(() => {
     if (!snapshot) return [];
+    const recordTab = (key) => ({
+      items: records[key]?.items || [],
+      total: snapshot.counts?.[RECORD_SOURCES[key].countKey] ?? 0,
+      nextCursor: records[key]?.nextCursor || "",
+      loadingMore: Boolean(records[key]?.loading),
+    });
     return [
       {
         key: "jobs",
         label: "Jobs",
-        items: snapshot.job_applications || [],
+        ...recordTab("jobs"),
         columns: [
           { key: "job_title", label: "Title" },
           { key: "company", label: "Company" },
@@ -257,7 +343,7 @@ export default function Page() {
       {
         key: "coding",
         label: "Coding",
-        items: snapshot.coding_problems || [],
+        ...recordTab("coding"),
         columns: [
           { key: "leetcode_number", label: "Number" },
           { key: "title", label: "Title" },
@@ -271,7 +357,7 @@ export default function Page() {
       {
         key: "projects",
         label: "Projects",
-        items: snapshot.projects || [],
+        ...recordTab("projects"),
         columns: [
           { key: "name", label: "Name" },
           { key: "active", label: "Active" },
@@ -283,7 +369,7 @@ export default function Page() {
       {
         key: "networking",
         label: "Networking",
-        items: snapshot.networking_contacts || [],
+        ...recordTab("networking"),
         columns: [
           { key: "person_name", label: "Name" },
           { key: "company", label: "Company" },
@@ -296,7 +382,7 @@ export default function Page() {
       {
         key: "daily_goals",
         label: "Daily Goals",
-        items: snapshot.daily_goals || [],
+        ...recordTab("daily_goals"),
         goalType: "daily",
         columns: [
           { key: "description", label: "Description" },
@@ -307,7 +393,7 @@ export default function Page() {
       {
         key: "weekly_goals",
         label: "Weekly Goals",
-        items: snapshot.weekly_goals || [],
+        ...recordTab("weekly_goals"),
         goalType: "weekly",
         columns: [
           { key: "description", label: "Description" },
@@ -318,7 +404,7 @@ export default function Page() {
       {
         key: "monthly_goals",
         label: "Monthly Goals",
-        items: snapshot.monthly_goals || [],
+        ...recordTab("monthly_goals"),
         goalType: "monthly",
         columns: [
           { key: "description", label: "Description" },
@@ -329,7 +415,7 @@ export default function Page() {
       {
         key: "meetings",
         label: "Meetings",
-        items: snapshot.meetings || [],
+        ...recordTab("meetings"),
         columns: [
           { key: "session_name", label: "Session" },
           { key: "session_type", label: "Type" },
@@ -340,7 +426,7 @@ export default function Page() {
         ],
       },
     ];
-  }, [snapshot]);
+  }, [snapshot, records]);
 
   useEffect(() => {
     if (baseTabs.length === 0) return;
@@ -407,19 +493,15 @@ export default function Page() {
 
   const summaryStats = useMemo(() => {
     if (!snapshot) return [];
-    const total = totalGoals(snapshot);
-    const done = countDone([
-      ...(snapshot.daily_goals || []),
-      ...(snapshot.weekly_goals || []),
-      ...(snapshot.monthly_goals || []),
-    ]);
+    const counts = snapshot.counts || {};
+    const total = totalGoals(counts);
     return [
-      { label: "Applications", value: snapshot.job_applications?.length || 0 },
-      { label: "Coding Problems", value: snapshot.coding_problems?.length || 0 },
-      { label: "Projects", value: snapshot.projects?.length || 0 },
-      { label: "Contacts", value: snapshot.networking_contacts?.length || 0 },
-      { label: "Goals Done", value: `${done} / ${total}` },
-      { label: "Meetings", value: snapshot.meetings?.length || 0 },
+      { label: "Applications", value: counts.job_applications || 0 },
+      { label: "Coding Problems", value: counts.coding_problems || 0 },
+      { label: "Projects", value: counts.projects || 0 },
+      { label: "Contacts", value: counts.networking_contacts || 0 },
+      { label: "Goals Done", value: `${counts.goals_completed || 0} / ${total}` },
+      { label: "Meetings", value: counts.meetings || 0 },
     ];
   }, [snapshot]);
 
@@ -467,6 +549,19 @@ export default function Page() {
             <Text fontSize="xs" color="gray.500">
               Session: {sessionId || "new"}
             </Text>
+            <HStack spacing={2}>
+              <Input
+                type="password"
+                size="xs"
+                maxW="220px"
+                placeholder="API token"
+                value={tokenInput}
+                onChange={(e) => setTokenInput(e.target.value)}
+              />
+              <Button size="xs" onClick={saveToken} isDisabled={tokenInput.trim() === apiToken}>
+                {tokenInput.trim() || !apiToken ? "Save token" : "Clear token"}
+              </Button>
+            </HStack>
           </VStack>
         </Flex>
 
@@ -573,7 +668,7 @@ export default function Page() {
                         <HStack spacing={2}>
                           <Text>{tab.label}</Text>
                           <Badge colorScheme="gray">
-                            {"count" in tab ? tab.count : tab.items.length}
+                            {"count" in tab ? tab.count : tab.total}
                           </Badge>
                         </HStack>
                       </Tab>
@@ -686,6 +781,22 @@ export default function Page() {
                               onPageSizeChange={(size) => setPageSize(tab.key, size)}
                             />
                           )}
+                          {tab.nextCursor && (
+                            <HStack mt={4} spacing={3}>
+                              <Text fontSize="sm" color="gray.600">
+                                Loaded {tab.items.length} of {tab.total}
+                                {searchQuery ? "; search covers loaded records only" : ""}
+                              </Text>
+                              <Button
+                                size="sm"
+                                variant="outline"
+                                onClick={() => loadMoreRecords(tab.key)}
+                                isLoading={tab.loadingMore}
+                              >
+                                Load more
+                              </Button>
+                            </HStack>
+                          )}
                         </TabPanel>
                       );
                     })}
@@ -813,6 +924,19 @@ function EntryCard({ title, description, children }) {
   );
 }
 
+const JOB_STATUSES = [
+  "saved",
+  "applied",
+  "recruiter_screen",
+  "technical",
+  "onsite",
+  "offer",
+  "accepted",
+  "rejected",
+  "withdrawn",
+  "ghosted",
+];
+
 function JobForm({ onSubmit }) {
   const [state, setState] = useState({
     jobTitle: "",
@@ -897,10 +1021,17 @@ function JobForm({ onSubmit }) {
       </FormControl>
       <FormControl>
         <FormLabel>Status</FormLabel>
-        <Input
+        <Select
+          placeholder="Auto (saved / applied)"
           value={state.status}
           onChange={(e) => setState({ ...state, status: e.target.value })}
-        />
+        >
+          {JOB_STATUSES.map((status) => (
+            <option key={status} value={status}>
+              {status.replace("_", " ")}
+            </option>
+          ))}
+        </Select>
       </FormControl>
       <FormControl>
         <FormLabel>Notes</FormLabel>
@@ -1730,14 +1861,6 @@ function buildPagination(totalPages, currentPage) {
   return pages;
 }
 
-function countDone(goals) {
-  return goals.filter((g) => g.completed).length;
-}
-
-function totalGoals(snapshot) {
-  return (
-    (snapshot.daily_goals?.length || 0) +
-    (snapshot.weekly_goals?.length || 0) +
-    (snapshot.monthly_goals?.length || 0)
-  );
+function totalGoals(counts) {
+  return (counts.daily_goals || 0) + (counts.weekly_goals || 0) + (counts.monthly_goals || 0);
 }